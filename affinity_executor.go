@@ -0,0 +1,108 @@
+package cmdexec
+
+import (
+	"context"
+	"hash/fnv"
+	"maps"
+	"slices"
+	"strconv"
+)
+
+// AffinityExecutor routes executions across several backend executors so
+// that configurations with the same affinity key always land on the same
+// backend. This lets stateful tools (Bazel servers, git object caches,
+// remote build hosts) benefit from locality across repeated executions.
+//
+// Routing uses rendezvous hashing over backend names, so adding or removing
+// a backend only moves the keys that were assigned to it. Backends passed to
+// NewAffinityExecutor are named by position; use NewNamedAffinityExecutor
+// when backends may be removed from the middle of the list.
+type AffinityExecutor struct {
+	names    []string
+	backends []Executor
+	keyFunc  func(ToolConfig) string
+}
+
+// WorkingDirAffinity is the default affinity key function. It routes by
+// ToolConfig.WorkingDir, so commands for the same repository share a backend.
+func WorkingDirAffinity(cfg ToolConfig) string {
+	return cfg.WorkingDir
+}
+
+// NewAffinityExecutor creates an executor that routes across backends using
+// keyFunc. If keyFunc is nil, WorkingDirAffinity is used. At least one
+// backend is required; NewAffinityExecutor panics otherwise.
+func NewAffinityExecutor(keyFunc func(ToolConfig) string, backends ...Executor) *AffinityExecutor {
+	if len(backends) == 0 {
+		panic("cmdexec: NewAffinityExecutor requires at least one backend")
+	}
+	names := make([]string, len(backends))
+	for i := range backends {
+		names[i] = strconv.Itoa(i)
+	}
+	return newAffinityExecutor(keyFunc, names, slices.Clone(backends))
+}
+
+// NewNamedAffinityExecutor is like NewAffinityExecutor, but routes by the
+// backend names, such as host names. A key keeps its backend as long as that
+// backend's name stays in the map. NewNamedAffinityExecutor panics if
+// backends is empty.
+func NewNamedAffinityExecutor(keyFunc func(ToolConfig) string, backends map[string]Executor) *AffinityExecutor {
+	if len(backends) == 0 {
+		panic("cmdexec: NewNamedAffinityExecutor requires at least one backend")
+	}
+	names := slices.Sorted(maps.Keys(backends))
+	execs := make([]Executor, len(names))
+	for i, name := range names {
+		execs[i] = backends[name]
+	}
+	return newAffinityExecutor(keyFunc, names, execs)
+}
+
+func newAffinityExecutor(keyFunc func(ToolConfig) string, names []string, backends []Executor) *AffinityExecutor {
+	if keyFunc == nil {
+		keyFunc = WorkingDirAffinity
+	}
+	return &AffinityExecutor{
+		names:    names,
+		backends: backends,
+		keyFunc:  keyFunc,
+	}
+}
+
+// Execute runs cfg on the backend selected for its affinity key.
+func (a *AffinityExecutor) Execute(ctx context.Context, cfg ToolConfig) (*ExecutionResult, error) {
+	return a.Backend(cfg).Execute(ctx, cfg) //nolint:wrapcheck // delegation pattern
+}
+
+// IsAvailable reports whether command is available on every backend, since
+// any of them may be selected.
+func (a *AffinityExecutor) IsAvailable(command string) bool {
+	for _, b := range a.backends {
+		if !b.IsAvailable(command) {
+			return false
+		}
+	}
+	return true
+}
+
+// Backend returns the executor that cfg would be routed to.
+func (a *AffinityExecutor) Backend(cfg ToolConfig) Executor {
+	return a.backends[a.backendIndex(a.keyFunc(cfg))]
+}
+
+// backendIndex picks the backend with the highest rendezvous score for key.
+func (a *AffinityExecutor) backendIndex(key string) int {
+	best := 0
+	var bestScore uint64
+	for i, name := range a.names {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(key))
+		_, _ = h.Write([]byte{0})
+		_, _ = h.Write([]byte(name))
+		if score := h.Sum64(); i == 0 || score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	return best
+}
@@ -0,0 +1,115 @@
+package cmdexec
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestAffinityExecutor_StickyRouting(t *testing.T) {
+	backends := []*MockExecutor{NewMockExecutor(), NewMockExecutor(), NewMockExecutor()}
+	ae := NewAffinityExecutor(nil, backends[0], backends[1], backends[2])
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		if _, err := ae.Execute(ctx, ToolConfig{Command: "git", Args: []string{"status"}, WorkingDir: "/repo/a"}); err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+	}
+
+	used := 0
+	for _, b := range backends {
+		if n := len(b.GetCallHistory()); n > 0 {
+			used++
+			if n != 5 {
+				t.Errorf("backend received %d calls, want all 5", n)
+			}
+		}
+	}
+	if used != 1 {
+		t.Errorf("%d backends used for one key, want 1", used)
+	}
+}
+
+func TestAffinityExecutor_SpreadsKeys(t *testing.T) {
+	backends := []*MockExecutor{NewMockExecutor(), NewMockExecutor(), NewMockExecutor()}
+	ae := NewAffinityExecutor(nil, backends[0], backends[1], backends[2])
+	ctx := context.Background()
+
+	for i := 0; i < 60; i++ {
+		cfg := ToolConfig{Command: "git", WorkingDir: fmt.Sprintf("/repo/%d", i)}
+		if _, err := ae.Execute(ctx, cfg); err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+	}
+	for i, b := range backends {
+		if len(b.GetCallHistory()) == 0 {
+			t.Errorf("backend %d received no calls", i)
+		}
+	}
+}
+
+func TestAffinityExecutor_CustomKeyAndStability(t *testing.T) {
+	a, b, c := NewMockExecutor(), NewMockExecutor(), NewMockExecutor()
+	byCommand := func(cfg ToolConfig) string { return cfg.Command }
+
+	two := NewAffinityExecutor(byCommand, a, b)
+	three := NewAffinityExecutor(byCommand, a, b, c)
+
+	// Keys that stay on an existing backend after adding one must not move
+	// between the original backends.
+	for i := 0; i < 50; i++ {
+		cfg := ToolConfig{Command: fmt.Sprintf("tool-%d", i)}
+		before, after := two.Backend(cfg), three.Backend(cfg)
+		if after != c && after != before {
+			t.Errorf("key %q moved between original backends", cfg.Command)
+		}
+	}
+}
+
+func TestNamedAffinityExecutor_RemoveBackend(t *testing.T) {
+	a, b, c := NewMockExecutor(), NewMockExecutor(), NewMockExecutor()
+	byCommand := func(cfg ToolConfig) string { return cfg.Command }
+
+	three := NewNamedAffinityExecutor(byCommand, map[string]Executor{"a": a, "b": b, "c": c})
+	// Removing a backend from the middle must only move the keys it owned.
+	two := NewNamedAffinityExecutor(byCommand, map[string]Executor{"a": a, "c": c})
+	moved := 0
+	for i := 0; i < 50; i++ {
+		cfg := ToolConfig{Command: fmt.Sprintf("tool-%d", i)}
+		before, after := three.Backend(cfg), two.Backend(cfg)
+		if before == b {
+			moved++
+			continue
+		}
+		if after != before {
+			t.Errorf("key %q moved off a backend that was not removed", cfg.Command)
+		}
+	}
+	if moved == 0 {
+		t.Error("no key was routed to the removed backend")
+	}
+}
+
+func TestAffinityExecutor_IsAvailable(t *testing.T) {
+	a, b := NewMockExecutor(), NewMockExecutor()
+	a.SetAvailableCommand("bazel", true)
+	ae := NewAffinityExecutor(nil, a, b)
+
+	if ae.IsAvailable("bazel") {
+		t.Error("IsAvailable() = true, want false when a backend lacks the command")
+	}
+	b.SetAvailableCommand("bazel", true)
+	if !ae.IsAvailable("bazel") {
+		t.Error("IsAvailable() = false, want true when all backends have the command")
+	}
+}
+
+func TestNewAffinityExecutor_PanicsWithoutBackends(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic")
+		}
+	}()
+	NewAffinityExecutor(nil)
+}
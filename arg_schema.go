@@ -0,0 +1,50 @@
+package cmdexec
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ArgValidator checks the arguments of a command before it runs, for
+// example to keep destructive flags away from a known tool. Set it as
+// ToolConfig.ArgValidator; ToolConfig.Validate calls it with Args.
+type ArgValidator interface {
+	// ValidateArgs returns an error describing the first unacceptable
+	// argument, preferably a *ValidationError naming it.
+	ValidateArgs(args []string) error
+}
+
+// ArgSchema is an ArgValidator that accepts or rejects flags by name.
+// Arguments starting with "-" are flags, named by the text before any "=",
+// so "--output=x" is the flag "--output"; after a "--" argument, all
+// arguments are positional. Combined short flags such as "-rf" are
+// compared as written. Positional arguments are not checked.
+type ArgSchema struct {
+	// AllowedFlags, if not empty, are the only flags accepted.
+	AllowedFlags []string `json:"allowedFlags,omitempty"`
+
+	// ForbiddenFlags are rejected, even if AllowedFlags lists them.
+	ForbiddenFlags []string `json:"forbiddenFlags,omitempty"`
+}
+
+// ValidateArgs returns a *ValidationError for the first flag that is
+// forbidden or not allowed. Its Field names the argument, as in "Args[2]".
+func (s *ArgSchema) ValidateArgs(args []string) error {
+	for i, arg := range args {
+		if arg == "--" {
+			return nil
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			continue
+		}
+		flag, _, _ := strings.Cut(arg, "=")
+		switch {
+		case slices.Contains(s.ForbiddenFlags, flag):
+			return &ValidationError{Field: fmt.Sprintf("Args[%d]", i), Message: fmt.Sprintf("flag %s is forbidden", flag)}
+		case len(s.AllowedFlags) > 0 && !slices.Contains(s.AllowedFlags, flag):
+			return &ValidationError{Field: fmt.Sprintf("Args[%d]", i), Message: fmt.Sprintf("flag %s is not allowed", flag)}
+		}
+	}
+	return nil
+}
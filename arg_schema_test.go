@@ -0,0 +1,71 @@
+package cmdexec
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestArgSchemaValidateArgs(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name      string
+		schema    ArgSchema
+		args      []string
+		wantField string
+		wantMsg   string
+	}{
+		{name: "no flags", schema: ArgSchema{ForbiddenFlags: []string{"--force"}}, args: []string{"push", "origin"}},
+		{name: "forbidden", schema: ArgSchema{ForbiddenFlags: []string{"--force"}}, args: []string{"push", "--force"}, wantField: "Args[1]", wantMsg: "--force is forbidden"},
+		{name: "forbidden with value", schema: ArgSchema{ForbiddenFlags: []string{"--output"}}, args: []string{"--output=x"}, wantField: "Args[0]", wantMsg: "--output is forbidden"},
+		{name: "forbidden wins over allowed", schema: ArgSchema{AllowedFlags: []string{"-f"}, ForbiddenFlags: []string{"-f"}}, args: []string{"-f"}, wantField: "Args[0]", wantMsg: "-f is forbidden"},
+		{name: "allowed", schema: ArgSchema{AllowedFlags: []string{"-n", "--verbose"}}, args: []string{"-n", "file", "--verbose"}},
+		{name: "not allowed", schema: ArgSchema{AllowedFlags: []string{"-n"}}, args: []string{"-n", "-rf"}, wantField: "Args[1]", wantMsg: "-rf is not allowed"},
+		{name: "after double dash", schema: ArgSchema{ForbiddenFlags: []string{"--force"}}, args: []string{"--", "--force"}},
+		{name: "single dash is positional", schema: ArgSchema{AllowedFlags: []string{"-n"}}, args: []string{"-"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := tt.schema.ValidateArgs(tt.args)
+			if tt.wantField == "" {
+				if err != nil {
+					t.Fatalf("ValidateArgs() error = %v, want nil", err)
+				}
+				return
+			}
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("ValidateArgs() error = %v, want *ValidationError", err)
+			}
+			if validationErr.Field != tt.wantField || !strings.Contains(validationErr.Message, tt.wantMsg) {
+				t.Errorf("ValidateArgs() = %q %q, want %q containing %q", validationErr.Field, validationErr.Message, tt.wantField, tt.wantMsg)
+			}
+		})
+	}
+}
+
+type argValidatorFunc func([]string) error
+
+func (f argValidatorFunc) ValidateArgs(args []string) error { return f(args) }
+
+func TestToolConfigValidateArgValidator(t *testing.T) {
+	t.Parallel()
+	cfg := ToolConfig{
+		Command:      "git",
+		Args:         []string{"push", "--force"},
+		ArgValidator: &ArgSchema{ForbiddenFlags: []string{"--force"}},
+	}
+	_, err := NewBasicExecutor().Execute(context.Background(), cfg)
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || validationErr.Field != "Args[1]" {
+		t.Fatalf("Execute() error = %v, want ValidationError for Args[1]", err)
+	}
+
+	cfg.ArgValidator = argValidatorFunc(func([]string) error { return errors.New("too many arguments") })
+	err = cfg.Validate()
+	if !errors.As(err, &validationErr) || validationErr.Field != "Args" || validationErr.Message != "too many arguments" {
+		t.Fatalf("Validate() error = %v, want ValidationError for Args", err)
+	}
+}
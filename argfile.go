@@ -0,0 +1,129 @@
+package cmdexec
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ArgFileStyle selects how arguments written to an argument file are passed
+// to the command.
+type ArgFileStyle int
+
+const (
+	// ArgFileAt passes the file as a single "@path" argument, the
+	// convention used by gcc, clang, javac, and many other compilers.
+	// Arguments are written double-quoted and whitespace-separated.
+	ArgFileAt ArgFileStyle = iota
+
+	// ArgFileFlag passes the file as "<Flag> path" (for example
+	// "--args-file path"). Arguments are written one per line, so they
+	// must not contain newlines.
+	ArgFileFlag
+
+	// ArgFileXargs runs the command through "xargs -0 -a path", which
+	// splits the arguments into as many invocations as needed. Arguments
+	// are written NUL-separated. This requires GNU xargs.
+	ArgFileXargs
+)
+
+// ArgFileConfig configures passing long argument lists through a temporary
+// file instead of the command line, avoiding ARG_MAX limits.
+type ArgFileConfig struct {
+	// Style selects the tool-specific convention for passing the file.
+	Style ArgFileStyle
+
+	// Flag is the option that introduces the file for ArgFileFlag.
+	Flag string
+
+	// Threshold is the total size in bytes of the arguments (including a
+	// separator per argument) above which the file is used. Below it, the
+	// arguments are passed on the command line as usual. Zero means the
+	// file is always used.
+	Threshold int
+
+	// KeepArgs is the number of leading arguments kept on the command line
+	// (for example a subcommand such as "build"). The remaining arguments
+	// go into the file.
+	KeepArgs int
+}
+
+func (ac *ArgFileConfig) validate() error {
+	if ac.Style < ArgFileAt || ac.Style > ArgFileXargs {
+		return &ValidationError{Field: "ArgFile.Style", Message: "unknown argument file style"}
+	}
+	if ac.Style == ArgFileFlag && ac.Flag == "" {
+		return &ValidationError{Field: "ArgFile.Flag", Message: "flag is required for ArgFileFlag style"}
+	}
+	if ac.Threshold < 0 {
+		return &ValidationError{Field: "ArgFile.Threshold", Message: "threshold cannot be negative"}
+	}
+	if ac.KeepArgs < 0 {
+		return &ValidationError{Field: "ArgFile.KeepArgs", Message: "keepArgs cannot be negative"}
+	}
+	return nil
+}
+
+// applyArgFile rewrites cfg to pass its arguments through a temporary file
+// when cfg.ArgFile is set and the threshold is exceeded. The file is tracked
+// in res and removed when res is cleaned up.
+func applyArgFile(cfg ToolConfig, res *tempResources) (ToolConfig, error) {
+	ac := cfg.ArgFile
+	if ac == nil || (ac.Threshold > 0 && argsSize(cfg.Args) <= ac.Threshold) {
+		return cfg, nil
+	}
+
+	keep := min(ac.KeepArgs, len(cfg.Args))
+	kept, moved := cfg.Args[:keep], cfg.Args[keep:]
+
+	content, err := encodeArgFile(ac.Style, moved)
+	if err != nil {
+		return cfg, err
+	}
+	path, err := res.createFile("cmdexec-args-*", content)
+	if err != nil {
+		return cfg, err
+	}
+
+	out := cfg
+	switch ac.Style {
+	case ArgFileAt:
+		out.Args = append(append([]string(nil), kept...), "@"+path)
+	case ArgFileFlag:
+		out.Args = append(append([]string(nil), kept...), ac.Flag, path)
+	case ArgFileXargs:
+		out.Command = "xargs"
+		out.Args = append([]string{"-0", "-a", path, cfg.Command}, kept...)
+	}
+	return out, nil
+}
+
+// argsSize returns the number of bytes args occupy on a command line.
+func argsSize(args []string) int {
+	n := 0
+	for _, a := range args {
+		n += len(a) + 1
+	}
+	return n
+}
+
+func encodeArgFile(style ArgFileStyle, args []string) (string, error) {
+	var b strings.Builder
+	for _, a := range args {
+		switch style {
+		case ArgFileAt:
+			b.WriteString(`"`)
+			b.WriteString(strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(a))
+			b.WriteString("\"\n")
+		case ArgFileFlag:
+			if strings.ContainsAny(a, "\r\n") {
+				return "", &ValidationError{Field: "Args", Message: fmt.Sprintf("argument %q contains a newline and cannot be written to a line-based argument file", a)}
+			}
+			b.WriteString(a)
+			b.WriteString("\n")
+		case ArgFileXargs:
+			b.WriteString(a)
+			b.WriteByte(0)
+		}
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,113 @@
+package cmdexec
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestBasicExecutor_Execute_ArgFileAt(t *testing.T) {
+	// The script prints the argument file's path and contents; $0 is "@path".
+	result, err := NewBasicExecutor().Execute(context.Background(), ToolConfig{
+		Command: "sh",
+		Args:    []string{"-c", `echo "${0#@}"; cat "${0#@}"`, "plain", `with "quotes"`, `back\slash`},
+		ArgFile: &ArgFileConfig{Style: ArgFileAt, KeepArgs: 2},
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	path, contents, _ := strings.Cut(result.Output, "\n")
+	want := "\"plain\"\n\"with \\\"quotes\\\"\"\n\"back\\\\slash\"\n"
+	if contents != want {
+		t.Errorf("argfile contents = %q, want %q", contents, want)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("argument file %q was not removed", path)
+	}
+}
+
+func TestBasicExecutor_Execute_ArgFileFlag(t *testing.T) {
+	result, err := NewBasicExecutor().Execute(context.Background(), ToolConfig{
+		Command: "sh",
+		Args:    []string{"-c", `echo "$1"; cat "$2"`, "sh", "one", "two words"},
+		ArgFile: &ArgFileConfig{Style: ArgFileFlag, Flag: "--args-file", KeepArgs: 3},
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	want := "--args-file\none\ntwo words\n"
+	if result.Output != want {
+		t.Errorf("Output = %q, want %q", result.Output, want)
+	}
+}
+
+func TestBasicExecutor_Execute_ArgFileXargs(t *testing.T) {
+	result, err := NewBasicExecutor().Execute(context.Background(), ToolConfig{
+		Command: "echo",
+		Args:    []string{"a", "b c"},
+		ArgFile: &ArgFileConfig{Style: ArgFileXargs},
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.ExitCode != 0 {
+		t.Skipf("xargs -a not supported here: %s", result.Stderr)
+	}
+	if result.Output != "a b c\n" {
+		t.Errorf("Output = %q, want %q", result.Output, "a b c\n")
+	}
+	if result.Command != "echo" {
+		t.Errorf("result.Command = %q, want original command", result.Command)
+	}
+}
+
+func TestBasicExecutor_Execute_ArgFileBelowThreshold(t *testing.T) {
+	result, err := NewBasicExecutor().Execute(context.Background(), ToolConfig{
+		Command: "echo",
+		Args:    []string{"short"},
+		ArgFile: &ArgFileConfig{Style: ArgFileAt, Threshold: 1024},
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Output != "short\n" {
+		t.Errorf("Output = %q, want args passed directly", result.Output)
+	}
+}
+
+func TestBasicExecutor_Execute_ArgFileFlagRejectsNewlines(t *testing.T) {
+	_, err := NewBasicExecutor().Execute(context.Background(), ToolConfig{
+		Command: "echo",
+		Args:    []string{"multi\nline"},
+		ArgFile: &ArgFileConfig{Style: ArgFileFlag, Flag: "--args"},
+	})
+	var valErr *ValidationError
+	if !errors.As(err, &valErr) {
+		t.Fatalf("error = %v, want *ValidationError", err)
+	}
+}
+
+func TestArgFileConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name  string
+		cfg   ArgFileConfig
+		field string
+	}{
+		{"unknown style", ArgFileConfig{Style: ArgFileStyle(99)}, "ArgFile.Style"},
+		{"flag style without flag", ArgFileConfig{Style: ArgFileFlag}, "ArgFile.Flag"},
+		{"negative threshold", ArgFileConfig{Threshold: -1}, "ArgFile.Threshold"},
+		{"negative keep", ArgFileConfig{KeepArgs: -1}, "ArgFile.KeepArgs"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := ToolConfig{Command: "echo", ArgFile: &tt.cfg}
+			var valErr *ValidationError
+			if err := cfg.Validate(); !errors.As(err, &valErr) || valErr.Field != tt.field {
+				t.Errorf("Validate() error = %v, want ValidationError on %s", err, tt.field)
+			}
+		})
+	}
+}
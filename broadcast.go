@@ -0,0 +1,247 @@
+package cmdexec
+
+import (
+	"bytes"
+	"io"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// SlowConsumerPolicy selects what a Broadcaster does when a subscriber's
+// queue is full because it consumes output more slowly than it is written.
+type SlowConsumerPolicy int
+
+const (
+	// SlowConsumerDrop discards the chunks that do not fit in the queue and
+	// counts them in Subscription.Dropped. It is the default.
+	SlowConsumerDrop SlowConsumerPolicy = iota
+
+	// SlowConsumerBlock waits for room in the queue. This slows down every
+	// other subscriber and, once the pipe fills, the command itself.
+	SlowConsumerBlock
+
+	// SlowConsumerDisconnect unsubscribes the subscriber. Chunks already
+	// queued are still delivered.
+	SlowConsumerDisconnect
+)
+
+// Broadcaster is an io.Writer that copies everything written to it to any
+// number of subscribers, each fed from its own goroutine and queue. Set it
+// as StdoutWriter or StderrWriter to feed a single execution to, for
+// example, a websocket, a log file and an in-memory buffer at once, without
+// a slow subscriber holding up the others.
+//
+// A Broadcaster is safe for concurrent use. Call Close after the last
+// execution using it has finished to wait for queued output to be delivered.
+type Broadcaster struct {
+	// writeMu serializes writes, so every subscriber sees the chunks in
+	// the same order. It is held while a write waits for a blocking
+	// subscriber, unlike mu, which guards the subscriber list.
+	writeMu sync.Mutex
+
+	mu     sync.Mutex
+	subs   []*Subscription
+	closed bool
+}
+
+// NewBroadcaster returns a Broadcaster with no subscribers.
+func NewBroadcaster() *Broadcaster {
+	return &Broadcaster{}
+}
+
+// Subscription is a subscriber attached to a Broadcaster.
+type Subscription struct {
+	b       *Broadcaster
+	w       io.Writer
+	policy  SlowConsumerPolicy
+	queue   chan []byte
+	stop    chan struct{}
+	once    sync.Once
+	done    chan struct{}
+	onDone  func()
+	dropped atomic.Int64
+
+	mu  sync.Mutex
+	err error
+}
+
+// Subscribe attaches w to the broadcaster. Up to queueSize chunks are
+// buffered for w before policy applies; a queueSize below 1 is treated as
+// 1. If writing to w fails, the subscription stops writing, discards the
+// rest of the output and reports the error from Err.
+func (b *Broadcaster) Subscribe(w io.Writer, queueSize int, policy SlowConsumerPolicy) *Subscription {
+	return b.subscribe(w, queueSize, policy, nil)
+}
+
+// SubscribeChan attaches a channel subscriber. Each chunk written to the
+// broadcaster is sent on the returned channel as a separate copy. The
+// channel is closed when the subscription ends, after Unsubscribe or Close.
+func (b *Broadcaster) SubscribeChan(queueSize int, policy SlowConsumerPolicy) (<-chan []byte, *Subscription) {
+	ch := make(chan []byte)
+	sub := b.subscribe(chanWriter(ch), queueSize, policy, func() { close(ch) })
+	return ch, sub
+}
+
+func (b *Broadcaster) subscribe(w io.Writer, queueSize int, policy SlowConsumerPolicy, onDone func()) *Subscription {
+	sub := &Subscription{
+		b:      b,
+		w:      w,
+		policy: policy,
+		queue:  make(chan []byte, max(queueSize, 1)),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+		onDone: onDone,
+	}
+	go sub.run()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		sub.end()
+		return sub
+	}
+	b.subs = append(b.subs, sub)
+	return sub
+}
+
+// Write queues a copy of p for every subscriber. It fails only after Close.
+func (b *Broadcaster) Write(p []byte) (int, error) {
+	b.writeMu.Lock()
+	defer b.writeMu.Unlock()
+
+	b.mu.Lock()
+	closed, subs := b.closed, slices.Clone(b.subs)
+	b.mu.Unlock()
+	if closed {
+		return 0, io.ErrClosedPipe
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	chunk := bytes.Clone(p)
+	for _, sub := range subs {
+		if !sub.offer(chunk) {
+			b.remove(sub)
+		}
+	}
+	return len(p), nil
+}
+
+// remove detaches sub and ends its subscription, without waiting for its
+// queued output.
+func (b *Broadcaster) remove(sub *Subscription) {
+	b.mu.Lock()
+	if i := slices.Index(b.subs, sub); i >= 0 {
+		b.subs = slices.Delete(b.subs, i, i+1)
+	}
+	b.mu.Unlock()
+	sub.end()
+}
+
+// Close stops accepting writes, ends every subscription and waits until
+// each has delivered its queued output.
+func (b *Broadcaster) Close() error {
+	b.mu.Lock()
+	subs := b.subs
+	b.subs = nil
+	b.closed = true
+	b.mu.Unlock()
+
+	for _, sub := range subs {
+		sub.end()
+	}
+	for _, sub := range subs {
+		<-sub.done
+	}
+	return nil
+}
+
+// offer queues chunk according to the subscription's policy. It returns
+// false if the subscriber must be disconnected. A blocking offer gives up
+// once the subscription ends.
+func (s *Subscription) offer(chunk []byte) bool {
+	if s.policy == SlowConsumerBlock {
+		select {
+		case s.queue <- chunk:
+			return true
+		case <-s.stop:
+			return false
+		}
+	}
+	select {
+	case s.queue <- chunk:
+		return true
+	default:
+	}
+	s.dropped.Add(int64(len(chunk)))
+	return s.policy != SlowConsumerDisconnect
+}
+
+// end ends the subscription. The subscriber still delivers the chunks
+// queued so far.
+func (s *Subscription) end() {
+	s.once.Do(func() { close(s.stop) })
+}
+
+func (s *Subscription) run() {
+	defer close(s.done)
+	if s.onDone != nil {
+		defer s.onDone()
+	}
+	for {
+		select {
+		case chunk := <-s.queue:
+			s.deliver(chunk)
+		case <-s.stop:
+			for {
+				select {
+				case chunk := <-s.queue:
+					s.deliver(chunk)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (s *Subscription) deliver(chunk []byte) {
+	if s.Err() != nil {
+		s.dropped.Add(int64(len(chunk)))
+		return
+	}
+	if _, err := s.w.Write(chunk); err != nil {
+		s.mu.Lock()
+		s.err = err
+		s.mu.Unlock()
+	}
+}
+
+// Unsubscribe detaches the subscriber and waits until its queued output has
+// been delivered. It is safe to call more than once.
+func (s *Subscription) Unsubscribe() {
+	s.b.remove(s)
+	<-s.done
+}
+
+// Dropped returns the number of bytes that were not delivered to the
+// subscriber, because its queue was full or writing to it failed.
+func (s *Subscription) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// Err returns the error that stopped delivery to the subscriber, if any.
+func (s *Subscription) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// chanWriter sends each write on a channel as a separate copy.
+type chanWriter chan []byte
+
+func (c chanWriter) Write(p []byte) (int, error) {
+	c <- bytes.Clone(p)
+	return len(p), nil
+}
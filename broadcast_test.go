@@ -0,0 +1,196 @@
+package cmdexec
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+// gatedWriter blocks every write until release is closed.
+type gatedWriter struct {
+	release chan struct{}
+	mu      sync.Mutex
+	buf     bytes.Buffer
+}
+
+func (g *gatedWriter) Write(p []byte) (int, error) {
+	<-g.release
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.buf.Write(p)
+}
+
+func TestBroadcaster_Execute(t *testing.T) {
+	b := NewBroadcaster()
+	var first, second bytes.Buffer
+	b.Subscribe(&first, 16, SlowConsumerBlock)
+	b.Subscribe(&second, 16, SlowConsumerBlock)
+	ch, _ := b.SubscribeChan(16, SlowConsumerBlock)
+
+	var received bytes.Buffer
+	chanDone := make(chan struct{})
+	go func() {
+		defer close(chanDone)
+		for chunk := range ch {
+			received.Write(chunk)
+		}
+	}()
+
+	result, err := NewBasicExecutor().Execute(context.Background(), ToolConfig{
+		Command:      "sh",
+		Args:         []string{"-c", "echo one; echo two"},
+		StdoutWriter: b,
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if err := b.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	<-chanDone
+
+	for name, got := range map[string]string{
+		"first": first.String(), "second": second.String(), "chan": received.String(), "result": result.Output,
+	} {
+		if got != "one\ntwo\n" {
+			t.Errorf("%s got %q, want %q", name, got, "one\ntwo\n")
+		}
+	}
+}
+
+func TestBroadcaster_SlowConsumerDrop(t *testing.T) {
+	b := NewBroadcaster()
+	slow := &gatedWriter{release: make(chan struct{})}
+	var fast bytes.Buffer
+	slowSub := b.Subscribe(slow, 1, SlowConsumerDrop)
+	fastSub := b.Subscribe(&fast, 16, SlowConsumerDrop)
+
+	for _, s := range []string{"a", "b", "c", "d"} {
+		if _, err := b.Write([]byte(s)); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	close(slow.release)
+	if err := b.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if fast.String() != "abcd" || fastSub.Dropped() != 0 {
+		t.Errorf("fast subscriber got %q with %d dropped, want %q and 0", fast.String(), fastSub.Dropped(), "abcd")
+	}
+	// The slow writer holds one chunk and queues one more; the rest drop.
+	if got := int64(slow.buf.Len()) + slowSub.Dropped(); got != 4 || slowSub.Dropped() == 0 {
+		t.Errorf("slow subscriber got %q with %d dropped, want some of 4 bytes dropped",
+			slow.buf.String(), slowSub.Dropped())
+	}
+}
+
+func TestBroadcaster_SlowConsumerDisconnect(t *testing.T) {
+	b := NewBroadcaster()
+	ch, sub := b.SubscribeChan(1, SlowConsumerDisconnect)
+
+	// Nobody receives, so the queue fills and the subscriber is dropped.
+	for range 4 {
+		if _, err := b.Write([]byte("x")); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if sub.Dropped() == 0 {
+		t.Error("Dropped() = 0, want the rejected chunk counted")
+	}
+
+	var got int
+	for chunk := range ch {
+		got += len(chunk)
+	}
+	if got == 0 || got >= 4 {
+		t.Errorf("received %d bytes before disconnect, want between 1 and 3", got)
+	}
+	sub.Unsubscribe()
+	if err := b.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+}
+
+func TestBroadcaster_UnsubscribeBlockedWrite(t *testing.T) {
+	b := NewBroadcaster()
+	slow := &gatedWriter{release: make(chan struct{})}
+	sub := b.Subscribe(slow, 1, SlowConsumerBlock)
+
+	// The gated writer holds the first chunk and the queue holds the second,
+	// so the third write blocks on the subscriber.
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		for _, s := range []string{"a", "b", "c"} {
+			if _, err := b.Write([]byte(s)); err != nil {
+				t.Errorf("Write() error = %v", err)
+			}
+		}
+	}()
+	select {
+	case <-written:
+		t.Fatal("Write() returned while the subscriber was blocked")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	unsubscribed := make(chan struct{})
+	go func() {
+		defer close(unsubscribed)
+		sub.Unsubscribe()
+	}()
+	select {
+	case <-written:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Write() stayed blocked after Unsubscribe()")
+	}
+	// Subscribing must not wait for the stalled subscriber either.
+	b.Subscribe(io.Discard, 1, SlowConsumerBlock)
+
+	close(slow.release)
+	<-unsubscribed
+	if got := slow.buf.String(); got != "ab" {
+		t.Errorf("unsubscribed writer got %q, want %q", got, "ab")
+	}
+	if err := b.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+}
+
+func TestBroadcaster_WriterError(t *testing.T) {
+	b := NewBroadcaster()
+	sub := b.Subscribe(failingWriter{}, 4, SlowConsumerBlock)
+	for range 3 {
+		if _, err := b.Write([]byte("ab")); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	sub.Unsubscribe()
+
+	if sub.Err() == nil {
+		t.Error("Err() = nil, want the write error")
+	}
+	if sub.Dropped() != 4 {
+		t.Errorf("Dropped() = %d, want 4", sub.Dropped())
+	}
+	// Unsubscribing again is a no-op.
+	sub.Unsubscribe()
+}
+
+func TestBroadcaster_Closed(t *testing.T) {
+	b := NewBroadcaster()
+	if err := b.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, err := b.Write([]byte("x")); !errors.Is(err, io.ErrClosedPipe) {
+		t.Errorf("Write() after Close error = %v, want io.ErrClosedPipe", err)
+	}
+	ch, _ := b.SubscribeChan(1, SlowConsumerDrop)
+	if _, ok := <-ch; ok {
+		t.Error("channel of a subscription made after Close should be closed")
+	}
+}
@@ -0,0 +1,137 @@
+package cmdexec
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// ChaosConfig configures the faults injected by a ChaosExecutor. Each rate is
+// the probability, from 0 to 1, that a fault is injected into an execution.
+type ChaosConfig struct {
+	// Seed seeds the random source. Executions issued in the same order with
+	// the same seed see the same faults.
+	Seed uint64
+
+	// LatencyRate is the probability of delaying an execution by a random
+	// duration of up to MaxLatency before it runs.
+	LatencyRate float64
+	MaxLatency  time.Duration
+
+	// ErrorRate is the probability of failing an execution, without running
+	// it, with an error picked from Errors. If Errors is empty, a
+	// *TimeoutError for the command is returned.
+	ErrorRate float64
+	Errors    []error
+
+	// ExitRate is the probability of replacing the exit code of a completed
+	// execution with ExitCode, or 1 if ExitCode is zero.
+	ExitRate float64
+	ExitCode int
+
+	// TruncateRate is the probability of cutting the stdout and stderr of a
+	// completed execution to a random length and marking them truncated.
+	TruncateRate float64
+}
+
+// ChaosExecutor wraps an Executor and randomly injects latency, errors,
+// failing exit codes, and truncated output into the executions it delegates,
+// so applications can test their retry and circuit-breaker handling. It is
+// intended for tests and staging environments.
+type ChaosExecutor struct {
+	executor Executor
+	cfg      ChaosConfig
+
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// chaosPlan holds the faults drawn for one execution.
+type chaosPlan struct {
+	latency    time.Duration
+	err        error
+	exit       bool
+	truncate   bool
+	stdoutFrac float64
+	stderrFrac float64
+}
+
+// NewChaosExecutor creates a ChaosExecutor that delegates to executor.
+func NewChaosExecutor(executor Executor, cfg ChaosConfig) *ChaosExecutor {
+	return &ChaosExecutor{
+		executor: executor,
+		cfg:      cfg,
+		rng:      rand.New(rand.NewPCG(cfg.Seed, cfg.Seed)), //nolint:gosec // fault injection does not need a secure source
+	}
+}
+
+// Execute runs cfg on the wrapped executor, injecting faults as configured.
+func (c *ChaosExecutor) Execute(ctx context.Context, cfg ToolConfig) (*ExecutionResult, error) {
+	plan := c.draw(cfg)
+
+	if plan.latency > 0 {
+		select {
+		case <-time.After(plan.latency):
+		case <-ctx.Done():
+			return nil, fmt.Errorf("context done during injected latency: %w", ctx.Err())
+		}
+	}
+	if plan.err != nil {
+		return nil, plan.err
+	}
+
+	result, err := c.executor.Execute(ctx, cfg)
+	if err != nil || result == nil || (!plan.exit && !plan.truncate) {
+		return result, err //nolint:wrapcheck // delegation pattern
+	}
+
+	// Modify a copy, since the wrapped executor may return shared results.
+	faulty := *result
+	if plan.exit {
+		faulty.ExitCode = c.cfg.ExitCode
+		if faulty.ExitCode == 0 {
+			faulty.ExitCode = 1
+		}
+	}
+	if plan.truncate {
+		faulty.Output = faulty.Output[:int(plan.stdoutFrac*float64(len(faulty.Output)))]
+		faulty.Stderr = faulty.Stderr[:int(plan.stderrFrac*float64(len(faulty.Stderr)))]
+		faulty.StdoutTruncated = true
+		faulty.StderrTruncated = true
+	}
+	return &faulty, nil
+}
+
+// IsAvailable delegates to the wrapped executor.
+func (c *ChaosExecutor) IsAvailable(command string) bool {
+	return c.executor.IsAvailable(command)
+}
+
+// draw decides the faults for one execution. Every random value is drawn
+// on every call, so the sequence of faults depends only on the seed and the
+// number of executions.
+func (c *ChaosExecutor) draw(cfg ToolConfig) chaosPlan {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var plan chaosPlan
+	latencyHit, latencyFrac := c.rng.Float64() < c.cfg.LatencyRate, c.rng.Float64()
+	errorHit, errorIndex := c.rng.Float64() < c.cfg.ErrorRate, c.rng.IntN(max(len(c.cfg.Errors), 1))
+	plan.exit = c.rng.Float64() < c.cfg.ExitRate
+	plan.truncate = c.rng.Float64() < c.cfg.TruncateRate
+	plan.stdoutFrac, plan.stderrFrac = c.rng.Float64(), c.rng.Float64()
+
+	if latencyHit {
+		plan.latency = time.Duration(latencyFrac * float64(c.cfg.MaxLatency))
+	}
+	if errorHit {
+		if len(c.cfg.Errors) > 0 {
+			plan.err = c.cfg.Errors[errorIndex]
+		} else {
+			plan.err = &TimeoutError{Command: buildCommandString(cfg.Command, cfg.Args), Timeout: cfg.Timeout}
+		}
+	}
+	return plan
+}
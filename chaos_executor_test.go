@@ -0,0 +1,98 @@
+package cmdexec
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func newChaosMock() *MockExecutor {
+	mock := NewMockExecutor()
+	mock.SetResult(&ExecutionResult{Command: "tool", Output: "0123456789", Stderr: "warnings"}, nil)
+	return mock
+}
+
+func TestChaosExecutor_NoFaults(t *testing.T) {
+	chaos := NewChaosExecutor(newChaosMock(), ChaosConfig{Seed: 1})
+	for range 20 {
+		result, err := chaos.Execute(context.Background(), ToolConfig{Command: "tool"})
+		if err != nil || result.ExitCode != 0 || result.Output != "0123456789" || result.StdoutTruncated {
+			t.Fatalf("Execute() = (%+v, %v), want the delegated result unchanged", result, err)
+		}
+	}
+}
+
+func TestChaosExecutor_AlwaysFaults(t *testing.T) {
+	injected := errors.New("injected")
+
+	chaos := NewChaosExecutor(newChaosMock(), ChaosConfig{ErrorRate: 1, Errors: []error{injected}})
+	if _, err := chaos.Execute(context.Background(), ToolConfig{Command: "tool"}); !errors.Is(err, injected) {
+		t.Errorf("error = %v, want the injected error", err)
+	}
+
+	chaos = NewChaosExecutor(newChaosMock(), ChaosConfig{ErrorRate: 1})
+	var timeoutErr *TimeoutError
+	if _, err := chaos.Execute(context.Background(), ToolConfig{Command: "tool"}); !errors.As(err, &timeoutErr) {
+		t.Errorf("error = %v, want the default *TimeoutError", err)
+	}
+
+	mock := newChaosMock()
+	chaos = NewChaosExecutor(mock, ChaosConfig{ExitRate: 1, ExitCode: 137, TruncateRate: 1})
+	result, err := chaos.Execute(context.Background(), ToolConfig{Command: "tool"})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.ExitCode != 137 || !result.StdoutTruncated || !result.StderrTruncated {
+		t.Errorf("result = %+v, want exit code 137 and truncated output", result)
+	}
+	if len(result.Output) >= 10 && len(result.Stderr) >= 8 {
+		t.Errorf("Output = %q, Stderr = %q; want them cut short", result.Output, result.Stderr)
+	}
+
+	// The wrapped executor's result is not modified.
+	original, _ := mock.Execute(context.Background(), ToolConfig{Command: "tool"})
+	if original.ExitCode != 0 || original.Output != "0123456789" {
+		t.Errorf("delegated result was modified: %+v", original)
+	}
+}
+
+func TestChaosExecutor_Latency(t *testing.T) {
+	chaos := NewChaosExecutor(newChaosMock(), ChaosConfig{LatencyRate: 1, MaxLatency: time.Hour})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := chaos.Execute(ctx, ToolConfig{Command: "tool"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error = %v, want context.DeadlineExceeded during the injected latency", err)
+	}
+}
+
+func TestChaosExecutor_Reproducible(t *testing.T) {
+	cfg := ChaosConfig{Seed: 42, ErrorRate: 0.3, ExitRate: 0.3, TruncateRate: 0.3}
+	run := func() []string {
+		chaos := NewChaosExecutor(newChaosMock(), cfg)
+		var outcomes []string
+		for range 50 {
+			result, err := chaos.Execute(context.Background(), ToolConfig{Command: "tool"})
+			if err != nil {
+				outcomes = append(outcomes, "error")
+				continue
+			}
+			outcomes = append(outcomes, result.Output+"|"+result.Stderr+"|"+string(rune('0'+result.ExitCode)))
+		}
+		return outcomes
+	}
+
+	first, second := run(), run()
+	faults := 0
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("execution %d: %q then %q, want the same faults for the same seed", i, first[i], second[i])
+		}
+		if first[i] != "0123456789|warnings|0" {
+			faults++
+		}
+	}
+	if faults == 0 || faults == len(first) {
+		t.Errorf("%d of %d executions faulted, want some but not all", faults, len(first))
+	}
+}
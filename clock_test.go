@@ -0,0 +1,18 @@
+package cmdexec
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start)
+	if !clock.Now().Equal(start) {
+		t.Errorf("Now() = %v, want %v", clock.Now(), start)
+	}
+	clock.Advance(time.Minute)
+	if want := start.Add(time.Minute); !clock.Now().Equal(want) {
+		t.Errorf("Now() after Advance = %v, want %v", clock.Now(), want)
+	}
+}
@@ -2,7 +2,12 @@ package cmdexec
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
 	"sync"
+	"time"
 )
 
 // ConcurrentResult represents the result of a concurrent command execution.
@@ -43,9 +48,23 @@ type Executor interface {
 type ConcurrentExecutor struct {
 	executor       Executor
 	maxConcurrency int
+	prefixed       *prefixedOutput
+	onItemComplete func(index int, r ConcurrentResult)
+	onProgress     func(done, total int)
+	batchError     bool
+	defaultTimeout time.Duration
+	deduplicate    bool
 	mu             sync.RWMutex
+
+	// batches holds the semaphores of running batches that follow
+	// maxConcurrency, so SetMaxConcurrency can resize them.
+	batches map[*weightedSemaphore]struct{}
 }
 
+// followMaxConcurrency is passed as the limit of batches that use the
+// executor's max concurrency and follow changes to it while they run.
+const followMaxConcurrency = 0
+
 // NewConcurrentExecutor creates a new concurrent executor wrapping the given executor.
 func NewConcurrentExecutor(executor Executor) *ConcurrentExecutor {
 	return &ConcurrentExecutor{
@@ -64,7 +83,11 @@ func (ce *ConcurrentExecutor) IsAvailable(command string) bool {
 	return ce.executor.IsAvailable(command)
 }
 
-// SetMaxConcurrency sets the maximum number of concurrent executions.
+// SetMaxConcurrency sets the maximum number of concurrent executions. The
+// new limit also applies to batches started by ExecuteAll, ExecuteAllStream,
+// ExecuteMap and Start that are still running: raising it starts waiting
+// commands immediately, and lowering it holds back new commands until enough
+// running ones have finished. Running commands are never interrupted.
 func (ce *ConcurrentExecutor) SetMaxConcurrency(maxConcurrency int) {
 	ce.mu.Lock()
 	defer ce.mu.Unlock()
@@ -72,6 +95,9 @@ func (ce *ConcurrentExecutor) SetMaxConcurrency(maxConcurrency int) {
 		maxConcurrency = 1
 	}
 	ce.maxConcurrency = maxConcurrency
+	for semaphore := range ce.batches {
+		semaphore.resize(maxConcurrency)
+	}
 }
 
 // GetMaxConcurrency returns the current maximum concurrency setting.
@@ -81,52 +107,514 @@ func (ce *ConcurrentExecutor) GetMaxConcurrency() int {
 	return ce.maxConcurrency
 }
 
+// SetPrefixedOutput makes ExecuteAll and ExecuteConcurrent stream the
+// stdout and stderr lines of every command to w, each prefixed with a label
+// identifying the command (see PrefixWriter). Labels are produced by
+// labelFunc, or DefaultPrefixLabel if it is nil, and padded to equal width.
+// Output is still captured in each result. Pass a nil w to disable.
+func (ce *ConcurrentExecutor) SetPrefixedOutput(w io.Writer, labelFunc func(index int, cfg ToolConfig) string) {
+	ce.mu.Lock()
+	defer ce.mu.Unlock()
+	if w == nil {
+		ce.prefixed = nil
+		return
+	}
+	if labelFunc == nil {
+		labelFunc = DefaultPrefixLabel
+	}
+	ce.prefixed = &prefixedOutput{w: w, labelFunc: labelFunc}
+}
+
+// SetOnItemComplete sets a callback that batch executions call with each
+// result as soon as its command finishes. Calls are serialized, so fn need
+// not be safe for concurrent use, but a slow fn delays reporting the
+// remaining results. Pass nil to disable.
+func (ce *ConcurrentExecutor) SetOnItemComplete(fn func(index int, r ConcurrentResult)) {
+	ce.mu.Lock()
+	defer ce.mu.Unlock()
+	ce.onItemComplete = fn
+}
+
+// SetOnProgress sets a callback that batch executions call after each
+// command finishes with the number of finished commands and the batch size,
+// for reporting such as "37/200 done". It is called after the
+// SetOnItemComplete callback, and calls are serialized. Pass nil to disable.
+func (ce *ConcurrentExecutor) SetOnProgress(fn func(done, total int)) {
+	ce.mu.Lock()
+	defer ce.mu.Unlock()
+	ce.onProgress = fn
+}
+
+// SetBatchError makes ExecuteAll, ExecuteConcurrent and ExecuteMap return a
+// *BatchError alongside the results if any command failed, so callers can
+// check a batch with a single err != nil. A command fails if Execute
+// returned an error or ToolConfig.Succeeded rejects its result. By default
+// batch executions return a nil error and failures are only reported in the
+// results.
+func (ce *ConcurrentExecutor) SetBatchError(enabled bool) {
+	ce.mu.Lock()
+	defer ce.mu.Unlock()
+	ce.batchError = enabled
+}
+
+// SetDefaultTimeout sets the timeout of batch commands whose
+// ToolConfig.Timeout is zero. Giving each command its own timeout, instead
+// of putting a deadline on the batch context, lets one command that is
+// allowed to be slow override it without loosening the deadline of the
+// others. Pass 0 to disable.
+func (ce *ConcurrentExecutor) SetDefaultTimeout(timeout time.Duration) {
+	ce.mu.Lock()
+	defer ce.mu.Unlock()
+	ce.defaultTimeout = timeout
+}
+
+// SetDeduplicate makes ExecuteAll, ExecuteConcurrent, ExecuteMap and
+// ExecuteAllStream run identical configs of a batch only once and report
+// the result at every index that holds one of them, which saves work on
+// generated batch inputs. Each index gets its own copy of the
+// ExecutionResult. Configs are identical if their command, arguments,
+// environment, working directory and execution settings match; configs with
+// stdin, writers, callbacks, hooks, a Redactor or a SpoolThreshold are
+// never deduplicated. Start and ExecuteItems do not deduplicate, since
+// their commands are cancelled individually.
+func (ce *ConcurrentExecutor) SetDeduplicate(enabled bool) {
+	ce.mu.Lock()
+	defer ce.mu.Unlock()
+	ce.deduplicate = enabled
+}
+
+// BatchItem is a command of a batch run by ExecuteItems.
+type BatchItem struct {
+	Config ToolConfig
+
+	// Context, if set, is the parent context of this command. The command
+	// is cancelled when either it or the batch context is done, and it
+	// carries the values of Context.
+	Context context.Context
+}
+
+// ExecuteItems runs all commands concurrently using the default max
+// concurrency, like ExecuteAll, giving each command its own parent context
+// where BatchItem.Context is set. ctx remains an umbrella over the whole
+// batch.
+func (ce *ConcurrentExecutor) ExecuteItems(ctx context.Context, items []BatchItem) ([]ConcurrentResult, error) {
+	configs := make([]ToolConfig, len(items))
+	contexts := make([]context.Context, len(items))
+	var cancels []context.CancelFunc
+	defer func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}()
+	for i, item := range items {
+		configs[i] = item.Config
+		contexts[i] = ctx
+		if item.Context != nil {
+			var cancel context.CancelFunc
+			contexts[i], cancel = mergeContext(ctx, item.Context)
+			cancels = append(cancels, cancel)
+		}
+	}
+	return ce.executeConcurrent(ctx, configs, followMaxConcurrency, func(index int) context.Context {
+		return contexts[index]
+	})
+}
+
+// mergeContext returns a context derived from ctx that is also cancelled
+// when umbrella is done.
+func mergeContext(umbrella, ctx context.Context) (context.Context, context.CancelFunc) {
+	merged, cancel := context.WithCancel(ctx)
+	if umbrella.Err() != nil {
+		// AfterFunc would cancel asynchronously.
+		cancel()
+	}
+	stop := context.AfterFunc(umbrella, cancel)
+	return merged, func() {
+		stop()
+		cancel()
+	}
+}
+
 // ExecuteAll runs all commands concurrently using the default max concurrency.
 func (ce *ConcurrentExecutor) ExecuteAll(ctx context.Context, configs []ToolConfig) ([]ConcurrentResult, error) {
-	maxConcurrency := ce.GetMaxConcurrency()
-	return ce.ExecuteConcurrent(ctx, configs, maxConcurrency)
+	return ce.executeConcurrent(ctx, configs, followMaxConcurrency, nil)
 }
 
-// ExecuteConcurrent runs multiple commands with the specified concurrency limit.
+// ExecuteMap runs all commands concurrently using the default max
+// concurrency, like ExecuteAll, for callers that identify commands by name
+// (target, host, package). The results are keyed like configs. Index refers
+// to the position of the key in sorted order, which is also the index
+// passed to the SetOnItemComplete callback. With SetBatchError, the results
+// are returned together with the *BatchError.
+func (ce *ConcurrentExecutor) ExecuteMap(ctx context.Context, configs map[string]ToolConfig) (map[string]ConcurrentResult, error) {
+	keys := slices.Sorted(maps.Keys(configs))
+	list := make([]ToolConfig, len(keys))
+	for i, key := range keys {
+		list[i] = configs[key]
+	}
+
+	results, err := ce.ExecuteAll(ctx, list)
+	byKey := make(map[string]ConcurrentResult, len(results))
+	for _, r := range results {
+		byKey[keys[r.Index]] = r
+	}
+	return byKey, err
+}
+
+// Batch is a handle on a batch started with ConcurrentExecutor.Start. It
+// lets callers cancel individual commands while the others keep running.
+type Batch struct {
+	cancels []context.CancelFunc
+	done    chan struct{}
+	results []ConcurrentResult
+	err     error
+}
+
+// Start runs all commands concurrently in the background, like ExecuteAll,
+// and returns a handle to cancel individual commands and wait for the
+// results.
+func (ce *ConcurrentExecutor) Start(ctx context.Context, configs []ToolConfig) *Batch {
+	b := &Batch{
+		cancels: make([]context.CancelFunc, len(configs)),
+		done:    make(chan struct{}),
+	}
+	contexts := make([]context.Context, len(configs))
+	for i := range configs {
+		contexts[i], b.cancels[i] = context.WithCancel(ctx)
+	}
+	go func() {
+		defer close(b.done)
+		b.results, b.err = ce.executeConcurrent(ctx, configs, followMaxConcurrency, func(index int) context.Context {
+			return contexts[index]
+		})
+		for _, cancel := range b.cancels {
+			cancel()
+		}
+	}()
+	return b
+}
+
+// Len returns the number of commands in the batch.
+func (b *Batch) Len() int {
+	return len(b.cancels)
+}
+
+// Cancel cancels the context of the command at index, aborting it if it is
+// running and making it fail fast if it has not started yet. The other
+// commands are not affected. Indices out of range are ignored.
+func (b *Batch) Cancel(index int) {
+	if index >= 0 && index < len(b.cancels) {
+		b.cancels[index]()
+	}
+}
+
+// CancelAll cancels every command in the batch.
+func (b *Batch) CancelAll() {
+	for _, cancel := range b.cancels {
+		cancel()
+	}
+}
+
+// Done returns a channel that is closed once every command has finished.
+func (b *Batch) Done() <-chan struct{} {
+	return b.done
+}
+
+// Wait blocks until every command has finished and returns the results, as
+// ExecuteAll does.
+func (b *Batch) Wait() ([]ConcurrentResult, error) {
+	<-b.done
+	return b.results, b.err
+}
+
+// ExecuteConcurrent runs multiple commands with the specified concurrency
+// limit. Each command counts against the limit with its ToolConfig.Weight.
 func (ce *ConcurrentExecutor) ExecuteConcurrent(ctx context.Context, configs []ToolConfig, maxConcurrency int) ([]ConcurrentResult, error) {
+	return ce.executeConcurrent(ctx, configs, max(maxConcurrency, 1), nil)
+}
+
+// executeConcurrent implements ExecuteConcurrent. If itemCtx is not nil, it
+// supplies the context of each command instead of ctx.
+func (ce *ConcurrentExecutor) executeConcurrent(ctx context.Context, configs []ToolConfig, maxConcurrency int, itemCtx func(index int) context.Context) ([]ConcurrentResult, error) {
+	results := make([]ConcurrentResult, len(configs))
+	ce.run(ctx, configs, maxConcurrency, itemCtx, func(r ConcurrentResult) {
+		results[r.Index] = r
+	})
+
+	ce.mu.RLock()
+	batchError := ce.batchError
+	ce.mu.RUnlock()
+	if batchError {
+		if err := newBatchError(results); err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+// newBatchError returns a *BatchError for the failed results, or nil if
+// every command succeeded.
+func newBatchError(results []ConcurrentResult) error {
+	var failures []*BatchItemError
+	for _, r := range results {
+		if err := itemError(r); err != nil {
+			failures = append(failures, err)
+		}
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+	return &BatchError{Failures: failures, Total: len(results)}
+}
+
+// itemError returns the failure of r, or nil if its command succeeded.
+func itemError(r ConcurrentResult) *BatchItemError {
+	err := r.Error
+	if err == nil && r.Result != nil && !r.Config.Succeeded(r.Result) {
+		err = &ExitError{ExitCode: r.Result.ExitCode, Stderr: r.Result.Stderr}
+	}
+	if err == nil {
+		return nil
+	}
+	return &BatchItemError{
+		Index:   r.Index,
+		Command: buildCommandString(r.Config.Command, r.Config.Args),
+		Err:     err,
+	}
+}
+
+// SplitResults partitions batch results into those whose command succeeded
+// and those that failed, keeping their order. A command failed if Execute
+// returned an error or ToolConfig.Succeeded rejects its result.
+func SplitResults(results []ConcurrentResult) (succeeded, failed []ConcurrentResult) {
+	for _, r := range results {
+		if itemError(r) != nil {
+			failed = append(failed, r)
+		} else {
+			succeeded = append(succeeded, r)
+		}
+	}
+	return succeeded, failed
+}
+
+// FirstError returns a *BatchItemError for the first failed command in
+// results, or nil if every command succeeded. See SplitResults for what
+// counts as failed.
+func FirstError(results []ConcurrentResult) error {
+	for _, r := range results {
+		if err := itemError(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExecuteAllStream runs all commands concurrently using the default max
+// concurrency, like ExecuteAll, but sends each result on the returned channel
+// as soon as its command finishes, so callers can report progress and start
+// downstream work early. Results arrive in completion order; use Index to
+// match them to configs. The channel is buffered for the whole batch, so
+// commands are never held up by a slow reader, and it is closed once every
+// command has finished.
+func (ce *ConcurrentExecutor) ExecuteAllStream(ctx context.Context, configs []ToolConfig) <-chan ConcurrentResult {
+	ch := make(chan ConcurrentResult, len(configs))
+	go func() {
+		defer close(ch)
+		ce.run(ctx, configs, followMaxConcurrency, nil, func(r ConcurrentResult) {
+			ch <- r
+		})
+	}()
+	return ch
+}
+
+// ExecuteSequential runs commands one at a time, in order, for script-like
+// flows where later commands depend on earlier ones. If stopOnError is true,
+// it stops after the first command that fails (see SplitResults), and the
+// returned results end with that command; otherwise every command runs. It
+// honors prefixed output, the progress callbacks and SetBatchError like
+// ExecuteAll. A done ctx stops the sequence in either mode.
+func (ce *ConcurrentExecutor) ExecuteSequential(ctx context.Context, configs []ToolConfig, stopOnError bool) ([]ConcurrentResult, error) {
+	original := configs
+	ce.mu.RLock()
+	prefixed := ce.prefixed
+	onItemComplete, onProgress, batchError := ce.onItemComplete, ce.onProgress, ce.batchError
+	defaultTimeout := ce.defaultTimeout
+	ce.mu.RUnlock()
+	if prefixed != nil && len(configs) > 0 {
+		var flush func()
+		configs, flush = prefixed.apply(configs)
+		defer flush()
+	}
+
+	results := make([]ConcurrentResult, 0, len(configs))
+	emit := func(r ConcurrentResult) { results = append(results, r) }
+	if onItemComplete != nil || onProgress != nil {
+		emit = reportProgress(emit, len(configs), onItemComplete, onProgress)
+	}
+	for i, cfg := range configs {
+		if ctx.Err() != nil {
+			break
+		}
+		if cfg.Timeout == 0 {
+			cfg.Timeout = defaultTimeout
+		}
+		result, err := ce.executor.Execute(ctx, cfg)
+		r := ConcurrentResult{Index: i, Config: original[i], Result: result, Error: err}
+		emit(r)
+		if stopOnError && itemError(r) != nil {
+			break
+		}
+	}
+
+	if batchError {
+		if err := newBatchError(results); err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+// run executes configs with at most maxConcurrency running at once, or the
+// executor's current limit for followMaxConcurrency, and passes each result
+// to emit as its command finishes. Commands run with
+// itemCtx(index), or ctx if itemCtx is nil. emit may be called from several
+// goroutines at once. run returns after the last call to emit.
+func (ce *ConcurrentExecutor) run(ctx context.Context, configs []ToolConfig, maxConcurrency int, itemCtx func(index int) context.Context, emit func(ConcurrentResult)) {
 	if len(configs) == 0 {
-		return []ConcurrentResult{}, nil
+		return
 	}
 
-	if maxConcurrency <= 0 {
-		maxConcurrency = 1
+	// Results report the caller's configs, not the prefixing copies.
+	original := configs
+	ce.mu.RLock()
+	prefixed := ce.prefixed
+	onItemComplete, onProgress := ce.onItemComplete, ce.onProgress
+	defaultTimeout := ce.defaultTimeout
+	deduplicate := ce.deduplicate && itemCtx == nil
+	ce.mu.RUnlock()
+	groups := singletonGroups(len(configs))
+	if deduplicate {
+		groups = dedupGroups(configs)
+	}
+	if prefixed != nil {
+		var flush func()
+		configs, flush = prefixed.apply(configs)
+		defer flush()
+	}
+	if onItemComplete != nil || onProgress != nil {
+		emit = reportProgress(emit, len(configs), onItemComplete, onProgress)
 	}
 
-	// Create a semaphore to limit concurrency
-	semaphore := make(chan struct{}, maxConcurrency)
-	results := make([]ConcurrentResult, len(configs))
+	// Create a semaphore to limit the total weight of running commands
+	semaphore := ce.batchSemaphore(maxConcurrency)
+	defer ce.releaseBatchSemaphore(semaphore)
 	var wg sync.WaitGroup
 
-	// Execute commands concurrently
-	for i, cfg := range configs {
+	// Execute commands concurrently, once per group of identical configs
+	for _, group := range groups {
 		wg.Add(1)
-		go func(index int, config ToolConfig) {
+		go func(group []int, config ToolConfig) {
 			defer wg.Done()
+			index := group[0]
 
-			// Acquire semaphore
-			semaphore <- struct{}{}
-			defer func() { <-semaphore }()
+			runCtx := ctx
+			if itemCtx != nil {
+				runCtx = itemCtx(index)
+			}
+			if config.Timeout == 0 {
+				config.Timeout = defaultTimeout
+			}
 
-			// Execute the command
-			result, err := ce.executor.Execute(ctx, config)
+			result, err := ce.executeAdmitted(runCtx, semaphore, config)
 
-			// Store the result
-			results[index] = ConcurrentResult{
-				Index:  index,
-				Config: config,
-				Result: result,
-				Error:  err,
+			for n, i := range group {
+				emit(ConcurrentResult{
+					Index:  i,
+					Config: original[i],
+					Result: shareResult(result, n),
+					Error:  err,
+				})
 			}
-		}(i, cfg)
+		}(group, configs[group[0]])
 	}
 
 	// Wait for all commands to complete
 	wg.Wait()
+}
 
-	return results, nil
+// executeAdmitted runs config once semaphore admits its weight. Commands
+// cancelled while queued leave the queue and are not started.
+func (ce *ConcurrentExecutor) executeAdmitted(ctx context.Context, semaphore *weightedSemaphore, config ToolConfig) (*ExecutionResult, error) {
+	weight := max(config.Weight, 1)
+	if err := semaphore.acquire(ctx, weight); err != nil {
+		return nil, fmt.Errorf("context done before command started: %w", err)
+	}
+	defer semaphore.release(weight)
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("context done before command started: %w", err)
+	}
+	return ce.executor.Execute(ctx, config) //nolint:wrapcheck // delegation pattern
+}
+
+// reportProgress wraps emit to also call the progress callbacks, which may
+// be nil, one result at a time.
+func reportProgress(emit func(ConcurrentResult), total int, onItemComplete func(int, ConcurrentResult), onProgress func(int, int)) func(ConcurrentResult) {
+	var mu sync.Mutex
+	done := 0
+	return func(r ConcurrentResult) {
+		emit(r)
+		mu.Lock()
+		defer mu.Unlock()
+		done++
+		if onItemComplete != nil {
+			onItemComplete(r.Index, r)
+		}
+		if onProgress != nil {
+			onProgress(done, total)
+		}
+	}
+}
+
+// batchSemaphore creates the semaphore limiting a batch. For
+// followMaxConcurrency, it is registered so SetMaxConcurrency can resize it.
+func (ce *ConcurrentExecutor) batchSemaphore(maxConcurrency int) *weightedSemaphore {
+	if maxConcurrency != followMaxConcurrency {
+		return newWeightedSemaphore(maxConcurrency)
+	}
+	ce.mu.Lock()
+	defer ce.mu.Unlock()
+	semaphore := newWeightedSemaphore(ce.maxConcurrency)
+	if ce.batches == nil {
+		ce.batches = make(map[*weightedSemaphore]struct{})
+	}
+	ce.batches[semaphore] = struct{}{}
+	return semaphore
+}
+
+// releaseBatchSemaphore unregisters a semaphore created by batchSemaphore.
+func (ce *ConcurrentExecutor) releaseBatchSemaphore(semaphore *weightedSemaphore) {
+	ce.mu.Lock()
+	defer ce.mu.Unlock()
+	delete(ce.batches, semaphore)
+}
+
+// singletonGroups returns n groups of one index each.
+func singletonGroups(n int) [][]int {
+	groups := make([][]int, n)
+	for i := range groups {
+		groups[i] = []int{i}
+	}
+	return groups
+}
+
+// shareResult returns result for the first index of a group, and a deep
+// copy of it for the others, so each index owns its result.
+func shareResult(result *ExecutionResult, n int) *ExecutionResult {
+	if result == nil || n == 0 {
+		return result
+	}
+	c := *result
+	c.Args = slices.Clone(result.Args)
+	c.Labels = maps.Clone(result.Labels)
+	return &c
 }
@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"runtime"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -429,3 +431,488 @@ func (e *concurrencyTrackingExecutor) Execute(ctx context.Context, cfg ToolConfi
 func (e *concurrencyTrackingExecutor) IsAvailable(command string) bool {
 	return e.executor.IsAvailable(command)
 }
+
+func TestConcurrentExecutor_ExecuteAllStream(t *testing.T) {
+	executor := NewConcurrentExecutor(NewBasicExecutor())
+
+	ch := executor.ExecuteAllStream(context.Background(), []ToolConfig{
+		{Command: "sh", Args: []string{"-c", "sleep 0.3; echo slow"}},
+		{Command: "echo", Args: []string{"fast"}},
+	})
+
+	var order []int
+	for r := range ch {
+		if r.Error != nil {
+			t.Fatalf("result %d error = %v", r.Index, r.Error)
+		}
+		want := map[int]string{0: "slow\n", 1: "fast\n"}[r.Index]
+		if r.Result.Output != want {
+			t.Errorf("result %d Output = %q, want %q", r.Index, r.Result.Output, want)
+		}
+		order = append(order, r.Index)
+	}
+	if len(order) != 2 || order[0] != 1 {
+		t.Errorf("results arrived in order %v, want the fast command first", order)
+	}
+
+	if _, ok := <-executor.ExecuteAllStream(context.Background(), nil); ok {
+		t.Error("ExecuteAllStream() with no configs should close the channel without results")
+	}
+}
+
+func TestConcurrentExecutor_ProgressCallbacks(t *testing.T) {
+	mock := NewMockExecutor()
+	mock.SetResult(&ExecutionResult{Command: "echo"}, nil)
+	executor := NewConcurrentExecutor(mock)
+
+	// Callbacks are serialized, so they need no locking of their own.
+	seen := make(map[int]bool)
+	var progress []string
+	executor.SetOnItemComplete(func(index int, r ConcurrentResult) {
+		if r.Index != index || r.Result == nil {
+			t.Errorf("OnItemComplete(%d, %+v), want the finished result", index, r)
+		}
+		seen[index] = true
+	})
+	executor.SetOnProgress(func(done, total int) {
+		progress = append(progress, fmt.Sprintf("%d/%d", done, total))
+	})
+
+	configs := make([]ToolConfig, 5)
+	for i := range configs {
+		configs[i] = ToolConfig{Command: "echo"}
+	}
+	if _, err := executor.ExecuteAll(context.Background(), configs); err != nil {
+		t.Fatalf("ExecuteAll() error = %v", err)
+	}
+	if len(seen) != 5 {
+		t.Errorf("OnItemComplete saw indices %v, want all 5", seen)
+	}
+	if got := fmt.Sprint(progress); got != "[1/5 2/5 3/5 4/5 5/5]" {
+		t.Errorf("OnProgress calls = %s, want one per command in order", got)
+	}
+
+	executor.SetOnItemComplete(nil)
+	executor.SetOnProgress(nil)
+	progress = nil
+	if _, err := executor.ExecuteAll(context.Background(), configs); err != nil {
+		t.Fatalf("ExecuteAll() error = %v", err)
+	}
+	if progress != nil {
+		t.Errorf("OnProgress called after being disabled: %v", progress)
+	}
+}
+
+func TestConcurrentExecutor_ExecuteMap(t *testing.T) {
+	executor := NewConcurrentExecutor(NewBasicExecutor())
+
+	results, err := executor.ExecuteMap(context.Background(), map[string]ToolConfig{
+		"web": {Command: "echo", Args: []string{"web"}},
+		"api": {Command: "echo", Args: []string{"api"}},
+		"bad": {Command: "nonexistent-map-tool"},
+	})
+	if err != nil {
+		t.Fatalf("ExecuteMap() error = %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("ExecuteMap() returned %d results, want 3", len(results))
+	}
+	for _, key := range []string{"web", "api"} {
+		r := results[key]
+		if r.Error != nil || r.Result.Output != key+"\n" || r.Config.Args[0] != key {
+			t.Errorf("results[%q] = %+v, want the output of its own command", key, r)
+		}
+	}
+	if results["bad"].Error == nil {
+		t.Error(`results["bad"].Error = nil, want the execution error`)
+	}
+	// Indices follow the sorted keys.
+	if results["api"].Index != 0 || results["bad"].Index != 1 || results["web"].Index != 2 {
+		t.Errorf("indices = %d, %d, %d, want 0, 1, 2", results["api"].Index, results["bad"].Index, results["web"].Index)
+	}
+
+	empty, err := executor.ExecuteMap(context.Background(), nil)
+	if err != nil || len(empty) != 0 {
+		t.Errorf("ExecuteMap(nil) = (%v, %v), want an empty map", empty, err)
+	}
+}
+
+func TestConcurrentExecutor_SetBatchError(t *testing.T) {
+	executor := NewConcurrentExecutor(NewBasicExecutor())
+	configs := []ToolConfig{
+		{Command: "echo", Args: []string{"ok"}},
+		{Command: "sh", Args: []string{"-c", "echo broken >&2; exit 3"}},
+		{Command: "nonexistent-batch-tool"},
+		{Command: "sh", Args: []string{"-c", "exit 1"}, SuccessWhen: SucceedOnExitCodes(1)},
+	}
+
+	if _, err := executor.ExecuteAll(context.Background(), configs); err != nil {
+		t.Fatalf("ExecuteAll() error = %v, want nil unless SetBatchError is enabled", err)
+	}
+
+	executor.SetBatchError(true)
+	results, err := executor.ExecuteAll(context.Background(), configs)
+	if len(results) != 4 {
+		t.Fatalf("ExecuteAll() returned %d results, want 4 alongside the error", len(results))
+	}
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("ExecuteAll() error = %v, want *BatchError", err)
+	}
+	if got := fmt.Sprint(batchErr.FailedIndices()); got != "[1 2]" || batchErr.Total != 4 {
+		t.Errorf("FailedIndices() = %s, Total = %d, want [1 2] and 4", got, batchErr.Total)
+	}
+
+	var exitErr *ExitError
+	if !errors.As(batchErr.Failures[0], &exitErr) || exitErr.ExitCode != 3 {
+		t.Errorf("Failures[0] = %v, want an *ExitError with code 3", batchErr.Failures[0])
+	}
+	var notFound *ExecutableNotFoundError
+	if !errors.As(err, &notFound) {
+		t.Errorf("errors.As(err, *ExecutableNotFoundError) = false, want per-item errors reachable")
+	}
+	for _, want := range []string{"2 of 4 commands failed", "command 1 (sh -c", "exit status 3: broken", "command 2 (nonexistent-batch-tool)"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Error() = %q, want it to contain %q", err.Error(), want)
+		}
+	}
+
+	if _, err := executor.ExecuteAll(context.Background(), configs[:1]); err != nil {
+		t.Errorf("ExecuteAll() of succeeding commands error = %v, want nil", err)
+	}
+	byKey, err := executor.ExecuteMap(context.Background(), map[string]ToolConfig{"ok": configs[0], "bad": configs[2]})
+	if !errors.As(err, &batchErr) || len(byKey) != 2 {
+		t.Errorf("ExecuteMap() = (%d results, %v), want both results and a *BatchError", len(byKey), err)
+	}
+}
+
+func TestConcurrentExecutor_Start_Cancel(t *testing.T) {
+	executor := NewConcurrentExecutor(NewBasicExecutor())
+	batch := executor.Start(context.Background(), []ToolConfig{
+		{Command: "sh", Args: []string{"-c", "sleep 0.2; echo done"}},
+		{Command: "sh", Args: []string{"-c", "while sleep 0.05; do :; done"}},
+	})
+	if batch.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", batch.Len())
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	batch.Cancel(1)
+	batch.Cancel(5) // out of range is ignored
+
+	select {
+	case <-batch.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("batch did not finish after cancelling the runaway command")
+	}
+	results, err := batch.Wait()
+	if err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if results[0].Error != nil || results[0].Result.Output != "done\n" {
+		t.Errorf("results[0] = %+v, want the other command to finish normally", results[0])
+	}
+	if results[1].Error == nil {
+		t.Errorf("results[1] = %+v, want an error from cancellation", results[1])
+	}
+}
+
+func TestConcurrentExecutor_Start_CancelQueued(t *testing.T) {
+	executor := NewConcurrentExecutor(NewBasicExecutor())
+	executor.SetMaxConcurrency(1)
+	finished := make(chan int, 2)
+	executor.SetOnItemComplete(func(index int, _ ConcurrentResult) { finished <- index })
+	batch := executor.Start(context.Background(), []ToolConfig{
+		{Command: "sh", Args: []string{"-c", "while sleep 0.05; do :; done"}},
+		{Command: "echo", Args: []string{"queued"}},
+	})
+	defer func() {
+		batch.CancelAll()
+		_, _ = batch.Wait()
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	batch.Cancel(1)
+	select {
+	case index := <-finished:
+		if index != 1 {
+			t.Fatalf("item %d finished first, want the cancelled queued item 1", index)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("cancelled queued item waited for the running command")
+	}
+}
+
+func TestConcurrentExecutor_Start_CancelAll(t *testing.T) {
+	executor := NewConcurrentExecutor(NewBasicExecutor())
+	executor.SetMaxConcurrency(1)
+	batch := executor.Start(context.Background(), []ToolConfig{
+		{Command: "sh", Args: []string{"-c", "while sleep 0.05; do :; done"}},
+		{Command: "sh", Args: []string{"-c", "while sleep 0.05; do :; done"}},
+	})
+	time.Sleep(50 * time.Millisecond)
+	batch.CancelAll()
+
+	results, _ := batch.Wait()
+	for i, r := range results {
+		if r.Error == nil {
+			t.Errorf("results[%d] = %+v, want an error from cancellation", i, r)
+		}
+	}
+}
+
+func TestConcurrentExecutor_ExecuteAll_CancelledBeforeStart(t *testing.T) {
+	mock := NewMockExecutor()
+	mock.SetResult(&ExecutionResult{Command: "echo"}, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results, _ := NewConcurrentExecutor(mock).ExecuteAll(ctx, []ToolConfig{{Command: "echo"}, {Command: "echo"}})
+	for i, r := range results {
+		if !errors.Is(r.Error, context.Canceled) {
+			t.Errorf("results[%d].Error = %v, want context.Canceled", i, r.Error)
+		}
+	}
+	if n := len(mock.Executions()); n != 0 {
+		t.Errorf("%d commands were started, want none after cancellation", n)
+	}
+}
+
+func TestSplitResults(t *testing.T) {
+	failure := errors.New("boom")
+	results := []ConcurrentResult{
+		{Index: 0, Config: ToolConfig{Command: "ok"}, Result: &ExecutionResult{}},
+		{Index: 1, Config: ToolConfig{Command: "exit"}, Result: &ExecutionResult{ExitCode: 2, Stderr: "bad"}},
+		{Index: 2, Config: ToolConfig{Command: "accepted", SuccessWhen: SucceedOnExitCodes(1)}, Result: &ExecutionResult{ExitCode: 1}},
+		{Index: 3, Config: ToolConfig{Command: "err"}, Error: failure},
+	}
+
+	succeeded, failed := SplitResults(results)
+	indices := func(rs []ConcurrentResult) string {
+		var out []int
+		for _, r := range rs {
+			out = append(out, r.Index)
+		}
+		return fmt.Sprint(out)
+	}
+	if got := indices(succeeded); got != "[0 2]" {
+		t.Errorf("succeeded = %s, want [0 2]", got)
+	}
+	if got := indices(failed); got != "[1 3]" {
+		t.Errorf("failed = %s, want [1 3]", got)
+	}
+
+	err := FirstError(results)
+	var itemErr *BatchItemError
+	var exitErr *ExitError
+	if !errors.As(err, &itemErr) || itemErr.Index != 1 || !errors.As(err, &exitErr) || exitErr.ExitCode != 2 {
+		t.Errorf("FirstError() = %v, want the exit failure of index 1", err)
+	}
+	if err := FirstError(results[3:]); !errors.Is(err, failure) {
+		t.Errorf("FirstError() = %v, want the execution error", err)
+	}
+	if err := FirstError(succeeded); err != nil {
+		t.Errorf("FirstError() of succeeded results = %v, want nil", err)
+	}
+}
+
+func TestConcurrentExecutor_ExecuteConcurrent_Weight(t *testing.T) {
+	mock := NewMockExecutor()
+	mock.SetResult(&ExecutionResult{}, nil)
+
+	var mu sync.Mutex
+	var weight, maxWeight, count, maxCount int
+	tracking := &hookExecutor{inner: mock, before: func(cfg ToolConfig) {
+		mu.Lock()
+		defer mu.Unlock()
+		weight += max(cfg.Weight, 1)
+		count++
+		maxWeight, maxCount = max(maxWeight, weight), max(maxCount, count)
+	}, after: func(cfg ToolConfig) {
+		mu.Lock()
+		defer mu.Unlock()
+		weight -= max(cfg.Weight, 1)
+		count--
+	}}
+
+	configs := []ToolConfig{{Command: "bazel", Weight: 2}, {Command: "bazel", Weight: 2}}
+	for range 4 {
+		configs = append(configs, ToolConfig{Command: "git"})
+	}
+	results, err := NewConcurrentExecutor(tracking).ExecuteConcurrent(context.Background(), configs, 3)
+	if err != nil || len(results) != 6 {
+		t.Fatalf("ExecuteConcurrent() = (%d results, %v)", len(results), err)
+	}
+	if maxWeight > 3 {
+		t.Errorf("peak running weight = %d, want at most 3", maxWeight)
+	}
+	if maxCount < 2 {
+		t.Errorf("peak running commands = %d, want light commands to share the budget", maxCount)
+	}
+}
+
+// hookExecutor calls before and after around each execution, with a short
+// pause so that concurrent executions overlap.
+type hookExecutor struct {
+	inner         Executor
+	before, after func(ToolConfig)
+}
+
+func (e *hookExecutor) Execute(ctx context.Context, cfg ToolConfig) (*ExecutionResult, error) {
+	e.before(cfg)
+	defer e.after(cfg)
+	time.Sleep(20 * time.Millisecond)
+	return e.inner.Execute(ctx, cfg) //nolint:wrapcheck // test helper
+}
+
+func (e *hookExecutor) IsAvailable(command string) bool {
+	return e.inner.IsAvailable(command)
+}
+
+func TestConcurrentExecutor_ExecuteSequential(t *testing.T) {
+	dir := t.TempDir()
+	configs := []ToolConfig{
+		{Command: "sh", Args: []string{"-c", "echo one > log"}, WorkingDir: dir},
+		{Command: "sh", Args: []string{"-c", "cat log; exit 1"}, WorkingDir: dir},
+		{Command: "echo", Args: []string{"three"}},
+	}
+	executor := NewConcurrentExecutor(NewBasicExecutor())
+
+	results, err := executor.ExecuteSequential(context.Background(), configs, true)
+	if err != nil {
+		t.Fatalf("ExecuteSequential() error = %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("ExecuteSequential() returned %d results, want to stop after the failing command", len(results))
+	}
+	if results[1].Index != 1 || results[1].Result.Output != "one\n" || results[1].Result.ExitCode != 1 {
+		t.Errorf("results[1] = %+v, want the second command to see the first one's file and fail", results[1])
+	}
+
+	executor.SetBatchError(true)
+	results, err = executor.ExecuteSequential(context.Background(), configs, false)
+	if len(results) != 3 || results[2].Result.Output != "three\n" {
+		t.Errorf("ExecuteSequential() without stopOnError returned %+v, want all three commands", results)
+	}
+	if got := FirstError(results); err == nil || got == nil {
+		t.Errorf("ExecuteSequential() error = %v, want a *BatchError for the failed command", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if results, _ := executor.ExecuteSequential(ctx, configs, false); len(results) != 0 {
+		t.Errorf("ExecuteSequential() with a done context ran %d commands, want none", len(results))
+	}
+}
+
+func TestConcurrentExecutor_SetMaxConcurrency_InFlight(t *testing.T) {
+	exec := newBlockingExecutor()
+	executor := NewConcurrentExecutor(exec)
+	executor.SetMaxConcurrency(1)
+
+	configs := make([]ToolConfig, 4)
+	for i := range configs {
+		configs[i] = ToolConfig{Command: fmt.Sprint(i)}
+	}
+	batch := executor.Start(context.Background(), configs)
+	<-exec.started
+	select {
+	case cmd := <-exec.started:
+		t.Fatalf("command %s started beyond the limit of 1", cmd)
+	case <-time.After(30 * time.Millisecond):
+	}
+
+	// Raising the limit releases waiting commands of the running batch.
+	executor.SetMaxConcurrency(3)
+	for range 2 {
+		select {
+		case <-exec.started:
+		case <-time.After(5 * time.Second):
+			t.Fatal("raising the limit did not start more commands")
+		}
+	}
+	select {
+	case cmd := <-exec.started:
+		t.Fatalf("command %s started beyond the raised limit of 3", cmd)
+	case <-time.After(30 * time.Millisecond):
+	}
+
+	close(exec.release)
+	if _, err := batch.Wait(); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	executor.mu.RLock()
+	defer executor.mu.RUnlock()
+	if len(executor.batches) != 0 {
+		t.Errorf("%d batch semaphores still registered after the batch finished", len(executor.batches))
+	}
+}
+
+func TestConcurrentExecutor_SetDefaultTimeout(t *testing.T) {
+	executor := NewConcurrentExecutor(NewBasicExecutor())
+	executor.SetDefaultTimeout(100 * time.Millisecond)
+
+	loop := []string{"-c", "sleep 0.3; echo slow"}
+	results, err := executor.ExecuteAll(context.Background(), []ToolConfig{
+		{Command: "sh", Args: loop},
+		{Command: "sh", Args: loop, Timeout: 5 * time.Second},
+	})
+	if err != nil {
+		t.Fatalf("ExecuteAll() error = %v", err)
+	}
+	var timeoutErr *TimeoutError
+	if !errors.As(results[0].Error, &timeoutErr) || timeoutErr.Timeout != 100*time.Millisecond {
+		t.Errorf("results[0].Error = %v, want the default timeout", results[0].Error)
+	}
+	if results[1].Error != nil || results[1].Result.Output != "slow\n" {
+		t.Errorf("results[1] = %+v, want its own timeout to override the default", results[1])
+	}
+	if results[0].Config.Timeout != 0 {
+		t.Errorf("results[0].Config.Timeout = %v, want the caller's config", results[0].Config.Timeout)
+	}
+
+	sequential, _ := executor.ExecuteSequential(context.Background(), []ToolConfig{{Command: "sh", Args: loop}}, false)
+	if !errors.As(sequential[0].Error, &timeoutErr) {
+		t.Errorf("ExecuteSequential() error = %v, want the default timeout", sequential[0].Error)
+	}
+}
+
+type batchItemKey struct{}
+
+func TestConcurrentExecutor_ExecuteItems(t *testing.T) {
+	var seen sync.Map
+	mock := NewMockExecutor()
+	mock.ExpectCustom(func(ctx context.Context, cfg ToolConfig) bool {
+		seen.Store(cfg.Command, ctx.Value(batchItemKey{}))
+		return true
+	}).WillSucceed("ok", 0).Build()
+	executor := NewConcurrentExecutor(mock)
+
+	cancelled, cancel := context.WithCancel(context.WithValue(context.Background(), batchItemKey{}, "item"))
+	cancel()
+	results, err := executor.ExecuteItems(context.Background(), []BatchItem{
+		{Config: ToolConfig{Command: "plain"}},
+		{Config: ToolConfig{Command: "valued"}, Context: context.WithValue(context.Background(), batchItemKey{}, "item")},
+		{Config: ToolConfig{Command: "cancelled"}, Context: cancelled},
+	})
+	if err != nil {
+		t.Fatalf("ExecuteItems() error = %v", err)
+	}
+	if results[0].Error != nil || results[1].Error != nil {
+		t.Errorf("results = %+v, want the first two commands to run", results)
+	}
+	if v, _ := seen.Load("valued"); v != "item" {
+		t.Errorf("context value = %v, want the item context's value", v)
+	}
+	if !errors.Is(results[2].Error, context.Canceled) {
+		t.Errorf("results[2].Error = %v, want its cancelled context to stop only it", results[2].Error)
+	}
+
+	// The batch context is an umbrella over item contexts.
+	umbrella, cancelBatch := context.WithCancel(context.Background())
+	cancelBatch()
+	results, _ = executor.ExecuteItems(umbrella, []BatchItem{{Config: ToolConfig{Command: "x"}, Context: context.Background()}})
+	if !errors.Is(results[0].Error, context.Canceled) {
+		t.Errorf("results[0].Error = %v, want the batch cancellation to apply", results[0].Error)
+	}
+}
@@ -0,0 +1,117 @@
+package cmdexec
+
+import (
+	"io"
+	"slices"
+	"time"
+)
+
+// ToolConfigBuilder builds a ToolConfig fluently, which reads better than a
+// struct literal once many optional fields are set:
+//
+//	cfg := cmdexec.NewToolConfig("go", "test", "./...").
+//		WithTimeout(time.Minute).
+//		WithEnv("CI", "1").
+//		WithWorkingDir(dir).
+//		Build()
+//
+// Fields without a dedicated method can be set with With.
+type ToolConfigBuilder struct {
+	cfg ToolConfig
+}
+
+// NewToolConfig starts building a configuration that runs command with
+// args.
+func NewToolConfig(command string, args ...string) *ToolConfigBuilder {
+	return &ToolConfigBuilder{cfg: ToolConfig{Command: command, Args: slices.Clone(args)}}
+}
+
+// WithArgs appends args to the arguments.
+func (b *ToolConfigBuilder) WithArgs(args ...string) *ToolConfigBuilder {
+	b.cfg.Args = append(b.cfg.Args, args...)
+	return b
+}
+
+// WithWorkingDir sets the directory the command runs in.
+func (b *ToolConfigBuilder) WithWorkingDir(dir string) *ToolConfigBuilder {
+	b.cfg.WorkingDir = dir
+	return b
+}
+
+// WithTimeout sets the maximum duration the command may run.
+func (b *ToolConfigBuilder) WithTimeout(timeout time.Duration) *ToolConfigBuilder {
+	b.cfg.Timeout = timeout
+	return b
+}
+
+// WithIdleTimeout sets the maximum duration the command may go without
+// producing output.
+func (b *ToolConfigBuilder) WithIdleTimeout(timeout time.Duration) *ToolConfigBuilder {
+	b.cfg.IdleTimeout = timeout
+	return b
+}
+
+// WithEnv sets the environment variable key to value for the command.
+func (b *ToolConfigBuilder) WithEnv(key, value string) *ToolConfigBuilder {
+	if b.cfg.Env == nil {
+		b.cfg.Env = make(map[string]string)
+	}
+	b.cfg.Env[key] = value
+	return b
+}
+
+// WithRetries retries failed attempts up to maxRetries times, waiting delay
+// between attempts.
+func (b *ToolConfigBuilder) WithRetries(maxRetries int, delay time.Duration) *ToolConfigBuilder {
+	b.cfg.MaxRetries = maxRetries
+	b.cfg.RetryDelay = delay
+	return b
+}
+
+// WithStdin sets the reader providing the command's input.
+func (b *ToolConfigBuilder) WithStdin(stdin io.Reader) *ToolConfigBuilder {
+	b.cfg.Stdin = stdin
+	return b
+}
+
+// WithStdout streams the command's stdout to w while it runs.
+func (b *ToolConfigBuilder) WithStdout(w io.Writer) *ToolConfigBuilder {
+	b.cfg.StdoutWriter = w
+	return b
+}
+
+// WithStderr streams the command's stderr to w while it runs.
+func (b *ToolConfigBuilder) WithStderr(w io.Writer) *ToolConfigBuilder {
+	b.cfg.StderrWriter = w
+	return b
+}
+
+// WithCommandBuilder sets how the command is invoked, for example through
+// a shell with ShellCommandBuilder.
+func (b *ToolConfigBuilder) WithCommandBuilder(builder CommandBuilder) *ToolConfigBuilder {
+	b.cfg.CommandBuilder = builder
+	return b
+}
+
+// With applies fn to the configuration being built, to set fields that have
+// no dedicated method.
+func (b *ToolConfigBuilder) With(fn func(cfg *ToolConfig)) *ToolConfigBuilder {
+	fn(&b.cfg)
+	return b
+}
+
+// Build returns the configuration. It returns a clone, so the builder can
+// be reused to derive further configurations.
+func (b *ToolConfigBuilder) Build() ToolConfig {
+	return b.cfg.Clone()
+}
+
+// BuildValidated returns the configuration like Build, or the error of
+// ToolConfig.Validate if it is invalid.
+func (b *ToolConfigBuilder) BuildValidated() (ToolConfig, error) {
+	cfg := b.Build()
+	if err := cfg.Validate(); err != nil {
+		return ToolConfig{}, err
+	}
+	return cfg, nil
+}
@@ -0,0 +1,62 @@
+package cmdexec
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestToolConfigBuilder(t *testing.T) {
+	var stdout, stderr strings.Builder
+	stdin := strings.NewReader("input")
+	builder := NewToolConfig("go", "test").
+		WithArgs("./...").
+		WithWorkingDir("/repo").
+		WithTimeout(time.Minute).
+		WithIdleTimeout(10*time.Second).
+		WithEnv("CI", "1").
+		WithEnv("GOFLAGS", "-mod=mod").
+		WithRetries(2, time.Second).
+		WithStdin(stdin).
+		WithStdout(&stdout).
+		WithStderr(&stderr).
+		WithCommandBuilder(&ShellCommandBuilder{}).
+		With(func(cfg *ToolConfig) { cfg.Weight = 3 })
+	cfg := builder.Build()
+
+	if cfg.Command != "go" || strings.Join(cfg.Args, " ") != "test ./..." || cfg.WorkingDir != "/repo" {
+		t.Errorf("command = %q %q in %q", cfg.Command, cfg.Args, cfg.WorkingDir)
+	}
+	if cfg.Timeout != time.Minute || cfg.IdleTimeout != 10*time.Second || cfg.MaxRetries != 2 || cfg.RetryDelay != time.Second {
+		t.Errorf("timing = %v %v %d %v", cfg.Timeout, cfg.IdleTimeout, cfg.MaxRetries, cfg.RetryDelay)
+	}
+	if cfg.Env["CI"] != "1" || cfg.Env["GOFLAGS"] != "-mod=mod" {
+		t.Errorf("Env = %v", cfg.Env)
+	}
+	if cfg.Stdin != stdin || cfg.StdoutWriter != &stdout || cfg.StderrWriter != &stderr || cfg.Weight != 3 {
+		t.Error("Stdin, writers or Weight not set")
+	}
+	if _, ok := cfg.CommandBuilder.(*ShellCommandBuilder); !ok {
+		t.Errorf("CommandBuilder = %T, want *ShellCommandBuilder", cfg.CommandBuilder)
+	}
+
+	// Build returns independent configurations.
+	derived := builder.WithEnv("CI", "0").WithArgs("-v").Build()
+	if cfg.Env["CI"] != "1" || len(cfg.Args) != 2 {
+		t.Errorf("building again changed the earlier config: Env %v Args %q", cfg.Env, cfg.Args)
+	}
+	if derived.Env["CI"] != "0" || len(derived.Args) != 3 {
+		t.Errorf("derived config: Env %v Args %q", derived.Env, derived.Args)
+	}
+}
+
+func TestToolConfigBuilder_BuildValidated(t *testing.T) {
+	if cfg, err := NewToolConfig("echo", "hi").BuildValidated(); err != nil || cfg.Command != "echo" {
+		t.Errorf("BuildValidated() = (%+v, %v), want a valid config", cfg, err)
+	}
+	var validationErr *ValidationError
+	if _, err := NewToolConfig("echo").WithTimeout(-time.Second).BuildValidated(); !errors.As(err, &validationErr) {
+		t.Errorf("BuildValidated() with a negative timeout error = %v, want *ValidationError", err)
+	}
+}
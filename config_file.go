@@ -0,0 +1,167 @@
+package cmdexec
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Duration is a time.Duration that is encoded in JSON as a string such as
+// "30s" or "1m30s", as accepted by time.ParseDuration.
+type Duration time.Duration
+
+// MarshalJSON encodes the duration as a string.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(time.Duration(d).String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode duration: %w", err)
+	}
+	return data, nil
+}
+
+// UnmarshalJSON decodes a duration string.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"30s\": %w", err)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("invalid duration: %w", err)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// ToolConfigFile is the serializable subset of ToolConfig read by
+// LoadToolConfig, so command definitions can live in configuration files.
+// Fields that hold readers, writers or functions have no file form.
+type ToolConfigFile struct {
+	Command          string            `json:"command"`
+	Args             []string          `json:"args,omitempty"`
+	WorkingDir       string            `json:"workingDir,omitempty"`
+	EnsureWorkingDir bool              `json:"ensureWorkingDir,omitempty"`
+	ExpandVariables  bool              `json:"expandVariables,omitempty"`
+	MinVersion       string            `json:"minVersion,omitempty"`
+	Env              map[string]string `json:"env,omitempty"`
+	Labels           map[string]string `json:"labels,omitempty"`
+	Timeout          Duration          `json:"timeout,omitempty"`
+	IdleTimeout      Duration          `json:"idleTimeout,omitempty"`
+	CPUTimeLimit     Duration          `json:"cpuTimeLimit,omitempty"`
+	MaxRetries       int               `json:"maxRetries,omitempty"`
+	RetryDelay       Duration          `json:"retryDelay,omitempty"`
+	MaxRetryElapsed  Duration          `json:"maxRetryElapsed,omitempty"`
+	MaxStdoutBytes   int64             `json:"maxStdoutBytes,omitempty"`
+	MaxStderrBytes   int64             `json:"maxStderrBytes,omitempty"`
+	MaxOutputBytes   int64             `json:"maxOutputBytes,omitempty"`
+	CombineOutput    bool              `json:"combineOutput,omitempty"`
+	MergeStderr      bool              `json:"mergeStderr,omitempty"`
+	DiscardOutput    bool              `json:"discardOutput,omitempty"`
+	Weight           int               `json:"weight,omitempty"`
+
+	// Stdin, if not empty, is passed to the command as its input.
+	Stdin string `json:"stdin,omitempty"`
+
+	// StdinFile and MaxStdinBytes are as in ToolConfig.
+	StdinFile     string `json:"stdinFile,omitempty"`
+	MaxStdinBytes int64  `json:"maxStdinBytes,omitempty"`
+
+	// Shell runs the command through ShellCommandBuilder.
+	Shell bool `json:"shell,omitempty"`
+
+	// ArgSchema, if set, validates Args as ToolConfig.ArgValidator.
+	ArgSchema *ArgSchema `json:"argSchema,omitempty"`
+
+	// SuccessExitCodes, if not empty, are the exit codes that count as
+	// success, as with SucceedOnExitCodes.
+	SuccessExitCodes []int `json:"successExitCodes,omitempty"`
+}
+
+// ToolConfig converts the file form into a ToolConfig.
+func (f *ToolConfigFile) ToolConfig() ToolConfig {
+	cfg := ToolConfig{
+		Command:          f.Command,
+		Args:             f.Args,
+		WorkingDir:       f.WorkingDir,
+		EnsureWorkingDir: f.EnsureWorkingDir,
+		ExpandVariables:  f.ExpandVariables,
+		MinVersion:       f.MinVersion,
+		Env:              f.Env,
+		Labels:           f.Labels,
+		Timeout:          time.Duration(f.Timeout),
+		IdleTimeout:      time.Duration(f.IdleTimeout),
+		CPUTimeLimit:     time.Duration(f.CPUTimeLimit),
+		MaxRetries:       f.MaxRetries,
+		RetryDelay:       time.Duration(f.RetryDelay),
+		MaxRetryElapsed:  time.Duration(f.MaxRetryElapsed),
+		MaxStdoutBytes:   f.MaxStdoutBytes,
+		MaxStderrBytes:   f.MaxStderrBytes,
+		MaxOutputBytes:   f.MaxOutputBytes,
+		CombineOutput:    f.CombineOutput,
+		MergeStderr:      f.MergeStderr,
+		DiscardOutput:    f.DiscardOutput,
+		Weight:           f.Weight,
+		StdinFile:        f.StdinFile,
+		MaxStdinBytes:    f.MaxStdinBytes,
+	}
+	if f.Stdin != "" {
+		// A factory lets the config be retried and executed repeatedly.
+		stdin := f.Stdin
+		cfg.StdinFactory = func() io.Reader { return strings.NewReader(stdin) }
+	}
+	if f.Shell {
+		cfg.CommandBuilder = &ShellCommandBuilder{}
+	}
+	if f.ArgSchema != nil {
+		cfg.ArgValidator = f.ArgSchema
+	}
+	if len(f.SuccessExitCodes) > 0 {
+		cfg.SuccessWhen = SucceedOnExitCodes(f.SuccessExitCodes...)
+	}
+	return cfg
+}
+
+// LoadToolConfig reads one JSON-encoded ToolConfigFile from r and returns
+// the validated ToolConfig. Durations are strings such as "30s". Unknown
+// fields are rejected, so typos do not go unnoticed. Only JSON is
+// supported; convert YAML configs to JSON before loading them.
+func LoadToolConfig(r io.Reader) (ToolConfig, error) {
+	var file ToolConfigFile
+	if err := decodeConfigFile(r, &file); err != nil {
+		return ToolConfig{}, err
+	}
+	cfg := file.ToolConfig()
+	if err := cfg.Validate(); err != nil {
+		return ToolConfig{}, err
+	}
+	return cfg, nil
+}
+
+// LoadToolConfigs reads a JSON array of ToolConfigFiles from r, such as a
+// batch to pass to ConcurrentExecutor.ExecuteAll, and returns the validated
+// ToolConfigs. Errors name the index of the offending entry.
+func LoadToolConfigs(r io.Reader) ([]ToolConfig, error) {
+	var files []ToolConfigFile
+	if err := decodeConfigFile(r, &files); err != nil {
+		return nil, err
+	}
+	configs := make([]ToolConfig, len(files))
+	for i := range files {
+		configs[i] = files[i].ToolConfig()
+		if err := configs[i].Validate(); err != nil {
+			return nil, fmt.Errorf("config %d: %w", i, err)
+		}
+	}
+	return configs, nil
+}
+
+func decodeConfigFile(r io.Reader, v any) error {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("failed to decode tool config: %w", err)
+	}
+	return nil
+}
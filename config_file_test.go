@@ -0,0 +1,103 @@
+package cmdexec
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadToolConfig(t *testing.T) {
+	cfg, err := LoadToolConfig(strings.NewReader(`{
+		"command": "go",
+		"args": ["test", "./..."],
+		"workingDir": "/repo",
+		"env": {"CI": "1"},
+		"timeout": "1m30s",
+		"maxRetries": 2,
+		"retryDelay": "500ms",
+		"maxStdoutBytes": 1024,
+		"stdin": "input",
+		"shell": true,
+		"successExitCodes": [0, 1]
+	}`))
+	if err != nil {
+		t.Fatalf("LoadToolConfig() error = %v", err)
+	}
+	if cfg.Command != "go" || len(cfg.Args) != 2 || cfg.WorkingDir != "/repo" || cfg.Env["CI"] != "1" {
+		t.Errorf("config = %+v", cfg)
+	}
+	if cfg.Timeout != 90*time.Second || cfg.MaxRetries != 2 || cfg.RetryDelay != 500*time.Millisecond || cfg.MaxStdoutBytes != 1024 {
+		t.Errorf("limits = %v %d %v %d", cfg.Timeout, cfg.MaxRetries, cfg.RetryDelay, cfg.MaxStdoutBytes)
+	}
+	if _, ok := cfg.CommandBuilder.(*ShellCommandBuilder); !ok {
+		t.Errorf("CommandBuilder = %T, want *ShellCommandBuilder", cfg.CommandBuilder)
+	}
+	for range 2 {
+		if data, _ := io.ReadAll(cfg.StdinFactory()); string(data) != "input" {
+			t.Errorf("stdin = %q, want %q on every call", data, "input")
+		}
+	}
+	if !cfg.Succeeded(&ExecutionResult{ExitCode: 1}) || cfg.Succeeded(&ExecutionResult{ExitCode: 2}) {
+		t.Error("SuccessWhen does not follow successExitCodes")
+	}
+}
+
+func TestLoadToolConfig_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "malformed", input: `{"command": `},
+		{name: "unknown field", input: `{"command": "go", "timeoutSecs": 1}`},
+		{name: "numeric duration", input: `{"command": "go", "timeout": 30}`},
+		{name: "bad duration", input: `{"command": "go", "timeout": "soon"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadToolConfig(strings.NewReader(tt.input)); err == nil {
+				t.Error("LoadToolConfig() error = nil")
+			}
+		})
+	}
+
+	var validationErr *ValidationError
+	if _, err := LoadToolConfig(strings.NewReader(`{"command": ""}`)); !errors.As(err, &validationErr) {
+		t.Errorf("LoadToolConfig() of an invalid config error = %v, want *ValidationError", err)
+	}
+}
+
+func TestLoadToolConfigs(t *testing.T) {
+	configs, err := LoadToolConfigs(strings.NewReader(`[
+		{"command": "go", "args": ["vet", "./..."]},
+		{"command": "golangci-lint", "args": ["run"], "timeout": "5m", "weight": 2}
+	]`))
+	if err != nil {
+		t.Fatalf("LoadToolConfigs() error = %v", err)
+	}
+	if len(configs) != 2 || configs[0].Command != "go" || configs[1].Timeout != 5*time.Minute || configs[1].Weight != 2 {
+		t.Errorf("configs = %+v", configs)
+	}
+
+	_, err = LoadToolConfigs(strings.NewReader(`[{"command": "go"}, {"command": "go", "maxRetries": -1}]`))
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || !strings.HasPrefix(err.Error(), "config 1:") {
+		t.Errorf("LoadToolConfigs() error = %v, want a *ValidationError for config 1", err)
+	}
+	if _, err := LoadToolConfigs(strings.NewReader(`{"command": "go"}`)); err == nil {
+		t.Error("LoadToolConfigs() of an object error = nil, want an array to be required")
+	}
+}
+
+func TestDuration_JSON(t *testing.T) {
+	data, err := json.Marshal(Duration(90 * time.Second))
+	if err != nil || string(data) != `"1m30s"` {
+		t.Errorf("Marshal() = (%s, %v), want \"1m30s\"", data, err)
+	}
+	var d Duration
+	if err := json.Unmarshal(data, &d); err != nil || time.Duration(d) != 90*time.Second {
+		t.Errorf("Unmarshal() = (%v, %v), want 1m30s", time.Duration(d), err)
+	}
+}
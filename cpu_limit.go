@@ -0,0 +1,29 @@
+package cmdexec
+
+import (
+	"log/slog"
+	"os"
+	"time"
+)
+
+// cpuLimitSeconds rounds limit up to whole seconds, the granularity of
+// RLIMIT_CPU.
+func cpuLimitSeconds(limit time.Duration) uint64 {
+	return uint64((limit + time.Second - 1) / time.Second) //nolint:gosec // limit is validated to be positive
+}
+
+// cpuTimeLimitHook returns an onStart hook that applies limit to the process
+// before calling next, or next unchanged if no limit is configured.
+func cpuTimeLimitHook(limit time.Duration, next func(*os.Process)) func(*os.Process) {
+	if limit <= 0 {
+		return next
+	}
+	return func(p *os.Process) {
+		if err := applyCPUTimeLimit(p, limit); err != nil {
+			slog.Debug("Failed to apply CPU time limit", "error", err)
+		}
+		if next != nil {
+			next(p)
+		}
+	}
+}
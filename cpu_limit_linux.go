@@ -0,0 +1,46 @@
+//go:build linux
+
+package cmdexec
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// cpuTimeLimitSupported reports whether CPUTimeLimit can be enforced.
+const cpuTimeLimitSupported = true
+
+// applyCPUTimeLimit sets RLIMIT_CPU on the started process. The kernel sends
+// SIGXCPU when the soft limit is reached and SIGKILL one second later.
+func applyCPUTimeLimit(p *os.Process, limit time.Duration) error {
+	secs := cpuLimitSeconds(limit)
+	rlim := unix.Rlimit{Cur: secs, Max: secs + 1}
+	if err := unix.Prlimit(p.Pid, unix.RLIMIT_CPU, &rlim, nil); err != nil {
+		return fmt.Errorf("failed to set CPU time limit on process %d: %w", p.Pid, err)
+	}
+	return nil
+}
+
+// cpuTimeLimitExceeded reports whether the kernel terminated the process for
+// exceeding RLIMIT_CPU: either by SIGXCPU at the soft limit, or by SIGKILL
+// at the hard limit after the process ignored SIGXCPU.
+func cpuTimeLimitExceeded(state *os.ProcessState, limit time.Duration) bool {
+	if limit <= 0 {
+		return false
+	}
+	ws, ok := waitStatus(state)
+	if !ok || !ws.Signaled() {
+		return false
+	}
+	switch ws.Signal() {
+	case unix.SIGXCPU:
+		return true
+	case unix.SIGKILL:
+		used := state.UserTime() + state.SystemTime()
+		return used >= time.Duration(cpuLimitSeconds(limit))*time.Second //nolint:gosec // bounded by limit
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+//go:build linux
+
+package cmdexec
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBasicExecutor_Execute_CPUTimeLimit(t *testing.T) {
+	start := time.Now()
+	result, err := NewBasicExecutor().Execute(context.Background(), ToolConfig{
+		Command:      "sh",
+		Args:         []string{"-c", "while :; do :; done"},
+		CPUTimeLimit: time.Second,
+		Timeout:      10 * time.Second,
+	})
+	if result != nil {
+		t.Errorf("result = %v, want nil", result)
+	}
+	var cpuErr *CPUTimeLimitError
+	if !errors.As(err, &cpuErr) {
+		t.Fatalf("error = %v, want *CPUTimeLimitError", err)
+	}
+	// Rusage accounting may differ slightly from the kernel's limit check.
+	if cpuErr.Used < 900*time.Millisecond {
+		t.Errorf("Used = %v, want about 1s", cpuErr.Used)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("CPU limit took %v to be enforced", elapsed)
+	}
+}
+
+func TestBasicExecutor_Execute_CPUTimeLimitNotHitBySleeping(t *testing.T) {
+	result, err := NewBasicExecutor().Execute(context.Background(), ToolConfig{
+		Command:      "sleep",
+		Args:         []string{"1.2"},
+		CPUTimeLimit: time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.ExitCode != 0 {
+		t.Errorf("ExitCode = %d, want 0", result.ExitCode)
+	}
+}
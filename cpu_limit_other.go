@@ -0,0 +1,22 @@
+//go:build !linux
+
+package cmdexec
+
+import (
+	"os"
+	"time"
+)
+
+// cpuTimeLimitSupported reports whether CPUTimeLimit can be enforced.
+const cpuTimeLimitSupported = false
+
+// applyCPUTimeLimit is unreachable on platforms without support, since
+// Validate rejects CPUTimeLimit there.
+func applyCPUTimeLimit(_ *os.Process, _ time.Duration) error {
+	return &ValidationError{Field: "CPUTimeLimit", Message: "CPU time limits are only supported on Linux"}
+}
+
+// cpuTimeLimitExceeded always reports false on platforms without support.
+func cpuTimeLimitExceeded(_ *os.ProcessState, _ time.Duration) bool {
+	return false
+}
@@ -0,0 +1,117 @@
+package cmdexec
+
+import (
+	"fmt"
+	"time"
+)
+
+// dedupFields are the ToolConfig fields that identify a deduplicable
+// command. Maps are printed with sorted keys, so formatting them yields a
+// stable fingerprint.
+type dedupFields struct {
+	Command               string
+	Args                  []string
+	WorkingDir            string
+	EnsureWorkingDir      bool
+	ExpandVariables       bool
+	MinVersion            string
+	Env                   map[string]string
+	Labels                map[string]string
+	Timeout               time.Duration
+	IdleTimeout           time.Duration
+	CPUTimeLimit          time.Duration
+	KillPolicy            KillPolicy
+	MaxRetries            int
+	RetryDelay            time.Duration
+	MaxRetryElapsed       time.Duration
+	StreamTimestampFormat string
+	FlushInterval         time.Duration
+	MaxStdoutBytes        int64
+	MaxStderrBytes        int64
+	MaxOutputBytes        int64
+	StdinFile             string
+	MaxStdinBytes         int64
+	TruncateMode          TruncateMode
+	ChecksumStdout        bool
+	OverflowPolicy        OverflowPolicy
+	CollapseRepeatedLines bool
+	InvalidUTF8           InvalidUTF8Policy
+	CombineOutput         bool
+	MergeStderr           bool
+	DiscardOutput         bool
+	Weight                int
+}
+
+// dedupKey returns the fingerprint of cfg, or false if cfg must not be
+// deduplicated. Configs with stdin, writers, callbacks, hooks or other
+// behavior that cannot be compared are never deduplicated, and neither are
+// spooled ones, whose results own temporary files.
+func dedupKey(cfg *ToolConfig) (string, bool) {
+	for _, set := range []bool{
+		cfg.Stdin != nil, cfg.StdinFactory != nil, cfg.StdinProvider != nil, cfg.CommandBuilder != nil,
+		cfg.StdoutWriter != nil, cfg.StderrWriter != nil,
+		cfg.OnStdoutLine != nil, cfg.OnStderrLine != nil, cfg.OutputLog != nil,
+		cfg.ProgressPattern != nil, cfg.OnProgress != nil, cfg.CommandValidator != nil, cfg.ArgValidator != nil,
+		cfg.CaptureFilter != nil, cfg.OutputEncoding != nil, cfg.SpoolThreshold > 0,
+		cfg.PreExec != nil, cfg.PostExec != nil, cfg.ArgFile != nil, cfg.Redactor != nil,
+		cfg.SuccessWhen != nil, cfg.Fallback != nil, cfg.OnRetry != nil,
+		cfg.RetryPolicy != nil, cfg.RetryIf != nil,
+		len(cfg.ExtraFiles) > 0, cfg.ProcessAttributes != nil, cfg.VersionCheck != nil,
+	} {
+		if set {
+			return "", false
+		}
+	}
+	return fmt.Sprintf("%#v", dedupFields{
+		Command:               cfg.Command,
+		Args:                  cfg.Args,
+		WorkingDir:            cfg.WorkingDir,
+		EnsureWorkingDir:      cfg.EnsureWorkingDir,
+		ExpandVariables:       cfg.ExpandVariables,
+		MinVersion:            cfg.MinVersion,
+		Env:                   cfg.Env,
+		Labels:                cfg.Labels,
+		Timeout:               cfg.Timeout,
+		IdleTimeout:           cfg.IdleTimeout,
+		CPUTimeLimit:          cfg.CPUTimeLimit,
+		KillPolicy:            cfg.KillPolicy,
+		MaxRetries:            cfg.MaxRetries,
+		RetryDelay:            cfg.RetryDelay,
+		MaxRetryElapsed:       cfg.MaxRetryElapsed,
+		StreamTimestampFormat: cfg.StreamTimestampFormat,
+		FlushInterval:         cfg.FlushInterval,
+		MaxStdoutBytes:        cfg.MaxStdoutBytes,
+		MaxStderrBytes:        cfg.MaxStderrBytes,
+		MaxOutputBytes:        cfg.MaxOutputBytes,
+		StdinFile:             cfg.StdinFile,
+		MaxStdinBytes:         cfg.MaxStdinBytes,
+		TruncateMode:          cfg.TruncateMode,
+		ChecksumStdout:        cfg.ChecksumStdout,
+		OverflowPolicy:        cfg.OverflowPolicy,
+		CollapseRepeatedLines: cfg.CollapseRepeatedLines,
+		InvalidUTF8:           cfg.InvalidUTF8,
+		CombineOutput:         cfg.CombineOutput,
+		MergeStderr:           cfg.MergeStderr,
+		DiscardOutput:         cfg.DiscardOutput,
+		Weight:                cfg.Weight,
+	}), true
+}
+
+// dedupGroups groups the indices of identical configs. Each group lists
+// its indices in order; groups are ordered by their first index.
+func dedupGroups(configs []ToolConfig) [][]int {
+	groups := make([][]int, 0, len(configs))
+	byKey := make(map[string]int)
+	for i := range configs {
+		key, ok := dedupKey(&configs[i])
+		if ok {
+			if g, seen := byKey[key]; seen {
+				groups[g] = append(groups[g], i)
+				continue
+			}
+			byKey[key] = len(groups)
+		}
+		groups = append(groups, []int{i})
+	}
+	return groups
+}
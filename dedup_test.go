@@ -0,0 +1,81 @@
+package cmdexec
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDedupGroups(t *testing.T) {
+	hook := func(string) {}
+	configs := []ToolConfig{
+		{Command: "go", Args: []string{"vet", "./..."}, Env: map[string]string{"A": "1", "B": "2"}},
+		{Command: "go", Args: []string{"test"}},
+		{Command: "go", Args: []string{"vet", "./..."}, Env: map[string]string{"B": "2", "A": "1"}},
+		{Command: "go", Args: []string{"vet", "./..."}, Env: map[string]string{"A": "1", "B": "2"}, Timeout: time.Second},
+		{Command: "go", Args: []string{"test"}, OnStdoutLine: hook},
+		{Command: "go", Args: []string{"test"}, OnStdoutLine: hook},
+		{Command: "go", Args: []string{"test"}},
+	}
+	if got := fmt.Sprint(dedupGroups(configs)); got != "[[0 2] [1 6] [3] [4] [5]]" {
+		t.Errorf("dedupGroups() = %s, want [[0 2] [1 6] [3] [4] [5]]", got)
+	}
+}
+
+func TestShareResult(t *testing.T) {
+	result := &ExecutionResult{Args: []string{"build"}, Labels: map[string]string{"target": "app"}}
+	if shareResult(result, 0) != result {
+		t.Error("shareResult() copied the result for the first index")
+	}
+	c := shareResult(result, 1)
+	c.Args[0] = "test"
+	c.Labels["target"] = "lib"
+	if result.Args[0] != "build" || result.Labels["target"] != "app" {
+		t.Errorf("mutating a copy changed the original to %v %v", result.Args, result.Labels)
+	}
+}
+
+func TestConcurrentExecutor_SetDeduplicate(t *testing.T) {
+	mock := NewMockExecutor()
+	mock.ExpectCustom(func(context.Context, ToolConfig) bool { return true }).
+		WillSucceed("built", 0).
+		Build()
+	executor := NewConcurrentExecutor(mock)
+	executor.SetDeduplicate(true)
+	var progress []string
+	executor.SetOnProgress(func(done, total int) {
+		progress = append(progress, fmt.Sprintf("%d/%d", done, total))
+	})
+
+	build := ToolConfig{Command: "bazel", Args: []string{"build", "//app"}}
+	configs := []ToolConfig{build, {Command: "bazel", Args: []string{"build", "//lib"}}, build, build}
+	results, err := executor.ExecuteAll(context.Background(), configs)
+	if err != nil {
+		t.Fatalf("ExecuteAll() error = %v", err)
+	}
+	if n := len(mock.Executions()); n != 2 {
+		t.Errorf("%d commands ran, want 2 unique ones", n)
+	}
+	for i, r := range results {
+		if r.Index != i || r.Error != nil || r.Result == nil || r.Result.Output != "built" {
+			t.Errorf("results[%d] = %+v, want the shared result", i, r)
+		}
+	}
+	if results[0].Result == results[2].Result {
+		t.Error("duplicate indices share one *ExecutionResult, want a copy each")
+	}
+	if got := strings.Join(progress, " "); got != "1/4 2/4 3/4 4/4" {
+		t.Errorf("progress = %s, want one report per index", got)
+	}
+
+	// Start cancels commands individually, so it never deduplicates.
+	mock.ClearCallHistory()
+	if _, err := executor.Start(context.Background(), configs).Wait(); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if n := len(mock.Executions()); n != 4 {
+		t.Errorf("Start ran %d commands, want all 4", n)
+	}
+}
@@ -0,0 +1,59 @@
+package cmdexec
+
+import (
+	"context"
+	"sync"
+)
+
+var (
+	defaultMu       sync.RWMutex
+	defaultExecutor Executor = NewBasicExecutor()
+)
+
+// Default returns the process-wide Executor used by OutputDefault and the
+// other *Default helpers. It is a BasicExecutor unless replaced with
+// SetDefault.
+func Default() Executor {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultExecutor
+}
+
+// SetDefault makes executor the process-wide default and returns the
+// previous one, so tests can swap in a MockExecutor and restore the
+// original afterwards:
+//
+//	prev := cmdexec.SetDefault(mock)
+//	t.Cleanup(func() { cmdexec.SetDefault(prev) })
+//
+// A nil executor restores a new BasicExecutor.
+func SetDefault(executor Executor) Executor {
+	if executor == nil {
+		executor = NewBasicExecutor()
+	}
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	prev := defaultExecutor
+	defaultExecutor = executor
+	return prev
+}
+
+// OutputDefault is like Output with the default executor.
+func OutputDefault(ctx context.Context, command string, args ...string) ([]byte, error) {
+	return Output(ctx, Default(), command, args...)
+}
+
+// OutputLinesDefault is like OutputLines with the default executor.
+func OutputLinesDefault(ctx context.Context, command string, args ...string) ([]string, error) {
+	return OutputLines(ctx, Default(), command, args...)
+}
+
+// CombinedOutputDefault is like CombinedOutput with the default executor.
+func CombinedOutputDefault(ctx context.Context, command string, args ...string) ([]byte, error) {
+	return CombinedOutput(ctx, Default(), command, args...)
+}
+
+// RunDefault is like Run with the default executor.
+func RunDefault(ctx context.Context, command string, args ...string) error {
+	return Run(ctx, Default(), command, args...)
+}
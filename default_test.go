@@ -0,0 +1,41 @@
+package cmdexec
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestDefault(t *testing.T) {
+	if _, ok := Default().(*BasicExecutor); !ok {
+		t.Fatalf("Default() = %T, want *BasicExecutor", Default())
+	}
+
+	mock := NewMockExecutor()
+	mock.ExpectCommandWithArgs("git", "status").WillSucceed("clean\n", 0).Build()
+	mock.ExpectCommandWithArgs("git", "log").WillSucceed("a\nb\n", 0).Build()
+	mock.ExpectCommandWithArgs("git", "push").WillFail("rejected", 1).Build()
+	prev := SetDefault(mock)
+	t.Cleanup(func() { SetDefault(prev) })
+
+	ctx := context.Background()
+	if out, err := OutputDefault(ctx, "git", "status"); err != nil || string(out) != "clean\n" {
+		t.Errorf("OutputDefault() = %q, %v", out, err)
+	}
+	if lines, err := OutputLinesDefault(ctx, "git", "log"); err != nil || !slices.Equal(lines, []string{"a", "b"}) {
+		t.Errorf("OutputLinesDefault() = %q, %v", lines, err)
+	}
+	if err := RunDefault(ctx, "git", "push"); err == nil {
+		t.Error("RunDefault() error = nil, want the exit error")
+	}
+	if out, err := CombinedOutputDefault(ctx, "git", "status"); err != nil || string(out) != "clean\n" {
+		t.Errorf("CombinedOutputDefault() = %q, %v", out, err)
+	}
+
+	if got := SetDefault(nil); got != mock {
+		t.Errorf("SetDefault() returned %T, want the mock", got)
+	}
+	if _, ok := Default().(*BasicExecutor); !ok {
+		t.Errorf("Default() after SetDefault(nil) = %T, want *BasicExecutor", Default())
+	}
+}
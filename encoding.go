@@ -0,0 +1,155 @@
+package cmdexec
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// OutputDecoder converts command output from a legacy character encoding to
+// UTF-8. It is satisfied by *encoding.Decoder from golang.org/x/text, so any
+// encoding provided there (for example japanese.ShiftJIS.NewDecoder()) can be
+// used directly. This package provides decoders for Latin-1, Windows-1252,
+// and UTF-16.
+type OutputDecoder interface {
+	Bytes(b []byte) ([]byte, error)
+}
+
+// InvalidUTF8Policy selects how bytes that are not valid UTF-8 are handled in
+// ExecutionResult after any OutputEncoding has been applied.
+type InvalidUTF8Policy int
+
+const (
+	// InvalidUTF8Keep leaves output byte for byte as produced. It is the
+	// default.
+	InvalidUTF8Keep InvalidUTF8Policy = iota
+
+	// InvalidUTF8Replace replaces each run of invalid bytes with U+FFFD.
+	InvalidUTF8Replace
+
+	// InvalidUTF8Strip removes invalid bytes.
+	InvalidUTF8Strip
+)
+
+// decoderFunc adapts a total decoding function to OutputDecoder.
+type decoderFunc func(b []byte) []byte
+
+func (f decoderFunc) Bytes(b []byte) ([]byte, error) {
+	return f(b), nil
+}
+
+// Built-in output decoders.
+var (
+	// EncodingLatin1 decodes ISO-8859-1.
+	EncodingLatin1 OutputDecoder = decoderFunc(decodeLatin1)
+
+	// EncodingWindows1252 decodes Windows code page 1252, the default ANSI
+	// code page of Western European Windows installations.
+	EncodingWindows1252 OutputDecoder = decoderFunc(decodeWindows1252)
+
+	// EncodingUTF16LE decodes little-endian UTF-16, as written by many
+	// Windows tools. A leading byte order mark is removed.
+	EncodingUTF16LE OutputDecoder = decoderFunc(func(b []byte) []byte {
+		return decodeUTF16(b, binary.LittleEndian)
+	})
+
+	// EncodingUTF16BE decodes big-endian UTF-16. A leading byte order mark
+	// is removed.
+	EncodingUTF16BE OutputDecoder = decoderFunc(func(b []byte) []byte {
+		return decodeUTF16(b, binary.BigEndian)
+	})
+)
+
+func decodeLatin1(b []byte) []byte {
+	out := make([]byte, 0, len(b))
+	for _, c := range b {
+		out = utf8.AppendRune(out, rune(c))
+	}
+	return out
+}
+
+// windows1252High maps bytes 0x80-0x9F, where Windows-1252 differs from
+// Latin-1. Unassigned positions map to the C1 control of the same value, as
+// Windows does.
+var windows1252High = [32]rune{
+	0x20AC, 0x0081, 0x201A, 0x0192, 0x201E, 0x2026, 0x2020, 0x2021,
+	0x02C6, 0x2030, 0x0160, 0x2039, 0x0152, 0x008D, 0x017D, 0x008F,
+	0x0090, 0x2018, 0x2019, 0x201C, 0x201D, 0x2022, 0x2013, 0x2014,
+	0x02DC, 0x2122, 0x0161, 0x203A, 0x0153, 0x009D, 0x017E, 0x0178,
+}
+
+func decodeWindows1252(b []byte) []byte {
+	out := make([]byte, 0, len(b))
+	for _, c := range b {
+		r := rune(c)
+		if c >= 0x80 && c <= 0x9F {
+			r = windows1252High[c-0x80]
+		}
+		out = utf8.AppendRune(out, r)
+	}
+	return out
+}
+
+func decodeUTF16(b []byte, order binary.ByteOrder) []byte {
+	units := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		units = append(units, order.Uint16(b[i:]))
+	}
+	if len(units) > 0 && units[0] == 0xFEFF {
+		units = units[1:]
+	}
+	out := make([]byte, 0, len(units))
+	for _, r := range utf16.Decode(units) {
+		out = utf8.AppendRune(out, r)
+	}
+	// An odd trailing byte cannot be decoded.
+	if len(b)%2 == 1 {
+		out = utf8.AppendRune(out, utf8.RuneError)
+	}
+	return out
+}
+
+// outputTranscoder applies ToolConfig.OutputEncoding and InvalidUTF8 to
+// captured output.
+type outputTranscoder struct {
+	decoder OutputDecoder
+	policy  InvalidUTF8Policy
+}
+
+func newOutputTranscoder(cfg ToolConfig) *outputTranscoder {
+	if cfg.OutputEncoding == nil && cfg.InvalidUTF8 == InvalidUTF8Keep {
+		return nil
+	}
+	return &outputTranscoder{decoder: cfg.OutputEncoding, policy: cfg.InvalidUTF8}
+}
+
+// transcode converts s to UTF-8. If the decoder fails, s is kept as is and
+// only the invalid byte policy is applied. It is safe to call on a nil
+// transcoder.
+func (t *outputTranscoder) transcode(s string) string {
+	if t == nil || s == "" {
+		return s
+	}
+	if t.decoder != nil {
+		if decoded, err := t.decoder.Bytes([]byte(s)); err == nil {
+			s = string(decoded)
+		}
+	}
+	switch t.policy {
+	case InvalidUTF8Replace:
+		return strings.ToValidUTF8(s, string(utf8.RuneError))
+	case InvalidUTF8Strip:
+		return strings.ToValidUTF8(s, "")
+	default:
+		return s
+	}
+}
+
+func (p InvalidUTF8Policy) validate() error {
+	if p < InvalidUTF8Keep || p > InvalidUTF8Strip {
+		return &ValidationError{Field: "InvalidUTF8", Message: fmt.Sprintf("unknown invalid UTF-8 policy %d", p)}
+	}
+	return nil
+}
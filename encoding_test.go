@@ -0,0 +1,70 @@
+package cmdexec
+
+import (
+	"context"
+	"testing"
+)
+
+func TestBuiltinDecoders(t *testing.T) {
+	tests := []struct {
+		name    string
+		decoder OutputDecoder
+		in      []byte
+		want    string
+	}{
+		{"latin1", EncodingLatin1, []byte("caf\xe9 \xa9"), "café ©"},
+		{"windows1252", EncodingWindows1252, []byte("\x80 \x93quoted\x94 caf\xe9"), "€ “quoted” café"},
+		{"utf16le", EncodingUTF16LE, []byte{0xff, 0xfe, 'h', 0, 'i', 0, 0xac, 0x20}, "hi€"},
+		{"utf16be", EncodingUTF16BE, []byte{0, 'h', 0, 'i', 0xd8, 0x3d, 0xde, 0x00}, "hi😀"},
+		{"utf16le odd length", EncodingUTF16LE, []byte{'a', 0, 'b'}, "a�"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.decoder.Bytes(tt.in)
+			if err != nil {
+				t.Fatalf("Bytes() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Bytes() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOutputTranscoder_InvalidUTF8(t *testing.T) {
+	in := "ok\xff\xfeend"
+	tests := []struct {
+		policy InvalidUTF8Policy
+		want   string
+	}{
+		{InvalidUTF8Keep, in},
+		{InvalidUTF8Replace, "ok�end"},
+		{InvalidUTF8Strip, "okend"},
+	}
+	for _, tt := range tests {
+		tc := newOutputTranscoder(ToolConfig{InvalidUTF8: tt.policy})
+		if got := tc.transcode(in); got != tt.want {
+			t.Errorf("policy %d: transcode() = %q, want %q", tt.policy, got, tt.want)
+		}
+	}
+}
+
+func TestBasicExecutor_Execute_OutputEncoding(t *testing.T) {
+	var streamed []string
+	result, err := NewBasicExecutor().Execute(context.Background(), ToolConfig{
+		Command:        "printf",
+		Args:           []string{`caf\351\n`},
+		OutputEncoding: EncodingWindows1252,
+		OnStdoutLine:   func(line string) { streamed = append(streamed, line) },
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Output != "café\n" {
+		t.Errorf("Output = %q, want %q", result.Output, "café\n")
+	}
+	if len(streamed) != 1 || streamed[0] != "caf\xe9" {
+		t.Errorf("streamed = %q, want the original bytes", streamed)
+	}
+}
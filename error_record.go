@@ -0,0 +1,250 @@
+package cmdexec
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Error kinds recorded in ErrorRecord.Kind.
+const (
+	ErrorKindValidation         = "validation"
+	ErrorKindTimeout            = "timeout"
+	ErrorKindIdleTimeout        = "idleTimeout"
+	ErrorKindCPUTimeLimit       = "cpuTimeLimit"
+	ErrorKindExecutableNotFound = "executableNotFound"
+	ErrorKindWorkingDir         = "workingDir"
+	ErrorKindCommandNotAllowed  = "commandNotAllowed"
+	ErrorKindOutputLimit        = "outputLimit"
+	ErrorKindStdinLimit         = "stdinLimit"
+	ErrorKindRetryExhausted     = "retryExhausted"
+	ErrorKindExit               = "exit"
+	ErrorKindUnexpectedCommand  = "unexpectedCommand"
+	ErrorKindCanceled           = "canceled"
+	ErrorKindDeadlineExceeded   = "deadlineExceeded"
+	ErrorKindPoolClosed         = "poolClosed"
+)
+
+// ErrorRecord is a serializable form of an execution error, so transcripts
+// and fake executor tables can reproduce typed errors such as *TimeoutError
+// rather than only their messages. Kind selects the error type and which of
+// the other fields apply; an empty Kind stands for an error of another type,
+// replayed with Message and wrapping Cause.
+type ErrorRecord struct {
+	Kind string `json:"kind,omitempty"`
+
+	// Message is the message of the original error. It is informational
+	// for every kind except the empty one.
+	Message string `json:"message,omitempty"`
+
+	Command string `json:"command,omitempty"`
+
+	// Field and Reason are the Field and Message of a *ValidationError;
+	// Reason is also the Reason of a *CommandNotAllowedError.
+	Field  string `json:"field,omitempty"`
+	Reason string `json:"reason,omitempty"`
+
+	// Dir is the directory of a *WorkingDirError.
+	Dir string `json:"dir,omitempty"`
+
+	// Duration is the Timeout, IdleTimeout or CPU time Limit, and Used the
+	// CPU time used, formatted like "1.5s".
+	Duration string `json:"duration,omitempty"`
+	Used     string `json:"used,omitempty"`
+
+	// Signal is the KillPolicy signal of a timeout.
+	Signal string `json:"signal,omitempty"`
+
+	// Stream and Limit describe an output or stdin limit.
+	Stream string `json:"stream,omitempty"`
+	Limit  int64  `json:"limit,omitempty"`
+
+	// ExitCode and Stderr are those of an *ExitError.
+	ExitCode int    `json:"exitCode,omitempty"`
+	Stderr   string `json:"stderr,omitempty"`
+
+	// Attempts, LastResult and History are those of a *RetryExhaustedError.
+	Attempts   int              `json:"attempts,omitempty"`
+	LastResult *ExecutionResult `json:"lastResult,omitempty"`
+	History    []AttemptSummary `json:"history,omitempty"`
+
+	// Cause is the wrapped error: the LastError of a *RetryExhaustedError,
+	// the Err of a *WorkingDirError, or the error an untyped error wraps.
+	Cause *ErrorRecord `json:"cause,omitempty"`
+}
+
+// NewErrorRecord records err, or returns nil if err is nil.
+func NewErrorRecord(err error) *ErrorRecord {
+	if err == nil {
+		return nil
+	}
+	r := &ErrorRecord{Message: err.Error()}
+	if r.recordTyped(err) {
+		return r
+	}
+	for kind, sentinel := range recordedSentinels {
+		if err == sentinel {
+			r.Kind = kind
+			return r
+		}
+	}
+	r.Cause = NewErrorRecord(errors.Unwrap(err))
+	return r
+}
+
+// recordedSentinels are the sentinel errors recorded by kind. Errors
+// wrapping them are recorded with an empty Kind and the sentinel as Cause.
+var recordedSentinels = map[string]error{
+	ErrorKindCanceled:         context.Canceled,
+	ErrorKindDeadlineExceeded: context.DeadlineExceeded,
+	ErrorKindPoolClosed:       ErrPoolClosed,
+}
+
+// recordTyped records the fields of the error types of this package. It
+// returns false if err is of another type; errors wrapping them are
+// recorded through Cause.
+func (r *ErrorRecord) recordTyped(err error) bool {
+	switch e := err.(type) {
+	case *ValidationError:
+		r.Kind, r.Field, r.Reason = ErrorKindValidation, e.Field, e.Message
+	case *TimeoutError:
+		r.Kind, r.Command, r.Duration, r.Signal = ErrorKindTimeout, e.Command, durationString(e.Timeout), e.Signal
+	case *IdleTimeoutError:
+		r.Kind, r.Command, r.Duration, r.Signal = ErrorKindIdleTimeout, e.Command, durationString(e.IdleTimeout), e.Signal
+	case *CPUTimeLimitError:
+		r.Kind, r.Command, r.Duration, r.Used = ErrorKindCPUTimeLimit, e.Command, durationString(e.Limit), durationString(e.Used)
+	case *ExecutableNotFoundError:
+		r.Kind, r.Command = ErrorKindExecutableNotFound, e.Command
+	case *WorkingDirError:
+		r.Kind, r.Dir, r.Cause = ErrorKindWorkingDir, e.Dir, NewErrorRecord(e.Err)
+	case *CommandNotAllowedError:
+		r.Kind, r.Command, r.Reason = ErrorKindCommandNotAllowed, e.Command, e.Reason
+	case *OutputLimitError:
+		r.Kind, r.Stream, r.Limit = ErrorKindOutputLimit, e.Stream, e.Limit
+	case *StdinLimitError:
+		r.Kind, r.Command, r.Limit = ErrorKindStdinLimit, e.Command, e.Limit
+	case *RetryExhaustedError:
+		r.Kind, r.Command, r.Attempts = ErrorKindRetryExhausted, e.Command, e.Attempts
+		r.LastResult, r.History = e.LastResult, e.History
+		r.Cause = NewErrorRecord(e.LastError)
+	case *ExitError:
+		r.Kind, r.ExitCode, r.Stderr = ErrorKindExit, e.ExitCode, e.Stderr
+	case *UnexpectedCommandError:
+		r.Kind, r.Command = ErrorKindUnexpectedCommand, e.Command
+	default:
+		return false
+	}
+	return true
+}
+
+// Err rebuilds the recorded error. It returns nil for a nil record.
+func (r *ErrorRecord) Err() error {
+	if r == nil {
+		return nil
+	}
+	if sentinel, ok := recordedSentinels[r.Kind]; ok {
+		return sentinel
+	}
+	switch r.Kind {
+	case ErrorKindValidation:
+		return &ValidationError{Field: r.Field, Message: r.Reason}
+	case ErrorKindTimeout, ErrorKindIdleTimeout, ErrorKindCPUTimeLimit:
+		return r.timeLimitErr()
+	case ErrorKindExecutableNotFound:
+		return &ExecutableNotFoundError{Command: r.Command}
+	case ErrorKindWorkingDir:
+		return &WorkingDirError{Dir: r.Dir, Err: r.Cause.Err()}
+	case ErrorKindCommandNotAllowed:
+		return &CommandNotAllowedError{Command: r.Command, Reason: r.Reason}
+	case ErrorKindOutputLimit:
+		return &OutputLimitError{Stream: r.Stream, Limit: r.Limit}
+	case ErrorKindStdinLimit:
+		return &StdinLimitError{Command: r.Command, Limit: r.Limit}
+	case ErrorKindRetryExhausted:
+		return &RetryExhaustedError{
+			Command: r.Command, Attempts: r.Attempts, LastError: r.Cause.Err(),
+			LastResult: r.LastResult, History: r.History,
+		}
+	case ErrorKindExit:
+		return &ExitError{ExitCode: r.ExitCode, Stderr: r.Stderr}
+	case ErrorKindUnexpectedCommand:
+		return &UnexpectedCommandError{Command: r.Command}
+	}
+	return &recordedError{msg: r.Message, cause: r.Cause.Err()}
+}
+
+// timeLimitErr rebuilds a timeout or CPU time limit error.
+func (r *ErrorRecord) timeLimitErr() error {
+	limit := parseRecordedDuration(r.Duration)
+	switch r.Kind {
+	case ErrorKindTimeout:
+		return &TimeoutError{Command: r.Command, Timeout: limit, Signal: r.Signal}
+	case ErrorKindIdleTimeout:
+		return &IdleTimeoutError{Command: r.Command, IdleTimeout: limit, Signal: r.Signal}
+	}
+	return &CPUTimeLimitError{Command: r.Command, Limit: limit, Used: parseRecordedDuration(r.Used)}
+}
+
+// parseRecordedDuration parses a duration written by durationString. A
+// malformed duration reads as zero; the message of the error is what
+// matters most.
+func parseRecordedDuration(s string) time.Duration {
+	d, _ := time.ParseDuration(s)
+	return d
+}
+
+// recordedError is a replayed error of a type ErrorRecord has no kind for.
+// It keeps the original message and unwraps to the recorded cause.
+type recordedError struct {
+	msg   string
+	cause error
+}
+
+func (e *recordedError) Error() string {
+	return e.msg
+}
+
+func (e *recordedError) Unwrap() error {
+	return e.cause
+}
+
+// attemptSummaryJSON is the JSON form of AttemptSummary, with the duration
+// as a string like the other durations of this package.
+type attemptSummaryJSON struct {
+	Attempt  int    `json:"attempt"`
+	ExitCode int    `json:"exitCode"`
+	Duration string `json:"duration,omitempty"`
+	Stderr   string `json:"stderr,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// MarshalJSON encodes the summary with camelCase keys.
+func (s AttemptSummary) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(attemptSummaryJSON{
+		Attempt: s.Attempt, ExitCode: s.ExitCode, Duration: durationString(s.Duration),
+		Stderr: s.Stderr, Error: s.Error,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal attempt summary: %w", err)
+	}
+	return data, nil
+}
+
+// UnmarshalJSON decodes a summary written by MarshalJSON.
+func (s *AttemptSummary) UnmarshalJSON(data []byte) error {
+	var aux attemptSummaryJSON
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return fmt.Errorf("failed to unmarshal attempt summary: %w", err)
+	}
+	var d time.Duration
+	if aux.Duration != "" {
+		var err error
+		if d, err = time.ParseDuration(aux.Duration); err != nil {
+			return fmt.Errorf("invalid duration format: %w", err)
+		}
+	}
+	*s = AttemptSummary{Attempt: aux.Attempt, ExitCode: aux.ExitCode, Duration: d, Stderr: aux.Stderr, Error: aux.Error}
+	return nil
+}
@@ -0,0 +1,66 @@
+package cmdexec
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"testing"
+	"time"
+)
+
+func TestErrorRecord_RoundTrip(t *testing.T) {
+	timeout := &TimeoutError{Command: "make", Timeout: 90 * time.Second, Signal: "SIGTERM"}
+	tests := []struct {
+		name  string
+		err   error
+		check func(error) bool
+	}{
+		{name: "timeout", err: timeout, check: func(err error) bool {
+			var te *TimeoutError
+			return errors.As(err, &te) && *te == *timeout
+		}},
+		{name: "not found", err: &ExecutableNotFoundError{Command: "rg"}, check: func(err error) bool {
+			var nf *ExecutableNotFoundError
+			return errors.As(err, &nf) && nf.Command == "rg"
+		}},
+		{name: "retry exhausted", err: &RetryExhaustedError{
+			Command: "curl", Attempts: 3, LastError: &ExitError{ExitCode: 7},
+			History: []AttemptSummary{{Attempt: 1, ExitCode: 7, Duration: time.Second, Error: "exit 7"}},
+		}, check: func(err error) bool {
+			var re *RetryExhaustedError
+			var exit *ExitError
+			return errors.As(err, &re) && re.Attempts == 3 && re.History[0].Duration == time.Second &&
+				errors.As(err, &exit) && exit.ExitCode == 7
+		}},
+		{name: "working dir", err: &WorkingDirError{Dir: "/nope", Err: fs.ErrNotExist}, check: func(err error) bool {
+			var wd *WorkingDirError
+			return errors.As(err, &wd) && wd.Dir == "/nope"
+		}},
+		{name: "wrapped sentinel", err: fmt.Errorf("context done before command started: %w", context.Canceled), check: func(err error) bool {
+			return errors.Is(err, context.Canceled) && err.Error() == "context done before command started: context canceled"
+		}},
+		{name: "plain", err: errors.New("connection refused"), check: func(err error) bool {
+			return err.Error() == "connection refused"
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(NewErrorRecord(tt.err))
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			var record ErrorRecord
+			if err := json.Unmarshal(data, &record); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if got := record.Err(); !tt.check(got) || got.Error() != tt.err.Error() {
+				t.Errorf("Err() = %#v (%v), want an error like %v", got, got, tt.err)
+			}
+		})
+	}
+	if NewErrorRecord(nil) != nil || (*ErrorRecord)(nil).Err() != nil {
+		t.Error("a nil error should record as nil and back")
+	}
+}
@@ -0,0 +1,139 @@
+package cmdexec
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+)
+
+// Execution is a handle to a command that runs in the background. It
+// mirrors the Start/Wait and StdoutPipe/StderrPipe workflow of exec.Cmd so
+// that code built around readers (scanners, decoders, parsers) can be
+// moved onto an Executor without adapters.
+//
+// Create one with NewExecution, obtain any pipes, then call Start followed
+// by Wait. Output is captured in the result as usual; pipes receive a copy.
+type Execution struct {
+	executor Executor
+	cfg      ToolConfig
+
+	mu      sync.Mutex
+	started bool
+	pipes   []*io.PipeWriter
+	done    chan struct{}
+	result  *ExecutionResult
+	err     error
+}
+
+// NewExecution returns an unstarted Execution of cfg on executor. The
+// configuration is cloned, so later changes to cfg have no effect.
+func NewExecution(executor Executor, cfg ToolConfig) *Execution {
+	return &Execution{
+		executor: executor,
+		cfg:      cfg.Clone(),
+		done:     make(chan struct{}),
+	}
+}
+
+// StdoutPipe returns a reader connected to the command's stdout. It must be
+// called before Start. As with exec.Cmd, the reader must be drained
+// concurrently with the command, because the command blocks while the pipe
+// is full; Wait closes the pipe once the command exits. Output written after
+// the reader is closed is discarded. An existing StdoutWriter continues to
+// receive output as well.
+func (x *Execution) StdoutPipe() (io.ReadCloser, error) {
+	return x.pipe("StdoutPipe", &x.cfg.StdoutWriter)
+}
+
+// StderrPipe is like StdoutPipe, for stderr.
+func (x *Execution) StderrPipe() (io.ReadCloser, error) {
+	return x.pipe("StderrPipe", &x.cfg.StderrWriter)
+}
+
+func (x *Execution) pipe(op string, dst *io.Writer) (io.ReadCloser, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if x.started {
+		return nil, &ExecutionStateError{Op: op, Message: "called after Start"}
+	}
+	pr, pw := io.Pipe()
+	x.pipes = append(x.pipes, pw)
+	sink := &pipeSink{pw: pw}
+	if *dst != nil {
+		*dst = io.MultiWriter(*dst, sink)
+	} else {
+		*dst = sink
+	}
+	return pr, nil
+}
+
+// Start validates the configuration and starts the command in the
+// background. An Execution can be started only once.
+func (x *Execution) Start(ctx context.Context) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if x.started {
+		return &ExecutionStateError{Op: "Start", Message: "already started"}
+	}
+	if err := x.cfg.Validate(); err != nil {
+		return err
+	}
+	x.started = true
+
+	go func() {
+		result, err := x.executor.Execute(ctx, x.cfg)
+		for _, pw := range x.pipes {
+			_ = pw.Close()
+		}
+		x.result, x.err = result, err
+		close(x.done)
+	}()
+	return nil
+}
+
+// Wait waits for the command to finish and returns the outcome, following
+// the same contract as Executor.Execute. It may be called more than once.
+func (x *Execution) Wait() (*ExecutionResult, error) {
+	x.mu.Lock()
+	started := x.started
+	x.mu.Unlock()
+
+	if !started {
+		return nil, &ExecutionStateError{Op: "Wait", Message: "not started"}
+	}
+	<-x.done
+	return x.result, x.err
+}
+
+// Done returns a channel that is closed when the command has finished.
+func (x *Execution) Done() <-chan struct{} {
+	return x.done
+}
+
+// ExecutionStateError is returned when an Execution method is called in
+// the wrong state, such as StdoutPipe after Start.
+type ExecutionStateError struct {
+	Op      string
+	Message string
+}
+
+func (e *ExecutionStateError) Error() string {
+	return "execution " + e.Op + ": " + e.Message
+}
+
+// pipeSink writes to a pipe, discarding output once the reader is closed
+// so that an abandoned reader does not fail the command.
+type pipeSink struct {
+	pw *io.PipeWriter
+}
+
+func (s *pipeSink) Write(p []byte) (int, error) {
+	n, err := s.pw.Write(p)
+	if errors.Is(err, io.ErrClosedPipe) {
+		return len(p), nil
+	}
+	return n, err //nolint:wrapcheck // io.Writer passthrough
+}
@@ -0,0 +1,118 @@
+package cmdexec
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestExecution_StdoutPipe(t *testing.T) {
+	x := NewExecution(NewBasicExecutor(), ToolConfig{
+		Command: "sh",
+		Args:    []string{"-c", "echo a; echo b; echo c"},
+	})
+	stdout, err := x.StdoutPipe()
+	if err != nil {
+		t.Fatalf("StdoutPipe() error = %v", err)
+	}
+	if err := x.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("scan error = %v", err)
+	}
+	if want := []string{"a", "b", "c"}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("lines = %q, want %q", lines, want)
+	}
+
+	result, err := x.Wait()
+	if err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if result.Output != "a\nb\nc\n" {
+		t.Errorf("Output = %q, captured output should be unaffected", result.Output)
+	}
+}
+
+func TestExecution_StderrPipe(t *testing.T) {
+	x := NewExecution(NewBasicExecutor(), ToolConfig{
+		Command: "sh",
+		Args:    []string{"-c", "echo oops >&2"},
+	})
+	stderr, err := x.StderrPipe()
+	if err != nil {
+		t.Fatalf("StderrPipe() error = %v", err)
+	}
+	if err := x.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	data, err := io.ReadAll(stderr)
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if string(data) != "oops\n" {
+		t.Errorf("stderr = %q, want %q", data, "oops\n")
+	}
+	if _, err := x.Wait(); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+}
+
+func TestExecution_ClosedReaderDoesNotFailCommand(t *testing.T) {
+	x := NewExecution(NewBasicExecutor(), ToolConfig{
+		Command: "sh",
+		Args:    []string{"-c", "echo one; echo two"},
+	})
+	stdout, err := x.StdoutPipe()
+	if err != nil {
+		t.Fatalf("StdoutPipe() error = %v", err)
+	}
+	_ = stdout.Close()
+	if err := x.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	result, err := x.Wait()
+	if err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if result.Output != "one\ntwo\n" {
+		t.Errorf("Output = %q", result.Output)
+	}
+}
+
+func TestExecution_StateErrors(t *testing.T) {
+	var stateErr *ExecutionStateError
+
+	x := NewExecution(NewBasicExecutor(), ToolConfig{Command: "true"})
+	if _, err := x.Wait(); !errors.As(err, &stateErr) {
+		t.Errorf("Wait() before Start error = %v, want *ExecutionStateError", err)
+	}
+	if err := x.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if err := x.Start(context.Background()); !errors.As(err, &stateErr) {
+		t.Errorf("second Start() error = %v, want *ExecutionStateError", err)
+	}
+	if _, err := x.StdoutPipe(); !errors.As(err, &stateErr) {
+		t.Errorf("StdoutPipe() after Start error = %v, want *ExecutionStateError", err)
+	}
+	<-x.Done()
+	if _, err := x.Wait(); err != nil {
+		t.Errorf("Wait() error = %v", err)
+	}
+
+	invalid := NewExecution(NewBasicExecutor(), ToolConfig{})
+	var valErr *ValidationError
+	if err := invalid.Start(context.Background()); !errors.As(err, &valErr) {
+		t.Errorf("Start() error = %v, want *ValidationError", err)
+	}
+}
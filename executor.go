@@ -3,10 +3,14 @@ package cmdexec
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
-	"io"
+	"hash"
+	"io/fs"
 	"log/slog"
+	"maps"
 	"os"
 	"os/exec"
 	"strings"
@@ -14,11 +18,15 @@ import (
 )
 
 // BasicExecutor handles the execution of external tools and commands.
-type BasicExecutor struct{}
+type BasicExecutor struct {
+	// versions caches the tool versions found for MinVersion checks. It is
+	// nil in a zero BasicExecutor, which then checks versions every time.
+	versions *versionCache
+}
 
 // NewBasicExecutor creates a new BasicExecutor instance.
 func NewBasicExecutor() *BasicExecutor {
-	return &BasicExecutor{}
+	return &BasicExecutor{versions: newVersionCache()}
 }
 
 // Execute runs a tool with the given configuration and returns the result.
@@ -37,71 +45,235 @@ func NewBasicExecutor() *BasicExecutor {
 // Typed errors returned:
 //   - *ValidationError: invalid ToolConfig fields.
 //   - *TimeoutError: command exceeded configured Timeout.
+//   - *IdleTimeoutError: command produced no output for IdleTimeout.
+//   - *CPUTimeLimitError: command consumed more CPU time than CPUTimeLimit.
+//   - *OutputLimitError: output exceeded a limit under OverflowAbort.
+//   - *StdinLimitError: input exceeded MaxStdinBytes.
 //   - *ExecutableNotFoundError: command not found in PATH.
 //   - *RetryExhaustedError: all retry attempts failed (wraps last error).
 //   - *CommandNotAllowedError: command rejected by CommandValidator.
+//   - *VersionTooOldError: the tool is older than MinVersion.
 //   - context.Canceled / context.DeadlineExceeded: context was cancelled.
+//
+// If set, cfg.PreExec runs before validation and cfg.PostExec runs with the
+// final result and error, after cfg.Redactor has been applied to them.
 func (e *BasicExecutor) Execute(ctx context.Context, cfg ToolConfig) (*ExecutionResult, error) {
+	return e.executeWithStartHook(ctx, cfg, nil)
+}
+
+// executeWithStartHook is Execute, calling onStart, if not nil, with the
+// process of each attempt once it has started. Wrappers in this package use
+// it to signal the process.
+func (e *BasicExecutor) executeWithStartHook(ctx context.Context, cfg ToolConfig, onStart func(*os.Process)) (*ExecutionResult, error) {
+	if cfg.PreExec != nil {
+		if err := cfg.PreExec(ctx, &cfg); err != nil {
+			return nil, fmt.Errorf("pre-exec hook: %w", err)
+		}
+	}
+
+	result, err := e.execute(ctx, cfg, onStart)
+	if result != nil && cfg.Labels != nil {
+		result.Labels = maps.Clone(cfg.Labels)
+	}
+
+	if cfg.Redactor != nil {
+		redactor := cfg.Redactor.bind(cfg.Env, cfg.Args)
+		redactor.redactResult(result)
+		err = redactor.redactError(err)
+	}
+
+	if cfg.PostExec != nil {
+		cfg.PostExec(ctx, result, err)
+	}
+	return result, err
+}
+
+// execute runs cfg and, while the command fails, its chain of fallbacks.
+func (e *BasicExecutor) execute(ctx context.Context, cfg ToolConfig, onStart func(*os.Process)) (*ExecutionResult, error) {
+	result, err := e.executeConfig(ctx, cfg, onStart)
+	for depth := 1; cfg.Fallback != nil && shouldFallBack(ctx, cfg, result, err); depth++ {
+		discardFailure(result, err)
+		cfg = *cfg.Fallback
+		result, err = e.executeConfig(ctx, cfg, onStart)
+		if result != nil {
+			result.FallbackDepth = depth
+		}
+	}
+	return result, err
+}
+
+// shouldFallBack reports whether an execution failed in a way that a
+// fallback command may fix.
+func shouldFallBack(ctx context.Context, cfg ToolConfig, result *ExecutionResult, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err == nil {
+		return !cfg.Succeeded(result)
+	}
+	var validationErr *ValidationError
+	return !errors.As(err, &validationErr)
+}
+
+// discardFailure releases the spool files of a failed execution that is
+// superseded by a fallback.
+func discardFailure(result *ExecutionResult, err error) {
+	_ = result.Cleanup()
+	var retryErr *RetryExhaustedError
+	if errors.As(err, &retryErr) {
+		_ = retryErr.LastResult.Cleanup()
+	}
+}
+
+// executeConfig validates cfg and runs it, with retries if configured.
+func (e *BasicExecutor) executeConfig(ctx context.Context, cfg ToolConfig, onStart func(*os.Process)) (*ExecutionResult, error) {
+	if cfg.ExpandVariables {
+		cfg = cfg.Expand()
+	}
+
 	if err := cfg.Validate(); err != nil {
 		return nil, err
 	}
 
+	if err := prepareWorkingDir(&cfg); err != nil {
+		return nil, err
+	}
+
+	if err := e.checkMinVersion(ctx, cfg); err != nil {
+		return nil, err
+	}
+
 	// Fast path: no retries configured
-	if cfg.MaxRetries == 0 {
-		if cfg.StdinFactory != nil {
-			cfg.Stdin = cfg.StdinFactory()
+	if cfg.MaxRetries == 0 && cfg.RetryPolicy == nil {
+		release, err := prepareStdin(&cfg)
+		if err != nil {
+			return nil, err
 		}
-		return e.executeOnce(ctx, cfg)
+		defer release()
+		return e.executeOnce(ctx, cfg, onStart)
 	}
 
-	return e.executeWithRetries(ctx, cfg)
+	return e.executeWithRetries(ctx, cfg, onStart)
+}
+
+// checkMinVersion checks cfg.MinVersion, running each distinct version
+// check only once per executor.
+func (e *BasicExecutor) checkMinVersion(ctx context.Context, cfg ToolConfig) error {
+	if cfg.MinVersion == "" {
+		return nil
+	}
+	check := func() (string, error) { return ToolVersion(ctx, e, cfg) }
+	cache := e.versions
+	key, ok := versionCacheKey(cfg)
+	if !ok {
+		cache = nil
+	}
+	version, err := cache.version(key, check)
+	if err != nil {
+		return err
+	}
+	return checkVersion(cfg, version)
 }
 
 // executeWithRetries runs the command with retry logic.
-func (e *BasicExecutor) executeWithRetries(ctx context.Context, cfg ToolConfig) (*ExecutionResult, error) {
-	maxAttempts := 1 + cfg.MaxRetries
+func (e *BasicExecutor) executeWithRetries(ctx context.Context, cfg ToolConfig, onStart func(*os.Process)) (*ExecutionResult, error) {
+	policy := cfg.retryPolicy()
+	budget := newRetryBudget(cfg.MaxRetryElapsed)
 	var lastResult *ExecutionResult
 	var lastErr error
-
-	for attempt := 1; attempt <= maxAttempts; attempt++ {
-		// Recreate stdin from factory for each attempt
-		if cfg.StdinFactory != nil {
-			cfg.Stdin = cfg.StdinFactory()
+	var history []AttemptSummary
+	var waited time.Duration
+
+	attempt := 1
+	for ; ; attempt++ {
+		// Recreate stdin for each attempt. If that fails after the first
+		// attempt, report the earlier attempts along with the error.
+		release, err := prepareStdin(&cfg)
+		if err != nil {
+			if attempt == 1 {
+				return nil, err
+			}
+			retryErr := e.buildRetryExhaustedError(cfg, attempt, lastResult, err)
+			retryErr.History = append(history, summarizeAttempt(attempt, nil, err, 0))
+			return nil, retryErr
 		}
 
-		result, err := e.executeOnce(ctx, cfg)
+		attemptStart := time.Now()
+		result, err := e.executeOnce(ctx, cfg, onStart)
+		attemptDuration := time.Since(attemptStart)
+		release()
 
 		// Success case
-		if err == nil && result.ExitCode == 0 {
+		if err == nil && cfg.Succeeded(result) {
+			_ = lastResult.Cleanup()
+			recordAttempts(result, attempt, waited)
 			return result, nil
 		}
 
 		// Non-retryable error: executable not found
 		if _, isNotFound := err.(*ExecutableNotFoundError); isNotFound {
+			_ = lastResult.Cleanup()
 			return nil, err
 		}
 
 		// Abort retries on context cancellation/timeout
 		if ctx.Err() != nil {
+			_ = lastResult.Cleanup()
+			_ = result.Cleanup()
 			if err != nil {
 				return nil, err
 			}
 			return nil, fmt.Errorf("context done: %w", ctx.Err())
 		}
 
-		// Store last attempt for final error reporting
+		// Store last attempt for final error reporting, discarding the
+		// spool files of the previous one.
+		_ = lastResult.Cleanup()
 		lastResult = result
 		lastErr = err
+		history = append(history, summarizeAttempt(attempt, result, err, attemptDuration))
 
-		// If not the last attempt, sleep with context awareness
-		if attempt < maxAttempts {
-			if err := e.waitRetryDelay(ctx, cfg.RetryDelay); err != nil {
-				return nil, err
-			}
+		// A failure the policy does not consider transient is reported as
+		// is, as if retries were not configured.
+		if !policy.ShouldRetry(result, err) {
+			recordAttempts(result, attempt, waited)
+			return result, err
+		}
+
+		delay, ok := policy.NextDelay(attempt)
+		if !ok || !budget.allows(ctx, delay, attemptDuration) {
+			break
+		}
+		if cfg.OnRetry != nil {
+			notifyRetry(cfg, attempt, result, err, delay)
+		}
+		if err := e.waitRetryDelay(ctx, delay); err != nil {
+			_ = lastResult.Cleanup()
+			return nil, err
 		}
+		waited += max(delay, 0)
 	}
 
-	return nil, e.buildRetryExhaustedError(cfg, maxAttempts, lastResult, lastErr)
+	retryErr := e.buildRetryExhaustedError(cfg, attempt, lastResult, lastErr)
+	retryErr.History = history
+	return nil, retryErr
+}
+
+// recordAttempts records on result, if any, how many attempts produced it
+// and how long the retries waited in between.
+func recordAttempts(result *ExecutionResult, attempts int, waited time.Duration) {
+	if result != nil {
+		result.Attempts = attempts
+		result.RetryWait = waited
+	}
+}
+
+// notifyRetry calls cfg.OnRetry with the failed attempt, redacted with
+// cfg.Redactor.
+func notifyRetry(cfg ToolConfig, attempt int, result *ExecutionResult, err error, delay time.Duration) {
+	redactor := cfg.Redactor.bind(cfg.Env, cfg.Args)
+	redactor.redactResult(result)
+	cfg.OnRetry(attempt, result, redactor.redactError(err), delay)
 }
 
 func (e *BasicExecutor) waitRetryDelay(ctx context.Context, delay time.Duration) error {
@@ -118,7 +290,11 @@ func (e *BasicExecutor) waitRetryDelay(ctx context.Context, delay time.Duration)
 
 func (e *BasicExecutor) buildRetryExhaustedError(cfg ToolConfig, attempts int, lastResult *ExecutionResult, lastErr error) *RetryExhaustedError {
 	cmdStr := buildCommandString(cfg.Command, cfg.Args)
-	if lastErr == nil {
+	switch {
+	case lastErr != nil:
+	case lastResult.ExitCode == 0:
+		lastErr = errors.New("command output did not meet SuccessWhen")
+	default:
 		lastErr = fmt.Errorf("command exited with code %d", lastResult.ExitCode)
 	}
 	return &RetryExhaustedError{
@@ -130,26 +306,109 @@ func (e *BasicExecutor) buildRetryExhaustedError(cfg ToolConfig, attempts int, l
 }
 
 // executeOnce performs a single execution attempt.
-func (e *BasicExecutor) executeOnce(ctx context.Context, cfg ToolConfig) (*ExecutionResult, error) {
+func (e *BasicExecutor) executeOnce(ctx context.Context, cfg ToolConfig, onStart func(*os.Process)) (*ExecutionResult, error) {
 	execCtx, cancel := e.createExecutionContext(ctx, cfg.Timeout)
 	if cancel != nil {
 		defer cancel()
 	}
 
-	cmd := e.createCommand(execCtx, cfg)
+	res := newTempResources()
+	defer res.cleanup()
+
+	// Spool files outlive the attempt when they are handed to the result.
+	spool := newTempResources()
+	keepSpool := false
+	defer func() {
+		if !keepSpool {
+			spool.cleanup()
+		}
+	}()
+
+	cmdCfg, err := applyArgFile(cfg, res)
+	if err != nil {
+		return nil, err
+	}
+
+	stdinFile, err := openStdinFile(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if stdinFile != nil {
+		defer stdinFile.Close()
+		cfg.Stdin = stdinFile
+	}
+
+	runCtx, idle := newIdleWatchdog(execCtx, cfg.IdleTimeout)
+	defer idle.stop()
+	runCtx, overflow := newOverflowGuard(runCtx, cfg.OverflowPolicy)
+	defer overflow.stop()
+	runCtx, stdinLimit := newStdinGuard(runCtx, cfg.MaxStdinBytes)
+	defer stdinLimit.stop()
+	cfg.Stdin = stdinLimit.wrap(cfg.Stdin)
+
+	cmd := e.createCommand(runCtx, cmdCfg)
 	e.setupCommand(cmd, cfg)
+	idle.configure(cmd)
+	overflow.configure(cmd)
+	kill := installKillPolicy(cmd, cfg.KillPolicy)
 
 	slog.Debug("Executing command",
 		"command", cfg.Command,
-		"args", cfg.Args,
-		"working_dir", cfg.WorkingDir)
+		"args", cfg.Redactor.bind(cfg.Env, cfg.Args).redactArgs(cfg.Args),
+		"working_dir", cfg.WorkingDir,
+		"labels", cfg.Labels,
+		"metadata", Metadata(ctx))
 
-	cr := e.executeCommand(cmd, cfg)
+	cr := e.executeCommand(ctx, cmd, cfg, idle, overflow, spool, cpuTimeLimitHook(cfg.CPUTimeLimit, onStart))
+	kill.stop()
+	cr.killSignal = kill.signal()
+
+	if stdinLimit.limitExceeded() {
+		return nil, &StdinLimitError{Command: buildCommandString(cfg.Command, cfg.Args), Limit: cfg.MaxStdinBytes}
+	}
+
+	if limitErr := overflow.exceeded(); limitErr != nil {
+		return nil, limitErr
+	}
+
+	if err := e.checkTermination(ctx, execCtx, cfg, cr, idle); err != nil {
+		return nil, err
+	}
+
+	exitCode, err := e.processExecutionError(cr.err, cfg.Command)
+	if err != nil {
+		return nil, err
+	}
+
+	keepSpool = true
+	return e.buildExecutionResult(cfg, cr, exitCode), nil
+}
+
+// checkTermination maps an execution that was stopped by a limit or by
+// context cancellation to the corresponding typed error. It returns nil if
+// the process ran to completion on its own.
+func (e *BasicExecutor) checkTermination(ctx, execCtx context.Context, cfg ToolConfig, cr executeCommandResult, idle *idleWatchdog) error {
+	if cr.err != nil && idle.fired() && ctx.Err() == nil && execCtx.Err() == nil {
+		return &IdleTimeoutError{
+			Command:     buildCommandString(cfg.Command, cfg.Args),
+			IdleTimeout: cfg.IdleTimeout,
+			Signal:      cr.stopSignal(),
+		}
+	}
+
+	if cpuTimeLimitExceeded(cr.state, cfg.CPUTimeLimit) {
+		return &CPUTimeLimitError{
+			Command: buildCommandString(cfg.Command, cfg.Args),
+			Limit:   cfg.CPUTimeLimit,
+			Used:    cr.state.UserTime() + cr.state.SystemTime(),
+		}
+	}
 
 	if timedOut := e.handleTimeout(ctx, execCtx, cr.err, cfg); timedOut {
-		return nil, &TimeoutError{
+		return &TimeoutError{
 			Command: buildCommandString(cfg.Command, cfg.Args),
 			Timeout: cfg.Timeout,
+			Signal:  cr.stopSignal(),
 		}
 	}
 
@@ -158,15 +417,10 @@ func (e *BasicExecutor) executeOnce(ctx context.Context, cfg ToolConfig) (*Execu
 	// Surface the parent's context error directly so callers can
 	// distinguish upstream deadlines from executor timeouts.
 	if cr.err != nil && ctx.Err() != nil {
-		return nil, fmt.Errorf("parent context done: %w", ctx.Err())
-	}
-
-	exitCode, err := e.processExecutionError(cr.err, cfg.Command)
-	if err != nil {
-		return nil, err
+		return fmt.Errorf("parent context done: %w", ctx.Err())
 	}
 
-	return e.buildExecutionResult(cfg, cr, exitCode), nil
+	return nil
 }
 
 func (e *BasicExecutor) createExecutionContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
@@ -200,82 +454,150 @@ func (e *BasicExecutor) setupCommand(cmd *exec.Cmd, cfg ToolConfig) {
 	if cfg.Stdin != nil {
 		cmd.Stdin = cfg.Stdin
 	}
+
+	cmd.ExtraFiles = cfg.ExtraFiles
+	applyProcessAttributes(cmd, cfg.ProcessAttributes)
+}
+
+// errNotDirectory is the cause of a WorkingDirError for a path that exists
+// but is not a directory.
+var errNotDirectory = errors.New("not a directory")
+
+// prepareWorkingDir creates the working directory of cfg if
+// EnsureWorkingDir is set, and checks that it is a directory, so a bad
+// directory fails with a *WorkingDirError rather than an opaque start error.
+func prepareWorkingDir(cfg *ToolConfig) error {
+	if cfg.WorkingDir == "" {
+		return nil
+	}
+	if cfg.EnsureWorkingDir {
+		if err := os.MkdirAll(cfg.WorkingDir, 0o750); err != nil {
+			return &WorkingDirError{Dir: cfg.WorkingDir, Err: unwrapPathError(err)}
+		}
+	}
+	info, err := os.Stat(cfg.WorkingDir)
+	if err != nil {
+		return &WorkingDirError{Dir: cfg.WorkingDir, Err: unwrapPathError(err)}
+	}
+	if !info.IsDir() {
+		return &WorkingDirError{Dir: cfg.WorkingDir, Err: errNotDirectory}
+	}
+	return nil
+}
+
+// unwrapPathError returns the cause of a *fs.PathError, whose path would
+// repeat the directory already named by WorkingDirError.
+func unwrapPathError(err error) error {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return pathErr.Err
+	}
+	return err
 }
 
 type executeCommandResult struct {
 	stdout, stderr           bytes.Buffer
+	combined                 *combinedBuffer
 	startTime, endTime       time.Time
 	stdoutTrunc, stderrTrunc bool
+	stdoutSpool, stderrSpool string
+	stdoutSHA256             string
+	stdoutTotal, stderrTotal int64
+	stdoutDrop, stderrDrop   int64
+	state                    *os.ProcessState
+	killSignal               string
 	err                      error
 }
 
-func (e *BasicExecutor) executeCommand(cmd *exec.Cmd, cfg ToolConfig) executeCommandResult {
+// stopSignal names the signal that stopped the process: the terminating
+// signal from its wait status, or else the last KillPolicy signal it
+// received before exiting on its own.
+func (cr executeCommandResult) stopSignal() string {
+	if signal, _ := terminationSignal(cr.state); signal != "" {
+		return signal
+	}
+	return cr.killSignal
+}
+
+func (e *BasicExecutor) executeCommand(ctx context.Context, cmd *exec.Cmd, cfg ToolConfig, idle *idleWatchdog, overflow *overflowGuard, spool *tempResources, onStart func(*os.Process)) executeCommandResult {
 	var r executeCommandResult
-	var stdoutW, stderrW io.Writer = &r.stdout, &r.stderr
+	if cfg.DiscardOutput {
+		// Leaving Stdout and Stderr nil connects them to the null device,
+		// so no pipes or copying goroutines are set up.
+		r.startTime = time.Now()
+		r.err = runProcess(cmd, cfg.ProcessAttributes, onStart)
+		r.endTime = time.Now()
+		r.state = cmd.ProcessState
+		return r
+	}
 
-	// Apply output size limits
-	var stdoutLW, stderrLW *limitedWriter
-	if cfg.MaxStdoutBytes > 0 {
-		stdoutLW = &limitedWriter{w: &r.stdout, n: cfg.MaxStdoutBytes}
-		stdoutW = stdoutLW
+	cfg.TruncateMode = overflow.truncateMode(cfg.TruncateMode)
+	stdoutOpts, stderrOpts := stdoutOptions(cfg), stderrOptions(cfg)
+	stdoutOpts.spool, stderrOpts.spool = spool, spool
+	stdoutOpts.onOverflow = overflow.hook("stdout", cfg.MaxStdoutBytes)
+	stderrOpts.onOverflow = overflow.hook("stderr", cfg.MaxStderrBytes)
+	if progress := newProgressCallback(cfg); progress != nil {
+		stdoutOpts.onLine = chainLineCallbacks(stdoutOpts.onLine, progress)
+		stderrOpts.onLine = chainLineCallbacks(stderrOpts.onLine, progress)
 	}
-	if cfg.MaxStderrBytes > 0 {
-		stderrLW = &limitedWriter{w: &r.stderr, n: cfg.MaxStderrBytes}
-		stderrW = stderrLW
+	if logStdout, logStderr := cfg.OutputLog.lineCallbacks(ctx, cfg); logStdout != nil {
+		stdoutOpts.onLine = chainLineCallbacks(stdoutOpts.onLine, logStdout)
+		stderrOpts.onLine = chainLineCallbacks(stderrOpts.onLine, logStderr)
 	}
-
-	// Apply streaming writers via tee
-	if cfg.StdoutWriter != nil {
-		stdoutW = io.MultiWriter(stdoutW, cfg.StdoutWriter)
+	var shared *sharedLimit
+	if cfg.MaxOutputBytes > 0 {
+		shared = newSharedLimit(cfg.MaxOutputBytes, cfg.TruncateMode)
+		shared.onOverflow = overflow.hook("output", cfg.MaxOutputBytes)
+		stdoutOpts.shared, stderrOpts.shared = shared, shared
+	}
+	var checksum hash.Hash
+	if cfg.ChecksumStdout {
+		checksum = sha256.New()
+		stdoutOpts.checksum = checksum
 	}
-	if cfg.StderrWriter != nil {
-		stderrW = io.MultiWriter(stderrW, cfg.StderrWriter)
+	if cfg.CombineOutput {
+		r.combined = newCombinedBuffer(cfg.MaxOutputBytes, cfg.TruncateMode)
+		stdoutOpts.combined = r.combined
+		stderrOpts.combined = r.combined
+	}
+	stdout := newOutputStream(&r.stdout, stdoutOpts, cfg)
+	stderr := newOutputStream(&r.stderr, stderrOpts, cfg)
+	cmd.Stdout = idle.wrap(stdout.writer)
+	cmd.Stderr = idle.wrap(stderr.writer)
+	if cfg.MergeStderr {
+		// os/exec shares a single pipe when both fields hold the same writer.
+		cmd.Stderr = cmd.Stdout
 	}
 
-	cmd.Stdout = stdoutW
-	cmd.Stderr = stderrW
+	stdout.start()
+	stderr.start()
 
 	r.startTime = time.Now()
-	r.err = cmd.Run()
+	r.err = runProcess(cmd, cfg.ProcessAttributes, onStart)
 	r.endTime = time.Now()
+	r.state = cmd.ProcessState
 
-	if stdoutLW != nil {
-		r.stdoutTrunc = stdoutLW.truncated
+	stdout.finish()
+	stderr.finish()
+	if err := shared.flush(); err != nil {
+		slog.Debug("Failed to flush shared output limit", "error", err)
 	}
-	if stderrLW != nil {
-		r.stderrTrunc = stderrLW.truncated
+	r.combined.flush()
+	stdout.close()
+	stderr.close()
+	r.stdoutTrunc = stdout.truncated()
+	r.stderrTrunc = stderr.truncated()
+	r.stdoutTotal, r.stdoutDrop = stdout.totalBytes(), stdout.droppedBytes()
+	r.stderrTotal, r.stderrDrop = stderr.totalBytes(), stderr.droppedBytes()
+	r.stdoutSpool = stdout.spool.path()
+	r.stderrSpool = stderr.spool.path()
+	if checksum != nil {
+		r.stdoutSHA256 = hex.EncodeToString(checksum.Sum(nil))
 	}
 
 	return r
 }
 
-// limitedWriter wraps a writer and stops writing after n bytes,
-// silently discarding excess data while tracking truncation.
-type limitedWriter struct {
-	w         io.Writer
-	n         int64 // bytes remaining
-	truncated bool
-}
-
-func (lw *limitedWriter) Write(p []byte) (int, error) {
-	if lw.n <= 0 {
-		lw.truncated = true
-		return len(p), nil
-	}
-	if int64(len(p)) > lw.n {
-		lw.truncated = true
-		n, err := lw.w.Write(p[:lw.n])
-		lw.n = 0
-		if err != nil {
-			return n, err //nolint:wrapcheck
-		}
-		return len(p), nil
-	}
-	n, err := lw.w.Write(p)
-	lw.n -= int64(n)
-	return n, err //nolint:wrapcheck
-}
-
 func (e *BasicExecutor) handleTimeout(parentCtx, execCtx context.Context, err error, cfg ToolConfig) bool {
 	return err != nil && execCtx.Err() == context.DeadlineExceeded && cfg.Timeout > 0 && parentCtx.Err() == nil
 }
@@ -298,24 +620,43 @@ func (e *BasicExecutor) processExecutionError(err error, command string) (int, e
 		return exitErr.ExitCode(), nil
 	}
 
+	// The directory may have been removed or made inaccessible after it
+	// was checked.
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) && pathErr.Op == "chdir" {
+		return 0, &WorkingDirError{Dir: pathErr.Path, Err: pathErr.Err}
+	}
+
 	// Unknown execution errors (I/O failures, permission errors, etc.)
 	// are returned rather than silently converted to exit code -1.
 	return 0, fmt.Errorf("command %q: %w", command, err)
 }
 
 func (e *BasicExecutor) buildExecutionResult(cfg ToolConfig, cr executeCommandResult, exitCode int) *ExecutionResult {
+	tc := newOutputTranscoder(cfg)
+	signal, coreDumped := terminationSignal(cr.state)
 	return &ExecutionResult{
-		Command:         cfg.Command,
-		Args:            cfg.Args,
-		WorkingDir:      cfg.WorkingDir,
-		Output:          cr.stdout.String(),
-		Stderr:          cr.stderr.String(),
-		ExitCode:        exitCode,
-		StartTime:       cr.startTime,
-		EndTime:         cr.endTime,
-		TimedOut:        false,
-		StdoutTruncated: cr.stdoutTrunc,
-		StderrTruncated: cr.stderrTrunc,
+		Command:            cfg.Command,
+		Args:               cfg.Args,
+		WorkingDir:         cfg.WorkingDir,
+		Output:             tc.transcode(cr.stdout.String()),
+		Stderr:             tc.transcode(cr.stderr.String()),
+		Combined:           tc.transcode(cr.combined.String()),
+		ExitCode:           exitCode,
+		Signal:             signal,
+		CoreDumped:         coreDumped,
+		StartTime:          cr.startTime,
+		EndTime:            cr.endTime,
+		TimedOut:           false,
+		StdoutTruncated:    cr.stdoutTrunc,
+		StderrTruncated:    cr.stderrTrunc,
+		StdoutBytesTotal:   cr.stdoutTotal,
+		StderrBytesTotal:   cr.stderrTotal,
+		StdoutBytesDropped: cr.stdoutDrop,
+		StderrBytesDropped: cr.stderrDrop,
+		StdoutSpoolPath:    cr.stdoutSpool,
+		StderrSpoolPath:    cr.stderrSpool,
+		StdoutSHA256:       cr.stdoutSHA256,
 	}
 }
 
@@ -325,16 +666,47 @@ func (e *BasicExecutor) IsAvailable(command string) bool {
 	return err == nil
 }
 
-// buildCommandString constructs a shell-like command string for display purposes.
+// CanonicalString renders cfg's command line as a stable, POSIX-shell-quoted
+// string. Arguments that contain only shell-safe characters are left bare;
+// everything else (including empty arguments) is single-quoted, so the output
+// can be pasted into a shell and round-trips exactly. It is suitable for
+// display, deduplication keys, and audit logs.
+//
+// Only Command and Args are rendered; Env, WorkingDir, and other settings are
+// not part of the string.
+func CanonicalString(cfg ToolConfig) string {
+	return buildCommandString(cfg.Command, cfg.Args)
+}
+
+// buildCommandString constructs a shell-quoted command string for display purposes.
 func buildCommandString(command string, args []string) string {
-	parts := []string{command}
+	parts := make([]string, 0, 1+len(args))
+	parts = append(parts, quoteIfNeeded(command))
 	for _, arg := range args {
-		// Simple quoting for args with spaces
-		if strings.Contains(arg, " ") {
-			parts = append(parts, fmt.Sprintf("%q", arg))
-		} else {
-			parts = append(parts, arg)
-		}
+		parts = append(parts, quoteIfNeeded(arg))
 	}
 	return strings.Join(parts, " ")
 }
+
+// quoteIfNeeded returns s unchanged if it contains only characters that are
+// never special to a POSIX shell, and a single-quoted form otherwise.
+func quoteIfNeeded(s string) string {
+	if s == "" {
+		return "''"
+	}
+	for _, r := range s {
+		if !isShellSafe(r) {
+			return shellQuote(s)
+		}
+	}
+	return s
+}
+
+// isShellSafe reports whether r can appear unquoted in a shell word.
+func isShellSafe(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return true
+	}
+	return strings.ContainsRune("@%+=:,./_-", r)
+}
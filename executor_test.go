@@ -3,10 +3,14 @@ package cmdexec
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"testing"
@@ -417,7 +421,37 @@ func TestBuildCommandString(t *testing.T) {
 			name:    "args with spaces",
 			command: "echo",
 			args:    []string{"hello world", "foo"},
-			want:    `echo "hello world" foo`,
+			want:    `echo 'hello world' foo`,
+		},
+		{
+			name:    "empty arg",
+			command: "git",
+			args:    []string{"commit", "-m", ""},
+			want:    `git commit -m ''`,
+		},
+		{
+			name:    "shell metacharacters",
+			command: "sh",
+			args:    []string{"-c", "echo $HOME; ls|wc"},
+			want:    `sh -c 'echo $HOME; ls|wc'`,
+		},
+		{
+			name:    "embedded single quote",
+			command: "echo",
+			args:    []string{"it's"},
+			want:    `echo 'it'"'"'s'`,
+		},
+		{
+			name:    "tab and newline",
+			command: "printf",
+			args:    []string{"a\tb\n"},
+			want:    "printf 'a\tb\n'",
+		},
+		{
+			name:    "command with space",
+			command: "/opt/my tools/run",
+			args:    []string{"--flag=value"},
+			want:    `'/opt/my tools/run' --flag=value`,
 		},
 		{
 			name:    "no args",
@@ -437,6 +471,18 @@ func TestBuildCommandString(t *testing.T) {
 	}
 }
 
+func TestCanonicalString(t *testing.T) {
+	cfg := ToolConfig{
+		Command: "grep",
+		Args:    []string{"-e", "a b", "file.txt"},
+		Env:     map[string]string{"LC_ALL": "C"},
+	}
+	want := "grep -e 'a b' file.txt"
+	if got := CanonicalString(cfg); got != want {
+		t.Errorf("CanonicalString() = %q, want %q", got, want)
+	}
+}
+
 func TestBasicExecutor_Execute_Permissions(t *testing.T) {
 	if runtime.GOOS == "windows" {
 		t.Skip("Skipping permission test on Windows")
@@ -670,6 +716,9 @@ func TestBasicExecutor_Execute_RetrySuccessAfterFailure(t *testing.T) {
 	if !strings.Contains(result.Output, "success") {
 		t.Errorf("Output = %q, want to contain 'success'", result.Output)
 	}
+	if result.Attempts != 3 || result.RetryWait != 20*time.Millisecond {
+		t.Errorf("Attempts = %d, RetryWait = %v, want 3 and 20ms", result.Attempts, result.RetryWait)
+	}
 }
 
 func TestBasicExecutor_Execute_RetryExhausted(t *testing.T) {
@@ -818,6 +867,9 @@ func TestBasicExecutor_Execute_NoRetryOnZeroMaxRetries(t *testing.T) {
 	if result.ExitCode != 1 {
 		t.Errorf("ExitCode = %d, want 1", result.ExitCode)
 	}
+	if result.Attempts != 0 {
+		t.Errorf("Attempts = %d, want 0 without retries", result.Attempts)
+	}
 }
 
 func TestBasicExecutor_Execute_RetryNotFoundNotRetried(t *testing.T) {
@@ -1409,3 +1461,265 @@ func TestAllowCommands(t *testing.T) {
 		t.Error("sh should not be allowed")
 	}
 }
+
+func TestBasicExecutor_Execute_PreExecMutatesConfig(t *testing.T) {
+	executor := NewBasicExecutor()
+	result, err := executor.Execute(context.Background(), ToolConfig{
+		Command: "sh",
+		Args:    []string{"-c", "echo $INJECTED"},
+		PreExec: func(_ context.Context, cfg *ToolConfig) error {
+			cfg.Env = map[string]string{"INJECTED": "from-hook"}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Output != "from-hook\n" {
+		t.Errorf("Output = %q, want %q", result.Output, "from-hook\n")
+	}
+}
+
+func TestBasicExecutor_Execute_PreExecErrorAborts(t *testing.T) {
+	hookErr := errors.New("denied")
+	postCalled := false
+	executor := NewBasicExecutor()
+	result, err := executor.Execute(context.Background(), ToolConfig{
+		Command:  "echo",
+		PreExec:  func(context.Context, *ToolConfig) error { return hookErr },
+		PostExec: func(context.Context, *ExecutionResult, error) { postCalled = true },
+	})
+	if result != nil {
+		t.Errorf("result = %v, want nil", result)
+	}
+	if !errors.Is(err, hookErr) {
+		t.Errorf("error = %v, want wrapping %v", err, hookErr)
+	}
+	if postCalled {
+		t.Error("PostExec should not run when PreExec fails")
+	}
+}
+
+func TestBasicExecutor_Execute_PostExecReceivesOutcome(t *testing.T) {
+	executor := NewBasicExecutor()
+
+	var gotResult *ExecutionResult
+	var gotErr error
+	calls := 0
+	result, err := executor.Execute(context.Background(), ToolConfig{
+		Command: "sh",
+		Args:    []string{"-c", "exit 2"},
+		PostExec: func(_ context.Context, r *ExecutionResult, e error) {
+			calls++
+			gotResult, gotErr = r, e
+		},
+	})
+	if calls != 1 {
+		t.Fatalf("PostExec calls = %d, want 1", calls)
+	}
+	if gotResult != result || gotErr != err {
+		t.Error("PostExec did not receive the values returned by Execute")
+	}
+
+	// Validation failures are reported to PostExec as well.
+	gotErr = nil
+	_, err = executor.Execute(context.Background(), ToolConfig{
+		PostExec: func(_ context.Context, _ *ExecutionResult, e error) { gotErr = e },
+	})
+	var valErr *ValidationError
+	if !errors.As(gotErr, &valErr) || gotErr != err {
+		t.Errorf("PostExec error = %v, want the ValidationError from Execute", gotErr)
+	}
+}
+
+func TestBasicExecutor_Execute_CombineOutput(t *testing.T) {
+	executor := NewBasicExecutor()
+	script := "echo out1; sleep 0.05; echo err1 >&2; sleep 0.05; echo out2"
+
+	result, err := executor.Execute(context.Background(), ToolConfig{
+		Command:       "sh",
+		Args:          []string{"-c", script},
+		CombineOutput: true,
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if want := "out1\nerr1\nout2\n"; result.Combined != want {
+		t.Errorf("Combined = %q, want %q", result.Combined, want)
+	}
+	if result.Output != "out1\nout2\n" || result.Stderr != "err1\n" {
+		t.Errorf("Output = %q, Stderr = %q; per-stream capture should be unaffected", result.Output, result.Stderr)
+	}
+
+	result, err = executor.Execute(context.Background(), ToolConfig{
+		Command:        "sh",
+		Args:           []string{"-c", script},
+		CombineOutput:  true,
+		MaxOutputBytes: 9,
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if want := "out1\nerr1"; result.Combined != want {
+		t.Errorf("Combined = %q with MaxOutputBytes 9, want %q", result.Combined, want)
+	}
+
+	result, err = executor.Execute(context.Background(), ToolConfig{Command: "sh", Args: []string{"-c", script}})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Combined != "" {
+		t.Errorf("Combined = %q, want empty without CombineOutput", result.Combined)
+	}
+}
+
+func TestBasicExecutor_Execute_DiscardOutput(t *testing.T) {
+	var stdoutW bytes.Buffer
+	result, err := NewBasicExecutor().Execute(context.Background(), ToolConfig{
+		Command:       "sh",
+		Args:          []string{"-c", "echo out; echo err >&2; exit 3"},
+		DiscardOutput: true,
+		StdoutWriter:  &stdoutW,
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.ExitCode != 3 {
+		t.Errorf("ExitCode = %d, want 3", result.ExitCode)
+	}
+	if result.Output != "" || result.Stderr != "" || stdoutW.Len() != 0 {
+		t.Errorf("Output = %q, Stderr = %q, StdoutWriter got %q; want all empty",
+			result.Output, result.Stderr, stdoutW.String())
+	}
+}
+
+func TestBasicExecutor_Execute_ChecksumStdout(t *testing.T) {
+	full := "line one\nline two\nline three\n"
+	sum := sha256.Sum256([]byte(full))
+	want := hex.EncodeToString(sum[:])
+
+	result, err := NewBasicExecutor().Execute(context.Background(), ToolConfig{
+		Command:        "printf",
+		Args:           []string{full},
+		ChecksumStdout: true,
+		MaxStdoutBytes: 5,
+		CaptureFilter:  func(line string) bool { return line != "line one" },
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.StdoutSHA256 != want {
+		t.Errorf("StdoutSHA256 = %q, want %q", result.StdoutSHA256, want)
+	}
+	if result.Output != "line " {
+		t.Errorf("Output = %q, want the filtered and truncated output", result.Output)
+	}
+
+	result, err = NewBasicExecutor().Execute(context.Background(), ToolConfig{Command: "printf", Args: []string{full}})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.StdoutSHA256 != "" {
+		t.Errorf("StdoutSHA256 = %q, want empty without ChecksumStdout", result.StdoutSHA256)
+	}
+}
+
+func TestBasicExecutor_Execute_MergeStderr(t *testing.T) {
+	var lines []string
+	var stderrW bytes.Buffer
+	result, err := NewBasicExecutor().Execute(context.Background(), ToolConfig{
+		Command:      "sh",
+		Args:         []string{"-c", "echo out1; echo err1 >&2; echo out2; echo err2 >&2"},
+		MergeStderr:  true,
+		OnStdoutLine: func(line string) { lines = append(lines, line) },
+		StderrWriter: &stderrW,
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	// A single pipe preserves the exact write order without sleeps.
+	if want := "out1\nerr1\nout2\nerr2\n"; result.Output != want {
+		t.Errorf("Output = %q, want %q", result.Output, want)
+	}
+	if result.Stderr != "" || stderrW.Len() != 0 {
+		t.Errorf("Stderr = %q, StderrWriter got %q; want both empty", result.Stderr, stderrW.String())
+	}
+	if len(lines) != 4 {
+		t.Errorf("OnStdoutLine got %q, want 4 lines", lines)
+	}
+}
+
+func TestBasicExecutor_Execute_ExtraFiles(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("ExtraFiles are not supported on Windows")
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = r.Close() }()
+
+	_, err = NewBasicExecutor().Execute(context.Background(), ToolConfig{
+		Command:    "sh",
+		Args:       []string{"-c", "echo activated >&3"},
+		ExtraFiles: []*os.File{w},
+	})
+	_ = w.Close()
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil || string(data) != "activated\n" {
+		t.Errorf("fd 3 received (%q, %v), want %q", data, err, "activated\n")
+	}
+}
+
+func TestBasicExecutor_Execute_WorkingDir(t *testing.T) {
+	base := t.TempDir()
+	file := filepath.Join(base, "file")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	executor := NewBasicExecutor()
+	ctx := context.Background()
+
+	var dirErr *WorkingDirError
+	missing := filepath.Join(base, "a", "b")
+	_, err := executor.Execute(ctx, ToolConfig{Command: "pwd", WorkingDir: missing})
+	if !errors.As(err, &dirErr) || dirErr.Dir != missing || !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Execute() in a missing directory error = %v, want a *WorkingDirError wrapping fs.ErrNotExist", err)
+	}
+	if _, err := executor.Execute(ctx, ToolConfig{Command: "pwd", WorkingDir: file}); !errors.As(err, &dirErr) {
+		t.Errorf("Execute() in a file error = %v, want *WorkingDirError", err)
+	}
+
+	result, err := executor.Execute(ctx, ToolConfig{Command: "pwd", WorkingDir: missing, EnsureWorkingDir: true})
+	if err != nil {
+		t.Fatalf("Execute() with EnsureWorkingDir error = %v", err)
+	}
+	if info, statErr := os.Stat(missing); statErr != nil || !info.IsDir() {
+		t.Errorf("EnsureWorkingDir did not create %s: %v", missing, statErr)
+	}
+	if got := strings.TrimSpace(result.Output); filepath.Base(got) != "b" {
+		t.Errorf("pwd = %q, want it to run in %s", got, missing)
+	}
+
+	if _, err := executor.Execute(ctx, ToolConfig{Command: "pwd", WorkingDir: filepath.Join(file, "sub"), EnsureWorkingDir: true}); !errors.As(err, &dirErr) {
+		t.Errorf("Execute() with an uncreatable directory error = %v, want *WorkingDirError", err)
+	}
+}
+
+func TestBasicExecutor_Execute_Labels(t *testing.T) {
+	cfg := ToolConfig{Command: "true", Labels: map[string]string{"target": "//app"}}
+	result, err := NewBasicExecutor().Execute(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Labels["target"] != "//app" {
+		t.Fatalf("result.Labels = %v, want the config's labels", result.Labels)
+	}
+	result.Labels["target"] = "changed"
+	if cfg.Labels["target"] != "//app" {
+		t.Error("result.Labels should be a copy")
+	}
+}
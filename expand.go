@@ -0,0 +1,56 @@
+package cmdexec
+
+import (
+	"os"
+	"strings"
+)
+
+// Expand returns a copy of the configuration with a leading ~ and $VAR or
+// ${VAR} references expanded in Command, Args and WorkingDir, so configs
+// loaded from files can say "~/bin/tool" or "$HOME/src". Variables are
+// looked up in Env first and then in the environment of the current
+// process; unset variables expand to the empty string, as with
+// os.ExpandEnv. ~ is replaced by the home directory only when it is the
+// whole string or followed by a slash; ~user forms are left as is. The
+// returned configuration has ExpandVariables cleared, so it is not
+// expanded twice.
+func (tc ToolConfig) Expand() ToolConfig {
+	clone := tc.Clone()
+	clone.ExpandVariables = false
+	lookup := func(key string) string {
+		if value, ok := tc.Env[key]; ok {
+			return value
+		}
+		return os.Getenv(key)
+	}
+	clone.Command = expandValue(tc.Command, lookup)
+	clone.WorkingDir = expandValue(tc.WorkingDir, lookup)
+	for i, arg := range clone.Args {
+		clone.Args[i] = expandValue(arg, lookup)
+	}
+	return clone
+}
+
+// expandValue expands a leading tilde and the variables in s. The home
+// directory itself is inserted literally.
+func expandValue(s string, lookup func(string) string) string {
+	if s == "~" || strings.HasPrefix(s, "~/") {
+		if home := homeDir(lookup); home != "" {
+			return home + os.Expand(s[1:], lookup)
+		}
+	}
+	return os.Expand(s, lookup)
+}
+
+// homeDir returns HOME as seen by the command, falling back to the home
+// directory of the current user, or the empty string if it is unknown.
+func homeDir(lookup func(string) string) string {
+	if home := lookup("HOME"); home != "" {
+		return home
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return home
+}
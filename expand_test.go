@@ -0,0 +1,67 @@
+package cmdexec
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestToolConfig_Expand(t *testing.T) {
+	t.Setenv("HOME", "/home/me")
+	t.Setenv("CMDEXEC_EXPAND_TEST", "from-process")
+	cfg := ToolConfig{
+		Command:         "~/bin/tool",
+		Args:            []string{"$CMDEXEC_EXPAND_TEST", "${TARGET}/out", "~", "a~/b", "~other/x", "$UNSET_CMDEXEC_VAR."},
+		WorkingDir:      "$HOME/src",
+		Env:             map[string]string{"TARGET": "/srv"},
+		ExpandVariables: true,
+	}
+	got := cfg.Expand()
+
+	if got.Command != "/home/me/bin/tool" || got.WorkingDir != "/home/me/src" {
+		t.Errorf("Expand() command %q dir %q, want paths under /home/me", got.Command, got.WorkingDir)
+	}
+	wantArgs := []string{"from-process", "/srv/out", "/home/me", "a~/b", "~other/x", "."}
+	if !slices.Equal(got.Args, wantArgs) {
+		t.Errorf("Expand() args = %q, want %q", got.Args, wantArgs)
+	}
+	if got.ExpandVariables {
+		t.Error("Expand() should clear ExpandVariables")
+	}
+	if cfg.Args[0] != "$CMDEXEC_EXPAND_TEST" {
+		t.Errorf("Expand() modified the original args: %q", cfg.Args)
+	}
+
+	// HOME in Env overrides the process environment, also for ~.
+	cfg.Env["HOME"] = "/home/other"
+	if got := cfg.Expand(); got.Command != "/home/other/bin/tool" || got.WorkingDir != "/home/other/src" {
+		t.Errorf("Expand() with HOME in Env = %q, %q, want paths under /home/other", got.Command, got.WorkingDir)
+	}
+}
+
+func TestBasicExecutor_Execute_ExpandVariables(t *testing.T) {
+	dir := t.TempDir()
+	cfg := ToolConfig{
+		Command:    "pwd",
+		WorkingDir: "${DIR}",
+		Env:        map[string]string{"DIR": dir},
+	}
+	executor := NewBasicExecutor()
+	if _, err := executor.Execute(context.Background(), cfg); err == nil {
+		t.Fatal("Execute() without ExpandVariables should pass ${DIR} literally and fail")
+	}
+
+	cfg.ExpandVariables = true
+	result, err := executor.Execute(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if got := strings.TrimSpace(result.Output); filepath.Base(got) != filepath.Base(dir) {
+		t.Errorf("pwd = %q, want %s", got, dir)
+	}
+	if result.WorkingDir != dir {
+		t.Errorf("result.WorkingDir = %q, want the expanded %q", result.WorkingDir, dir)
+	}
+}
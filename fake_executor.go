@@ -0,0 +1,136 @@
+package cmdexec
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path"
+	"slices"
+	"time"
+)
+
+// FakeRule maps executions matching a command and argument patterns to a
+// canned response.
+type FakeRule struct {
+	// Command is the exact command the rule matches.
+	Command string `json:"command"`
+
+	// Args are path.Match patterns for the arguments, one per argument, so
+	// "*" matches any single argument. If nil, any arguments match.
+	Args []string `json:"args,omitempty"`
+
+	// Output, Stderr and ExitCode make up the result of a match.
+	Output   string `json:"output,omitempty"`
+	Stderr   string `json:"stderr,omitempty"`
+	ExitCode int    `json:"exitCode,omitempty"`
+
+	// Error, if not empty, makes a match fail with this message instead of
+	// returning a result.
+	Error string `json:"error,omitempty"`
+
+	// ErrorDetail, if set, makes a match fail with the error it describes,
+	// such as {"kind": "timeout", "command": "make", "duration": "1m"} for a
+	// *TimeoutError. It takes precedence over Error.
+	ErrorDetail *ErrorRecord `json:"errorDetail,omitempty"`
+}
+
+// matches reports whether the rule matches command and args. Patterns are
+// checked when the rule is created, so match errors cannot occur.
+func (r *FakeRule) matches(command string, args []string) bool {
+	if r.Command != command {
+		return false
+	}
+	if r.Args == nil {
+		return true
+	}
+	return slices.EqualFunc(r.Args, args, func(pattern, arg string) bool {
+		ok, _ := path.Match(pattern, arg)
+		return ok
+	})
+}
+
+// FakeExecutor answers executions from a table of FakeRules, so large test
+// suites can keep their command fixtures as data rather than as builder
+// calls on a MockExecutor. The first matching rule wins; an execution
+// matching no rule fails with an *UnexpectedCommandError.
+type FakeExecutor struct {
+	rules []FakeRule
+}
+
+// fakeTable is the file format read by LoadFakeExecutor.
+type fakeTable struct {
+	Rules []FakeRule `json:"rules"`
+}
+
+// NewFakeExecutor returns a FakeExecutor answering from rules. It returns a
+// *ValidationError if a rule has no command or a malformed pattern.
+func NewFakeExecutor(rules []FakeRule) (*FakeExecutor, error) {
+	for i, rule := range rules {
+		if rule.Command == "" {
+			return nil, &ValidationError{Field: "Command", Message: fmt.Sprintf("rule %d has no command", i)}
+		}
+		for _, pattern := range rule.Args {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, &ValidationError{Field: "Args", Message: fmt.Sprintf("rule %d has malformed pattern %q", i, pattern)}
+			}
+		}
+	}
+	return &FakeExecutor{rules: slices.Clone(rules)}, nil
+}
+
+// LoadFakeExecutor reads a JSON table of the form {"rules": [...]} from
+// path and returns a FakeExecutor answering from it. Only JSON is
+// supported; convert YAML fixtures to JSON before loading them.
+func LoadFakeExecutor(path string) (*FakeExecutor, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // reading the caller's fixture is the purpose
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fake executor table: %w", err)
+	}
+	var table fakeTable
+	if err := json.Unmarshal(data, &table); err != nil {
+		return nil, fmt.Errorf("failed to parse fake executor table %s: %w", path, err)
+	}
+	return NewFakeExecutor(table.Rules)
+}
+
+// Execute answers cfg from the first matching rule. Output is streamed to
+// the StdoutWriter and StderrWriter of cfg like a real execution's.
+func (f *FakeExecutor) Execute(_ context.Context, cfg ToolConfig) (*ExecutionResult, error) {
+	for i := range f.rules {
+		rule := &f.rules[i]
+		if !rule.matches(cfg.Command, cfg.Args) {
+			continue
+		}
+		if rule.ErrorDetail != nil {
+			return nil, rule.ErrorDetail.Err()
+		}
+		if rule.Error != "" {
+			return nil, errors.New(rule.Error)
+		}
+		now := time.Now()
+		result := &ExecutionResult{
+			Command:    cfg.Command,
+			Args:       cfg.Args,
+			WorkingDir: cfg.WorkingDir,
+			Output:     rule.Output,
+			Stderr:     rule.Stderr,
+			ExitCode:   rule.ExitCode,
+			Labels:     maps.Clone(cfg.Labels),
+			StartTime:  now,
+			EndTime:    now,
+		}
+		streamMockOutput(cfg, result, nil)
+		return result, nil
+	}
+	return nil, &UnexpectedCommandError{Command: buildCommandString(cfg.Command, cfg.Args)}
+}
+
+// IsAvailable reports whether a rule matches command.
+func (f *FakeExecutor) IsAvailable(command string) bool {
+	return slices.ContainsFunc(f.rules, func(r FakeRule) bool {
+		return r.Command == command
+	})
+}
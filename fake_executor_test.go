@@ -0,0 +1,103 @@
+package cmdexec
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const fakeTableJSON = `{
+  "rules": [
+    {"command": "git", "args": ["rev-parse", "HEAD"], "output": "abc123\n"},
+    {"command": "git", "args": ["push", "*"], "stderr": "rejected\n", "exitCode": 1},
+    {"command": "go", "output": "ok\n"},
+    {"command": "deploy", "error": "connection refused"},
+    {"command": "make", "errorDetail": {"kind": "timeout", "command": "make", "duration": "1m"}}
+  ]
+}`
+
+func TestFakeExecutor(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fake.json")
+	if err := os.WriteFile(path, []byte(fakeTableJSON), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	fake, err := LoadFakeExecutor(path)
+	if err != nil {
+		t.Fatalf("LoadFakeExecutor() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		cfg      ToolConfig
+		wantOut  string
+		wantCode int
+		wantErr  string
+	}{
+		{name: "exact args", cfg: ToolConfig{Command: "git", Args: []string{"rev-parse", "HEAD"}}, wantOut: "abc123\n"},
+		{name: "pattern", cfg: ToolConfig{Command: "git", Args: []string{"push", "origin"}}, wantCode: 1},
+		{name: "any args", cfg: ToolConfig{Command: "go", Args: []string{"test", "./..."}}, wantOut: "ok\n"},
+		{name: "error", cfg: ToolConfig{Command: "deploy"}, wantErr: "connection refused"},
+		{name: "argument count differs", cfg: ToolConfig{Command: "git", Args: []string{"push"}}, wantErr: "unexpected command"},
+		{name: "no rule", cfg: ToolConfig{Command: "rm"}, wantErr: "unexpected command"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := fake.Execute(context.Background(), tt.cfg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Execute() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if result.Output != tt.wantOut || result.ExitCode != tt.wantCode || result.Command != tt.cfg.Command {
+				t.Errorf("Execute() = %+v, want output %q exit code %d", result, tt.wantOut, tt.wantCode)
+			}
+		})
+	}
+
+	var unexpected *UnexpectedCommandError
+	if _, err := fake.Execute(context.Background(), ToolConfig{Command: "rm"}); !errors.As(err, &unexpected) {
+		t.Errorf("Execute(rm) error = %v, want *UnexpectedCommandError", err)
+	}
+	var timeout *TimeoutError
+	if _, err := fake.Execute(context.Background(), ToolConfig{Command: "make"}); !errors.As(err, &timeout) || timeout.Timeout != time.Minute {
+		t.Errorf("Execute(make) error = %#v, want *TimeoutError after 1m", err)
+	}
+	if !fake.IsAvailable("git") || fake.IsAvailable("rm") {
+		t.Error("IsAvailable() should report the commands with rules only")
+	}
+
+	var stdout strings.Builder
+	if _, err := fake.Execute(context.Background(), ToolConfig{Command: "go", StdoutWriter: &stdout}); err != nil || stdout.String() != "ok\n" {
+		t.Errorf("StdoutWriter received %q (err %v), want the output", stdout.String(), err)
+	}
+}
+
+func TestNewFakeExecutor_Invalid(t *testing.T) {
+	var validationErr *ValidationError
+	if _, err := NewFakeExecutor([]FakeRule{{Output: "x"}}); !errors.As(err, &validationErr) || validationErr.Field != "Command" {
+		t.Errorf("rule without command error = %v, want *ValidationError for Command", err)
+	}
+	if _, err := NewFakeExecutor([]FakeRule{{Command: "git", Args: []string{"["}}}); !errors.As(err, &validationErr) || validationErr.Field != "Args" {
+		t.Errorf("malformed pattern error = %v, want *ValidationError for Args", err)
+	}
+
+	dir := t.TempDir()
+	if _, err := LoadFakeExecutor(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("LoadFakeExecutor(missing) error = nil")
+	}
+	bad := filepath.Join(dir, "bad.json")
+	if err := os.WriteFile(bad, []byte("rules: []"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFakeExecutor(bad); err == nil {
+		t.Error("LoadFakeExecutor(non-JSON) error = nil")
+	}
+}
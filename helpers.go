@@ -27,6 +27,47 @@ func Output(ctx context.Context, executor Executor, command string, args ...stri
 	return []byte(result.Output), nil
 }
 
+// OutputLines runs a command and returns its stdout split into lines, with
+// surrounding whitespace trimmed and empty lines removed. Returns an error if
+// the command exits with a non-zero status.
+func OutputLines(ctx context.Context, executor Executor, command string, args ...string) ([]string, error) {
+	output, err := Output(ctx, executor, command, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
+
+// OutputRaw runs a command and returns its stdout bytes, mirroring
+// exec.Cmd.Output: on a non-zero exit it returns the captured stdout together
+// with an *ExitError rather than discarding it. Output is returned byte for
+// byte, so it is suitable for binary data.
+func OutputRaw(ctx context.Context, executor Executor, command string, args ...string) ([]byte, error) {
+	result, err := executor.Execute(ctx, ToolConfig{
+		Command: command,
+		Args:    args,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute %s: %w", command, err)
+	}
+
+	if result.ExitCode != 0 {
+		return result.OutputBytes(), &ExitError{
+			ExitCode: result.ExitCode,
+			Stderr:   result.Stderr,
+		}
+	}
+
+	return result.OutputBytes(), nil
+}
+
 // Run runs a command and returns an error if it exits with a non-zero status,
 // similar to exec.Command().Run().
 func Run(ctx context.Context, executor Executor, command string, args ...string) error {
@@ -48,27 +89,23 @@ func Run(ctx context.Context, executor Executor, command string, args ...string)
 	return nil
 }
 
-// CombinedOutput runs a command and returns stdout followed by stderr.
-// Unlike exec.Command().CombinedOutput(), which interleaves stdout and stderr
-// in real time, this function captures them separately and concatenates stdout
-// then stderr (separated by a newline if both are non-empty).
+// CombinedOutput runs a command and returns its stdout and stderr interleaved
+// in the order they were written, similar to exec.Command().CombinedOutput().
+// If the executor does not populate ExecutionResult.Combined (for example a
+// MockExecutor), it falls back to stdout followed by stderr, separated by a
+// newline if both are non-empty.
 // Returns an error if the command exits with a non-zero status.
 func CombinedOutput(ctx context.Context, executor Executor, command string, args ...string) ([]byte, error) {
 	result, err := executor.Execute(ctx, ToolConfig{
-		Command: command,
-		Args:    args,
+		Command:       command,
+		Args:          args,
+		CombineOutput: true,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute %s: %w", command, err)
 	}
 
-	combined := result.Output
-	if result.Stderr != "" {
-		if combined != "" {
-			combined += "\n"
-		}
-		combined += result.Stderr
-	}
+	combined := combinedOutput(result)
 
 	if result.ExitCode != 0 {
 		return []byte(combined), &ExitError{
@@ -80,6 +117,22 @@ func CombinedOutput(ctx context.Context, executor Executor, command string, args
 	return []byte(combined), nil
 }
 
+// combinedOutput returns result.Combined, or if the executor did not
+// populate it, stdout and stderr joined by a newline if both are non-empty.
+func combinedOutput(result *ExecutionResult) string {
+	if result.Combined != "" {
+		return result.Combined
+	}
+	combined := result.Output
+	if result.Stderr != "" {
+		if combined != "" {
+			combined += "\n"
+		}
+		combined += result.Stderr
+	}
+	return combined
+}
+
 // OutputWithWorkDir runs a command in a specific working directory and returns its stdout output.
 // Similar to Output but allows specifying a working directory.
 func OutputWithWorkDir(ctx context.Context, executor Executor, workDir, command string, args ...string) ([]byte, error) {
@@ -125,25 +178,20 @@ func RunWithWorkDir(ctx context.Context, executor Executor, workDir, command str
 }
 
 // CombinedOutputWithWorkDir runs a command in a specific working directory and
-// returns stdout followed by stderr. Similar to CombinedOutput but allows
+// returns its interleaved stdout and stderr. Similar to CombinedOutput but allows
 // specifying a working directory.
 func CombinedOutputWithWorkDir(ctx context.Context, executor Executor, workDir, command string, args ...string) ([]byte, error) {
 	result, err := executor.Execute(ctx, ToolConfig{
-		Command:    command,
-		Args:       args,
-		WorkingDir: workDir,
+		Command:       command,
+		Args:          args,
+		WorkingDir:    workDir,
+		CombineOutput: true,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute %s: %w", command, err)
 	}
 
-	combined := result.Output
-	if result.Stderr != "" {
-		if combined != "" {
-			combined += "\n"
-		}
-		combined += result.Stderr
-	}
+	combined := combinedOutput(result)
 
 	if result.ExitCode != 0 {
 		return []byte(combined), &ExitError{
@@ -181,11 +229,13 @@ func OutputWithStdin(ctx context.Context, executor Executor, stdin string, comma
 	return []byte(result.Output), nil
 }
 
-// CombinedOutputWithStdin runs a command with stdin input and returns stdout followed by stderr.
+// CombinedOutputWithStdin runs a command with stdin input and returns its
+// interleaved stdout and stderr, like CombinedOutput.
 func CombinedOutputWithStdin(ctx context.Context, executor Executor, stdin string, command string, args ...string) ([]byte, error) {
 	cfg := ToolConfig{
-		Command: command,
-		Args:    args,
+		Command:       command,
+		Args:          args,
+		CombineOutput: true,
 	}
 
 	// Set stdin if provided
@@ -198,13 +248,7 @@ func CombinedOutputWithStdin(ctx context.Context, executor Executor, stdin strin
 		return nil, fmt.Errorf("failed to execute %s: %w", command, err)
 	}
 
-	combined := result.Output
-	if result.Stderr != "" {
-		if combined != "" {
-			combined += "\n"
-		}
-		combined += result.Stderr
-	}
+	combined := combinedOutput(result)
 
 	if result.ExitCode != 0 {
 		return []byte(combined), &ExitError{
@@ -216,7 +260,45 @@ func CombinedOutputWithStdin(ctx context.Context, executor Executor, stdin strin
 	return []byte(combined), nil
 }
 
-// ExitError is returned when a command exits with a non-zero status.
+// OutputWithConfig runs cfg and returns its stdout output. Unlike Output, it
+// accepts a full configuration and honors cfg.SuccessWhen: an execution
+// that does not succeed returns an *ExitError.
+func OutputWithConfig(ctx context.Context, executor Executor, cfg ToolConfig) ([]byte, error) {
+	result, err := executor.Execute(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute %s: %w", cfg.Command, err)
+	}
+
+	if !cfg.Succeeded(result) {
+		return nil, &ExitError{
+			ExitCode: result.ExitCode,
+			Stderr:   result.Stderr,
+		}
+	}
+
+	return []byte(result.Output), nil
+}
+
+// RunWithConfig runs cfg and returns an *ExitError if it does not succeed,
+// as decided by cfg.SuccessWhen or a zero exit code.
+func RunWithConfig(ctx context.Context, executor Executor, cfg ToolConfig) error {
+	result, err := executor.Execute(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to execute %s: %w", cfg.Command, err)
+	}
+
+	if !cfg.Succeeded(result) {
+		return &ExitError{
+			ExitCode: result.ExitCode,
+			Stderr:   result.Stderr,
+		}
+	}
+
+	return nil
+}
+
+// ExitError is returned when a command exits with a non-zero status, or
+// does not meet ToolConfig.SuccessWhen.
 type ExitError struct {
 	ExitCode int
 	Stderr   string
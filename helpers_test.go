@@ -480,3 +480,114 @@ func TestExitError(t *testing.T) {
 		})
 	}
 }
+
+func TestCombinedOutput_Interleaved(t *testing.T) {
+	out, err := cmdexec.CombinedOutput(context.Background(), cmdexec.NewBasicExecutor(),
+		"sh", "-c", "echo a; sleep 0.05; echo b >&2; sleep 0.05; echo c")
+	if err != nil {
+		t.Fatalf("CombinedOutput() error = %v", err)
+	}
+	if want := "a\nb\nc\n"; string(out) != want {
+		t.Errorf("CombinedOutput() = %q, want %q", out, want)
+	}
+}
+
+func TestOutputRaw(t *testing.T) {
+	mock := cmdexec.NewMockExecutor()
+	mock.SetResult(&cmdexec.ExecutionResult{
+		Command:  "tar",
+		Output:   "\x00\xffpartial",
+		Stderr:   "tar: error",
+		ExitCode: 2,
+	}, nil)
+
+	output, err := cmdexec.OutputRaw(context.Background(), mock, "tar", "-c")
+	if string(output) != "\x00\xffpartial" {
+		t.Errorf("OutputRaw() output = %q, want stdout kept on failure", output)
+	}
+	exitErr, ok := err.(*cmdexec.ExitError)
+	if !ok || exitErr.ExitCode != 2 || exitErr.Stderr != "tar: error" {
+		t.Errorf("OutputRaw() error = %v, want *ExitError with code 2", err)
+	}
+
+	mock = cmdexec.NewMockExecutor()
+	mock.SetResult(nil, &cmdexec.ExecutableNotFoundError{Command: "tar"})
+	if output, err := cmdexec.OutputRaw(context.Background(), mock, "tar"); err == nil || output != nil {
+		t.Errorf("OutputRaw() = (%q, %v), want (nil, error)", output, err)
+	}
+}
+
+func TestOutputRaw_Binary(t *testing.T) {
+	output, err := cmdexec.OutputRaw(context.Background(), cmdexec.NewBasicExecutor(), "printf", `\000\001\377`)
+	if err != nil {
+		t.Fatalf("OutputRaw() error = %v", err)
+	}
+	if want := []byte{0x00, 0x01, 0xff}; string(output) != string(want) {
+		t.Errorf("OutputRaw() = %x, want %x", output, want)
+	}
+}
+
+func TestOutputLines(t *testing.T) {
+	mock := cmdexec.NewMockExecutor()
+	mock.SetResult(&cmdexec.ExecutionResult{
+		Command: "git",
+		Output:  "  main\r\n\n* feature  \n\t\n",
+	}, nil)
+
+	lines, err := cmdexec.OutputLines(context.Background(), mock, "git", "branch")
+	if err != nil {
+		t.Fatalf("OutputLines() error = %v", err)
+	}
+	if len(lines) != 2 || lines[0] != "main" || lines[1] != "* feature" {
+		t.Errorf("OutputLines() = %q, want [main \"* feature\"]", lines)
+	}
+
+	mock = cmdexec.NewMockExecutor()
+	mock.SetResult(&cmdexec.ExecutionResult{Command: "git", ExitCode: 1}, nil)
+	if _, err := cmdexec.OutputLines(context.Background(), mock, "git"); err == nil {
+		t.Error("OutputLines() error = nil, want error for non-zero exit")
+	}
+}
+
+func TestOutputWithConfig(t *testing.T) {
+	mock := cmdexec.NewMockExecutor()
+	mock.SetResult(&cmdexec.ExecutionResult{Command: "grep", ExitCode: 1}, nil)
+
+	cfg := cmdexec.ToolConfig{
+		Command:     "grep",
+		Args:        []string{"-r", "TODO", "."},
+		SuccessWhen: cmdexec.SucceedOnExitCodes(0, 1),
+	}
+	output, err := cmdexec.OutputWithConfig(context.Background(), mock, cfg)
+	if err != nil || len(output) != 0 {
+		t.Errorf("OutputWithConfig() = (%q, %v), want no matches and no error", output, err)
+	}
+
+	mock = cmdexec.NewMockExecutor()
+	mock.SetResult(&cmdexec.ExecutionResult{Command: "grep", ExitCode: 2, Stderr: "grep: bad regex"}, nil)
+	if _, err := cmdexec.OutputWithConfig(context.Background(), mock, cfg); err == nil {
+		t.Error("OutputWithConfig() error = nil, want *ExitError for exit code 2")
+	}
+}
+
+func TestRunWithConfig(t *testing.T) {
+	mock := cmdexec.NewMockExecutor()
+	mock.SetResult(&cmdexec.ExecutionResult{Command: "lint", Output: "3 problems\n"}, nil)
+
+	err := cmdexec.RunWithConfig(context.Background(), mock, cmdexec.ToolConfig{
+		Command: "lint",
+		SuccessWhen: func(result *cmdexec.ExecutionResult) bool {
+			return result.ExitCode == 0 && !strings.Contains(result.Output, "problems")
+		},
+	})
+	exitErr, ok := err.(*cmdexec.ExitError)
+	if !ok || exitErr.ExitCode != 0 {
+		t.Errorf("RunWithConfig() error = %v, want *ExitError for a failed SuccessWhen", err)
+	}
+
+	mock = cmdexec.NewMockExecutor()
+	mock.SetResult(&cmdexec.ExecutionResult{Command: "true"}, nil)
+	if err := cmdexec.RunWithConfig(context.Background(), mock, cmdexec.ToolConfig{Command: "true"}); err != nil {
+		t.Errorf("RunWithConfig() error = %v, want nil", err)
+	}
+}
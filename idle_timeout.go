@@ -0,0 +1,88 @@
+package cmdexec
+
+import (
+	"context"
+	"io"
+	"os/exec"
+	"sync/atomic"
+	"time"
+)
+
+// idleWaitDelay bounds how long Wait may block on output pipes held open by
+// descendants after an idle process has been killed.
+const idleWaitDelay = time.Second
+
+// idleWatchdog cancels an execution when no output has been produced for
+// the configured duration.
+type idleWatchdog struct {
+	timeout time.Duration
+	timer   *time.Timer
+	cancel  context.CancelFunc
+	expired atomic.Bool
+}
+
+// newIdleWatchdog derives a context from ctx that is cancelled after timeout
+// elapses without activity. If timeout is zero, it returns ctx and a nil
+// watchdog; all idleWatchdog methods are safe to call on nil.
+func newIdleWatchdog(ctx context.Context, timeout time.Duration) (context.Context, *idleWatchdog) {
+	if timeout <= 0 {
+		return ctx, nil
+	}
+	idleCtx, cancel := context.WithCancel(ctx)
+	w := &idleWatchdog{timeout: timeout, cancel: cancel}
+	w.timer = time.AfterFunc(timeout, func() {
+		w.expired.Store(true)
+		cancel()
+	})
+	return idleCtx, w
+}
+
+// touch records output activity, restarting the idle countdown.
+func (w *idleWatchdog) touch() {
+	if w == nil || w.expired.Load() {
+		return
+	}
+	w.timer.Reset(w.timeout)
+}
+
+// fired reports whether the watchdog cancelled the execution.
+func (w *idleWatchdog) fired() bool {
+	return w != nil && w.expired.Load()
+}
+
+// stop releases the watchdog's timer and context.
+func (w *idleWatchdog) stop() {
+	if w == nil {
+		return
+	}
+	w.timer.Stop()
+	w.cancel()
+}
+
+// configure bounds how long cmd may wait for its output pipes after the
+// watchdog kills it, so hung descendants cannot block the execution.
+func (w *idleWatchdog) configure(cmd *exec.Cmd) {
+	if w == nil || cmd.WaitDelay != 0 {
+		return
+	}
+	cmd.WaitDelay = idleWaitDelay
+}
+
+// wrap returns a writer that records activity on every write.
+func (w *idleWatchdog) wrap(dst io.Writer) io.Writer {
+	if w == nil {
+		return dst
+	}
+	return &activityWriter{w: dst, touch: w.touch}
+}
+
+// activityWriter calls touch before forwarding each write.
+type activityWriter struct {
+	w     io.Writer
+	touch func()
+}
+
+func (aw *activityWriter) Write(p []byte) (int, error) {
+	aw.touch()
+	return aw.w.Write(p) //nolint:wrapcheck // transparent writer wrapper
+}
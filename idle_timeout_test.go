@@ -0,0 +1,63 @@
+package cmdexec
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBasicExecutor_Execute_IdleTimeout(t *testing.T) {
+	start := time.Now()
+	result, err := NewBasicExecutor().Execute(context.Background(), ToolConfig{
+		Command:     "sh",
+		Args:        []string{"-c", "echo started; sleep 5"},
+		IdleTimeout: 200 * time.Millisecond,
+	})
+	if result != nil {
+		t.Errorf("result = %v, want nil", result)
+	}
+	var idleErr *IdleTimeoutError
+	if !errors.As(err, &idleErr) {
+		t.Fatalf("error = %v, want *IdleTimeoutError", err)
+	}
+	if idleErr.IdleTimeout != 200*time.Millisecond {
+		t.Errorf("IdleTimeout = %v, want 200ms", idleErr.IdleTimeout)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("idle timeout took %v to fire", elapsed)
+	}
+}
+
+func TestBasicExecutor_Execute_IdleTimeoutResetByOutput(t *testing.T) {
+	result, err := NewBasicExecutor().Execute(context.Background(), ToolConfig{
+		Command:     "sh",
+		Args:        []string{"-c", "for i in 1 2 3 4 5 6; do echo $i; sleep 0.1; done"},
+		IdleTimeout: 400 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.ExitCode != 0 {
+		t.Errorf("ExitCode = %d, want 0", result.ExitCode)
+	}
+}
+
+func TestBasicExecutor_Execute_IdleTimeoutStderrCountsAsActivity(t *testing.T) {
+	_, err := NewBasicExecutor().Execute(context.Background(), ToolConfig{
+		Command:     "sh",
+		Args:        []string{"-c", "for i in 1 2 3 4 5 6; do echo $i >&2; sleep 0.1; done"},
+		IdleTimeout: 400 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+}
+
+func TestIdleTimeoutError(t *testing.T) {
+	err := &IdleTimeoutError{Command: "bazel build", IdleTimeout: 30 * time.Second}
+	want := "command 'bazel build' produced no output for 30s"
+	if err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+}
@@ -0,0 +1,85 @@
+package cmdexec
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ExecuteJSONLines runs cfg and decodes each line of stdout as a JSON value
+// of type T, calling fn for each value as it arrives. It suits long-running
+// commands that emit JSON lines, such as "docker events --format json".
+//
+// fn runs on the goroutine that copies stdout, so a slow callback applies
+// backpressure to the command rather than buffering unboundedly. Blank lines
+// are skipped. If a line cannot be decoded or fn returns an error, the
+// command is cancelled and ExecuteJSONLines returns that error (a decode
+// failure is a *JSONLineError); otherwise it returns the result and error
+// of Execute. Any OnStdoutLine callback in cfg is still invoked.
+func ExecuteJSONLines[T any](ctx context.Context, executor Executor, cfg ToolConfig, fn func(T) error) (*ExecutionResult, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		firstErr error
+		lineNo   int
+	)
+	fail := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+	}
+	failed := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return firstErr != nil
+	}
+
+	onLine := cfg.OnStdoutLine
+	cfg.OnStdoutLine = func(line string) {
+		if onLine != nil {
+			onLine(line)
+		}
+		lineNo++
+		if failed() || strings.TrimSpace(line) == "" {
+			return
+		}
+		var v T
+		if err := json.Unmarshal([]byte(line), &v); err != nil {
+			fail(&JSONLineError{Line: lineNo, Err: err})
+			return
+		}
+		if err := fn(v); err != nil {
+			fail(err)
+		}
+	}
+
+	result, err := executor.Execute(ctx, cfg)
+	if failed() {
+		_ = result.Cleanup()
+		return nil, firstErr
+	}
+	return result, err //nolint:wrapcheck // preserves the Execute error contract
+}
+
+// JSONLineError reports a stdout line that could not be decoded by
+// ExecuteJSONLines.
+type JSONLineError struct {
+	Line int
+	Err  error
+}
+
+func (e *JSONLineError) Error() string {
+	return fmt.Sprintf("invalid JSON on stdout line %d: %v", e.Line, e.Err)
+}
+
+// Unwrap returns the underlying decoding error.
+func (e *JSONLineError) Unwrap() error {
+	return e.Err
+}
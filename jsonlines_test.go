@@ -0,0 +1,76 @@
+package cmdexec
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type testEvent struct {
+	ID     int    `json:"id"`
+	Status string `json:"status"`
+}
+
+func TestExecuteJSONLines(t *testing.T) {
+	var events []testEvent
+	var lines int
+	result, err := ExecuteJSONLines(context.Background(), NewBasicExecutor(), ToolConfig{
+		Command:      "sh",
+		Args:         []string{"-c", `echo '{"id":1,"status":"start"}'; echo; echo '{"id":2,"status":"done"}'`},
+		OnStdoutLine: func(string) { lines++ },
+	}, func(ev testEvent) error {
+		events = append(events, ev)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ExecuteJSONLines() error = %v", err)
+	}
+	if result.ExitCode != 0 {
+		t.Errorf("ExitCode = %d, want 0", result.ExitCode)
+	}
+	want := []testEvent{{1, "start"}, {2, "done"}}
+	if len(events) != 2 || events[0] != want[0] || events[1] != want[1] {
+		t.Errorf("events = %+v, want %+v", events, want)
+	}
+	if lines != 3 {
+		t.Errorf("OnStdoutLine called %d times, want 3", lines)
+	}
+}
+
+func TestExecuteJSONLines_DecodeError(t *testing.T) {
+	start := time.Now()
+	_, err := ExecuteJSONLines(context.Background(), NewBasicExecutor(), ToolConfig{
+		Command: "sh",
+		Args:    []string{"-c", `echo '{"id":1}'; echo 'not json'; while sleep 0.05; do :; done`},
+	}, func(testEvent) error { return nil })
+
+	var lineErr *JSONLineError
+	if !errors.As(err, &lineErr) || lineErr.Line != 2 {
+		t.Fatalf("ExecuteJSONLines() error = %v, want *JSONLineError on line 2", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("command was not cancelled after decode error (took %v)", elapsed)
+	}
+}
+
+func TestExecuteJSONLines_CallbackError(t *testing.T) {
+	stop := errors.New("stop")
+	calls := 0
+	_, err := ExecuteJSONLines(context.Background(), NewBasicExecutor(), ToolConfig{
+		Command: "sh",
+		Args:    []string{"-c", `while true; do echo '{"id":1}'; sleep 0.01; done`},
+	}, func(testEvent) error {
+		calls++
+		if calls == 3 {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) {
+		t.Fatalf("ExecuteJSONLines() error = %v, want %v", err, stop)
+	}
+	if calls != 3 {
+		t.Errorf("callback called %d times after failing, want 3", calls)
+	}
+}
@@ -0,0 +1,121 @@
+package cmdexec
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"sync/atomic"
+	"time"
+)
+
+// killPolicyWaitDelayMargin is added to a kill policy's total duration when
+// bounding how long Wait may block on output pipes, so os/exec does not cut
+// the escalation short.
+const killPolicyWaitDelayMargin = time.Second
+
+// KillStep is one step of a KillPolicy: Signal is sent to the process, which
+// then gets Wait to exit before the next step is taken.
+type KillStep struct {
+	Signal os.Signal
+	Wait   time.Duration
+}
+
+// KillPolicy is the termination ritual applied when an execution is
+// cancelled (by Timeout, IdleTimeout, or its context). Steps are taken in
+// order until the process exits; if it survives the last step, it is killed
+// outright. An empty policy kills immediately, which is the default.
+type KillPolicy []KillStep
+
+// NewKillPolicy builds a KillPolicy that sends each signal in turn, waiting
+// the same grace period after each one, for example
+// NewKillPolicy(5*time.Second, unix.SIGINT, unix.SIGTERM, unix.SIGKILL).
+func NewKillPolicy(grace time.Duration, signals ...os.Signal) KillPolicy {
+	policy := make(KillPolicy, len(signals))
+	for i, sig := range signals {
+		policy[i] = KillStep{Signal: sig, Wait: grace}
+	}
+	return policy
+}
+
+func (kp KillPolicy) validate() error {
+	for i, step := range kp {
+		if step.Signal == nil {
+			return &ValidationError{Field: "KillPolicy", Message: fmt.Sprintf("step %d has no signal", i)}
+		}
+		if step.Wait < 0 {
+			return &ValidationError{Field: "KillPolicy", Message: fmt.Sprintf("step %d wait cannot be negative", i)}
+		}
+	}
+	return nil
+}
+
+// totalWait returns the sum of all step waits.
+func (kp KillPolicy) totalWait() time.Duration {
+	var total time.Duration
+	for _, step := range kp {
+		total += step.Wait
+	}
+	return total
+}
+
+// killEscalation runs a KillPolicy against one command.
+type killEscalation struct {
+	policy KillPolicy
+	exited chan struct{}
+	last   atomic.Value // string: signalName of the last signal delivered
+}
+
+// installKillPolicy configures cmd to run policy when its context is
+// cancelled. It returns nil if policy is empty; all killEscalation methods
+// are safe to call on nil.
+func installKillPolicy(cmd *exec.Cmd, policy KillPolicy) *killEscalation {
+	if len(policy) == 0 || cmd.Cancel == nil {
+		return nil
+	}
+	k := &killEscalation{policy: policy, exited: make(chan struct{})}
+	cmd.Cancel = func() error {
+		go k.run(cmd.Process)
+		return nil
+	}
+	if minDelay := policy.totalWait() + killPolicyWaitDelayMargin; cmd.WaitDelay < minDelay {
+		cmd.WaitDelay = minDelay
+	}
+	return k
+}
+
+func (k *killEscalation) run(p *os.Process) {
+	for _, step := range k.policy {
+		if err := p.Signal(step.Signal); err != nil {
+			slog.Debug("Failed to deliver kill policy signal", "signal", signalName(step.Signal), "error", err)
+			return
+		}
+		k.last.Store(signalName(step.Signal))
+		select {
+		case <-k.exited:
+			return
+		case <-time.After(step.Wait):
+		}
+	}
+	if err := p.Kill(); err == nil {
+		k.last.Store(signalName(os.Kill))
+	}
+}
+
+// stop tells the escalation that the process has exited.
+func (k *killEscalation) stop() {
+	if k == nil {
+		return
+	}
+	close(k.exited)
+}
+
+// signal returns the name of the last signal delivered by the policy, or ""
+// if the policy never ran.
+func (k *killEscalation) signal() string {
+	if k == nil {
+		return ""
+	}
+	s, _ := k.last.Load().(string)
+	return s
+}
@@ -0,0 +1,84 @@
+//go:build unix
+
+package cmdexec
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestBasicExecutor_Execute_KillPolicyGraceful(t *testing.T) {
+	_, err := NewBasicExecutor().Execute(context.Background(), ToolConfig{
+		Command:    "sh",
+		Args:       []string{"-c", "trap 'exit 0' INT; while :; do sleep 0.05; done"},
+		Timeout:    200 * time.Millisecond,
+		KillPolicy: NewKillPolicy(2*time.Second, unix.SIGINT, unix.SIGTERM),
+	})
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("error = %v, want *TimeoutError", err)
+	}
+	if timeoutErr.Signal != "SIGINT" {
+		t.Errorf("Signal = %q, want %q", timeoutErr.Signal, "SIGINT")
+	}
+}
+
+func TestBasicExecutor_Execute_KillPolicyEscalates(t *testing.T) {
+	start := time.Now()
+	_, err := NewBasicExecutor().Execute(context.Background(), ToolConfig{
+		Command:    "sh",
+		Args:       []string{"-c", "trap '' INT TERM; while :; do sleep 0.05; done"},
+		Timeout:    100 * time.Millisecond,
+		KillPolicy: NewKillPolicy(200*time.Millisecond, unix.SIGINT, unix.SIGTERM),
+	})
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("error = %v, want *TimeoutError", err)
+	}
+	if timeoutErr.Signal != "SIGKILL" {
+		t.Errorf("Signal = %q, want %q", timeoutErr.Signal, "SIGKILL")
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("escalation took %v", elapsed)
+	}
+}
+
+func TestBasicExecutor_Execute_NoKillPolicyReportsKill(t *testing.T) {
+	_, err := NewBasicExecutor().Execute(context.Background(), ToolConfig{
+		Command: "sleep",
+		Args:    []string{"5"},
+		Timeout: 100 * time.Millisecond,
+	})
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("error = %v, want *TimeoutError", err)
+	}
+	// The default cancellation kills the process, which the wait status
+	// reports even without a KillPolicy.
+	if timeoutErr.Signal != "SIGKILL" {
+		t.Errorf("Signal = %q, want %q", timeoutErr.Signal, "SIGKILL")
+	}
+}
+
+func TestKillPolicy_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy KillPolicy
+	}{
+		{"nil signal", KillPolicy{{Signal: nil, Wait: time.Second}}},
+		{"negative wait", KillPolicy{{Signal: unix.SIGTERM, Wait: -time.Second}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := ToolConfig{Command: "echo", KillPolicy: tt.policy}
+			var valErr *ValidationError
+			if err := cfg.Validate(); !errors.As(err, &valErr) || valErr.Field != "KillPolicy" {
+				t.Errorf("Validate() error = %v, want ValidationError on KillPolicy", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package cmdexec
+
+import (
+	"bytes"
+)
+
+// LineWriter is an io.Writer that splits written data into lines and calls
+// a callback for each one, without the line terminator. Both "\n" and "\r"
+// end a line ("\r\n" counts once), so carriage-return progress updates from
+// tools such as curl or rsync are delivered as individual lines. Partial
+// lines are buffered until their terminator arrives or Flush is called.
+//
+// A LineWriter is not safe for concurrent use.
+type LineWriter struct {
+	fn        func(string)
+	partial   []byte
+	pendingCR bool
+}
+
+// NewLineWriter returns a LineWriter that calls fn for every line.
+func NewLineWriter(fn func(line string)) *LineWriter {
+	return &LineWriter{fn: fn}
+}
+
+// Write implements io.Writer. It never returns an error.
+func (lw *LineWriter) Write(p []byte) (int, error) {
+	data := p
+	if lw.pendingCR && len(data) > 0 && data[0] == '\n' {
+		data = data[1:]
+	}
+	lw.pendingCR = false
+
+	for len(data) > 0 {
+		i := bytes.IndexAny(data, "\r\n")
+		if i < 0 {
+			lw.partial = append(lw.partial, data...)
+			break
+		}
+		lw.emit(data[:i])
+		if data[i] == '\r' {
+			if i+1 == len(data) {
+				lw.pendingCR = true
+			} else if data[i+1] == '\n' {
+				i++
+			}
+		}
+		data = data[i+1:]
+	}
+	return len(p), nil
+}
+
+func (lw *LineWriter) emit(tail []byte) {
+	if len(lw.partial) > 0 {
+		line := string(lw.partial) + string(tail)
+		lw.partial = lw.partial[:0]
+		lw.fn(line)
+		return
+	}
+	lw.fn(string(tail))
+}
+
+// Flush delivers any buffered partial line. It always returns nil.
+func (lw *LineWriter) Flush() error {
+	if len(lw.partial) > 0 {
+		lw.emit(nil)
+	}
+	return nil
+}
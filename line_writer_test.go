@@ -0,0 +1,75 @@
+package cmdexec
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestLineWriter(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes []string
+		want   []string
+	}{
+		{"single line", []string{"hello\n"}, []string{"hello"}},
+		{"multiple lines", []string{"a\nb\nc\n"}, []string{"a", "b", "c"}},
+		{"split across writes", []string{"hel", "lo\nwor", "ld\n"}, []string{"hello", "world"}},
+		{"partial flushed", []string{"a\nno newline"}, []string{"a", "no newline"}},
+		{"crlf", []string{"a\r\nb\r\n"}, []string{"a", "b"}},
+		{"crlf split across writes", []string{"a\r", "\nb\n"}, []string{"a", "b"}},
+		{"cr progress", []string{"10%\r50%\r100%\n"}, []string{"10%", "50%", "100%"}},
+		{"empty lines", []string{"\n\n"}, []string{"", ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			lw := NewLineWriter(func(line string) { got = append(got, line) })
+			for _, w := range tt.writes {
+				n, err := lw.Write([]byte(w))
+				if err != nil || n != len(w) {
+					t.Fatalf("Write() = (%d, %v), want (%d, nil)", n, err, len(w))
+				}
+			}
+			_ = lw.Flush()
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("lines = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBasicExecutor_Execute_LineCallbacks(t *testing.T) {
+	var mu sync.Mutex
+	var stdoutLines, stderrLines []string
+
+	result, err := NewBasicExecutor().Execute(context.Background(), ToolConfig{
+		Command: "sh",
+		Args:    []string{"-c", "echo one; echo err >&2; printf 'two\\nthree'"},
+		OnStdoutLine: func(line string) {
+			mu.Lock()
+			defer mu.Unlock()
+			stdoutLines = append(stdoutLines, line)
+		},
+		OnStderrLine: func(line string) {
+			mu.Lock()
+			defer mu.Unlock()
+			stderrLines = append(stderrLines, line)
+		},
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	if want := []string{"one", "two", "three"}; !reflect.DeepEqual(stdoutLines, want) {
+		t.Errorf("stdout lines = %q, want %q", stdoutLines, want)
+	}
+	if want := []string{"err"}; !reflect.DeepEqual(stderrLines, want) {
+		t.Errorf("stderr lines = %q, want %q", stderrLines, want)
+	}
+	if result.Output != "one\ntwo\nthree" {
+		t.Errorf("Output = %q, captured output should be unaffected", result.Output)
+	}
+}
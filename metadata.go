@@ -0,0 +1,31 @@
+package cmdexec
+
+import (
+	"context"
+	"maps"
+)
+
+// metadataKey is the context key of the execution metadata.
+type metadataKey struct{}
+
+// WithMetadata returns a copy of ctx carrying the metadata key with value,
+// in addition to any metadata ctx already carries. Executions started with
+// the context are tagged with it: debug logs, OutputLog records,
+// RecordingExecutor transcripts and MockExecutor call history include it,
+// which correlates executions with, e.g., request IDs without setting
+// ToolConfig.Labels on every config.
+func WithMetadata(ctx context.Context, key, value string) context.Context {
+	md := maps.Clone(Metadata(ctx))
+	if md == nil {
+		md = make(map[string]string, 1)
+	}
+	md[key] = value
+	return context.WithValue(ctx, metadataKey{}, md)
+}
+
+// Metadata returns the metadata set on ctx with WithMetadata, or nil if
+// there is none. The returned map must not be modified.
+func Metadata(ctx context.Context) map[string]string {
+	md, _ := ctx.Value(metadataKey{}).(map[string]string)
+	return md
+}
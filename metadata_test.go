@@ -0,0 +1,67 @@
+package cmdexec
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"maps"
+	"testing"
+)
+
+func TestWithMetadata(t *testing.T) {
+	t.Parallel()
+	if md := Metadata(context.Background()); md != nil {
+		t.Errorf("Metadata() = %v, want nil", md)
+	}
+
+	parent := WithMetadata(context.Background(), "request_id", "r1")
+	child := WithMetadata(parent, "user", "alice")
+	if got, want := Metadata(child), map[string]string{"request_id": "r1", "user": "alice"}; !maps.Equal(got, want) {
+		t.Errorf("Metadata(child) = %v, want %v", got, want)
+	}
+	if got, want := Metadata(parent), map[string]string{"request_id": "r1"}; !maps.Equal(got, want) {
+		t.Errorf("Metadata(parent) = %v, want %v", got, want)
+	}
+	if got := Metadata(WithMetadata(parent, "request_id", "r2")); got["request_id"] != "r2" {
+		t.Errorf("overridden request_id = %q, want r2", got["request_id"])
+	}
+}
+
+func TestMetadata_Wrappers(t *testing.T) {
+	t.Parallel()
+	ctx := WithMetadata(context.Background(), "request_id", "r1")
+
+	mock := NewMockExecutor()
+	recorder := NewRecordingExecutor(mock, "")
+	if _, err := recorder.Execute(ctx, ToolConfig{Command: "echo"}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if got := recorder.Transcript().Entries[0].Metadata; got["request_id"] != "r1" {
+		t.Errorf("transcript metadata = %v, want request_id r1", got)
+	}
+	if got := mock.CallHistory[0].Metadata; got["request_id"] != "r1" {
+		t.Errorf("call metadata = %v, want request_id r1", got)
+	}
+}
+
+func TestMetadata_OutputLog(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	ctx := WithMetadata(context.Background(), "request_id", "r1")
+	_, err := NewBasicExecutor().Execute(ctx, ToolConfig{
+		Command:   "echo",
+		Args:      []string{"hello"},
+		OutputLog: &OutputLog{Logger: logger},
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	records := decodeOutputLog(t, &buf)
+	if len(records) != 1 {
+		t.Fatalf("got %d records, want 1: %s", len(records), buf.String())
+	}
+	if md, _ := records[0]["metadata"].(map[string]any); md["request_id"] != "r1" {
+		t.Errorf("record metadata = %v, want request_id r1", records[0]["metadata"])
+	}
+}
@@ -2,7 +2,12 @@ package cmdexec
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -24,6 +29,27 @@ type MockExecutor struct {
 	// Default behavior when no expectation matches
 	DefaultResult *ExecutionResult
 	DefaultError  error
+
+	// strict makes calls matching no expectation fail.
+	strict bool
+
+	// honorTimeout makes delays longer than ToolConfig.Timeout time out.
+	honorTimeout bool
+
+	// validate runs ToolConfig.Validate on each call.
+	validate bool
+
+	// stdinWait bounds how long a call waits for its input to end; zero
+	// means until the context of the call is done.
+	stdinWait time.Duration
+
+	// clock timestamps calls and results; nil means the system clock.
+	clock Clock
+
+	// orderGroups counts the InOrder groups, and violations records the
+	// calls that were out of order or, in strict mode, unexpected.
+	orderGroups int
+	violations  []error
 }
 
 // MockExpectation represents an expected call to Execute with a predefined response.
@@ -35,9 +61,86 @@ type MockExpectation struct {
 	Result *ExecutionResult
 	Error  error
 
+	// Sequence, if not empty, replaces Result: successive matches return
+	// its results in order, repeating the last one once it is exhausted.
+	Sequence []*ExecutionResult
+
+	// Delay is how long Execute waits before returning the response.
+	Delay time.Duration
+
+	// Chunks, if not empty, are written one by one to the StdoutWriter of
+	// the call instead of the Output of the result.
+	Chunks []string
+
+	// Handler, if set, computes the response of each matched call instead
+	// of Result and Error. The mock is not locked while it runs.
+	Handler func(ctx context.Context, cfg ToolConfig) (*ExecutionResult, error)
+
 	// Times specifies how many times this expectation can be used (0 = unlimited)
 	Times int
 	used  int
+
+	// description identifies the expectation in error messages, and
+	// command is the command it expects, if known.
+	description string
+	command     string
+
+	// never marks an expectation added by ExpectNever.
+	never bool
+
+	// group and position place the expectation in an InOrder sequence;
+	// group 0 means it is unordered.
+	group    int
+	position int
+}
+
+// satisfied reports whether the expectation has been used as often as it
+// requires: Times times, or at least once if Times is unlimited.
+func (e *MockExpectation) satisfied() bool {
+	if e.Times > 0 {
+		return e.used >= e.Times
+	}
+	return e.used > 0
+}
+
+// MockOrderError is returned by MockExecutor.Execute, and reported by
+// AssertExpectationsMet, when a call matches an expectation of an InOrder
+// sequence before the expectations preceding it were met.
+type MockOrderError struct {
+	// Command is the command that was executed too early.
+	Command string
+
+	// Expectation and Pending describe the matched expectation and the
+	// first preceding one that was not met yet.
+	Expectation string
+	Pending     string
+}
+
+func (e *MockOrderError) Error() string {
+	return fmt.Sprintf("command %q matched expectation %s out of order: expectation %s has not been met yet",
+		e.Command, e.Expectation, e.Pending)
+}
+
+// UnexpectedCommandError is returned by MockExecutor.Execute in strict mode,
+// and reported by AssertExpectationsMet, when a call matches no expectation.
+type UnexpectedCommandError struct {
+	Command string
+}
+
+func (e *UnexpectedCommandError) Error() string {
+	return fmt.Sprintf("unexpected command: %q matches no expectation", e.Command)
+}
+
+// ForbiddenCommandError is reported by AssertExpectationsMet, and returned
+// by MockExecutor.Execute in strict mode, when a call matches an
+// expectation added by ExpectNever.
+type ForbiddenCommandError struct {
+	Command     string
+	Expectation string
+}
+
+func (e *ForbiddenCommandError) Error() string {
+	return fmt.Sprintf("forbidden command: %q matches ExpectNever(%s)", e.Command, e.Expectation)
 }
 
 // MockCall represents a recorded call to Execute.
@@ -45,6 +148,23 @@ type MockCall struct {
 	Config    ToolConfig
 	Timestamp time.Time
 	Context   context.Context
+
+	// Stdin is the input the call provided through Stdin, StdinFactory,
+	// StdinProvider or StdinFile, up to maxMockStdinBytes of it.
+	Stdin string
+
+	// StdinTruncated reports that the input was longer than
+	// maxMockStdinBytes, so Stdin holds only its beginning.
+	StdinTruncated bool
+
+	// StdinIncomplete reports that the input did not end before the
+	// context of the call was done or the wait set with SetStdinWait
+	// passed, as with a reader such as os.Stdin that is never closed.
+	// Stdin is empty then.
+	StdinIncomplete bool
+
+	// Metadata is the metadata of Context; see WithMetadata.
+	Metadata map[string]string
 }
 
 // NewMockExecutor creates a new MockExecutor instance.
@@ -56,33 +176,195 @@ func NewMockExecutor() *MockExecutor {
 	}
 }
 
-// Execute implements the Executor interface.
+// Execute implements the Executor interface. The mock is not locked while
+// it waits out the Delay of the matched expectation, so concurrent calls
+// overlap like real executions would.
 func (m *MockExecutor) Execute(ctx context.Context, cfg ToolConfig) (*ExecutionResult, error) {
+	stdin, err := m.readStdin(ctx, cfg)
+	if err != nil {
+		m.mu.Lock()
+		m.recordCall(ctx, cfg, stdin)
+		m.mu.Unlock()
+		return nil, err
+	}
+	result, exp, err := m.respond(ctx, cfg, stdin)
+	if exp == nil {
+		return withMockLabels(result, cfg), err
+	}
+	delay, timedOut := exp.Delay, m.exceedsTimeout(cfg, exp.Delay)
+	if timedOut {
+		delay = cfg.Timeout
+	}
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, fmt.Errorf("context done during mock delay: %w", ctx.Err())
+		}
+	}
+	if timedOut {
+		return nil, &TimeoutError{Command: buildCommandString(cfg.Command, cfg.Args), Timeout: cfg.Timeout}
+	}
+	if exp.Handler != nil {
+		result, err = exp.Handler(ctx, cfg)
+	}
+	if result != nil {
+		streamMockOutput(cfg, result, exp.Chunks)
+	}
+	return withMockLabels(result, cfg), err
+}
+
+// withMockLabels returns result with the Labels of cfg, like a real
+// execution's. Results are shared between calls, so a labelled copy is
+// returned rather than modifying result.
+func withMockLabels(result *ExecutionResult, cfg ToolConfig) *ExecutionResult {
+	if result == nil || len(cfg.Labels) == 0 {
+		return result
+	}
+	labelled := *result
+	labelled.Labels = maps.Clone(cfg.Labels)
+	return &labelled
+}
+
+// exceedsTimeout reports whether a simulated execution taking delay runs
+// past the Timeout of cfg, if the mock honors timeouts.
+func (m *MockExecutor) exceedsTimeout(cfg ToolConfig, delay time.Duration) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.honorTimeout && cfg.Timeout > 0 && delay > cfg.Timeout
+}
+
+// streamMockOutput writes the output of a matched response to the
+// StdoutWriter and StderrWriter of cfg, as a real execution would stream it.
+// Write errors are ignored, since the response is predetermined anyway.
+func streamMockOutput(cfg ToolConfig, result *ExecutionResult, chunks []string) {
+	if cfg.StdoutWriter != nil {
+		if len(chunks) == 0 && result.Output != "" {
+			chunks = []string{result.Output}
+		}
+		for _, chunk := range chunks {
+			_, _ = io.WriteString(cfg.StdoutWriter, chunk)
+		}
+	}
+	if cfg.StderrWriter != nil && result.Stderr != "" {
+		_, _ = io.WriteString(cfg.StderrWriter, result.Stderr)
+	}
+}
+
+// maxMockStdinBytes bounds the input the mock captures per call.
+const maxMockStdinBytes = 1 << 20
+
+// mockStdin is the input captured from one call.
+type mockStdin struct {
+	data       string
+	truncated  bool
+	incomplete bool
+}
+
+// readStdin consumes the stdin of cfg, preferring StdinFile, StdinFactory
+// or StdinProvider over Stdin like a real execution does. Like the
+// BasicExecutor, it fails if the StdinFile cannot be opened or the
+// StdinProvider fails. A read error ends the input early.
+func (m *MockExecutor) readStdin(ctx context.Context, cfg ToolConfig) (mockStdin, error) {
+	m.mu.RLock()
+	wait := m.stdinWait
+	m.mu.RUnlock()
+
+	f, err := openStdinFile(cfg)
+	if err != nil {
+		return mockStdin{}, err
+	}
+	if f != nil {
+		return captureStdin(ctx, wait, f, func() { _ = f.Close() }), nil
+	}
+	release, err := prepareStdin(&cfg)
+	if err != nil {
+		return mockStdin{}, err
+	}
+	if cfg.Stdin == nil {
+		release()
+		return mockStdin{}, nil
+	}
+	return captureStdin(ctx, wait, cfg.Stdin, release), nil
+}
+
+// captureStdin reads r until it ends, keeping up to maxMockStdinBytes. If
+// ctx is done or wait, if positive, passes first, it gives up and marks
+// the input incomplete. release runs once the read has finished, so a
+// reader is never closed while it is still being read.
+func captureStdin(ctx context.Context, wait time.Duration, r io.Reader, release func()) mockStdin {
+	done := make(chan mockStdin, 1)
+	go func() {
+		defer release()
+		data, _ := io.ReadAll(io.LimitReader(r, maxMockStdinBytes+1))
+		truncated := len(data) > maxMockStdinBytes
+		if truncated {
+			data = data[:maxMockStdinBytes]
+		}
+		done <- mockStdin{data: string(data), truncated: truncated}
+	}()
+	var timeout <-chan time.Time
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case stdin := <-done:
+		return stdin
+	case <-timeout:
+	case <-ctx.Done():
+	}
+	return mockStdin{incomplete: true}
+}
+
+// respond records the call and resolves its response. It returns a copy of
+// the matched expectation, or nil if none matched.
+func (m *MockExecutor) respond(ctx context.Context, cfg ToolConfig, stdin mockStdin) (*ExecutionResult, *MockExpectation, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	// Record the call
-	m.CallHistory = append(m.CallHistory, MockCall{
-		Config:    cfg,
-		Timestamp: time.Now(),
-		Context:   ctx,
-	})
+	m.recordCall(ctx, cfg, stdin)
+
+	// Reject invalid configs like the BasicExecutor does
+	if m.validate {
+		if err := cfg.Validate(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := m.checkForbidden(ctx, cfg); err != nil && m.strict {
+		return nil, nil, err
+	}
 
 	// Find matching expectation
 	for i := range m.expectations {
 		exp := &m.expectations[i]
-		if exp.Matcher(ctx, cfg) && (exp.Times == 0 || exp.used < exp.Times) {
+		if !exp.never && exp.Matcher(ctx, cfg) && (exp.Times == 0 || exp.used < exp.Times) {
+			if err := m.checkOrder(exp, cfg); err != nil {
+				return nil, nil, err
+			}
 			exp.used++
-			return exp.Result, exp.Error
+			matched := *exp
+			if len(exp.Sequence) > 0 {
+				return exp.Sequence[min(exp.used, len(exp.Sequence))-1], &matched, nil
+			}
+			return exp.Result, &matched, exp.Error
 		}
 	}
 
-	// No expectation matched, use default behavior
+	// No expectation matched: fail in strict mode, else use default behavior
+	if m.strict {
+		err := &UnexpectedCommandError{Command: buildCommandString(cfg.Command, cfg.Args)}
+		m.violations = append(m.violations, err)
+		return nil, nil, err
+	}
 	if m.DefaultResult != nil || m.DefaultError != nil {
-		return m.DefaultResult, m.DefaultError
+		return m.DefaultResult, nil, m.DefaultError
 	}
 
 	// If no default is set, return a generic success result
+	now := m.timeNow()
 	return &ExecutionResult{
 		Command:    cfg.Command,
 		Args:       cfg.Args,
@@ -90,10 +372,63 @@ func (m *MockExecutor) Execute(ctx context.Context, cfg ToolConfig) (*ExecutionR
 		Output:     fmt.Sprintf("Mock execution of: %s %s", cfg.Command, strings.Join(cfg.Args, " ")),
 		Stderr:     "",
 		ExitCode:   0,
-		StartTime:  time.Now(),
-		EndTime:    time.Now(),
+		StartTime:  now,
+		EndTime:    now,
 		TimedOut:   false,
-	}, nil
+	}, nil, nil
+}
+
+// recordCall appends a call to the history. The caller must hold m.mu.
+func (m *MockExecutor) recordCall(ctx context.Context, cfg ToolConfig, stdin mockStdin) {
+	m.CallHistory = append(m.CallHistory, MockCall{
+		Config:          cfg,
+		Timestamp:       m.timeNow(),
+		Context:         ctx,
+		Stdin:           stdin.data,
+		StdinTruncated:  stdin.truncated,
+		StdinIncomplete: stdin.incomplete,
+		Metadata:        Metadata(ctx),
+	})
+}
+
+// checkForbidden returns a *ForbiddenCommandError, and records it, if the
+// call matches an ExpectNever expectation. The caller must hold m.mu.
+func (m *MockExecutor) checkForbidden(ctx context.Context, cfg ToolConfig) error {
+	for i := range m.expectations {
+		exp := &m.expectations[i]
+		if exp.never && exp.Matcher(ctx, cfg) {
+			exp.used++
+			err := &ForbiddenCommandError{
+				Command:     buildCommandString(cfg.Command, cfg.Args),
+				Expectation: exp.description,
+			}
+			m.violations = append(m.violations, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// checkOrder returns a *MockOrderError, and records it, if exp belongs to
+// an InOrder sequence whose earlier expectations are not all met. The
+// caller must hold m.mu.
+func (m *MockExecutor) checkOrder(exp *MockExpectation, cfg ToolConfig) error {
+	if exp.group == 0 {
+		return nil
+	}
+	for i := range m.expectations {
+		prev := &m.expectations[i]
+		if prev.group == exp.group && prev.position < exp.position && !prev.satisfied() {
+			err := &MockOrderError{
+				Command:     buildCommandString(cfg.Command, cfg.Args),
+				Expectation: exp.description,
+				Pending:     prev.description,
+			}
+			m.violations = append(m.violations, err)
+			return err
+		}
+	}
+	return nil
 }
 
 // IsAvailable implements the Executor interface.
@@ -113,6 +448,8 @@ func (m *MockExecutor) ExpectCommand(command string) *MockExpectationBuilder {
 			Matcher: func(_ context.Context, cfg ToolConfig) bool {
 				return cfg.Command == command
 			},
+			description: fmt.Sprintf("%q", command),
+			command:     command,
 		},
 	}
 }
@@ -136,16 +473,36 @@ func (m *MockExecutor) ExpectCommandWithArgs(command string, args ...string) *Mo
 				}
 				return true
 			},
+			description: fmt.Sprintf("%q", buildCommandString(command, args)),
+			command:     command,
 		},
 	}
 }
 
+// ExpectNever forbids calls of command with args, or with any arguments if
+// no args are given, for example to assert that a dry run deletes nothing.
+// A forbidden call is reported by AssertExpectationsMet; in strict mode, it
+// also fails with a *ForbiddenCommandError. Otherwise, it gets the response
+// it would have gotten without ExpectNever.
+func (m *MockExecutor) ExpectNever(command string, args ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expectations = append(m.expectations, MockExpectation{
+		Matcher: func(_ context.Context, cfg ToolConfig) bool {
+			return cfg.Command == command && (len(args) == 0 || slices.Equal(cfg.Args, args))
+		},
+		description: fmt.Sprintf("%q", buildCommandString(command, args)),
+		never:       true,
+	})
+}
+
 // ExpectCustom adds an expectation with a custom matcher function.
 func (m *MockExecutor) ExpectCustom(matcher func(ctx context.Context, cfg ToolConfig) bool) *MockExpectationBuilder {
 	return &MockExpectationBuilder{
 		mock: m,
 		expectation: MockExpectation{
-			Matcher: matcher,
+			Matcher:     matcher,
+			description: "custom matcher",
 		},
 	}
 }
@@ -158,6 +515,72 @@ func (m *MockExecutor) SetDefaultBehavior(result *ExecutionResult, err error) {
 	m.DefaultError = err
 }
 
+// SetStrict sets whether calls that match no expectation fail with an
+// *UnexpectedCommandError instead of returning the default response. Strict
+// mode catches commands a test did not anticipate; AssertExpectationsMet
+// reports them too.
+func (m *MockExecutor) SetStrict(strict bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.strict = strict
+}
+
+// SetValidate sets whether Execute runs ToolConfig.Validate, including the
+// CommandValidator, before matching expectations, so tests catch invalid
+// configs and allowlist violations the way the BasicExecutor would. It is
+// off by default; rejected calls are still recorded in the history.
+func (m *MockExecutor) SetValidate(validate bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.validate = validate
+}
+
+// SetStdinWait bounds how long each call waits for its input to end
+// before recording it as incomplete. By default a call waits until its
+// context is done, so a reader that is never closed, such as os.Stdin,
+// needs either a context deadline or a wait.
+func (m *MockExecutor) SetStdinWait(wait time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stdinWait = wait
+}
+
+// SetHonorTimeout sets whether the mock simulates ToolConfig.Timeout: a
+// call whose expectation has a WillDelay longer than the Timeout of the
+// config waits for the Timeout and fails with a *TimeoutError, as the
+// BasicExecutor would. Context deadlines interrupt delays regardless of
+// this setting.
+func (m *MockExecutor) SetHonorTimeout(honor bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.honorTimeout = honor
+}
+
+// SetClock sets the clock that timestamps recorded calls and the results
+// the mock creates, such as those of WillSucceed and WillFail. Set it
+// before building expectations, since their results are stamped when
+// built. A nil clock restores the system clock.
+func (m *MockExecutor) SetClock(clock Clock) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.clock = clock
+}
+
+// Now returns the current time of the mock's clock.
+func (m *MockExecutor) Now() time.Time {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.timeNow()
+}
+
+// timeNow returns the current time of the clock. The caller must hold m.mu.
+func (m *MockExecutor) timeNow() time.Time {
+	if m.clock == nil {
+		return time.Now()
+	}
+	return m.clock.Now()
+}
+
 // SetResult is a convenience method that sets the default behavior.
 // It's useful for simple test cases that don't need complex expectations.
 func (m *MockExecutor) SetResult(result *ExecutionResult, err error) {
@@ -200,54 +623,357 @@ func (m *MockExecutor) ClearCallHistory() {
 	m.CallHistory = make([]MockCall, 0)
 }
 
-// AssertExpectationsMet checks if all expectations with fixed times have been met.
+// AssertExpectationsMet checks if all expectations with fixed times have been
+// met, and reports the first call that violated an InOrder sequence, matched
+// an ExpectNever expectation or, in strict mode, matched no expectation.
 func (m *MockExecutor) AssertExpectationsMet() error {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	for _, exp := range m.expectations {
+	if len(m.violations) > 0 {
+		return m.violations[0]
+	}
+	var unmet []error
+	for i := range m.expectations {
+		exp := &m.expectations[i]
 		if exp.Times > 0 && exp.used < exp.Times {
-			return fmt.Errorf("expectation not met: expected %d calls, got %d", exp.Times, exp.used)
+			unmet = append(unmet, fmt.Errorf("expectation not met: %s expected %d calls, got %d\n  nearest calls: %s",
+				exp.description, exp.Times, exp.used, m.nearestCalls(exp)))
+		}
+	}
+	return errors.Join(unmet...)
+}
+
+// maxNearestCalls bounds the calls listed for an unmet expectation.
+const maxNearestCalls = 3
+
+// nearestCalls describes the recorded calls closest to an unmet
+// expectation: calls of the same command that it did not match or, if there
+// are none, the most recent calls. The caller must hold m.mu.
+func (m *MockExecutor) nearestCalls(exp *MockExpectation) string {
+	var nearest []string
+	for _, call := range m.CallHistory {
+		if exp.command != "" && call.Config.Command == exp.command && !exp.Matcher(call.Context, call.Config) {
+			nearest = append(nearest, fmt.Sprintf("%q", buildCommandString(call.Config.Command, call.Config.Args)))
+		}
+	}
+	if len(nearest) == 0 {
+		for _, call := range m.CallHistory[max(len(m.CallHistory)-maxNearestCalls, 0):] {
+			nearest = append(nearest, fmt.Sprintf("%q", buildCommandString(call.Config.Command, call.Config.Args)))
+		}
+	}
+	if len(nearest) == 0 {
+		return "none"
+	}
+	if len(nearest) > maxNearestCalls {
+		nearest = append(nearest[:maxNearestCalls], fmt.Sprintf("and %d more", len(nearest)-maxNearestCalls))
+	}
+	return strings.Join(nearest, ", ")
+}
+
+// AssertStdinContains checks that the call at index in the call history
+// provided stdin containing substr.
+func (m *MockExecutor) AssertStdinContains(index int, substr string) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if index < 0 || index >= len(m.CallHistory) {
+		return fmt.Errorf("no call at index %d: %d calls recorded", index, len(m.CallHistory))
+	}
+	if stdin := m.CallHistory[index].Stdin; !strings.Contains(stdin, substr) {
+		return fmt.Errorf("stdin of call %d does not contain %q: got %q", index, substr, stdin)
+	}
+	return nil
+}
+
+// TestingT is the part of testing.TB used by the MockExecutor assertions,
+// so the mock does not import the testing package into the programs that
+// use it.
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...any)
+	Fatalf(format string, args ...any)
+}
+
+// AssertCalled reports a test error unless command was called with args. If
+// no args are given, a call with any arguments counts. It returns whether
+// the assertion held.
+func (m *MockExecutor) AssertCalled(t TestingT, command string, args ...string) bool {
+	t.Helper()
+	if m.countCalls(command, args) == 0 {
+		t.Errorf("expected call %q, but it was not made; calls: %s", buildCommandString(command, args), m.describeCalls())
+		return false
+	}
+	return true
+}
+
+// AssertNotCalled reports a test error if command was called with args. If
+// no args are given, a call with any arguments counts. It returns whether
+// the assertion held.
+func (m *MockExecutor) AssertNotCalled(t TestingT, command string, args ...string) bool {
+	t.Helper()
+	if n := m.countCalls(command, args); n > 0 {
+		t.Errorf("expected no call %q, but it was made %d times", buildCommandString(command, args), n)
+		return false
+	}
+	return true
+}
+
+// AssertNumberOfCalls reports a test error unless command was called, with
+// any arguments, exactly n times. It returns whether the assertion held.
+func (m *MockExecutor) AssertNumberOfCalls(t TestingT, command string, n int) bool {
+	t.Helper()
+	if got := m.countCalls(command, nil); got != n {
+		t.Errorf("expected %d calls of %q, got %d", n, command, got)
+		return false
+	}
+	return true
+}
+
+// countCalls counts the calls of command with args, or with any arguments
+// if args is empty.
+func (m *MockExecutor) countCalls(command string, args []string) int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	count := 0
+	for _, call := range m.CallHistory {
+		if call.Config.Command == command && (len(args) == 0 || slices.Equal(call.Config.Args, args)) {
+			count++
 		}
 	}
+	return count
+}
+
+// describeCalls lists the recorded commands for assertion messages.
+func (m *MockExecutor) describeCalls() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if len(m.CallHistory) == 0 {
+		return "none"
+	}
+	calls := make([]string, len(m.CallHistory))
+	for i, call := range m.CallHistory {
+		calls[i] = fmt.Sprintf("%q", buildCommandString(call.Config.Command, call.Config.Args))
+	}
+	return strings.Join(calls, ", ")
+}
+
+// WriteCallHistoryGolden writes the call history to path in the golden
+// format, one shell-like line per call, so it can be reviewed and committed
+// as a fixture for AssertCallHistoryMatchesGolden.
+func (m *MockExecutor) WriteCallHistoryGolden(path string) error {
+	if err := os.WriteFile(path, []byte(m.callHistoryGolden()), 0o600); err != nil {
+		return fmt.Errorf("failed to write golden call history: %w", err)
+	}
 	return nil
 }
 
+// AssertCallHistoryMatchesGolden reports a test error, with a line diff,
+// unless the call history matches the golden file at path. It returns
+// whether the assertion held.
+func (m *MockExecutor) AssertCallHistoryMatchesGolden(t TestingT, path string) bool {
+	t.Helper()
+	want, err := os.ReadFile(path) //nolint:gosec // reading the caller's fixture is the purpose
+	if err != nil {
+		t.Errorf("failed to read golden call history: %v", err)
+		return false
+	}
+	got := m.callHistoryGolden()
+	if got != string(want) {
+		t.Errorf("call history does not match %s (-want +got):\n%s", path, lineDiff(string(want), got))
+		return false
+	}
+	return true
+}
+
+// callHistoryGolden renders the call history in the golden format: one
+// line per call with its working directory, environment (sorted and
+// redacted) and command line.
+func (m *MockExecutor) callHistoryGolden() string {
+	var b strings.Builder
+	for _, call := range m.GetCallHistory() {
+		entry := newTranscriptEntry(call.Config, nil, nil)
+		b.WriteString(entry.commandLine())
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// lineDiff lists the lines of want and got, marking lines only in want
+// with - and lines only in got with +. Lines are matched by their longest
+// common subsequence, so an inserted or removed call marks only its own
+// line.
+func lineDiff(want, got string) string {
+	a := strings.Split(strings.TrimSuffix(want, "\n"), "\n")
+	b := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var out strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			fmt.Fprintf(&out, "  %s\n", a[i])
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			fmt.Fprintf(&out, "- %s\n", a[i])
+			i++
+		default:
+			fmt.Fprintf(&out, "+ %s\n", b[j])
+			j++
+		}
+	}
+	return out.String()
+}
+
 // MockExpectationBuilder provides a fluent interface for building expectations.
 type MockExpectationBuilder struct {
 	mock        *MockExecutor
 	expectation MockExpectation
 }
 
+// InDir additionally requires the call to run in the working directory dir.
+func (b *MockExpectationBuilder) InDir(dir string) *MockExpectationBuilder {
+	return b.and(fmt.Sprintf("in %q", dir), func(cfg ToolConfig) bool {
+		return cfg.WorkingDir == dir
+	})
+}
+
+// WithEnv additionally requires the call to set the environment variable
+// key to value in its Env.
+func (b *MockExpectationBuilder) WithEnv(key, value string) *MockExpectationBuilder {
+	return b.and(fmt.Sprintf("with %s=%q", key, value), func(cfg ToolConfig) bool {
+		v, ok := cfg.Env[key]
+		return ok && v == value
+	})
+}
+
+// and narrows the matcher of the expectation by cond.
+func (b *MockExpectationBuilder) and(description string, cond func(cfg ToolConfig) bool) *MockExpectationBuilder {
+	matcher := b.expectation.Matcher
+	b.expectation.Matcher = func(ctx context.Context, cfg ToolConfig) bool {
+		return matcher(ctx, cfg) && cond(cfg)
+	}
+	b.expectation.description += " " + description
+	return b
+}
+
 // WillReturn sets the result to return when the expectation is matched.
 func (b *MockExpectationBuilder) WillReturn(result *ExecutionResult, err error) *MockExpectationBuilder {
 	b.expectation.Result = result
 	b.expectation.Error = err
+	b.expectation.Sequence = nil
+	b.expectation.Chunks = nil
+	b.expectation.Handler = nil
+	return b
+}
+
+// WillExecute makes matched calls compute their response with handler, for
+// example to echo back arguments or consult a fake filesystem. Output the
+// handler returns is streamed to the writers of the call like any other
+// response.
+func (b *MockExpectationBuilder) WillExecute(handler func(ctx context.Context, cfg ToolConfig) (*ExecutionResult, error)) *MockExpectationBuilder {
+	b.expectation.Result = nil
+	b.expectation.Error = nil
+	b.expectation.Sequence = nil
+	b.expectation.Chunks = nil
+	b.expectation.Handler = handler
+	return b
+}
+
+// WillReturnSequence makes successive matches return results in order, for
+// example failing twice and then succeeding to exercise retries. Once the
+// sequence is exhausted, the last result is repeated.
+func (b *MockExpectationBuilder) WillReturnSequence(results ...*ExecutionResult) *MockExpectationBuilder {
+	b.expectation.Sequence = results
+	b.expectation.Error = nil
+	b.expectation.Chunks = nil
+	b.expectation.Handler = nil
 	return b
 }
 
 // WillSucceed sets a successful execution result.
 func (b *MockExpectationBuilder) WillSucceed(output string, exitCode int) *MockExpectationBuilder {
-	b.expectation.Result = &ExecutionResult{
-		Output:    output,
-		ExitCode:  exitCode,
-		StartTime: time.Now(),
-		EndTime:   time.Now(),
-	}
+	b.expectation.Result = mockSuccess(b.mock.Now(), output, exitCode)
 	b.expectation.Error = nil
+	b.expectation.Sequence = nil
+	b.expectation.Chunks = nil
+	b.expectation.Handler = nil
+	return b
+}
+
+// WillStream sets a successful result whose output is chunks concatenated,
+// and makes Execute write the chunks one by one to the StdoutWriter of the
+// call, so code consuming streamed output can be tested.
+func (b *MockExpectationBuilder) WillStream(chunks ...string) *MockExpectationBuilder {
+	b.WillSucceed(strings.Join(chunks, ""), 0)
+	b.expectation.Chunks = chunks
 	return b
 }
 
 // WillFail sets a failed execution result.
 func (b *MockExpectationBuilder) WillFail(stderr string, exitCode int) *MockExpectationBuilder {
-	b.expectation.Result = &ExecutionResult{
+	b.expectation.Result = mockFailure(b.mock.Now(), stderr, exitCode)
+	b.expectation.Error = nil
+	b.expectation.Sequence = nil
+	b.expectation.Chunks = nil
+	b.expectation.Handler = nil
+	return b
+}
+
+// WillFailTimes makes the first n matches fail with stderr and exitCode.
+// Follow it with ThenSucceed to test retry logic in one expectation.
+func (b *MockExpectationBuilder) WillFailTimes(n int, stderr string, exitCode int) *MockExpectationBuilder {
+	failures := make([]*ExecutionResult, max(n, 0))
+	for i := range failures {
+		failures[i] = mockFailure(b.mock.Now(), stderr, exitCode)
+	}
+	return b.WillReturnSequence(failures...)
+}
+
+// ThenSucceed makes the match after the sequence set by WillFailTimes or
+// WillReturnSequence, and all later ones, succeed with output.
+func (b *MockExpectationBuilder) ThenSucceed(output string) *MockExpectationBuilder {
+	b.expectation.Sequence = append(b.expectation.Sequence, mockSuccess(b.mock.Now(), output, 0))
+	return b
+}
+
+// mockSuccess returns a result with output and exitCode.
+func mockSuccess(now time.Time, output string, exitCode int) *ExecutionResult {
+	return &ExecutionResult{
+		Output:    output,
+		ExitCode:  exitCode,
+		StartTime: now,
+		EndTime:   now,
+	}
+}
+
+// mockFailure returns a result with stderr and exitCode.
+func mockFailure(now time.Time, stderr string, exitCode int) *ExecutionResult {
+	return &ExecutionResult{
 		Stderr:    stderr,
 		ExitCode:  exitCode,
-		StartTime: time.Now(),
-		EndTime:   time.Now(),
+		StartTime: now,
+		EndTime:   now,
 	}
-	b.expectation.Error = nil
-	return b
 }
 
 // WillTimeout sets a timeout result.
@@ -257,6 +983,9 @@ func (b *MockExpectationBuilder) WillTimeout(timeout time.Duration) *MockExpecta
 		Command: "mock command",
 		Timeout: timeout,
 	}
+	b.expectation.Sequence = nil
+	b.expectation.Chunks = nil
+	b.expectation.Handler = nil
 	return b
 }
 
@@ -264,6 +993,18 @@ func (b *MockExpectationBuilder) WillTimeout(timeout time.Duration) *MockExpecta
 func (b *MockExpectationBuilder) WillError(err error) *MockExpectationBuilder {
 	b.expectation.Result = nil
 	b.expectation.Error = err
+	b.expectation.Sequence = nil
+	b.expectation.Chunks = nil
+	b.expectation.Handler = nil
+	return b
+}
+
+// WillDelay makes Execute wait for d before returning the response, to
+// exercise timeouts, concurrency limits and progress reporting without
+// running real commands. If the context is done during the delay, Execute
+// returns an error wrapping the context error instead.
+func (b *MockExpectationBuilder) WillDelay(d time.Duration) *MockExpectationBuilder {
+	b.expectation.Delay = d
 	return b
 }
 
@@ -278,6 +1019,23 @@ func (b *MockExpectationBuilder) Once() *MockExpectationBuilder {
 	return b.Times(1)
 }
 
+// InOrder adds the expectations of builders as an ordered sequence: a call
+// matching one of them fails with a *MockOrderError unless every expectation
+// before it in the sequence has been met (used Times times, or at least once
+// if Times is unlimited). Use it for workflows where one command must run
+// before another. InOrder builds the expectations itself, so do not call
+// Build on builders passed to it.
+func (m *MockExecutor) InOrder(builders ...*MockExpectationBuilder) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.orderGroups++
+	for i, b := range builders {
+		exp := b.expectation
+		exp.group, exp.position = m.orderGroups, i
+		m.expectations = append(m.expectations, exp)
+	}
+}
+
 // Build finalizes the expectation and adds it to the mock.
 func (b *MockExpectationBuilder) Build() {
 	b.mock.mu.Lock()
@@ -3,6 +3,10 @@ package cmdexec
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -460,3 +464,660 @@ func TestMockExecutor_RealWorldScenario(t *testing.T) {
 		t.Errorf("Expected 3 calls in history, got %d", len(history))
 	}
 }
+
+func TestMockExecutor_InOrder(t *testing.T) {
+	newMock := func() *MockExecutor {
+		mock := NewMockExecutor()
+		mock.InOrder(
+			mock.ExpectCommandWithArgs("go", "mod", "download").WillSucceed("", 0).Once(),
+			mock.ExpectCommandWithArgs("go", "test", "./...").WillSucceed("ok", 0),
+		)
+		return mock
+	}
+	download := ToolConfig{Command: "go", Args: []string{"mod", "download"}}
+	test := ToolConfig{Command: "go", Args: []string{"test", "./..."}}
+
+	t.Run("in order", func(t *testing.T) {
+		mock := newMock()
+		ctx := context.Background()
+		for _, cfg := range []ToolConfig{download, test, test} {
+			if _, err := mock.Execute(ctx, cfg); err != nil {
+				t.Fatalf("Execute(%v) error = %v", cfg.Args, err)
+			}
+		}
+		if err := mock.AssertExpectationsMet(); err != nil {
+			t.Errorf("AssertExpectationsMet() error = %v", err)
+		}
+	})
+
+	t.Run("out of order", func(t *testing.T) {
+		mock := newMock()
+		_, err := mock.Execute(context.Background(), test)
+		var orderErr *MockOrderError
+		if !errors.As(err, &orderErr) {
+			t.Fatalf("Execute() error = %v, want *MockOrderError", err)
+		}
+		if orderErr.Command != "go test ./..." || orderErr.Pending != `"go mod download"` {
+			t.Errorf("MockOrderError = %+v, want command %q pending %q", orderErr, "go test ./...", `"go mod download"`)
+		}
+		if _, err := mock.Execute(context.Background(), download); err != nil {
+			t.Fatalf("Execute(download) error = %v", err)
+		}
+		if err := mock.AssertExpectationsMet(); !errors.As(err, &orderErr) {
+			t.Errorf("AssertExpectationsMet() error = %v, want the recorded *MockOrderError", err)
+		}
+	})
+
+	t.Run("unordered expectations are unaffected", func(t *testing.T) {
+		mock := newMock()
+		mock.ExpectCommand("echo").WillSucceed("hi", 0).Build()
+		if _, err := mock.Execute(context.Background(), ToolConfig{Command: "echo"}); err != nil {
+			t.Errorf("Execute(echo) error = %v", err)
+		}
+	})
+}
+
+func TestMockExecutor_WillReturnSequence(t *testing.T) {
+	mock := NewMockExecutor()
+	mock.ExpectCommand("flaky").
+		WillReturnSequence(
+			&ExecutionResult{ExitCode: 1, Stderr: "try again"},
+			&ExecutionResult{ExitCode: 1, Stderr: "try again"},
+			&ExecutionResult{ExitCode: 0, Output: "done"},
+		).
+		Build()
+
+	ctx := context.Background()
+	wantCodes := []int{1, 1, 0, 0}
+	for i, want := range wantCodes {
+		result, err := mock.Execute(ctx, ToolConfig{Command: "flaky"})
+		if err != nil {
+			t.Fatalf("call %d error = %v", i, err)
+		}
+		if result.ExitCode != want {
+			t.Errorf("call %d ExitCode = %d, want %d", i, result.ExitCode, want)
+		}
+	}
+
+	// A later Will* call replaces the sequence.
+	mock = NewMockExecutor()
+	mock.ExpectCommand("flaky").
+		WillReturnSequence(&ExecutionResult{ExitCode: 1}).
+		WillSucceed("ok", 0).
+		Build()
+	if result, _ := mock.Execute(ctx, ToolConfig{Command: "flaky"}); result.Output != "ok" {
+		t.Errorf("Output = %q, want %q", result.Output, "ok")
+	}
+}
+
+func TestMockExecutor_WillDelay(t *testing.T) {
+	mock := NewMockExecutor()
+	mock.ExpectCommand("slow").WillSucceed("done", 0).WillDelay(30 * time.Millisecond).Build()
+
+	start := time.Now()
+	result, err := mock.Execute(context.Background(), ToolConfig{Command: "slow"})
+	if err != nil || result.Output != "done" {
+		t.Fatalf("Execute() = (%+v, %v), want output %q", result, err, "done")
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("Execute() returned after %v, want at least 30ms", elapsed)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	mock.ExpectCommand("slower").WillSucceed("done", 0).WillDelay(time.Minute).Build()
+	if _, err := mock.Execute(ctx, ToolConfig{Command: "slower"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Execute() error = %v, want context.DeadlineExceeded", err)
+	}
+	if n := len(mock.GetCallHistory()); n != 2 {
+		t.Errorf("call history has %d calls, want 2", n)
+	}
+}
+
+// chunkRecorder records each Write call separately.
+type chunkRecorder struct {
+	chunks []string
+}
+
+func (r *chunkRecorder) Write(p []byte) (int, error) {
+	r.chunks = append(r.chunks, string(p))
+	return len(p), nil
+}
+
+func TestMockExecutor_WillStream(t *testing.T) {
+	mock := NewMockExecutor()
+	mock.ExpectCommand("build").WillStream("step 1\n", "step 2\n").Build()
+
+	stdout := &chunkRecorder{}
+	result, err := mock.Execute(context.Background(), ToolConfig{Command: "build", StdoutWriter: stdout})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Output != "step 1\nstep 2\n" {
+		t.Errorf("Output = %q, want the concatenated chunks", result.Output)
+	}
+	if len(stdout.chunks) != 2 || stdout.chunks[0] != "step 1\n" || stdout.chunks[1] != "step 2\n" {
+		t.Errorf("StdoutWriter received %q, want the two chunks separately", stdout.chunks)
+	}
+}
+
+func TestMockExecutor_TeesOutputToWriters(t *testing.T) {
+	mock := NewMockExecutor()
+	mock.ExpectCommand("ok").WillSucceed("out", 0).Build()
+	mock.ExpectCommand("bad").WillFail("err", 2).Build()
+
+	var stdout, stderr strings.Builder
+	ctx := context.Background()
+	for _, command := range []string{"ok", "bad"} {
+		if _, err := mock.Execute(ctx, ToolConfig{Command: command, StdoutWriter: &stdout, StderrWriter: &stderr}); err != nil {
+			t.Fatalf("Execute(%s) error = %v", command, err)
+		}
+	}
+	if stdout.String() != "out" || stderr.String() != "err" {
+		t.Errorf("writers received stdout %q and stderr %q, want %q and %q", stdout.String(), stderr.String(), "out", "err")
+	}
+}
+
+func TestMockExecutor_Labels(t *testing.T) {
+	mock := NewMockExecutor()
+	shared := &ExecutionResult{Output: "ok"}
+	mock.ExpectCommand("build").WillReturn(shared, nil).Build()
+	cfg := ToolConfig{Command: "build", Labels: map[string]string{"target": "//app"}}
+
+	for _, m := range []*MockExecutor{mock, NewMockExecutor()} {
+		result, err := m.Execute(context.Background(), cfg)
+		if err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		if result.Labels["target"] != "//app" {
+			t.Errorf("result.Labels = %v, want the config's labels", result.Labels)
+		}
+	}
+	if shared.Labels != nil {
+		t.Errorf("expectation result was modified: Labels = %v", shared.Labels)
+	}
+}
+
+func TestMockExecutor_StdinNeverClosed(t *testing.T) {
+	mock := NewMockExecutor()
+	pr, pw := io.Pipe()
+	defer func() { _ = pw.Close() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		_, _ = mock.Execute(ctx, ToolConfig{Command: "cat", Stdin: pr})
+		mock.SetStdinWait(50 * time.Millisecond)
+		_, _ = mock.Execute(context.Background(), ToolConfig{Command: "cat", Stdin: pr})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Execute() hung reading stdin that is never closed")
+	}
+	for i, call := range mock.GetCallHistory() {
+		if call.Stdin != "" || !call.StdinIncomplete {
+			t.Errorf("call %d recorded stdin %q (incomplete %v), want it marked incomplete", i, call.Stdin, call.StdinIncomplete)
+		}
+	}
+}
+
+func TestMockExecutor_StdinTruncated(t *testing.T) {
+	mock := NewMockExecutor()
+	input := strings.Repeat("x", maxMockStdinBytes+10)
+	_, _ = mock.Execute(context.Background(), ToolConfig{Command: "cat", Stdin: strings.NewReader(input)})
+
+	call := mock.GetCallHistory()[0]
+	if len(call.Stdin) != maxMockStdinBytes || !call.StdinTruncated {
+		t.Errorf("recorded %d bytes (truncated %v), want %d and truncated", len(call.Stdin), call.StdinTruncated, maxMockStdinBytes)
+	}
+}
+
+func TestMockExecutor_StdinProviderError(t *testing.T) {
+	mock := NewMockExecutor()
+	mock.ExpectCommand("cat").WillSucceed("", 0).Build()
+	providerErr := errors.New("no input")
+	_, err := mock.Execute(context.Background(), ToolConfig{
+		Command:       "cat",
+		StdinProvider: func() (io.Reader, error) { return nil, providerErr },
+	})
+	if !errors.Is(err, providerErr) {
+		t.Errorf("Execute() error = %v, want the provider error", err)
+	}
+	if len(mock.GetCallHistory()) != 1 {
+		t.Errorf("call history has %d calls, want the failed call recorded", len(mock.GetCallHistory()))
+	}
+}
+
+func TestMockExecutor_StdinCapture(t *testing.T) {
+	mock := NewMockExecutor()
+	ctx := context.Background()
+	_, _ = mock.Execute(ctx, ToolConfig{Command: "cat", Stdin: strings.NewReader("hello world")})
+	_, _ = mock.Execute(ctx, ToolConfig{
+		Command:      "cat",
+		Stdin:        strings.NewReader("ignored"),
+		StdinFactory: func() io.Reader { return strings.NewReader("from factory") },
+	})
+	_, _ = mock.Execute(ctx, ToolConfig{Command: "true"})
+	_, _ = mock.Execute(ctx, ToolConfig{
+		Command:       "cat",
+		StdinProvider: func() (io.Reader, error) { return strings.NewReader("from provider"), nil },
+	})
+
+	history := mock.GetCallHistory()
+	if history[0].Stdin != "hello world" || history[1].Stdin != "from factory" || history[2].Stdin != "" {
+		t.Errorf("recorded stdin = %q, %q, %q", history[0].Stdin, history[1].Stdin, history[2].Stdin)
+	}
+	if history[3].Stdin != "from provider" {
+		t.Errorf("recorded provider stdin = %q", history[3].Stdin)
+	}
+
+	if err := mock.AssertStdinContains(0, "world"); err != nil {
+		t.Errorf("AssertStdinContains(0, world) error = %v", err)
+	}
+	if err := mock.AssertStdinContains(1, "world"); err == nil {
+		t.Error("AssertStdinContains(1, world) error = nil, want mismatch")
+	}
+	if err := mock.AssertStdinContains(4, ""); err == nil {
+		t.Error("AssertStdinContains(4) error = nil, want out-of-range error")
+	}
+}
+
+func TestMockExecutor_InDirWithEnv(t *testing.T) {
+	mock := NewMockExecutor()
+	mock.ExpectCommand("go").InDir("/repo").WithEnv("CI", "true").WillSucceed("ci", 0).Build()
+	mock.ExpectCommand("go").WillSucceed("other", 0).Build()
+
+	tests := []struct {
+		name string
+		cfg  ToolConfig
+		want string
+	}{
+		{name: "all match", cfg: ToolConfig{Command: "go", WorkingDir: "/repo", Env: map[string]string{"CI": "true", "X": "1"}}, want: "ci"},
+		{name: "wrong dir", cfg: ToolConfig{Command: "go", WorkingDir: "/other", Env: map[string]string{"CI": "true"}}, want: "other"},
+		{name: "wrong env value", cfg: ToolConfig{Command: "go", WorkingDir: "/repo", Env: map[string]string{"CI": "false"}}, want: "other"},
+		{name: "env missing", cfg: ToolConfig{Command: "go", WorkingDir: "/repo"}, want: "other"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := mock.Execute(context.Background(), tt.cfg)
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if result.Output != tt.want {
+				t.Errorf("Output = %q, want %q", result.Output, tt.want)
+			}
+		})
+	}
+}
+
+func TestMockExecutor_SetStrict(t *testing.T) {
+	mock := NewMockExecutor()
+	mock.SetDefaultBehavior(&ExecutionResult{Output: "default"}, nil)
+	mock.ExpectCommand("go").WillSucceed("ok", 0).Build()
+	mock.SetStrict(true)
+
+	ctx := context.Background()
+	if _, err := mock.Execute(ctx, ToolConfig{Command: "go"}); err != nil {
+		t.Fatalf("Execute(go) error = %v", err)
+	}
+	_, err := mock.Execute(ctx, ToolConfig{Command: "rm", Args: []string{"-rf", "/tmp/x"}})
+	var unexpected *UnexpectedCommandError
+	if !errors.As(err, &unexpected) || unexpected.Command != "rm -rf /tmp/x" {
+		t.Fatalf("Execute(rm) error = %v, want *UnexpectedCommandError for %q", err, "rm -rf /tmp/x")
+	}
+	if err := mock.AssertExpectationsMet(); !errors.As(err, &unexpected) {
+		t.Errorf("AssertExpectationsMet() error = %v, want the unexpected command", err)
+	}
+
+	mock.SetStrict(false)
+	if result, err := mock.Execute(ctx, ToolConfig{Command: "rm"}); err != nil || result.Output != "default" {
+		t.Errorf("non-strict Execute(rm) = (%+v, %v), want the default result", result, err)
+	}
+}
+
+// recordingTB captures the errors reported to it.
+type recordingTB struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestMockExecutor_CallAssertions(t *testing.T) {
+	mock := NewMockExecutor()
+	ctx := context.Background()
+	_, _ = mock.Execute(ctx, ToolConfig{Command: "go", Args: []string{"mod", "download"}})
+	_, _ = mock.Execute(ctx, ToolConfig{Command: "go", Args: []string{"test", "./..."}})
+
+	tests := []struct {
+		name   string
+		assert func(tb testing.TB) bool
+		want   bool
+	}{
+		{name: "called with args", assert: func(tb testing.TB) bool { return mock.AssertCalled(tb, "go", "test", "./...") }, want: true},
+		{name: "called with any args", assert: func(tb testing.TB) bool { return mock.AssertCalled(tb, "go") }, want: true},
+		{name: "not called with args", assert: func(tb testing.TB) bool { return mock.AssertCalled(tb, "go", "vet") }, want: false},
+		{name: "not called", assert: func(tb testing.TB) bool { return mock.AssertNotCalled(tb, "rm") }, want: true},
+		{name: "not called but was", assert: func(tb testing.TB) bool { return mock.AssertNotCalled(tb, "go", "mod", "download") }, want: false},
+		{name: "number of calls", assert: func(tb testing.TB) bool { return mock.AssertNumberOfCalls(tb, "go", 2) }, want: true},
+		{name: "wrong number of calls", assert: func(tb testing.TB) bool { return mock.AssertNumberOfCalls(tb, "go", 1) }, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tb := &recordingTB{TB: t}
+			if got := tt.assert(tb); got != tt.want {
+				t.Errorf("assertion = %v, want %v", got, tt.want)
+			}
+			if failed := len(tb.errors) > 0; failed == tt.want {
+				t.Errorf("reported errors %q, want failure %v", tb.errors, !tt.want)
+			}
+		})
+	}
+}
+
+func TestLineDiff_InsertedLine(t *testing.T) {
+	want := "go mod download\ngo build\ngo test\n"
+	got := "go mod download\ngo generate\ngo build\ngo test\n"
+	if diff, expected := lineDiff(want, got), "  go mod download\n+ go generate\n  go build\n  go test\n"; diff != expected {
+		t.Errorf("lineDiff() = %q, want %q", diff, expected)
+	}
+	if diff, expected := lineDiff(got, want), "  go mod download\n- go generate\n  go build\n  go test\n"; diff != expected {
+		t.Errorf("lineDiff() = %q, want %q", diff, expected)
+	}
+	if diff, expected := lineDiff("a\nb\n", "a\nc\n"), "  a\n- b\n+ c\n"; diff != expected {
+		t.Errorf("lineDiff() = %q, want %q", diff, expected)
+	}
+}
+
+func TestMockExecutor_CallHistoryGolden_InsertedCall(t *testing.T) {
+	ctx := context.Background()
+	record := func(commands ...string) *MockExecutor {
+		mock := NewMockExecutor()
+		for _, c := range commands {
+			_, _ = mock.Execute(ctx, ToolConfig{Command: "go", Args: []string{c}})
+		}
+		return mock
+	}
+	path := filepath.Join(t.TempDir(), "calls.golden")
+	if err := record("build", "vet", "test").WriteCallHistoryGolden(path); err != nil {
+		t.Fatalf("WriteCallHistoryGolden() error = %v", err)
+	}
+
+	tb := &recordingTB{TB: t}
+	record("build", "generate", "vet", "test").AssertCallHistoryMatchesGolden(tb, path)
+	if len(tb.errors) != 1 {
+		t.Fatalf("reported errors %q, want one", tb.errors)
+	}
+	if diff := tb.errors[0]; !strings.HasSuffix(diff, "  go build\n+ go generate\n  go vet\n  go test\n") {
+		t.Errorf("reported diff %q, want only the inserted call marked", diff)
+	}
+}
+
+func TestMockExecutor_CallHistoryGolden(t *testing.T) {
+	mock := NewMockExecutor()
+	ctx := context.Background()
+	_, _ = mock.Execute(ctx, ToolConfig{Command: "go", Args: []string{"mod", "download"}, WorkingDir: "/repo"})
+	_, _ = mock.Execute(ctx, ToolConfig{
+		Command:  "go",
+		Args:     []string{"test", "-run", "Test A"},
+		Env:      map[string]string{"TOKEN": "s3cret", "CI": "true"},
+		Redactor: &Redactor{EnvKeys: []string{"TOKEN"}},
+	})
+
+	path := filepath.Join(t.TempDir(), "calls.golden")
+	if err := mock.WriteCallHistoryGolden(path); err != nil {
+		t.Fatalf("WriteCallHistoryGolden() error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "cd /repo && go mod download\nCI=true TOKEN='[REDACTED]' go test -run 'Test A'\n"
+	if string(data) != want {
+		t.Errorf("golden file = %q, want %q", data, want)
+	}
+	if !mock.AssertCallHistoryMatchesGolden(t, path) {
+		t.Error("AssertCallHistoryMatchesGolden() = false right after writing the file")
+	}
+
+	_, _ = mock.Execute(ctx, ToolConfig{Command: "go", Args: []string{"vet"}})
+	tb := &recordingTB{TB: t}
+	if mock.AssertCallHistoryMatchesGolden(tb, path) {
+		t.Error("AssertCallHistoryMatchesGolden() = true after an extra call")
+	}
+	if len(tb.errors) != 1 || !strings.Contains(tb.errors[0], "+ go vet\n") || !strings.Contains(tb.errors[0], "  cd /repo && go mod download\n") {
+		t.Errorf("reported errors %q, want a diff with the extra call", tb.errors)
+	}
+	if mock.AssertCallHistoryMatchesGolden(tb, filepath.Join(t.TempDir(), "missing.golden")) {
+		t.Error("AssertCallHistoryMatchesGolden() = true for a missing file")
+	}
+}
+
+func TestMockExecutor_WillExecute(t *testing.T) {
+	mock := NewMockExecutor()
+	mock.ExpectCommand("echo").
+		WillExecute(func(_ context.Context, cfg ToolConfig) (*ExecutionResult, error) {
+			return &ExecutionResult{Command: cfg.Command, Output: strings.Join(cfg.Args, " ")}, nil
+		}).
+		Build()
+	mock.ExpectCommand("fail").
+		WillExecute(func(context.Context, ToolConfig) (*ExecutionResult, error) {
+			return nil, errors.New("boom")
+		}).
+		Build()
+
+	var stdout strings.Builder
+	ctx := context.Background()
+	result, err := mock.Execute(ctx, ToolConfig{Command: "echo", Args: []string{"a", "b"}, StdoutWriter: &stdout})
+	if err != nil || result.Output != "a b" {
+		t.Fatalf("Execute(echo) = (%+v, %v), want output %q", result, err, "a b")
+	}
+	if stdout.String() != "a b" {
+		t.Errorf("StdoutWriter received %q, want %q", stdout.String(), "a b")
+	}
+	if _, err := mock.Execute(ctx, ToolConfig{Command: "fail"}); err == nil || err.Error() != "boom" {
+		t.Errorf("Execute(fail) error = %v, want boom", err)
+	}
+
+	// A later Will* call replaces the handler.
+	mock = NewMockExecutor()
+	mock.ExpectCommand("echo").
+		WillExecute(func(context.Context, ToolConfig) (*ExecutionResult, error) { return nil, errors.New("boom") }).
+		WillSucceed("canned", 0).
+		Build()
+	if result, err := mock.Execute(ctx, ToolConfig{Command: "echo"}); err != nil || result.Output != "canned" {
+		t.Errorf("Execute() = (%+v, %v), want the canned result", result, err)
+	}
+}
+
+func TestMockExecutor_SetHonorTimeout(t *testing.T) {
+	mock := NewMockExecutor()
+	mock.ExpectCommand("slow").WillSucceed("done", 0).WillDelay(time.Minute).Build()
+	mock.ExpectCommand("quick").WillSucceed("done", 0).WillDelay(time.Millisecond).Build()
+	mock.SetHonorTimeout(true)
+	ctx := context.Background()
+
+	start := time.Now()
+	_, err := mock.Execute(ctx, ToolConfig{Command: "slow", Args: []string{"job"}, Timeout: 20 * time.Millisecond})
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) || timeoutErr.Command != "slow job" || timeoutErr.Timeout != 20*time.Millisecond {
+		t.Fatalf("Execute(slow) error = %v, want *TimeoutError after 20ms", err)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond || elapsed > 10*time.Second {
+		t.Errorf("Execute(slow) returned after %v, want about the timeout", elapsed)
+	}
+
+	if result, err := mock.Execute(ctx, ToolConfig{Command: "quick", Timeout: time.Second}); err != nil || result.Output != "done" {
+		t.Errorf("Execute(quick) = (%+v, %v), want the result within the timeout", result, err)
+	}
+
+	deadline, cancel := context.WithTimeout(ctx, 5*time.Millisecond)
+	defer cancel()
+	if _, err := mock.Execute(deadline, ToolConfig{Command: "slow", Timeout: time.Second}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Execute(slow) with an earlier context deadline error = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestMockExecutor_WillFailTimesThenSucceed(t *testing.T) {
+	mock := NewMockExecutor()
+	mock.ExpectCommand("flaky").WillFailTimes(2, "busy", 75).ThenSucceed("ok").Build()
+	mock.ExpectCommand("stable").WillFailTimes(0, "busy", 75).ThenSucceed("ok").Build()
+
+	ctx := context.Background()
+	for i, want := range []int{75, 75, 0, 0} {
+		result, err := mock.Execute(ctx, ToolConfig{Command: "flaky"})
+		if err != nil {
+			t.Fatalf("call %d error = %v", i, err)
+		}
+		if result.ExitCode != want {
+			t.Errorf("call %d ExitCode = %d, want %d", i, result.ExitCode, want)
+		}
+		if want != 0 && result.Stderr != "busy" {
+			t.Errorf("call %d Stderr = %q, want %q", i, result.Stderr, "busy")
+		}
+		if want == 0 && result.Output != "ok" {
+			t.Errorf("call %d Output = %q, want %q", i, result.Output, "ok")
+		}
+	}
+	if result, _ := mock.Execute(ctx, ToolConfig{Command: "stable"}); result.ExitCode != 0 {
+		t.Errorf("stable ExitCode = %d, want 0", result.ExitCode)
+	}
+}
+
+func TestMockExecutor_Validation(t *testing.T) {
+	mock := NewMockExecutor()
+	mock.ExpectCommand("rm").WillSucceed("removed", 0).Build()
+	ctx := context.Background()
+	mock.SetValidate(true)
+	denyRm := func(command string, _ []string) error {
+		if command == "rm" {
+			return errors.New("rm is not allowed")
+		}
+		return nil
+	}
+
+	var notAllowed *CommandNotAllowedError
+	if _, err := mock.Execute(ctx, ToolConfig{Command: "rm", CommandValidator: denyRm}); !errors.As(err, &notAllowed) {
+		t.Errorf("Execute(rm) error = %v, want *CommandNotAllowedError", err)
+	}
+	var validationErr *ValidationError
+	if _, err := mock.Execute(ctx, ToolConfig{Command: "rm", Timeout: -time.Second}); !errors.As(err, &validationErr) {
+		t.Errorf("Execute() with negative timeout error = %v, want *ValidationError", err)
+	}
+	if n := len(mock.GetCallHistory()); n != 2 {
+		t.Errorf("call history has %d calls, want rejected calls recorded", n)
+	}
+
+	mock.SetValidate(false)
+	if result, err := mock.Execute(ctx, ToolConfig{Command: "rm", CommandValidator: denyRm}); err != nil || result.Output != "removed" {
+		t.Errorf("Execute(rm) without validation = (%+v, %v), want the expectation", result, err)
+	}
+}
+
+func TestMockExecutor_SetClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start)
+	mock := NewMockExecutor()
+	mock.SetClock(clock)
+	mock.ExpectCommand("git").WillSucceed("ok", 0).Build()
+	ctx := context.Background()
+
+	result, _ := mock.Execute(ctx, ToolConfig{Command: "git"})
+	if !result.StartTime.Equal(start) || !result.EndTime.Equal(start) {
+		t.Errorf("WillSucceed result times %v to %v, want %v", result.StartTime, result.EndTime, start)
+	}
+	clock.Advance(time.Second)
+	result, _ = mock.Execute(ctx, ToolConfig{Command: "echo"})
+	if want := start.Add(time.Second); !result.StartTime.Equal(want) {
+		t.Errorf("generic result StartTime = %v, want %v", result.StartTime, want)
+	}
+
+	history := mock.GetCallHistory()
+	if !history[0].Timestamp.Equal(start) || !history[1].Timestamp.Equal(start.Add(time.Second)) {
+		t.Errorf("call timestamps = %v, %v, want the clock's time", history[0].Timestamp, history[1].Timestamp)
+	}
+
+	mock.SetClock(nil)
+	if now := mock.Now(); now.Before(start.AddDate(1, 0, 0)) {
+		t.Errorf("Now() with the system clock = %v", now)
+	}
+}
+
+func TestMockExecutor_ExpectNever(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("reported by AssertExpectationsMet", func(t *testing.T) {
+		mock := NewMockExecutor()
+		mock.ExpectNever("rm")
+		mock.ExpectCommand("rm").WillSucceed("removed", 0).Build()
+		if err := mock.AssertExpectationsMet(); err != nil {
+			t.Fatalf("AssertExpectationsMet() before any call error = %v", err)
+		}
+
+		result, err := mock.Execute(ctx, ToolConfig{Command: "rm", Args: []string{"-rf", "build"}})
+		if err != nil || result.Output != "removed" {
+			t.Errorf("Execute(rm) = (%+v, %v), want the normal response outside strict mode", result, err)
+		}
+		var forbidden *ForbiddenCommandError
+		if err := mock.AssertExpectationsMet(); !errors.As(err, &forbidden) || forbidden.Command != "rm -rf build" {
+			t.Errorf("AssertExpectationsMet() error = %v, want *ForbiddenCommandError", err)
+		}
+	})
+
+	t.Run("args", func(t *testing.T) {
+		mock := NewMockExecutor()
+		mock.ExpectNever("git", "push", "--force")
+		_, _ = mock.Execute(ctx, ToolConfig{Command: "git", Args: []string{"push"}})
+		if err := mock.AssertExpectationsMet(); err != nil {
+			t.Errorf("AssertExpectationsMet() error = %v, want other args allowed", err)
+		}
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		mock := NewMockExecutor()
+		mock.SetStrict(true)
+		mock.ExpectNever("rm")
+		var forbidden *ForbiddenCommandError
+		if _, err := mock.Execute(ctx, ToolConfig{Command: "rm"}); !errors.As(err, &forbidden) {
+			t.Errorf("Execute(rm) error = %v, want *ForbiddenCommandError", err)
+		}
+	})
+}
+
+func TestMockExecutor_AssertExpectationsMetDetails(t *testing.T) {
+	mock := NewMockExecutor()
+	mock.ExpectCommandWithArgs("go", "test", "./...").WillSucceed("ok", 0).Times(2).Build()
+	mock.ExpectCommand("lint").WillSucceed("ok", 0).Once().Build()
+	ctx := context.Background()
+	_, _ = mock.Execute(ctx, ToolConfig{Command: "go", Args: []string{"test", "./..."}})
+	_, _ = mock.Execute(ctx, ToolConfig{Command: "go", Args: []string{"test", "./pkg"}})
+	_, _ = mock.Execute(ctx, ToolConfig{Command: "echo"})
+
+	err := mock.AssertExpectationsMet()
+	if err == nil {
+		t.Fatal("AssertExpectationsMet() error = nil, want two unmet expectations")
+	}
+	for _, want := range []string{
+		`expectation not met: "go test ./..." expected 2 calls, got 1`,
+		`nearest calls: "go test ./pkg"` + "\n",
+		`expectation not met: "lint" expected 1 calls, got 0`,
+		`nearest calls: "go test ./...", "go test ./pkg", "echo"`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("AssertExpectationsMet() error = %q, want it to contain %q", err, want)
+		}
+	}
+
+	empty := NewMockExecutor()
+	empty.ExpectCommand("lint").Once().Build()
+	if err := empty.AssertExpectationsMet(); err == nil || !strings.Contains(err.Error(), "nearest calls: none") {
+		t.Errorf("AssertExpectationsMet() without calls error = %v", err)
+	}
+}
@@ -0,0 +1,31 @@
+//go:build linux
+
+package cmdexec
+
+import (
+	"fmt"
+	"runtime"
+
+	"golang.org/x/sys/unix"
+)
+
+// noNewPrivilegesSupported reports whether NoNewPrivileges can be applied.
+const noNewPrivilegesSupported = true
+
+// runWithNoNewPrivileges calls run on a new OS thread with no_new_privs
+// set. The flag is a thread attribute that children inherit, and os/exec
+// starts children from the calling thread. It cannot be cleared again, so
+// the thread stays locked and is discarded when run returns, leaving the
+// rest of the process unaffected.
+func runWithNoNewPrivileges(run func() error) error {
+	errc := make(chan error, 1)
+	go func() {
+		runtime.LockOSThread()
+		if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+			errc <- fmt.Errorf("failed to set no_new_privs: %w", err)
+			return
+		}
+		errc <- run()
+	}()
+	return <-errc
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package cmdexec
+
+// noNewPrivilegesSupported reports whether NoNewPrivileges can be applied.
+const noNewPrivilegesSupported = false
+
+// runWithNoNewPrivileges is unreachable on platforms without support, since
+// Validate rejects NoNewPrivileges there.
+func runWithNoNewPrivileges(_ func() error) error {
+	return &ValidationError{Field: "ProcessAttributes.NoNewPrivileges", Message: "no_new_privs is only supported on Linux"}
+}
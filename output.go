@@ -0,0 +1,734 @@
+package cmdexec
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// outputStream assembles the writer chain for one process output stream:
+// optional filters applied to the raw process output, followed by a tee
+// into the (optionally size-limited) capture buffer and the caller's
+// streaming writer.
+type outputStream struct {
+	// writer is the entry point handed to the process.
+	writer io.Writer
+
+	limited *limitedWriter
+	shared  *sharedLimitWriter
+	spool   *spoolWriter
+	counter *countingWriter
+
+	// flushInterval and periodicFlush drive periodic flushing of the
+	// caller's writer while the process runs.
+	flushInterval time.Duration
+	periodicFlush func() error
+	stopFlush     chan struct{}
+	flushDone     chan struct{}
+
+	// finishers run in order once the process has exited, flushing any
+	// state buffered by filters and by the caller's writer.
+	finishers []func() error
+}
+
+// streamOptions holds the settings that differ between stdout and stderr.
+type streamOptions struct {
+	name     string
+	maxBytes int64
+	writer   io.Writer
+	onLine   func(string)
+
+	// combined, if set, is shared by both streams and receives output in
+	// the order it is read from the process.
+	combined io.Writer
+
+	// spool tracks the spool file created when SpoolThreshold is exceeded.
+	spool *tempResources
+
+	// shared, if set, is the MaxOutputBytes budget shared by both streams.
+	shared *sharedLimit
+
+	// onOverflow, if set, is called when output beyond maxBytes is
+	// discarded.
+	onOverflow func()
+
+	// checksum, if set, receives the raw output before any filtering.
+	checksum io.Writer
+}
+
+func stdoutOptions(cfg ToolConfig) streamOptions {
+	return streamOptions{
+		name:     "stdout",
+		maxBytes: cfg.MaxStdoutBytes,
+		writer:   cfg.StdoutWriter,
+		onLine:   cfg.OnStdoutLine,
+	}
+}
+
+func stderrOptions(cfg ToolConfig) streamOptions {
+	return streamOptions{
+		name:     "stderr",
+		maxBytes: cfg.MaxStderrBytes,
+		writer:   cfg.StderrWriter,
+		onLine:   cfg.OnStderrLine,
+	}
+}
+
+func newOutputStream(buf *bytes.Buffer, opts streamOptions, cfg ToolConfig) *outputStream {
+	s := &outputStream{}
+
+	var captureW io.Writer = buf
+	if cfg.SpoolThreshold > 0 && opts.spool != nil {
+		s.spool = &spoolWriter{
+			buf:       buf,
+			threshold: cfg.SpoolThreshold,
+			res:       opts.spool,
+			pattern:   "cmdexec-" + opts.name + "-*",
+		}
+		captureW = s.spool
+	}
+	if opts.shared != nil {
+		s.shared = opts.shared.writer(captureW)
+		captureW = s.shared
+	}
+	if opts.maxBytes > 0 {
+		s.limited = newLimitedWriter(captureW, opts.maxBytes, cfg.TruncateMode)
+		s.limited.onOverflow = opts.onOverflow
+		captureW = s.limited
+	}
+	var filterFlush func() error
+	if cfg.CaptureFilter != nil {
+		fw := &lineFilterWriter{w: captureW, keep: cfg.CaptureFilter}
+		filterFlush = fw.flush
+		captureW = fw
+	}
+	// Count ahead of the filter so the totals cover everything written.
+	s.counter = &countingWriter{w: captureW}
+	captureW = s.counter
+
+	sinks := []io.Writer{captureW}
+	var streamFlush func() error
+	if opts.writer != nil {
+		stream, flush := s.setupFlushing(opts.writer, cfg.FlushInterval)
+		streamFlush = flush
+		if cfg.StreamTimestampFormat != "" {
+			// Flushing the timestamp writer also flushes the stream.
+			tw := NewTimestampWriter(cfg.StreamTimestampFormat, stream)
+			stream, streamFlush = tw, tw.Flush
+		}
+		sinks = append(sinks, stream)
+	}
+	if opts.combined != nil {
+		sinks = append(sinks, opts.combined)
+	}
+	var lineFlush func() error
+	if opts.onLine != nil {
+		lw := NewLineWriter(opts.onLine)
+		lineFlush = lw.Flush
+		sinks = append(sinks, lw)
+	}
+
+	w := captureW
+	if len(sinks) > 1 {
+		w = io.MultiWriter(sinks...)
+	}
+
+	if cfg.CollapseRepeatedLines {
+		cw := NewCollapsingWriter(w)
+		s.finishers = append(s.finishers, cw.Flush)
+		w = cw
+	}
+	if opts.checksum != nil {
+		w = io.MultiWriter(opts.checksum, w)
+	}
+
+	if filterFlush != nil {
+		s.finishers = append(s.finishers, filterFlush)
+	}
+	// The caller's sinks are flushed last, after filters have drained.
+	if lineFlush != nil {
+		s.finishers = append(s.finishers, lineFlush)
+	}
+	if streamFlush != nil {
+		s.finishers = append(s.finishers, streamFlush)
+	}
+	if s.limited != nil {
+		s.finishers = append(s.finishers, s.limited.flush)
+	}
+
+	s.writer = w
+	return s
+}
+
+// setupFlushing detects whether stream supports flushing and, if periodic
+// flushing is requested, wraps it so flushes are serialized with writes.
+func (s *outputStream) setupFlushing(stream io.Writer, interval time.Duration) (io.Writer, func() error) {
+	flush := flushFunc(stream)
+	if flush == nil {
+		return stream, nil
+	}
+	if interval <= 0 {
+		return stream, flush
+	}
+	fw := &flushingWriter{w: stream, flush: flush}
+	s.flushInterval = interval
+	s.periodicFlush = fw.Flush
+	return fw, fw.Flush
+}
+
+// flushFunc returns a function that flushes w if it follows one of the
+// common flush conventions (bufio.Writer, gzip.Writer, http.Flusher,
+// os.File), or nil otherwise.
+func flushFunc(w io.Writer) func() error {
+	switch f := w.(type) {
+	case interface{ Flush() error }:
+		return f.Flush
+	case interface{ Flush() }:
+		return func() error {
+			f.Flush()
+			return nil
+		}
+	case interface{ Sync() error }:
+		return f.Sync
+	}
+	return nil
+}
+
+// start begins periodic flushing, if configured. It must be called before
+// the process starts writing.
+func (s *outputStream) start() {
+	if s.periodicFlush == nil {
+		return
+	}
+	s.stopFlush = make(chan struct{})
+	s.flushDone = make(chan struct{})
+	go func() {
+		defer close(s.flushDone)
+		ticker := time.NewTicker(s.flushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stopFlush:
+				return
+			case <-ticker.C:
+				if err := s.periodicFlush(); err != nil {
+					slog.Debug("Failed to flush output writer", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// finish stops periodic flushing and flushes buffered state after the
+// process has exited. Output kept by a shared limit is written to the
+// capture buffer only when the limit is flushed, after which close must be
+// called.
+func (s *outputStream) finish() {
+	if s.stopFlush != nil {
+		close(s.stopFlush)
+		<-s.flushDone
+	}
+	for _, f := range s.finishers {
+		if err := f(); err != nil {
+			slog.Debug("Failed to flush output stream", "error", err)
+		}
+	}
+}
+
+// close releases the spool file, if any. It must be called after finish and
+// after the shared limit, if any, has been flushed.
+func (s *outputStream) close() {
+	if s.spool == nil {
+		return
+	}
+	if err := s.spool.close(); err != nil {
+		slog.Debug("Failed to close spool file", "error", err)
+	}
+}
+
+// truncated reports whether the capture buffer hit its size limit.
+func (s *outputStream) truncated() bool {
+	return (s.limited != nil && s.limited.truncated) || s.shared.truncated()
+}
+
+// totalBytes returns the number of bytes offered for capture, including any
+// that were dropped by the size limit.
+func (s *outputStream) totalBytes() int64 {
+	return s.counter.n
+}
+
+// droppedBytes returns the number of bytes discarded by the size limit. It
+// is accurate once finish has run.
+func (s *outputStream) droppedBytes() int64 {
+	dropped := s.shared.droppedBytes()
+	if s.limited != nil {
+		dropped += s.limited.dropped
+	}
+	return dropped
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	cw.n += int64(len(p))
+	return cw.w.Write(p) //nolint:wrapcheck // io.Writer passthrough
+}
+
+// combinedBuffer collects stdout and stderr into a single buffer. Writes
+// from the two stream copiers are serialized, so the buffer preserves the
+// order in which output was read.
+type combinedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+
+	// limited, if set, applies MaxOutputBytes to the buffer.
+	limited *limitedWriter
+}
+
+// newCombinedBuffer returns a combinedBuffer keeping at most maxBytes
+// according to mode, or everything if maxBytes is zero.
+func newCombinedBuffer(maxBytes int64, mode TruncateMode) *combinedBuffer {
+	b := &combinedBuffer{}
+	if maxBytes > 0 {
+		b.limited = newLimitedWriter(&b.buf, maxBytes, mode)
+	}
+	return b
+}
+
+func (b *combinedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.limited != nil {
+		return b.limited.Write(p)
+	}
+	return b.buf.Write(p) //nolint:wrapcheck // bytes.Buffer never fails
+}
+
+// flush writes the kept tail of limited output to the buffer. It is safe
+// to call on a nil buffer.
+func (b *combinedBuffer) flush() {
+	if b == nil || b.limited == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	_ = b.limited.flush()
+}
+
+// String returns the combined output. It is safe to call on a nil buffer.
+func (b *combinedBuffer) String() string {
+	if b == nil {
+		return ""
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// flushingWriter serializes writes and flushes to a writer that is flushed
+// periodically from another goroutine.
+type flushingWriter struct {
+	mu    sync.Mutex
+	w     io.Writer
+	flush func() error
+}
+
+func (fw *flushingWriter) Write(p []byte) (int, error) {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	return fw.w.Write(p) //nolint:wrapcheck // transparent writer wrapper
+}
+
+// Flush flushes the underlying writer.
+func (fw *flushingWriter) Flush() error {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	return fw.flush()
+}
+
+// TruncateMode selects which part of an output stream is kept when it
+// exceeds MaxStdoutBytes or MaxStderrBytes.
+type TruncateMode int
+
+const (
+	// TruncateHead keeps the first bytes and drops the rest. It is the
+	// default.
+	TruncateHead TruncateMode = iota
+
+	// TruncateTail keeps the last bytes, which is usually where a failing
+	// build or test run reports its errors.
+	TruncateTail
+
+	// TruncateHeadAndTail keeps the first half and the last half of the
+	// limit, dropping the middle.
+	TruncateHeadAndTail
+)
+
+// newLimitedWriter returns a limitedWriter that keeps at most maxBytes of
+// the output written to it, according to mode.
+func newLimitedWriter(w io.Writer, maxBytes int64, mode TruncateMode) *limitedWriter {
+	switch mode {
+	case TruncateTail:
+		return &limitedWriter{w: w, tailMax: maxBytes}
+	case TruncateHeadAndTail:
+		tail := maxBytes / 2
+		return &limitedWriter{w: w, n: maxBytes - tail, tailMax: tail}
+	default:
+		return &limitedWriter{w: w, n: maxBytes}
+	}
+}
+
+// limitedWriter wraps a writer and keeps at most n head bytes plus tailMax
+// tail bytes, silently discarding excess data while tracking truncation.
+// Head bytes are written through immediately; the tail is buffered and
+// written by flush once the output is complete.
+type limitedWriter struct {
+	w         io.Writer
+	n         int64 // head bytes remaining
+	tailMax   int64
+	tail      []byte
+	truncated bool
+	dropped   int64
+
+	// onOverflow, if set, is called before head-only output is discarded.
+	onOverflow func()
+}
+
+func (lw *limitedWriter) Write(p []byte) (int, error) {
+	total := len(p)
+	if lw.n > 0 {
+		k := min(lw.n, int64(len(p)))
+		n, err := lw.w.Write(p[:k])
+		lw.n -= int64(n)
+		if err != nil {
+			return n, err //nolint:wrapcheck
+		}
+		p = p[k:]
+	}
+	if len(p) == 0 {
+		return total, nil
+	}
+	if lw.tailMax == 0 {
+		if lw.onOverflow != nil {
+			lw.onOverflow()
+		}
+		lw.truncated = true
+		lw.dropped += int64(len(p))
+		return total, nil
+	}
+
+	lw.tail = append(lw.tail, p...)
+	if excess := int64(len(lw.tail)) - lw.tailMax; excess > 0 {
+		lw.truncated = true
+		// Compact occasionally rather than on every write.
+		if excess > lw.tailMax {
+			lw.dropped += excess
+			lw.tail = append(lw.tail[:0], lw.tail[excess:]...)
+		}
+	}
+	return total, nil
+}
+
+// flush writes the buffered tail to the underlying writer.
+func (lw *limitedWriter) flush() error {
+	if excess := int64(len(lw.tail)) - lw.tailMax; excess > 0 {
+		lw.dropped += excess
+		lw.tail = lw.tail[excess:]
+	}
+	if len(lw.tail) == 0 {
+		return nil
+	}
+	_, err := lw.w.Write(lw.tail)
+	lw.tail = nil
+	return err //nolint:wrapcheck
+}
+
+// lineFilterWriter forwards only the lines for which keep returns true,
+// including their terminators. keep receives each line without its
+// terminator. A trailing partial line is buffered until flush.
+type lineFilterWriter struct {
+	w       io.Writer
+	keep    func(line string) bool
+	partial []byte
+}
+
+func (fw *lineFilterWriter) Write(p []byte) (int, error) {
+	data := p
+	for len(data) > 0 {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			fw.partial = append(fw.partial, data...)
+			break
+		}
+		line := data[:i+1]
+		if len(fw.partial) > 0 {
+			line = append(fw.partial, line...)
+			fw.partial = nil
+		}
+		data = data[i+1:]
+		if err := fw.writeLine(line); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (fw *lineFilterWriter) writeLine(line []byte) error {
+	text := strings.TrimSuffix(strings.TrimSuffix(string(line), "\n"), "\r")
+	if !fw.keep(text) {
+		return nil
+	}
+	_, err := fw.w.Write(line)
+	return err //nolint:wrapcheck // io.Writer passthrough
+}
+
+// flush filters and writes a trailing partial line.
+func (fw *lineFilterWriter) flush() error {
+	if len(fw.partial) == 0 {
+		return nil
+	}
+	line := fw.partial
+	fw.partial = nil
+	return fw.writeLine(line)
+}
+
+// LinesMatching returns a ToolConfig.CaptureFilter that keeps the lines
+// matching re.
+func LinesMatching(re *regexp.Regexp) func(line string) bool {
+	return re.MatchString
+}
+
+// sharedLimit is a single MaxOutputBytes budget shared by stdout and
+// stderr. Like limitedWriter it keeps a head that is written through
+// immediately and a tail that is buffered, but both are shared: the head is
+// filled in the order output arrives from either stream, and the tail keeps
+// the most recent chunks of either stream. Call flush once both streams
+// have finished to write the kept tail back to each stream's capture.
+type sharedLimit struct {
+	mu       sync.Mutex
+	n        int64 // head bytes remaining
+	tailMax  int64
+	tail     []sharedChunk
+	tailSize int64
+
+	// onOverflow, if set, is called before head-only output is discarded.
+	// It runs with mu held, so a blocking callback blocks both streams.
+	onOverflow func()
+}
+
+// sharedChunk is a piece of buffered tail output and the stream it came
+// from.
+type sharedChunk struct {
+	w    *sharedLimitWriter
+	data []byte
+}
+
+// newSharedLimit returns a sharedLimit that keeps at most maxBytes of the
+// output of both streams combined, according to mode.
+func newSharedLimit(maxBytes int64, mode TruncateMode) *sharedLimit {
+	switch mode {
+	case TruncateTail:
+		return &sharedLimit{tailMax: maxBytes}
+	case TruncateHeadAndTail:
+		tail := maxBytes / 2
+		return &sharedLimit{n: maxBytes - tail, tailMax: tail}
+	default:
+		return &sharedLimit{n: maxBytes}
+	}
+}
+
+// writer returns the writer for one stream, writing kept output to w.
+func (l *sharedLimit) writer(w io.Writer) *sharedLimitWriter {
+	return &sharedLimitWriter{limit: l, w: w}
+}
+
+// flush writes the buffered tail to the streams it came from, in order.
+func (l *sharedLimit) flush() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var firstErr error
+	for _, c := range l.tail {
+		if _, err := c.w.w.Write(c.data); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	l.tail, l.tailSize = nil, 0
+	return firstErr //nolint:wrapcheck // io.Writer passthrough
+}
+
+// evict drops the oldest tail chunks until the tail fits in tailMax.
+func (l *sharedLimit) evict() {
+	for l.tailSize > l.tailMax {
+		c := &l.tail[0]
+		k := min(l.tailSize-l.tailMax, int64(len(c.data)))
+		c.w.truncatedFlag = true
+		c.w.dropped += k
+		l.tailSize -= k
+		c.data = c.data[k:]
+		if len(c.data) == 0 {
+			l.tail = l.tail[1:]
+		}
+	}
+}
+
+// sharedLimitWriter is one stream's view of a sharedLimit.
+type sharedLimitWriter struct {
+	limit         *sharedLimit
+	w             io.Writer
+	truncatedFlag bool
+	dropped       int64
+}
+
+func (sw *sharedLimitWriter) Write(p []byte) (int, error) {
+	l := sw.limit
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	total := len(p)
+	if l.n > 0 {
+		k := min(l.n, int64(len(p)))
+		n, err := sw.w.Write(p[:k])
+		l.n -= int64(n)
+		if err != nil {
+			return n, err //nolint:wrapcheck // io.Writer passthrough
+		}
+		p = p[k:]
+	}
+	if len(p) == 0 {
+		return total, nil
+	}
+	if l.tailMax == 0 {
+		if l.onOverflow != nil {
+			l.onOverflow()
+		}
+		sw.truncatedFlag = true
+		sw.dropped += int64(len(p))
+		return total, nil
+	}
+	l.tail = append(l.tail, sharedChunk{w: sw, data: bytes.Clone(p)})
+	l.tailSize += int64(len(p))
+	l.evict()
+	return total, nil
+}
+
+// truncated reports whether any of this stream's output was dropped. It is
+// safe to call on a nil writer.
+func (sw *sharedLimitWriter) truncated() bool {
+	if sw == nil {
+		return false
+	}
+	sw.limit.mu.Lock()
+	defer sw.limit.mu.Unlock()
+	return sw.truncatedFlag
+}
+
+// droppedBytes returns the number of this stream's bytes that were
+// dropped. It is safe to call on a nil writer.
+func (sw *sharedLimitWriter) droppedBytes() int64 {
+	if sw == nil {
+		return 0
+	}
+	sw.limit.mu.Lock()
+	defer sw.limit.mu.Unlock()
+	return sw.dropped
+}
+
+// CollapsingWriter is a line-oriented filter that collapses runs of identical
+// lines. The first line of a run is written through immediately; repeats are
+// counted and replaced by a single "last message repeated N times" line when
+// the run ends. This keeps the logs of retry-looping tools small while
+// preserving how often each message occurred.
+//
+// Call Flush after the last Write to emit any pending summary and trailing
+// partial line. A CollapsingWriter is not safe for concurrent use.
+type CollapsingWriter struct {
+	w       io.Writer
+	partial []byte
+	last    []byte
+	repeats int
+}
+
+// NewCollapsingWriter returns a CollapsingWriter that writes to w.
+func NewCollapsingWriter(w io.Writer) *CollapsingWriter {
+	return &CollapsingWriter{w: w}
+}
+
+// Write implements io.Writer. Complete lines are processed immediately;
+// an incomplete trailing line is buffered until its newline arrives.
+func (c *CollapsingWriter) Write(p []byte) (int, error) {
+	data := p
+	for len(data) > 0 {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			c.partial = append(c.partial, data...)
+			break
+		}
+		var line []byte
+		if len(c.partial) > 0 {
+			line = append(c.partial, data[:i+1]...)
+			c.partial = nil
+		} else {
+			line = data[:i+1]
+		}
+		data = data[i+1:]
+		if err := c.writeLine(line); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (c *CollapsingWriter) writeLine(line []byte) error {
+	if c.last != nil && bytes.Equal(line, c.last) {
+		c.repeats++
+		return nil
+	}
+	if err := c.writeSummary(); err != nil {
+		return err
+	}
+	c.last = append(c.last[:0], line...)
+	if _, err := c.w.Write(line); err != nil {
+		return fmt.Errorf("collapsing writer: %w", err)
+	}
+	return nil
+}
+
+func (c *CollapsingWriter) writeSummary() error {
+	if c.repeats == 0 {
+		return nil
+	}
+	n := c.repeats
+	c.repeats = 0
+	if _, err := fmt.Fprintf(c.w, "last message repeated %d times\n", n); err != nil {
+		return fmt.Errorf("collapsing writer: %w", err)
+	}
+	return nil
+}
+
+// Flush writes any pending repeat summary and the buffered partial line.
+func (c *CollapsingWriter) Flush() error {
+	if err := c.writeSummary(); err != nil {
+		return err
+	}
+	if len(c.partial) > 0 {
+		partial := c.partial
+		c.partial = nil
+		c.last = nil
+		if _, err := c.w.Write(partial); err != nil {
+			return fmt.Errorf("collapsing writer: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package cmdexec
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+)
+
+// nextExecutionID numbers the executions whose output is logged, so that
+// records of concurrent executions can be told apart.
+var nextExecutionID atomic.Uint64
+
+// OutputLog mirrors command output into slog, one record per line, so a
+// failing pipeline can be debugged from its logs without re-running it with
+// custom writers. Records have the message "Command output" and the
+// attributes exec_id, command, stream and line. Lines are redacted with
+// ToolConfig.Redactor.
+type OutputLog struct {
+	// Logger receives the records. If nil, slog.Default() is used.
+	Logger *slog.Logger
+
+	// StdoutLevel is the level of stdout records. If nil, slog.LevelDebug
+	// is used.
+	StdoutLevel slog.Leveler
+
+	// StderrLevel is the level of stderr records. If nil, slog.LevelError
+	// is used.
+	StderrLevel slog.Leveler
+}
+
+// lineCallbacks returns the stdout and stderr line callbacks that log one
+// execution of cfg, tagged with the metadata of ctx. It returns nil
+// callbacks for a nil OutputLog.
+func (l *OutputLog) lineCallbacks(ctx context.Context, cfg ToolConfig) (stdout, stderr func(string)) {
+	if l == nil {
+		return nil, nil
+	}
+	logger := l.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	logger = logger.With("exec_id", nextExecutionID.Add(1), "command", cfg.Command)
+	if len(cfg.Labels) > 0 {
+		logger = logger.With("labels", cfg.Labels)
+	}
+	if md := Metadata(ctx); len(md) > 0 {
+		logger = logger.With("metadata", md)
+	}
+	redactor := cfg.Redactor.bind(cfg.Env, cfg.Args)
+
+	logLine := func(stream string, level slog.Leveler) func(string) {
+		return func(line string) {
+			ctx := context.Background()
+			if !logger.Enabled(ctx, level.Level()) {
+				return
+			}
+			logger.Log(ctx, level.Level(), "Command output", "stream", stream, "line", redactor.redact(line))
+		}
+	}
+	return logLine("stdout", levelOr(l.StdoutLevel, slog.LevelDebug)),
+		logLine("stderr", levelOr(l.StderrLevel, slog.LevelError))
+}
+
+func levelOr(level slog.Leveler, fallback slog.Level) slog.Leveler {
+	if level == nil {
+		return fallback
+	}
+	return level
+}
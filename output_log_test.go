@@ -0,0 +1,110 @@
+package cmdexec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// outputLogRecord holds the attributes of a JSON log record.
+type outputLogRecord map[string]any
+
+func decodeOutputLog(t *testing.T, buf *bytes.Buffer) []outputLogRecord {
+	t.Helper()
+	var records []outputLogRecord
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var rec outputLogRecord
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatalf("decoding %q: %v", line, err)
+		}
+		records = append(records, rec)
+	}
+	return records
+}
+
+func TestBasicExecutor_Execute_OutputLog(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	_, err := NewBasicExecutor().Execute(context.Background(), ToolConfig{
+		Command:   "sh",
+		Args:      []string{"-c", "echo hello; echo token=s3cret >&2"},
+		OutputLog: &OutputLog{Logger: logger},
+		Redactor:  &Redactor{Secrets: []string{"s3cret"}},
+		Labels:    map[string]string{"tenant": "acme"},
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	records := decodeOutputLog(t, &buf)
+	if len(records) != 2 {
+		t.Fatalf("got %d records, want 2: %s", len(records), buf.String())
+	}
+	byStream := map[string]outputLogRecord{}
+	for _, rec := range records {
+		byStream[rec["stream"].(string)] = rec
+		if rec["msg"] != "Command output" || rec["command"] != "sh" || rec["exec_id"] == nil {
+			t.Errorf("record = %v, want message, command and exec_id set", rec)
+		}
+		if labels, _ := rec["labels"].(map[string]any); labels["tenant"] != "acme" {
+			t.Errorf("record labels = %v, want the config's labels", rec["labels"])
+		}
+	}
+	if rec := byStream["stdout"]; rec["level"] != "DEBUG" || rec["line"] != "hello" {
+		t.Errorf("stdout record = %v, want DEBUG \"hello\"", rec)
+	}
+	if rec := byStream["stderr"]; rec["level"] != "ERROR" || rec["line"] != "token=[REDACTED]" {
+		t.Errorf("stderr record = %v, want ERROR with the secret redacted", rec)
+	}
+	if records[0]["exec_id"] != records[1]["exec_id"] {
+		t.Errorf("exec_id differs between streams: %v and %v", records[0]["exec_id"], records[1]["exec_id"])
+	}
+}
+
+func TestBasicExecutor_Execute_OutputLogLevels(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+	_, err := NewBasicExecutor().Execute(context.Background(), ToolConfig{
+		Command: "sh",
+		Args:    []string{"-c", "echo out; echo err >&2"},
+		OutputLog: &OutputLog{
+			Logger:      logger,
+			StdoutLevel: slog.LevelInfo,
+			StderrLevel: slog.LevelWarn,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	levels := map[string]string{}
+	for _, rec := range decodeOutputLog(t, &buf) {
+		levels[rec["stream"].(string)] = rec["level"].(string)
+	}
+	if levels["stdout"] != "INFO" || levels["stderr"] != "WARN" {
+		t.Errorf("levels = %v, want stdout INFO and stderr WARN", levels)
+	}
+}
+
+func TestOutputLog_DisabledLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	stdout, _ := (&OutputLog{Logger: logger}).lineCallbacks(context.Background(), ToolConfig{Command: "x"})
+	stdout("ignored")
+	if buf.Len() != 0 {
+		t.Errorf("debug record logged at info level: %s", buf.String())
+	}
+
+	var nilLog *OutputLog
+	if stdout, stderr := nilLog.lineCallbacks(context.Background(), ToolConfig{}); stdout != nil || stderr != nil {
+		t.Error("nil OutputLog should return nil callbacks")
+	}
+}
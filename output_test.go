@@ -0,0 +1,364 @@
+package cmdexec
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCollapsingWriter(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes []string
+		want   string
+	}{
+		{
+			name:   "no repeats",
+			writes: []string{"a\nb\nc\n"},
+			want:   "a\nb\nc\n",
+		},
+		{
+			name:   "run in middle",
+			writes: []string{"a\nb\nb\nb\nc\n"},
+			want:   "a\nb\nlast message repeated 2 times\nc\n",
+		},
+		{
+			name:   "run at end is flushed",
+			writes: []string{"retry\nretry\nretry\n"},
+			want:   "retry\nlast message repeated 2 times\n",
+		},
+		{
+			name:   "lines split across writes",
+			writes: []string{"wa", "it\nwai", "t\nwait\ndone"},
+			want:   "wait\nlast message repeated 2 times\ndone",
+		},
+		{
+			name:   "non-adjacent duplicates are kept",
+			writes: []string{"x\ny\nx\n"},
+			want:   "x\ny\nx\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			cw := NewCollapsingWriter(&out)
+			for _, w := range tt.writes {
+				n, err := cw.Write([]byte(w))
+				if err != nil {
+					t.Fatalf("Write() error = %v", err)
+				}
+				if n != len(w) {
+					t.Errorf("Write() = %d, want %d", n, len(w))
+				}
+			}
+			if err := cw.Flush(); err != nil {
+				t.Fatalf("Flush() error = %v", err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("output = %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestBasicExecutor_Execute_CollapseRepeatedLines(t *testing.T) {
+	var streamed strings.Builder
+	result, err := NewBasicExecutor().Execute(context.Background(), ToolConfig{
+		Command:               "sh",
+		Args:                  []string{"-c", "for i in 1 2 3 4 5; do echo waiting; done; echo ready"},
+		CollapseRepeatedLines: true,
+		StdoutWriter:          &streamed,
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	want := "waiting\nlast message repeated 4 times\nready\n"
+	if result.Output != want {
+		t.Errorf("Output = %q, want %q", result.Output, want)
+	}
+	if streamed.String() != want {
+		t.Errorf("streamed = %q, want %q", streamed.String(), want)
+	}
+}
+
+func TestBasicExecutor_Execute_FlushesBufferedWriter(t *testing.T) {
+	var sink bytes.Buffer
+	bw := bufio.NewWriterSize(&sink, 4096)
+
+	_, err := NewBasicExecutor().Execute(context.Background(), ToolConfig{
+		Command:      "echo",
+		Args:         []string{"tail of output"},
+		StdoutWriter: bw,
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if sink.String() != "tail of output\n" {
+		t.Errorf("sink = %q, want flushed output", sink.String())
+	}
+}
+
+// countingFlusher records how often Flush is called.
+type countingFlusher struct {
+	bytes.Buffer
+	flushes atomic.Int32
+}
+
+func (c *countingFlusher) Flush() {
+	c.flushes.Add(1)
+}
+
+func TestBasicExecutor_Execute_PeriodicFlush(t *testing.T) {
+	w := &countingFlusher{}
+	_, err := NewBasicExecutor().Execute(context.Background(), ToolConfig{
+		Command:       "sh",
+		Args:          []string{"-c", "echo start; sleep 0.3; echo end"},
+		StdoutWriter:  w,
+		FlushInterval: 50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	// At least a couple of periodic flushes plus the final one.
+	if got := w.flushes.Load(); got < 3 {
+		t.Errorf("flushes = %d, want >= 3", got)
+	}
+}
+
+func TestFlushFunc(t *testing.T) {
+	if flushFunc(&bytes.Buffer{}) != nil {
+		t.Error("bytes.Buffer should not be detected as flushable")
+	}
+	if flushFunc(bufio.NewWriter(io.Discard)) == nil {
+		t.Error("bufio.Writer should be detected as flushable")
+	}
+	if flushFunc(os.Stdout) == nil {
+		t.Error("os.File should be detected as syncable")
+	}
+}
+
+func TestLimitedWriter_TruncateModes(t *testing.T) {
+	tests := []struct {
+		name          string
+		mode          TruncateMode
+		max           int64
+		writes        []string
+		want          string
+		wantTruncated bool
+		wantDropped   int64
+	}{
+		{"head", TruncateHead, 4, []string{"abc", "defg"}, "abcd", true, 3},
+		{"tail", TruncateTail, 4, []string{"abc", "defg"}, "defg", true, 3},
+		{"tail across many writes", TruncateTail, 3, []string{"a", "b", "c", "d", "e", "f", "g"}, "efg", true, 4},
+		{"head and tail", TruncateHeadAndTail, 6, []string{"0123", "4567", "89"}, "012789", true, 4},
+		{"head and tail odd limit", TruncateHeadAndTail, 5, []string{"0123456789"}, "012" + "89", true, 5},
+		{"tail within limit", TruncateTail, 10, []string{"abc", "def"}, "abcdef", false, 0},
+		{"head and tail within limit", TruncateHeadAndTail, 10, []string{"abc", "def"}, "abcdef", false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			lw := newLimitedWriter(&buf, tt.max, tt.mode)
+			for _, w := range tt.writes {
+				if n, err := lw.Write([]byte(w)); err != nil || n != len(w) {
+					t.Fatalf("Write() = (%d, %v), want (%d, nil)", n, err, len(w))
+				}
+			}
+			if err := lw.flush(); err != nil {
+				t.Fatalf("flush() error = %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("kept %q, want %q", buf.String(), tt.want)
+			}
+			if lw.truncated != tt.wantTruncated {
+				t.Errorf("truncated = %v, want %v", lw.truncated, tt.wantTruncated)
+			}
+			if lw.dropped != tt.wantDropped {
+				t.Errorf("dropped = %d, want %d", lw.dropped, tt.wantDropped)
+			}
+		})
+	}
+}
+
+func TestSharedLimit_TruncateModes(t *testing.T) {
+	type write struct {
+		stderr bool
+		data   string
+	}
+	tests := []struct {
+		name                   string
+		mode                   TruncateMode
+		max                    int64
+		writes                 []write
+		wantStdout, wantStderr string
+		wantDropped            [2]int64
+	}{
+		{"head", TruncateHead, 5, []write{{false, "abc"}, {true, "XYZ"}, {false, "def"}}, "abc", "XY", [2]int64{3, 1}},
+		{"tail", TruncateTail, 5, []write{{false, "abc"}, {true, "XYZ"}, {false, "def"}}, "def", "YZ", [2]int64{3, 1}},
+		{"head and tail", TruncateHeadAndTail, 4, []write{{false, "ab"}, {true, "XY"}, {false, "cd"}, {true, "Z"}}, "abd", "Z", [2]int64{1, 2}},
+		{"within limit", TruncateTail, 10, []write{{false, "abc"}, {true, "XYZ"}}, "abc", "XYZ", [2]int64{0, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out, errOut bytes.Buffer
+			l := newSharedLimit(tt.max, tt.mode)
+			writers := [2]*sharedLimitWriter{l.writer(&out), l.writer(&errOut)}
+			for _, w := range tt.writes {
+				sw := writers[0]
+				if w.stderr {
+					sw = writers[1]
+				}
+				if n, err := sw.Write([]byte(w.data)); err != nil || n != len(w.data) {
+					t.Fatalf("Write() = (%d, %v), want (%d, nil)", n, err, len(w.data))
+				}
+			}
+			if err := l.flush(); err != nil {
+				t.Fatalf("flush() error = %v", err)
+			}
+			if out.String() != tt.wantStdout || errOut.String() != tt.wantStderr {
+				t.Errorf("kept %q/%q, want %q/%q", out.String(), errOut.String(), tt.wantStdout, tt.wantStderr)
+			}
+			for i, sw := range writers {
+				if sw.droppedBytes() != tt.wantDropped[i] {
+					t.Errorf("writer %d dropped = %d, want %d", i, sw.droppedBytes(), tt.wantDropped[i])
+				}
+				if sw.truncated() != (tt.wantDropped[i] > 0) {
+					t.Errorf("writer %d truncated = %v, want %v", i, sw.truncated(), tt.wantDropped[i] > 0)
+				}
+			}
+		})
+	}
+}
+
+func TestBasicExecutor_Execute_MaxOutputBytes(t *testing.T) {
+	result, err := NewBasicExecutor().Execute(context.Background(), ToolConfig{
+		Command:        "sh",
+		Args:           []string{"-c", "echo 0123456789; sleep 0.1; echo abcdefghij >&2"},
+		MaxOutputBytes: 15,
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Output != "0123456789\n" || result.Stderr != "abcd" {
+		t.Errorf("Output = %q, Stderr = %q; want the first 15 bytes overall", result.Output, result.Stderr)
+	}
+	if result.StdoutTruncated || !result.StderrTruncated {
+		t.Errorf("StdoutTruncated = %v, StderrTruncated = %v; want false, true",
+			result.StdoutTruncated, result.StderrTruncated)
+	}
+	if result.StderrBytesTotal != 11 || result.StderrBytesDropped != 7 {
+		t.Errorf("StderrBytesTotal = %d, StderrBytesDropped = %d; want 11 and 7",
+			result.StderrBytesTotal, result.StderrBytesDropped)
+	}
+}
+
+func TestBasicExecutor_Execute_MaxOutputBytesTailWithSpool(t *testing.T) {
+	result, err := NewBasicExecutor().Execute(context.Background(), ToolConfig{
+		Command:        "sh",
+		Args:           []string{"-c", "echo first; echo second >&2; echo third"},
+		MaxOutputBytes: 6,
+		MaxStdoutBytes: 100,
+		TruncateMode:   TruncateTail,
+		SpoolThreshold: 1,
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	defer func() {
+		if err := result.Cleanup(); err != nil {
+			t.Errorf("Cleanup() error = %v", err)
+		}
+	}()
+	rc, err := result.StdoutReader()
+	if err != nil {
+		t.Fatalf("StdoutReader() error = %v", err)
+	}
+	stdout, err := io.ReadAll(rc)
+	_ = rc.Close()
+	if err != nil {
+		t.Fatalf("reading stdout: %v", err)
+	}
+	if string(stdout) != "third\n" {
+		t.Errorf("stdout = %q, want %q", stdout, "third\n")
+	}
+	if !result.StdoutTruncated || !result.StderrTruncated {
+		t.Errorf("StdoutTruncated = %v, StderrTruncated = %v; want both true",
+			result.StdoutTruncated, result.StderrTruncated)
+	}
+}
+
+func TestLineFilterWriter(t *testing.T) {
+	var buf bytes.Buffer
+	fw := &lineFilterWriter{w: &buf, keep: LinesMatching(regexp.MustCompile(`^(error|warning):`))}
+	for _, w := range []string{"info: a\nerror: b", "ad\r\nwarning: c\ninfo: d\nerror: tail"} {
+		if n, err := fw.Write([]byte(w)); err != nil || n != len(w) {
+			t.Fatalf("Write() = (%d, %v), want (%d, nil)", n, err, len(w))
+		}
+	}
+	if err := fw.flush(); err != nil {
+		t.Fatalf("flush() error = %v", err)
+	}
+	if want := "error: bad\r\nwarning: c\nerror: tail"; buf.String() != want {
+		t.Errorf("kept %q, want %q", buf.String(), want)
+	}
+}
+
+func TestBasicExecutor_Execute_CaptureFilter(t *testing.T) {
+	var streamed bytes.Buffer
+	result, err := NewBasicExecutor().Execute(context.Background(), ToolConfig{
+		Command:       "sh",
+		Args:          []string{"-c", "echo compiling; echo 'warning: unused'; echo done; echo 'error: failed' >&2"},
+		CaptureFilter: func(line string) bool { return strings.Contains(line, ":") },
+		StdoutWriter:  &streamed,
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Output != "warning: unused\n" || result.Stderr != "error: failed\n" {
+		t.Errorf("Output = %q, Stderr = %q; want only the filtered lines", result.Output, result.Stderr)
+	}
+	if result.StdoutBytesTotal != 31 || result.StderrBytesTotal != 14 {
+		t.Errorf("StdoutBytesTotal = %d, StderrBytesTotal = %d; want the unfiltered 31 and 14",
+			result.StdoutBytesTotal, result.StderrBytesTotal)
+	}
+	if want := "compiling\nwarning: unused\ndone\n"; streamed.String() != want {
+		t.Errorf("StdoutWriter got %q, want the full stream %q", streamed.String(), want)
+	}
+}
+
+func TestBasicExecutor_Execute_TruncateTail(t *testing.T) {
+	result, err := NewBasicExecutor().Execute(context.Background(), ToolConfig{
+		Command:        "sh",
+		Args:           []string{"-c", "i=0; while [ $i -lt 50 ]; do echo line$i; i=$((i+1)); done; echo FAILED"},
+		MaxStdoutBytes: 14,
+		TruncateMode:   TruncateTail,
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Output != "line49\nFAILED\n" {
+		t.Errorf("Output = %q, want the last 14 bytes", result.Output)
+	}
+	if !result.StdoutTruncated {
+		t.Error("StdoutTruncated = false, want true")
+	}
+	// 10 lines of 6 bytes, 40 lines of 7 bytes, and "FAILED\n".
+	if result.StdoutBytesTotal != 347 || result.StdoutBytesDropped != 333 {
+		t.Errorf("StdoutBytesTotal = %d, StdoutBytesDropped = %d; want 347 and 333",
+			result.StdoutBytesTotal, result.StdoutBytesDropped)
+	}
+	if result.StderrBytesTotal != 0 || result.StderrBytesDropped != 0 {
+		t.Errorf("stderr counts = %d/%d, want 0/0", result.StderrBytesTotal, result.StderrBytesDropped)
+	}
+}
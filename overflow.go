@@ -0,0 +1,125 @@
+package cmdexec
+
+import (
+	"context"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// overflowWaitDelay bounds how long Wait may block on output pipes held
+// open by descendants after an execution was aborted for overflowing.
+const overflowWaitDelay = time.Second
+
+// OverflowPolicy selects what happens when captured output reaches
+// MaxStdoutBytes, MaxStderrBytes or MaxOutputBytes.
+type OverflowPolicy int
+
+const (
+	// OverflowTruncate keeps running the command and discards the excess
+	// output according to TruncateMode. It is the default.
+	OverflowTruncate OverflowPolicy = iota
+
+	// OverflowAbort kills the command as soon as a limit is exceeded and
+	// makes Execute return an *OutputLimitError.
+	OverflowAbort
+
+	// OverflowBlock stops reading the stream that reached its limit, so a
+	// command writing more output blocks until Timeout or IdleTimeout ends
+	// the execution. It requires one of them to be set.
+	OverflowBlock
+)
+
+func (p OverflowPolicy) validate(tc *ToolConfig) error {
+	switch p {
+	case OverflowTruncate, OverflowAbort:
+		return nil
+	case OverflowBlock:
+		if tc.Timeout <= 0 && tc.IdleTimeout <= 0 {
+			return &ValidationError{Field: "OverflowPolicy", Message: "overflowBlock requires Timeout or IdleTimeout"}
+		}
+		return nil
+	}
+	return &ValidationError{Field: "OverflowPolicy", Message: "unknown overflow policy"}
+}
+
+// overflowGuard enforces an OverflowPolicy other than OverflowTruncate for
+// one execution attempt.
+type overflowGuard struct {
+	policy OverflowPolicy
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu  sync.Mutex
+	err *OutputLimitError
+}
+
+// newOverflowGuard derives a context from ctx that is cancelled when an
+// output limit is exceeded under OverflowAbort. For OverflowTruncate, it
+// returns ctx and a nil guard; all overflowGuard methods are safe to call
+// on nil.
+func newOverflowGuard(ctx context.Context, policy OverflowPolicy) (context.Context, *overflowGuard) {
+	if policy == OverflowTruncate {
+		return ctx, nil
+	}
+	guardCtx, cancel := context.WithCancel(ctx)
+	return guardCtx, &overflowGuard{policy: policy, ctx: guardCtx, cancel: cancel}
+}
+
+// truncateMode returns the TruncateMode limits must use. Only the head of
+// the output can be kept when the command is stopped at the limit.
+func (g *overflowGuard) truncateMode(mode TruncateMode) TruncateMode {
+	if g == nil {
+		return mode
+	}
+	return TruncateHead
+}
+
+// hook returns the function a limit calls when output for stream exceeds
+// limit, or nil if excess output is simply discarded.
+func (g *overflowGuard) hook(stream string, limit int64) func() {
+	if g == nil {
+		return nil
+	}
+	return func() {
+		g.mu.Lock()
+		if g.err == nil {
+			g.err = &OutputLimitError{Stream: stream, Limit: limit}
+		}
+		g.mu.Unlock()
+
+		if g.policy == OverflowAbort {
+			g.cancel()
+			return
+		}
+		<-g.ctx.Done()
+	}
+}
+
+// exceeded returns the error for the first exceeded limit if the execution
+// was aborted because of it, or nil otherwise.
+func (g *overflowGuard) exceeded() *OutputLimitError {
+	if g == nil || g.policy != OverflowAbort {
+		return nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.err
+}
+
+// configure bounds how long cmd may wait for its output pipes after the
+// guard aborts it, so hung descendants cannot block the execution.
+func (g *overflowGuard) configure(cmd *exec.Cmd) {
+	if g == nil || cmd.WaitDelay != 0 {
+		return
+	}
+	cmd.WaitDelay = overflowWaitDelay
+}
+
+// stop releases the guard's context.
+func (g *overflowGuard) stop() {
+	if g == nil {
+		return
+	}
+	g.cancel()
+}
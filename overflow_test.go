@@ -0,0 +1,101 @@
+package cmdexec
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBasicExecutor_Execute_OverflowAbort(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfg        ToolConfig
+		wantStream string
+		wantLimit  int64
+	}{
+		{
+			name:       "stdout limit",
+			cfg:        ToolConfig{MaxStdoutBytes: 10},
+			wantStream: "stdout",
+			wantLimit:  10,
+		},
+		{
+			name:       "combined limit",
+			cfg:        ToolConfig{MaxOutputBytes: 20, TruncateMode: TruncateTail},
+			wantStream: "output",
+			wantLimit:  20,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.Command = "sh"
+			cfg.Args = []string{"-c", "echo 0123456789abcdefghij0123456789; while sleep 0.05; do :; done"}
+			cfg.OverflowPolicy = OverflowAbort
+
+			start := time.Now()
+			result, err := NewBasicExecutor().Execute(context.Background(), cfg)
+			if result != nil {
+				t.Errorf("result = %v, want nil", result)
+			}
+			var limitErr *OutputLimitError
+			if !errors.As(err, &limitErr) {
+				t.Fatalf("error = %v, want *OutputLimitError", err)
+			}
+			if limitErr.Stream != tt.wantStream || limitErr.Limit != tt.wantLimit {
+				t.Errorf("OutputLimitError = %+v, want stream %q and limit %d", limitErr, tt.wantStream, tt.wantLimit)
+			}
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Errorf("abort took %v", elapsed)
+			}
+		})
+	}
+}
+
+func TestBasicExecutor_Execute_OverflowAbortWithinLimit(t *testing.T) {
+	result, err := NewBasicExecutor().Execute(context.Background(), ToolConfig{
+		Command:        "echo",
+		Args:           []string{"hi"},
+		MaxStdoutBytes: 10,
+		OverflowPolicy: OverflowAbort,
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Output != "hi\n" || result.StdoutTruncated {
+		t.Errorf("Output = %q, StdoutTruncated = %v; want %q, false", result.Output, result.StdoutTruncated, "hi\n")
+	}
+}
+
+func TestBasicExecutor_Execute_OverflowBlock(t *testing.T) {
+	// Enough output to fill the pipe once reading stops.
+	start := time.Now()
+	_, err := NewBasicExecutor().Execute(context.Background(), ToolConfig{
+		Command:        "head",
+		Args:           []string{"-c", "1000000", "/dev/zero"},
+		MaxStdoutBytes: 10,
+		OverflowPolicy: OverflowBlock,
+		Timeout:        300 * time.Millisecond,
+	})
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("error = %v, want *TimeoutError", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("blocked execution took %v to time out", elapsed)
+	}
+}
+
+func TestOverflowGuard_TruncateMode(t *testing.T) {
+	var nilGuard *overflowGuard
+	if got := nilGuard.truncateMode(TruncateTail); got != TruncateTail {
+		t.Errorf("nil guard truncateMode = %v, want TruncateTail", got)
+	}
+	_, g := newOverflowGuard(context.Background(), OverflowAbort)
+	defer g.stop()
+	if got := g.truncateMode(TruncateTail); got != TruncateHead {
+		t.Errorf("abort guard truncateMode = %v, want TruncateHead", got)
+	}
+}
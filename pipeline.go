@@ -0,0 +1,295 @@
+package cmdexec
+
+import (
+	"context"
+	"fmt"
+)
+
+// StepStatus is the outcome of a pipeline step.
+type StepStatus int
+
+const (
+	// StepSucceeded means the step ran and ToolConfig.Succeeded accepted
+	// its result.
+	StepSucceeded StepStatus = iota
+
+	// StepFailed means the step ran and returned an error or an
+	// unsuccessful result.
+	StepFailed
+
+	// StepSkipped means the step never ran, because a step it depends on
+	// did not succeed or the context was done before it could start.
+	StepSkipped
+)
+
+// PipelineStep is a named command in a Pipeline together with the names of
+// the steps that must succeed before it runs.
+type PipelineStep struct {
+	Name      string
+	Config    ToolConfig
+	DependsOn []string
+}
+
+// StepResult is the outcome of one pipeline step.
+type StepResult struct {
+	// Name is the name of the step.
+	Name string
+
+	// Status tells whether the step succeeded, failed or was skipped.
+	Status StepStatus
+
+	// Result is the execution result, or nil if the step was skipped or
+	// Execute returned an error.
+	Result *ExecutionResult
+
+	// Error is the error returned by Execute, or the context error if the
+	// step was skipped because the context was done.
+	Error error
+
+	// BlockedBy is the name of the step whose failure caused this step to
+	// be skipped, or empty.
+	BlockedBy string
+}
+
+// PipelineResult is the outcome of running a Pipeline.
+type PipelineResult struct {
+	// Steps holds a result for every step, in the order they were added.
+	Steps []StepResult
+}
+
+// Succeeded reports whether every step succeeded.
+func (pr *PipelineResult) Succeeded() bool {
+	for _, s := range pr.Steps {
+		if s.Status != StepSucceeded {
+			return false
+		}
+	}
+	return true
+}
+
+// Step returns the result of the named step, or nil if there is none.
+func (pr *PipelineResult) Step(name string) *StepResult {
+	for i := range pr.Steps {
+		if pr.Steps[i].Name == name {
+			return &pr.Steps[i]
+		}
+	}
+	return nil
+}
+
+// Pipeline runs a set of commands whose dependencies form a directed
+// acyclic graph, as in a build. A step starts as soon as all of its
+// dependencies have succeeded, so independent steps run concurrently. When a
+// step fails, the steps that depend on it, directly or transitively, are
+// skipped, while unrelated steps keep running.
+type Pipeline struct {
+	executor       Executor
+	steps          []PipelineStep
+	maxConcurrency int
+}
+
+// NewPipeline creates an empty pipeline that runs its steps on executor.
+func NewPipeline(executor Executor) *Pipeline {
+	return &Pipeline{
+		executor:       executor,
+		maxConcurrency: 10, // Default to 10 concurrent steps
+	}
+}
+
+// AddStep adds a step named name that runs cfg after every step in
+// dependsOn has succeeded. Steps may be added in any order; dependencies are
+// checked by Validate and Run. It returns p for chaining.
+func (p *Pipeline) AddStep(name string, cfg ToolConfig, dependsOn ...string) *Pipeline {
+	p.steps = append(p.steps, PipelineStep{Name: name, Config: cfg, DependsOn: dependsOn})
+	return p
+}
+
+// SetMaxConcurrency sets the maximum number of steps that run at once.
+func (p *Pipeline) SetMaxConcurrency(maxConcurrency int) {
+	if maxConcurrency <= 0 {
+		maxConcurrency = 1
+	}
+	p.maxConcurrency = maxConcurrency
+}
+
+// Validate checks that step names are unique and non-empty, that every
+// dependency names a step, and that the dependencies contain no cycle.
+func (p *Pipeline) Validate() error {
+	index, err := p.index()
+	if err != nil {
+		return err
+	}
+
+	// Kahn's algorithm: whatever cannot be ordered is part of a cycle.
+	pending := make([]int, len(p.steps))
+	dependents := make([][]int, len(p.steps))
+	var ready []int
+	for i, step := range p.steps {
+		for _, dep := range step.DependsOn {
+			dependents[index[dep]] = append(dependents[index[dep]], i)
+		}
+		pending[i] = len(step.DependsOn)
+		if pending[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+	ordered := 0
+	for len(ready) > 0 {
+		i := ready[len(ready)-1]
+		ready = ready[:len(ready)-1]
+		ordered++
+		for _, j := range dependents[i] {
+			if pending[j]--; pending[j] == 0 {
+				ready = append(ready, j)
+			}
+		}
+	}
+	if ordered < len(p.steps) {
+		for i, n := range pending {
+			if n > 0 {
+				return &ValidationError{Field: "DependsOn", Message: fmt.Sprintf("step %q is part of a dependency cycle", p.steps[i].Name)}
+			}
+		}
+	}
+	return nil
+}
+
+// index maps step names to their positions and checks the names and
+// dependencies refer to steps.
+func (p *Pipeline) index() (map[string]int, error) {
+	index := make(map[string]int, len(p.steps))
+	for i, step := range p.steps {
+		if step.Name == "" {
+			return nil, &ValidationError{Field: "Name", Message: "step name cannot be empty"}
+		}
+		if _, ok := index[step.Name]; ok {
+			return nil, &ValidationError{Field: "Name", Message: fmt.Sprintf("duplicate step %q", step.Name)}
+		}
+		index[step.Name] = i
+	}
+	for _, step := range p.steps {
+		for _, dep := range step.DependsOn {
+			if _, ok := index[dep]; !ok {
+				return nil, &ValidationError{Field: "DependsOn", Message: fmt.Sprintf("step %q depends on unknown step %q", step.Name, dep)}
+			}
+		}
+	}
+	return index, nil
+}
+
+// Run validates the pipeline and runs its steps. A step failing is
+// reported in the PipelineResult, not as an error; Run only returns an
+// error if the pipeline is invalid. If ctx is done, steps that have not
+// started are skipped.
+func (p *Pipeline) Run(ctx context.Context) (*PipelineResult, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	index, _ := p.index()
+
+	r := &pipelineRun{
+		pipeline:   p,
+		steps:      make([]StepResult, len(p.steps)),
+		decided:    make([]bool, len(p.steps)),
+		pending:    make([]int, len(p.steps)),
+		dependents: make([][]int, len(p.steps)),
+		done:       make(chan stepDone, len(p.steps)),
+	}
+	for i, step := range p.steps {
+		r.steps[i].Name = step.Name
+		r.pending[i] = len(step.DependsOn)
+		if r.pending[i] == 0 {
+			r.ready = append(r.ready, i)
+		}
+		for _, dep := range step.DependsOn {
+			r.dependents[index[dep]] = append(r.dependents[index[dep]], i)
+		}
+	}
+	r.run(ctx)
+	return &PipelineResult{Steps: r.steps}, nil
+}
+
+// stepDone reports a finished step to the pipeline scheduler.
+type stepDone struct {
+	index  int
+	result *ExecutionResult
+	err    error
+}
+
+// pipelineRun holds the scheduling state of one Pipeline.Run.
+type pipelineRun struct {
+	pipeline   *Pipeline
+	steps      []StepResult
+	decided    []bool
+	pending    []int
+	dependents [][]int
+	ready      []int
+	done       chan stepDone
+	running    int
+	finished   int
+}
+
+func (r *pipelineRun) run(ctx context.Context) {
+	for {
+		r.start(ctx)
+		if r.finished == len(r.steps) {
+			return
+		}
+		d := <-r.done
+		r.running--
+		r.complete(d)
+	}
+}
+
+// start launches ready steps up to the concurrency limit. If ctx is done,
+// ready steps are skipped instead.
+func (r *pipelineRun) start(ctx context.Context) {
+	for len(r.ready) > 0 && r.running < r.pipeline.maxConcurrency {
+		i := r.ready[0]
+		r.ready = r.ready[1:]
+		if err := ctx.Err(); err != nil {
+			r.decide(i, StepResult{Status: StepSkipped, Error: err})
+			r.skipDependents(i, r.steps[i].Name)
+			continue
+		}
+		r.running++
+		go func(index int, cfg ToolConfig) {
+			result, err := r.pipeline.executor.Execute(ctx, cfg)
+			r.done <- stepDone{index: index, result: result, err: err}
+		}(i, r.pipeline.steps[i].Config)
+	}
+}
+
+// complete records a finished step and releases or skips its dependents.
+func (r *pipelineRun) complete(d stepDone) {
+	cfg := &r.pipeline.steps[d.index].Config
+	if d.err != nil || !cfg.Succeeded(d.result) {
+		r.decide(d.index, StepResult{Status: StepFailed, Result: d.result, Error: d.err})
+		r.skipDependents(d.index, r.steps[d.index].Name)
+		return
+	}
+	r.decide(d.index, StepResult{Status: StepSucceeded, Result: d.result})
+	for _, j := range r.dependents[d.index] {
+		if r.pending[j]--; r.pending[j] == 0 && !r.decided[j] {
+			r.ready = append(r.ready, j)
+		}
+	}
+}
+
+// skipDependents skips every step that transitively depends on step i.
+func (r *pipelineRun) skipDependents(i int, blockedBy string) {
+	for _, j := range r.dependents[i] {
+		if r.decided[j] {
+			continue
+		}
+		r.decide(j, StepResult{Status: StepSkipped, BlockedBy: blockedBy})
+		r.skipDependents(j, blockedBy)
+	}
+}
+
+func (r *pipelineRun) decide(i int, sr StepResult) {
+	sr.Name = r.steps[i].Name
+	r.steps[i] = sr
+	r.decided[i] = true
+	r.finished++
+}
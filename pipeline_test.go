@@ -0,0 +1,193 @@
+package cmdexec
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPipeline_Run_Diamond(t *testing.T) {
+	p := NewPipeline(NewBasicExecutor())
+	p.AddStep("test", ToolConfig{Command: "sh", Args: []string{"-c", "echo test"}}, "build").
+		AddStep("build", ToolConfig{Command: "sh", Args: []string{"-c", "sleep 0.05; echo build"}}).
+		AddStep("lint", ToolConfig{Command: "echo", Args: []string{"lint"}}, "build").
+		AddStep("package", ToolConfig{Command: "echo", Args: []string{"package"}}, "test", "lint")
+
+	result, err := p.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if !result.Succeeded() {
+		t.Fatalf("Run() steps = %+v, want all succeeded", result.Steps)
+	}
+
+	names := make([]string, len(result.Steps))
+	for i, s := range result.Steps {
+		names[i] = s.Name
+	}
+	if got := strings.Join(names, ","); got != "test,build,lint,package" {
+		t.Errorf("Steps are in order %s, want the order they were added", got)
+	}
+
+	build, pkg := result.Step("build").Result, result.Step("package").Result
+	for _, name := range []string{"test", "lint"} {
+		step := result.Step(name).Result
+		if step.StartTime.Before(build.EndTime) {
+			t.Errorf("%s started before build finished", name)
+		}
+		if pkg.StartTime.Before(step.EndTime) {
+			t.Errorf("package started before %s finished", name)
+		}
+	}
+	if result.Step("missing") != nil {
+		t.Error("Step() of an unknown name should be nil")
+	}
+}
+
+func TestPipeline_Run_FailureSkipsDependents(t *testing.T) {
+	p := NewPipeline(NewBasicExecutor())
+	p.AddStep("build", ToolConfig{Command: "sh", Args: []string{"-c", "exit 2"}}).
+		AddStep("test", ToolConfig{Command: "echo", Args: []string{"test"}}, "build").
+		AddStep("deploy", ToolConfig{Command: "echo", Args: []string{"deploy"}}, "test").
+		AddStep("docs", ToolConfig{Command: "echo", Args: []string{"docs"}}).
+		AddStep("missing", ToolConfig{Command: "nonexistent-pipeline-tool"})
+
+	result, err := p.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if result.Succeeded() {
+		t.Error("Succeeded() = true, want false")
+	}
+
+	if s := result.Step("build"); s.Status != StepFailed || s.Result == nil || s.Result.ExitCode != 2 {
+		t.Errorf("build = %+v, want failed with exit code 2", s)
+	}
+	for _, name := range []string{"test", "deploy"} {
+		if s := result.Step(name); s.Status != StepSkipped || s.BlockedBy != "build" || s.Result != nil {
+			t.Errorf("%s = %+v, want skipped because of build", name, s)
+		}
+	}
+	if s := result.Step("docs"); s.Status != StepSucceeded {
+		t.Errorf("docs = %+v, want an unrelated step to succeed", s)
+	}
+	var notFound *ExecutableNotFoundError
+	if s := result.Step("missing"); s.Status != StepFailed || !errors.As(s.Error, &notFound) {
+		t.Errorf("missing = %+v, want failed with *ExecutableNotFoundError", s)
+	}
+}
+
+func TestPipeline_Run_SuccessWhen(t *testing.T) {
+	p := NewPipeline(NewBasicExecutor())
+	p.AddStep("diff", ToolConfig{Command: "sh", Args: []string{"-c", "exit 1"}, SuccessWhen: SucceedOnExitCodes(0, 1)}).
+		AddStep("report", ToolConfig{Command: "echo"}, "diff")
+
+	result, err := p.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if !result.Succeeded() {
+		t.Errorf("Run() steps = %+v, want SuccessWhen to decide success", result.Steps)
+	}
+}
+
+func TestPipeline_Run_ContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	p := NewPipeline(NewBasicExecutor())
+	p.AddStep("a", ToolConfig{Command: "echo"}).AddStep("b", ToolConfig{Command: "echo"}, "a")
+
+	result, err := p.Run(ctx)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if s := result.Step("a"); s.Status != StepSkipped || !errors.Is(s.Error, context.Canceled) {
+		t.Errorf("a = %+v, want skipped with context.Canceled", s)
+	}
+	if s := result.Step("b"); s.Status != StepSkipped || s.BlockedBy != "a" {
+		t.Errorf("b = %+v, want skipped because of a", s)
+	}
+}
+
+func TestPipeline_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		build  func(p *Pipeline)
+		errMsg string
+	}{
+		{
+			name: "valid",
+			build: func(p *Pipeline) {
+				p.AddStep("a", ToolConfig{Command: "echo"}).AddStep("b", ToolConfig{Command: "echo"}, "a")
+			},
+		},
+		{
+			name:   "empty name",
+			build:  func(p *Pipeline) { p.AddStep("", ToolConfig{Command: "echo"}) },
+			errMsg: "step name cannot be empty",
+		},
+		{
+			name: "duplicate",
+			build: func(p *Pipeline) {
+				p.AddStep("a", ToolConfig{Command: "echo"}).AddStep("a", ToolConfig{Command: "echo"})
+			},
+			errMsg: `duplicate step "a"`,
+		},
+		{
+			name:   "unknown dependency",
+			build:  func(p *Pipeline) { p.AddStep("a", ToolConfig{Command: "echo"}, "b") },
+			errMsg: `step "a" depends on unknown step "b"`,
+		},
+		{
+			name: "cycle",
+			build: func(p *Pipeline) {
+				p.AddStep("root", ToolConfig{Command: "echo"}).
+					AddStep("a", ToolConfig{Command: "echo"}, "root", "c").
+					AddStep("b", ToolConfig{Command: "echo"}, "a").
+					AddStep("c", ToolConfig{Command: "echo"}, "b")
+			},
+			errMsg: "dependency cycle",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPipeline(NewMockExecutor())
+			tt.build(p)
+			err := p.Validate()
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Validate() error = %v, want *ValidationError containing %q", err, tt.errMsg)
+			}
+			if _, err := p.Run(context.Background()); err == nil {
+				t.Error("Run() error = nil, want the validation error")
+			}
+		})
+	}
+}
+
+func TestPipeline_SetMaxConcurrency(t *testing.T) {
+	mock := NewMockExecutor()
+	mock.SetResult(&ExecutionResult{Command: "echo"}, nil)
+
+	p := NewPipeline(mock)
+	p.SetMaxConcurrency(0)
+	if p.maxConcurrency != 1 {
+		t.Errorf("maxConcurrency = %d, want 1", p.maxConcurrency)
+	}
+	for _, name := range []string{"a", "b", "c"} {
+		p.AddStep(name, ToolConfig{Command: "echo"})
+	}
+	result, err := p.Run(context.Background())
+	if err != nil || !result.Succeeded() {
+		t.Errorf("Run() = (%+v, %v), want all steps to succeed one at a time", result, err)
+	}
+}
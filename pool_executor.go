@@ -0,0 +1,270 @@
+package cmdexec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrPoolClosed is the error of executions submitted to a PoolExecutor
+// after Shutdown was called.
+var ErrPoolClosed = errors.New("pool executor is shut down")
+
+// QueueFullPolicy selects what PoolExecutor.Submit does when the queue of
+// pending executions is full. Without a queue (queueSize 0), the policies
+// other than QueueFullBlock only accept a submission if a worker is idle at
+// that moment.
+type QueueFullPolicy int
+
+const (
+	// QueueFullBlock makes Submit wait until there is room. It is the
+	// default.
+	QueueFullBlock QueueFullPolicy = iota
+
+	// QueueFullReject fails the new submission with a *QueueFullError.
+	QueueFullReject
+
+	// QueueFullDropOldest fails the oldest queued submission with a
+	// *QueueFullError to make room for the new one. Without a queue
+	// (queueSize 0), it behaves like QueueFullReject.
+	QueueFullDropOldest
+)
+
+// QueueFullError is the error of an execution rejected or dropped because
+// the queue of a PoolExecutor was full.
+type QueueFullError struct {
+	// QueueSize is the capacity of the queue.
+	QueueSize int
+
+	// Dropped is true if the execution had been queued and was dropped to
+	// make room for a newer one.
+	Dropped bool
+}
+
+func (e *QueueFullError) Error() string {
+	if e.Dropped {
+		return fmt.Sprintf("execution dropped from full queue (size %d)", e.QueueSize)
+	}
+	return fmt.Sprintf("execution rejected: queue is full (size %d)", e.QueueSize)
+}
+
+// Future is the pending outcome of an execution submitted to a
+// PoolExecutor.
+type Future struct {
+	done   chan struct{}
+	result *ExecutionResult
+	err    error
+}
+
+func newFuture() *Future {
+	return &Future{done: make(chan struct{})}
+}
+
+func (f *Future) complete(result *ExecutionResult, err error) {
+	f.result, f.err = result, err
+	close(f.done)
+}
+
+// Done returns a channel that is closed once the execution has finished.
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
+// Wait blocks until the execution has finished and returns its outcome,
+// following the Executor error contract. If ctx is done first, Wait returns
+// the context error; the execution itself keeps running.
+func (f *Future) Wait(ctx context.Context) (*ExecutionResult, error) {
+	select {
+	case <-f.done:
+		return f.result, f.err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("context done while waiting for execution: %w", ctx.Err())
+	}
+}
+
+// poolJob is an execution queued on a PoolExecutor.
+type poolJob struct {
+	ctx    context.Context
+	cfg    ToolConfig
+	future *Future
+}
+
+// PoolExecutor runs executions on a fixed set of long-lived workers fed by
+// a queue, so a service can bound the number of subprocesses it runs at
+// once across all callers rather than per batch. It implements Executor:
+// Execute submits the execution and waits for it.
+type PoolExecutor struct {
+	executor Executor
+	workers  int
+	jobs     chan poolJob
+	wg       sync.WaitGroup
+
+	// ctx is cancelled when Shutdown gives up waiting, to stop running and
+	// queued executions.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// closing is closed when Shutdown starts, to release submissions
+	// blocked on a full queue.
+	closing     chan struct{}
+	closingOnce sync.Once
+
+	mu         sync.RWMutex
+	closed     bool
+	fullPolicy QueueFullPolicy
+	active     atomic.Int64
+}
+
+// NewPoolExecutor starts a pool of workers that run executions on executor.
+// Up to queueSize submitted executions wait for a free worker; further
+// submissions are handled according to the QueueFullPolicy. workers is at
+// least 1 and queueSize at least 0.
+func NewPoolExecutor(executor Executor, workers, queueSize int) *PoolExecutor {
+	workers = max(workers, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &PoolExecutor{
+		executor: executor,
+		workers:  workers,
+		jobs:     make(chan poolJob, max(queueSize, 0)),
+		ctx:      ctx,
+		cancel:   cancel,
+		closing:  make(chan struct{}),
+	}
+	p.wg.Add(workers)
+	for range workers {
+		go p.work()
+	}
+	return p
+}
+
+func (p *PoolExecutor) work() {
+	defer p.wg.Done()
+	for job := range p.jobs {
+		p.active.Add(1)
+		ctx, cancel := context.WithCancel(job.ctx)
+		stop := context.AfterFunc(p.ctx, cancel)
+		job.future.complete(p.executor.Execute(ctx, job.cfg))
+		stop()
+		cancel()
+		p.active.Add(-1)
+	}
+}
+
+// SetQueueFullPolicy sets what Submit and Execute do when the queue is
+// full. Services accepting external requests should use QueueFullReject or
+// QueueFullDropOldest, so load bursts cannot pile up unbounded waiters.
+func (p *PoolExecutor) SetQueueFullPolicy(policy QueueFullPolicy) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.fullPolicy = policy
+}
+
+// Submit queues cfg for execution and returns a Future for its outcome.
+// When the queue is full, it acts according to the QueueFullPolicy, by
+// default blocking until there is room. After Shutdown, the Future fails
+// with ErrPoolClosed.
+func (p *PoolExecutor) Submit(cfg ToolConfig) *Future {
+	return p.submit(context.Background(), cfg)
+}
+
+func (p *PoolExecutor) submit(ctx context.Context, cfg ToolConfig) *Future {
+	future := newFuture()
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		future.complete(nil, ErrPoolClosed)
+		return future
+	}
+	job := poolJob{ctx: ctx, cfg: cfg, future: future}
+	if p.fullPolicy == QueueFullBlock {
+		select {
+		case p.jobs <- job:
+		case <-p.closing:
+			future.complete(nil, ErrPoolClosed)
+		case <-ctx.Done():
+			future.complete(nil, fmt.Errorf("context done while queueing execution: %w", ctx.Err()))
+		}
+		return future
+	}
+
+	for {
+		select {
+		case p.jobs <- job:
+			return future
+		default:
+		}
+		if p.fullPolicy != QueueFullDropOldest || cap(p.jobs) == 0 {
+			future.complete(nil, &QueueFullError{QueueSize: cap(p.jobs)})
+			return future
+		}
+		// A worker may take the oldest job first; then just retry.
+		select {
+		case oldest := <-p.jobs:
+			oldest.future.complete(nil, &QueueFullError{QueueSize: cap(p.jobs), Dropped: true})
+		default:
+		}
+	}
+}
+
+// Execute submits cfg and waits for it to finish. ctx bounds both the wait
+// for a free worker and the execution itself.
+func (p *PoolExecutor) Execute(ctx context.Context, cfg ToolConfig) (*ExecutionResult, error) {
+	future := p.submit(ctx, cfg)
+	<-future.Done()
+	return future.result, future.err
+}
+
+// IsAvailable delegates to the wrapped executor.
+func (p *PoolExecutor) IsAvailable(command string) bool {
+	return p.executor.IsAvailable(command)
+}
+
+// Workers returns the number of workers in the pool.
+func (p *PoolExecutor) Workers() int {
+	return p.workers
+}
+
+// QueueLength returns the number of submitted executions waiting for a
+// free worker.
+func (p *PoolExecutor) QueueLength() int {
+	return len(p.jobs)
+}
+
+// ActiveCount returns the number of executions currently running.
+func (p *PoolExecutor) ActiveCount() int {
+	return int(p.active.Load())
+}
+
+// Shutdown stops accepting executions and waits for the queued and running
+// ones to finish. If ctx is done first, Shutdown cancels the remaining
+// executions, waits for the workers to exit and returns the context error.
+// It is safe to call more than once.
+func (p *PoolExecutor) Shutdown(ctx context.Context) error {
+	// Release blocked submissions first; they hold p.mu while waiting.
+	p.closingOnce.Do(func() { close(p.closing) })
+
+	// Closing the queue waits for submissions in progress, so it happens
+	// with the draining, which ctx bounds.
+	drained := make(chan struct{})
+	go func() {
+		p.mu.Lock()
+		if !p.closed {
+			p.closed = true
+			close(p.jobs)
+		}
+		p.mu.Unlock()
+		p.wg.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+		p.cancel()
+		return nil
+	case <-ctx.Done():
+		p.cancel()
+		<-drained
+		return fmt.Errorf("pool shutdown interrupted: %w", ctx.Err())
+	}
+}
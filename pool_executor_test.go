@@ -0,0 +1,242 @@
+package cmdexec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// blockingExecutor reports each execution on started and then blocks until
+// release is closed or the context is done.
+type blockingExecutor struct {
+	started chan string
+	release chan struct{}
+}
+
+func newBlockingExecutor() *blockingExecutor {
+	return &blockingExecutor{started: make(chan string, 16), release: make(chan struct{})}
+}
+
+func (b *blockingExecutor) Execute(ctx context.Context, cfg ToolConfig) (*ExecutionResult, error) {
+	b.started <- cfg.Command
+	select {
+	case <-b.release:
+		return &ExecutionResult{Command: cfg.Command, Output: cfg.Command}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (b *blockingExecutor) IsAvailable(string) bool { return true }
+
+func TestPoolExecutor_Submit(t *testing.T) {
+	pool := NewPoolExecutor(NewBasicExecutor(), 2, 8)
+	defer func() { _ = pool.Shutdown(context.Background()) }()
+
+	var futures []*Future
+	for i := range 5 {
+		futures = append(futures, pool.Submit(ToolConfig{Command: "echo", Args: []string{fmt.Sprint(i)}}))
+	}
+	for i, f := range futures {
+		result, err := f.Wait(context.Background())
+		if err != nil {
+			t.Fatalf("future %d error = %v", i, err)
+		}
+		if want := fmt.Sprintf("%d\n", i); result.Output != want {
+			t.Errorf("future %d Output = %q, want %q", i, result.Output, want)
+		}
+	}
+
+	result, err := pool.Execute(context.Background(), ToolConfig{Command: "echo", Args: []string{"direct"}})
+	if err != nil || result.Output != "direct\n" {
+		t.Errorf("Execute() = (%+v, %v), want output %q", result, err, "direct\n")
+	}
+	if !pool.IsAvailable("sh") {
+		t.Error("IsAvailable(\"sh\") = false, want true")
+	}
+}
+
+func TestPoolExecutor_Introspection(t *testing.T) {
+	exec := newBlockingExecutor()
+	pool := NewPoolExecutor(exec, 2, 4)
+	if pool.Workers() != 2 {
+		t.Errorf("Workers() = %d, want 2", pool.Workers())
+	}
+
+	futures := []*Future{
+		pool.Submit(ToolConfig{Command: "a"}),
+		pool.Submit(ToolConfig{Command: "b"}),
+		pool.Submit(ToolConfig{Command: "c"}),
+	}
+	<-exec.started
+	<-exec.started
+	if pool.ActiveCount() != 2 || pool.QueueLength() != 1 {
+		t.Errorf("ActiveCount() = %d, QueueLength() = %d, want 2 and 1", pool.ActiveCount(), pool.QueueLength())
+	}
+
+	select {
+	case <-futures[0].Done():
+		t.Fatal("future finished before its execution was released")
+	default:
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := futures[0].Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wait() error = %v, want context.DeadlineExceeded", err)
+	}
+
+	close(exec.release)
+	if err := pool.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	for i, f := range futures {
+		if result, err := f.Wait(context.Background()); err != nil || result == nil {
+			t.Errorf("future %d = (%v, %v), want queued executions to finish on Shutdown", i, result, err)
+		}
+	}
+	if pool.ActiveCount() != 0 || pool.QueueLength() != 0 {
+		t.Errorf("after Shutdown ActiveCount() = %d, QueueLength() = %d, want 0", pool.ActiveCount(), pool.QueueLength())
+	}
+
+	if _, err := pool.Submit(ToolConfig{Command: "d"}).Wait(context.Background()); !errors.Is(err, ErrPoolClosed) {
+		t.Errorf("Submit() after Shutdown error = %v, want ErrPoolClosed", err)
+	}
+	if err := pool.Shutdown(context.Background()); err != nil {
+		t.Errorf("second Shutdown() error = %v, want nil", err)
+	}
+}
+
+func TestPoolExecutor_ShutdownInterrupted(t *testing.T) {
+	exec := newBlockingExecutor()
+	pool := NewPoolExecutor(exec, 1, 1)
+	running := pool.Submit(ToolConfig{Command: "a"})
+	queued := pool.Submit(ToolConfig{Command: "b"})
+	<-exec.started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := pool.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown() error = %v, want context.DeadlineExceeded", err)
+	}
+	for name, f := range map[string]*Future{"running": running, "queued": queued} {
+		if _, err := f.Wait(context.Background()); !errors.Is(err, context.Canceled) {
+			t.Errorf("%s execution error = %v, want it cancelled", name, err)
+		}
+	}
+}
+
+func TestPoolExecutor_ShutdownContextAlreadyDone(t *testing.T) {
+	exec := newBlockingExecutor()
+	pool := NewPoolExecutor(exec, 1, 0)
+	running := pool.Submit(ToolConfig{Command: "a"})
+	<-exec.started
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := pool.Shutdown(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Shutdown() error = %v, want context.Canceled", err)
+	}
+	if _, err := running.Wait(context.Background()); !errors.Is(err, context.Canceled) {
+		t.Errorf("running execution error = %v, want it cancelled", err)
+	}
+}
+
+func TestPoolExecutor_ShutdownWithBlockedSubmit(t *testing.T) {
+	exec := newBlockingExecutor()
+	pool := NewPoolExecutor(exec, 1, 1)
+	pool.Submit(ToolConfig{Command: "a"})
+	<-exec.started
+	pool.Submit(ToolConfig{Command: "b"})
+
+	blocked := make(chan *Future)
+	go func() { blocked <- pool.Submit(ToolConfig{Command: "c"}) }()
+	time.Sleep(20 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	shutdown := make(chan error)
+	go func() { shutdown <- pool.Shutdown(ctx) }()
+	select {
+	case err := <-shutdown:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Shutdown() error = %v, want context.DeadlineExceeded", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Shutdown() blocked behind a submission waiting on the full queue")
+	}
+	if _, err := (<-blocked).Wait(context.Background()); !errors.Is(err, ErrPoolClosed) {
+		t.Errorf("blocked submission error = %v, want ErrPoolClosed", err)
+	}
+}
+
+func TestPoolExecutor_ExecuteContextWhileQueued(t *testing.T) {
+	exec := newBlockingExecutor()
+	pool := NewPoolExecutor(exec, 1, 0)
+	defer func() {
+		close(exec.release)
+		_ = pool.Shutdown(context.Background())
+	}()
+	pool.Submit(ToolConfig{Command: "a"})
+	<-exec.started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := pool.Execute(ctx, ToolConfig{Command: "b"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Execute() error = %v, want context.DeadlineExceeded while waiting for a worker", err)
+	}
+}
+
+func TestPoolExecutor_QueueFullPolicy(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      QueueFullPolicy
+		queueSize   int
+		wantDropped string
+		wantFailed  string
+	}{
+		{name: "reject", policy: QueueFullReject, queueSize: 1, wantFailed: "c"},
+		{name: "drop oldest", policy: QueueFullDropOldest, queueSize: 1, wantDropped: "b"},
+		{name: "drop oldest without queue", policy: QueueFullDropOldest, queueSize: 0, wantFailed: "b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exec := newBlockingExecutor()
+			pool := NewPoolExecutor(exec, 1, tt.queueSize)
+
+			// Occupy the only worker before switching to a policy that
+			// never waits.
+			futures := map[string]*Future{"a": pool.Submit(ToolConfig{Command: "a"})}
+			<-exec.started
+			pool.SetQueueFullPolicy(tt.policy)
+			for _, name := range []string{"b", "c"} {
+				futures[name] = pool.Submit(ToolConfig{Command: name})
+			}
+			close(exec.release)
+			if err := pool.Shutdown(context.Background()); err != nil {
+				t.Fatalf("Shutdown() error = %v", err)
+			}
+
+			for name, f := range futures {
+				_, err := f.Wait(context.Background())
+				var fullErr *QueueFullError
+				switch {
+				case name == tt.wantDropped:
+					if !errors.As(err, &fullErr) || !fullErr.Dropped {
+						t.Errorf("%s error = %v, want a dropped *QueueFullError", name, err)
+					}
+				case name == tt.wantFailed || (tt.queueSize == 0 && name == "c"):
+					if !errors.As(err, &fullErr) || fullErr.Dropped || fullErr.QueueSize != tt.queueSize {
+						t.Errorf("%s error = %v, want a rejecting *QueueFullError", name, err)
+					}
+				default:
+					if err != nil {
+						t.Errorf("%s error = %v, want it to run", name, err)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package cmdexec
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PrefixWriter is an io.Writer that prefixes every line written to it with
+// a label, in the style of docker-compose ("web | listening on :80"). It
+// keeps the output of commands running in parallel readable when they
+// share a terminal or log.
+//
+// Each line is passed to the underlying writer in a single Write call, so
+// lines from several PrefixWriters sharing a writer do not interleave as
+// long as that writer is safe for concurrent use (as *os.File is). Line
+// splitting follows LineWriter, so carriage-return progress updates become
+// separate lines. Call Flush to emit a trailing partial line.
+type PrefixWriter struct {
+	w      io.Writer
+	prefix func() string
+	lines  *LineWriter
+
+	mu  sync.Mutex
+	err error
+}
+
+// NewPrefixWriter returns a PrefixWriter that writes to w, prefixing each
+// line with "label | ".
+func NewPrefixWriter(label string, w io.Writer) *PrefixWriter {
+	prefix := label + " | "
+	return newPrefixWriter(w, func() string { return prefix })
+}
+
+// NewTimestampWriter returns a PrefixWriter that writes to w, prefixing
+// each line with the time it was completed, formatted with layout (for
+// example time.RFC3339), and a space.
+func NewTimestampWriter(layout string, w io.Writer) *PrefixWriter {
+	return newPrefixWriter(w, func() string { return time.Now().Format(layout) + " " })
+}
+
+func newPrefixWriter(w io.Writer, prefix func() string) *PrefixWriter {
+	pw := &PrefixWriter{w: w, prefix: prefix}
+	pw.lines = NewLineWriter(pw.writeLine)
+	return pw
+}
+
+// Write implements io.Writer. It returns the first error encountered while
+// writing to the underlying writer, if any.
+func (pw *PrefixWriter) Write(p []byte) (int, error) {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+	if pw.err != nil {
+		return 0, pw.err
+	}
+	n, _ := pw.lines.Write(p)
+	return n, pw.err
+}
+
+// Flush writes any buffered partial line, followed by a newline, and then
+// flushes the underlying writer if it supports flushing.
+func (pw *PrefixWriter) Flush() error {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+	_ = pw.lines.Flush()
+	if pw.err != nil {
+		return pw.err
+	}
+	if flush := flushFunc(pw.w); flush != nil {
+		return flush()
+	}
+	return nil
+}
+
+func (pw *PrefixWriter) writeLine(line string) {
+	if pw.err != nil {
+		return
+	}
+	if _, err := io.WriteString(pw.w, pw.prefix()+line+"\n"); err != nil {
+		pw.err = err
+	}
+}
+
+// writeLineLocked writes one complete line under the writer's lock.
+func (pw *PrefixWriter) writeLineLocked(line string) {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+	pw.writeLine(line)
+}
+
+// syncWriter serializes writes to a writer shared by several goroutines.
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (sw *syncWriter) Write(p []byte) (int, error) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return sw.w.Write(p) //nolint:wrapcheck // io.Writer passthrough
+}
+
+// Flush flushes the underlying writer, if it supports flushing.
+func (sw *syncWriter) Flush() error {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	if flush := flushFunc(sw.w); flush != nil {
+		return flush()
+	}
+	return nil
+}
+
+// DefaultPrefixLabel labels a command by the base name of its executable
+// and its index in the batch, for example "go#2".
+func DefaultPrefixLabel(index int, cfg ToolConfig) string {
+	return fmt.Sprintf("%s#%d", filepath.Base(cfg.Command), index)
+}
+
+// prefixedOutput streams the output of a batch of commands to a shared
+// writer with per-command labels.
+type prefixedOutput struct {
+	w         io.Writer
+	labelFunc func(index int, cfg ToolConfig) string
+}
+
+// apply returns copies of configs whose stdout and stderr lines are also
+// written to the shared writer, prefixed with labels padded to equal width.
+// Existing OnStdoutLine/OnStderrLine callbacks are preserved. The returned
+// flush function must be called once all commands have finished.
+func (po *prefixedOutput) apply(configs []ToolConfig) ([]ToolConfig, func()) {
+	labels := make([]string, len(configs))
+	width := 0
+	for i, cfg := range configs {
+		labels[i] = po.labelFunc(i, cfg)
+		width = max(width, len(labels[i]))
+	}
+
+	shared := &syncWriter{w: po.w}
+	out := make([]ToolConfig, len(configs))
+	for i, cfg := range configs {
+		label := labels[i] + strings.Repeat(" ", width-len(labels[i]))
+		stdout := NewPrefixWriter(label, shared)
+		stderr := NewPrefixWriter(label, shared)
+		cfg.OnStdoutLine = chainLineCallbacks(cfg.OnStdoutLine, stdout.writeLineLocked)
+		cfg.OnStderrLine = chainLineCallbacks(cfg.OnStderrLine, stderr.writeLineLocked)
+		out[i] = cfg
+	}
+	return out, func() { _ = shared.Flush() }
+}
+
+// chainLineCallbacks returns a callback that invokes first (if set) and then
+// second.
+func chainLineCallbacks(first, second func(string)) func(string) {
+	if first == nil {
+		return second
+	}
+	return func(line string) {
+		first(line)
+		second(line)
+	}
+}
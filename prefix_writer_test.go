@@ -0,0 +1,128 @@
+package cmdexec
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrefixWriter(t *testing.T) {
+	var buf bytes.Buffer
+	pw := NewPrefixWriter("web", &buf)
+	for _, chunk := range []string{"listen", "ing\nready\n", "partial"} {
+		if _, err := pw.Write([]byte(chunk)); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err := pw.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	want := "web | listening\nweb | ready\nweb | partial\n"
+	if buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestPrefixWriter_Error(t *testing.T) {
+	pw := NewPrefixWriter("x", failingWriter{})
+	if _, err := pw.Write([]byte("line\n")); err == nil {
+		t.Fatal("Write() error = nil, want the underlying error")
+	}
+	if _, err := pw.Write([]byte("more\n")); err == nil {
+		t.Error("subsequent Write() error = nil, want the sticky error")
+	}
+}
+
+func TestConcurrentExecutor_SetPrefixedOutput(t *testing.T) {
+	var out bytes.Buffer
+	ce := NewConcurrentExecutor(NewBasicExecutor())
+	ce.SetPrefixedOutput(&out, nil)
+
+	var ownLines []string
+	configs := []ToolConfig{
+		{Command: "sh", Args: []string{"-c", "echo one; echo two >&2"}},
+		{Command: "echo", Args: []string{"three"}, OnStdoutLine: func(l string) { ownLines = append(ownLines, l) }},
+	}
+	results, err := ce.ExecuteAll(context.Background(), configs)
+	if err != nil {
+		t.Fatalf("ExecuteAll() error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	sort.Strings(lines)
+	want := []string{"echo#1 | three", "sh#0   | one", "sh#0   | two"}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("prefixed output = %q, want %q", lines, want)
+	}
+
+	if results[0].Result.Output != "one\n" {
+		t.Errorf("captured Output = %q, want %q", results[0].Result.Output, "one\n")
+	}
+	if results[0].Config.OnStdoutLine != nil {
+		t.Error("ConcurrentResult.Config should be the caller's config")
+	}
+	if len(ownLines) != 1 || ownLines[0] != "three" {
+		t.Errorf("existing OnStdoutLine got %q, want [three]", ownLines)
+	}
+}
+
+func TestTimestampWriter(t *testing.T) {
+	var buf bytes.Buffer
+	tw := NewTimestampWriter(time.RFC3339, &buf)
+	before := time.Now().Truncate(time.Second)
+	_, _ = tw.Write([]byte("first\nsecond\n"))
+	after := time.Now()
+
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		stamp, text, ok := strings.Cut(line, " ")
+		if !ok {
+			t.Fatalf("line %q has no timestamp", line)
+		}
+		ts, err := time.Parse(time.RFC3339, stamp)
+		if err != nil {
+			t.Fatalf("timestamp %q does not parse: %v", stamp, err)
+		}
+		if ts.Before(before) || ts.After(after) {
+			t.Errorf("timestamp %v outside [%v, %v]", ts, before, after)
+		}
+		if text != "first" && text != "second" {
+			t.Errorf("line text = %q", text)
+		}
+	}
+}
+
+func TestBasicExecutor_Execute_StreamTimestampFormat(t *testing.T) {
+	var stream bytes.Buffer
+	result, err := NewBasicExecutor().Execute(context.Background(), ToolConfig{
+		Command:               "sh",
+		Args:                  []string{"-c", "echo hello; printf tail"},
+		StdoutWriter:          &stream,
+		StreamTimestampFormat: time.RFC3339Nano,
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Output != "hello\ntail" {
+		t.Errorf("Output = %q, captured output should not be stamped", result.Output)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(stream.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("streamed = %q, want 2 stamped lines", stream.String())
+	}
+	for i, want := range []string{"hello", "tail"} {
+		stamp, text, _ := strings.Cut(lines[i], " ")
+		if _, err := time.Parse(time.RFC3339Nano, stamp); err != nil || text != want {
+			t.Errorf("line %d = %q, want a timestamp followed by %q", i, lines[i], want)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package cmdexec
+
+import (
+	"context"
+)
+
+// PreparedConfig is a validated, immutable snapshot of a ToolConfig that can
+// be executed any number of times, including concurrently. It owns private
+// copies of Args and Env, so mutating the ToolConfig it was prepared from has
+// no effect on later executions.
+type PreparedConfig struct {
+	cfg ToolConfig
+}
+
+// Prepare validates cfg and returns an immutable PreparedConfig.
+//
+// Because a PreparedConfig is meant to be reused, a plain Stdin reader is
+// rejected: it would be consumed by the first execution. Use StdinFactory
+// (for example SharedStdin.Factory) instead.
+func Prepare(cfg ToolConfig) (*PreparedConfig, error) {
+	snapshot := cfg.Clone()
+	if err := snapshot.Validate(); err != nil {
+		return nil, err
+	}
+	if snapshot.Stdin != nil && snapshot.StdinFactory == nil && snapshot.StdinProvider == nil {
+		return nil, &ValidationError{
+			Field:   "Stdin",
+			Message: "use StdinFactory or StdinProvider instead of Stdin for prepared configs; a single reader is consumed after the first execution",
+		}
+	}
+	return &PreparedConfig{cfg: snapshot}, nil
+}
+
+// Config returns a fresh copy of the prepared configuration. Callers may
+// modify the returned value freely.
+func (p *PreparedConfig) Config() ToolConfig {
+	return p.cfg.Clone()
+}
+
+// Execute runs the prepared configuration with the given executor.
+func (p *PreparedConfig) Execute(ctx context.Context, executor Executor) (*ExecutionResult, error) {
+	return executor.Execute(ctx, p.Config()) //nolint:wrapcheck // delegation pattern
+}
@@ -0,0 +1,76 @@
+package cmdexec
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestPrepare_Validates(t *testing.T) {
+	_, err := Prepare(ToolConfig{})
+	var valErr *ValidationError
+	if !errors.As(err, &valErr) || valErr.Field != "Command" {
+		t.Fatalf("Prepare() error = %v, want ValidationError on Command", err)
+	}
+}
+
+func TestPrepare_RejectsPlainStdin(t *testing.T) {
+	_, err := Prepare(ToolConfig{Command: "cat", Stdin: strings.NewReader("x")})
+	var valErr *ValidationError
+	if !errors.As(err, &valErr) || valErr.Field != "Stdin" {
+		t.Fatalf("Prepare() error = %v, want ValidationError on Stdin", err)
+	}
+
+	_, err = Prepare(ToolConfig{
+		Command:       "cat",
+		Stdin:         strings.NewReader("x"),
+		StdinProvider: func() (io.Reader, error) { return strings.NewReader("y"), nil },
+	})
+	if err != nil {
+		t.Errorf("Prepare() error = %v, want StdinProvider to take precedence over Stdin", err)
+	}
+}
+
+func TestPreparedConfig_IsolatedFromSource(t *testing.T) {
+	cfg := ToolConfig{
+		Command: "echo",
+		Args:    []string{"original"},
+		Env:     map[string]string{"KEY": "original"},
+	}
+	prepared, err := Prepare(cfg)
+	if err != nil {
+		t.Fatalf("Prepare() error = %v", err)
+	}
+
+	cfg.Args[0] = "mutated"
+	cfg.Env["KEY"] = "mutated"
+
+	got := prepared.Config()
+	if got.Args[0] != "original" || got.Env["KEY"] != "original" {
+		t.Errorf("prepared config was affected by source mutation: %+v", got)
+	}
+
+	got.Args[0] = "changed"
+	if prepared.Config().Args[0] != "original" {
+		t.Error("mutating Config() result affected the prepared config")
+	}
+}
+
+func TestPreparedConfig_Execute(t *testing.T) {
+	prepared, err := Prepare(ToolConfig{Command: "echo", Args: []string{"hi"}})
+	if err != nil {
+		t.Fatalf("Prepare() error = %v", err)
+	}
+
+	mock := NewMockExecutor()
+	for i := 0; i < 3; i++ {
+		if _, err := prepared.Execute(context.Background(), mock); err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+	}
+	if got := len(mock.GetCallHistory()); got != 3 {
+		t.Errorf("call count = %d, want 3", got)
+	}
+}
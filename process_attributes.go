@@ -0,0 +1,92 @@
+package cmdexec
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"slices"
+)
+
+// ProcessAttributes are operating system attributes of the child process,
+// for callers that need more control than exec.Cmd offers portably. Each
+// field is supported on some platforms only; Validate rejects fields the
+// current platform does not support instead of ignoring them.
+type ProcessAttributes struct {
+	// Setpgid starts the command in a new process group, so signals sent to
+	// the parent's group, such as Ctrl+C in a terminal, do not reach it.
+	// Unix only.
+	Setpgid bool
+
+	// Setsid starts the command in a new session without a controlling
+	// terminal. A session has its own process group, so Setsid cannot be
+	// combined with Setpgid. Unix only.
+	Setsid bool
+
+	// NoNewPrivileges sets no_new_privs for the command, so neither it nor
+	// its descendants can gain privileges through setuid binaries or file
+	// capabilities. The calling process is not affected. Linux only.
+	NoNewPrivileges bool
+
+	// CreationFlags are process creation flags such as
+	// windows.CREATE_NO_WINDOW. They are added to the flags the executor
+	// sets itself. Windows only.
+	CreationFlags uint32
+
+	// Credential, if set, runs the command as another user, which usually
+	// requires privileges. Unix only.
+	Credential *Credential
+}
+
+// Credential is the user and groups a command runs as.
+type Credential struct {
+	UID uint32
+	GID uint32
+
+	// Groups are the supplementary group IDs. If empty, the command has
+	// none.
+	Groups []uint32
+}
+
+// validate checks that the attributes are consistent and supported on the
+// current platform.
+func (a *ProcessAttributes) validate() error {
+	if a == nil {
+		return nil
+	}
+	if a.Setpgid && a.Setsid {
+		return &ValidationError{Field: "ProcessAttributes", Message: "setpgid cannot be combined with setsid, which creates its own process group"}
+	}
+	if field := a.unsupportedField(); field != "" {
+		return &ValidationError{
+			Field:   "ProcessAttributes." + field,
+			Message: fmt.Sprintf("%s is not supported on %s", field, runtime.GOOS),
+		}
+	}
+	return nil
+}
+
+// clone returns a deep copy of the attributes.
+func (a *ProcessAttributes) clone() *ProcessAttributes {
+	if a == nil {
+		return nil
+	}
+	clone := *a
+	if a.Credential != nil {
+		cred := *a.Credential
+		cred.Groups = slices.Clone(a.Credential.Groups)
+		clone.Credential = &cred
+	}
+	return &clone
+}
+
+// runProcess runs cmd like runCommand. If attrs ask for no_new_privs, the
+// command is started from a dedicated thread that has it set.
+func runProcess(cmd *exec.Cmd, attrs *ProcessAttributes, onStart func(*os.Process)) error {
+	if attrs != nil && attrs.NoNewPrivileges {
+		return runWithNoNewPrivileges(func() error {
+			return runCommand(cmd, onStart)
+		})
+	}
+	return runCommand(cmd, onStart)
+}
@@ -0,0 +1,29 @@
+//go:build !unix && !windows
+
+package cmdexec
+
+import (
+	"os/exec"
+)
+
+// unsupportedField returns the name of the first set field, as none are
+// supported on this platform.
+func (a *ProcessAttributes) unsupportedField() string {
+	switch {
+	case a.Setpgid:
+		return "Setpgid"
+	case a.Setsid:
+		return "Setsid"
+	case a.NoNewPrivileges:
+		return "NoNewPrivileges"
+	case a.CreationFlags != 0:
+		return "CreationFlags"
+	case a.Credential != nil:
+		return "Credential"
+	}
+	return ""
+}
+
+// applyProcessAttributes is a no-op, since Validate rejects all attributes
+// on this platform.
+func applyProcessAttributes(_ *exec.Cmd, _ *ProcessAttributes) {}
@@ -0,0 +1,135 @@
+package cmdexec
+
+import (
+	"context"
+	"errors"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// procStat returns the pid, process group and session fields of the
+// /proc/self/stat line of a command.
+func procStat(t *testing.T, output string) (pid, pgrp, session string) {
+	t.Helper()
+	// The command name in parentheses precedes the state field.
+	end := strings.LastIndexByte(output, ')')
+	if end < 0 {
+		t.Fatalf("unexpected /proc/self/stat output %q", output)
+	}
+	fields := strings.Fields(output[end+1:])
+	if len(fields) < 4 {
+		t.Fatalf("unexpected /proc/self/stat output %q", output)
+	}
+	return strings.Fields(output)[0], fields[2], fields[3]
+}
+
+func TestProcessAttributes_ProcessGroupAndSession(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("reads /proc")
+	}
+	executor := NewBasicExecutor()
+	for _, tt := range []struct {
+		name        string
+		attrs       *ProcessAttributes
+		wantGroup   bool
+		wantSession bool
+	}{
+		{name: "none"},
+		{name: "setpgid", attrs: &ProcessAttributes{Setpgid: true}, wantGroup: true},
+		{name: "setsid", attrs: &ProcessAttributes{Setsid: true}, wantGroup: true, wantSession: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := executor.Execute(context.Background(), ToolConfig{
+				Command:           "cat",
+				Args:              []string{"/proc/self/stat"},
+				ProcessAttributes: tt.attrs,
+			})
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			pid, pgrp, session := procStat(t, result.Output)
+			if (pgrp == pid) != tt.wantGroup || (session == pid) != tt.wantSession {
+				t.Errorf("pid %s has process group %s and session %s, want own group %v and own session %v",
+					pid, pgrp, session, tt.wantGroup, tt.wantSession)
+			}
+		})
+	}
+}
+
+func TestProcessAttributes_NoNewPrivileges(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("no_new_privs is Linux only")
+	}
+	executor := NewBasicExecutor()
+	run := func(attrs *ProcessAttributes) string {
+		t.Helper()
+		result, err := executor.Execute(context.Background(), ToolConfig{
+			Command:           "grep",
+			Args:              []string{"NoNewPrivs", "/proc/self/status"},
+			ProcessAttributes: attrs,
+		})
+		if err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		return strings.Join(strings.Fields(result.Output), " ")
+	}
+	if got := run(&ProcessAttributes{NoNewPrivileges: true}); got != "NoNewPrivs: 1" {
+		t.Errorf("child status = %q, want no_new_privs set", got)
+	}
+	// The flag must not leak into threads that start later commands.
+	for range 10 {
+		if got := run(nil); got != "NoNewPrivs: 0" {
+			t.Fatalf("later child status = %q, want no_new_privs unset", got)
+		}
+	}
+}
+
+func TestProcessAttributes_Credential(t *testing.T) {
+	if runtime.GOOS != "linux" || os.Geteuid() != 0 {
+		t.Skip("changing credentials needs root on Linux")
+	}
+	result, err := NewBasicExecutor().Execute(context.Background(), ToolConfig{
+		Command:           "id",
+		Args:              []string{"-u"},
+		ProcessAttributes: &ProcessAttributes{Credential: &Credential{UID: 65534, GID: 65534}},
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if got := strings.TrimSpace(result.Output); got != "65534" {
+		t.Errorf("id -u = %q, want 65534", got)
+	}
+}
+
+func TestProcessAttributes_Validate(t *testing.T) {
+	var validationErr *ValidationError
+	cfg := ToolConfig{Command: "true", ProcessAttributes: &ProcessAttributes{Setpgid: true, Setsid: true}}
+	if err := cfg.Validate(); !errors.As(err, &validationErr) || validationErr.Field != "ProcessAttributes" {
+		t.Errorf("Validate() with setpgid and setsid error = %v, want *ValidationError", err)
+	}
+
+	unsupported := &ProcessAttributes{CreationFlags: 0x08000000}
+	field := "ProcessAttributes.CreationFlags"
+	if runtime.GOOS == "windows" {
+		unsupported, field = &ProcessAttributes{Setsid: true}, "ProcessAttributes.Setsid"
+	}
+	cfg.ProcessAttributes = unsupported
+	if err := cfg.Validate(); !errors.As(err, &validationErr) || validationErr.Field != field {
+		t.Errorf("Validate() with unsupported attribute error = %v, want *ValidationError for %s", err, field)
+	}
+}
+
+func TestProcessAttributes_Clone(t *testing.T) {
+	cfg := ToolConfig{
+		Command:           "true",
+		ProcessAttributes: &ProcessAttributes{Setpgid: true, Credential: &Credential{UID: 1, Groups: []uint32{2}}},
+	}
+	clone := cfg.Clone()
+	clone.ProcessAttributes.Setpgid = false
+	clone.ProcessAttributes.Credential.Groups[0] = 3
+	if !cfg.ProcessAttributes.Setpgid || cfg.ProcessAttributes.Credential.Groups[0] != 2 {
+		t.Errorf("mutating the clone changed the original: %+v", cfg.ProcessAttributes)
+	}
+}
@@ -0,0 +1,45 @@
+//go:build unix
+
+package cmdexec
+
+import (
+	"os/exec"
+	"slices"
+	"syscall" //nolint:depguard // exec.Cmd.SysProcAttr is a syscall type; x/sys has no equivalent
+)
+
+// unsupportedField returns the name of the first set field that is not
+// supported on this platform, or the empty string.
+func (a *ProcessAttributes) unsupportedField() string {
+	switch {
+	case a.NoNewPrivileges && !noNewPrivilegesSupported:
+		return "NoNewPrivileges"
+	case a.CreationFlags != 0:
+		return "CreationFlags"
+	}
+	return ""
+}
+
+// applyProcessAttributes sets the attributes on cmd, keeping the attributes
+// its CommandBuilder already set.
+func applyProcessAttributes(cmd *exec.Cmd, attrs *ProcessAttributes) {
+	if attrs == nil {
+		return
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	if attrs.Setpgid {
+		cmd.SysProcAttr.Setpgid = true
+	}
+	if attrs.Setsid {
+		cmd.SysProcAttr.Setsid = true
+	}
+	if cred := attrs.Credential; cred != nil {
+		cmd.SysProcAttr.Credential = &syscall.Credential{
+			Uid:    cred.UID,
+			Gid:    cred.GID,
+			Groups: slices.Clone(cred.Groups),
+		}
+	}
+}
@@ -0,0 +1,36 @@
+//go:build windows
+
+package cmdexec
+
+import (
+	"os/exec"
+	"syscall" //nolint:depguard // exec.Cmd.SysProcAttr is a syscall type; x/sys has no equivalent
+)
+
+// unsupportedField returns the name of the first set field that is not
+// supported on this platform, or the empty string.
+func (a *ProcessAttributes) unsupportedField() string {
+	switch {
+	case a.Setpgid:
+		return "Setpgid"
+	case a.Setsid:
+		return "Setsid"
+	case a.NoNewPrivileges:
+		return "NoNewPrivileges"
+	case a.Credential != nil:
+		return "Credential"
+	}
+	return ""
+}
+
+// applyProcessAttributes adds the creation flags to cmd, keeping the flags
+// its CommandBuilder already set.
+func applyProcessAttributes(cmd *exec.Cmd, attrs *ProcessAttributes) {
+	if attrs == nil || attrs.CreationFlags == 0 {
+		return
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.CreationFlags |= attrs.CreationFlags
+}
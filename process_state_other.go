@@ -0,0 +1,16 @@
+//go:build !unix
+
+package cmdexec
+
+import "os"
+
+// signalName returns the name of sig as reported by the runtime.
+func signalName(sig os.Signal) string {
+	return sig.String()
+}
+
+// terminationSignal always reports no signal on platforms without Unix
+// signals.
+func terminationSignal(_ *os.ProcessState) (signal string, coreDumped bool) {
+	return "", false
+}
@@ -0,0 +1,48 @@
+//go:build unix
+
+package cmdexec
+
+import (
+	"os"
+	"reflect"
+
+	"golang.org/x/sys/unix"
+)
+
+// waitStatus extracts the raw wait status of an exited process.
+// ProcessState.Sys returns a syscall.WaitStatus; it is converted to the
+// identical unix.WaitStatus type so that this package does not need to
+// import syscall.
+func waitStatus(state *os.ProcessState) (unix.WaitStatus, bool) {
+	if state == nil {
+		return 0, false
+	}
+	v := reflect.ValueOf(state.Sys())
+	t := reflect.TypeOf(unix.WaitStatus(0))
+	if !v.IsValid() || !v.Type().ConvertibleTo(t) {
+		return 0, false
+	}
+	ws, ok := v.Convert(t).Interface().(unix.WaitStatus)
+	return ws, ok
+}
+
+// signalName returns the conventional name of sig, such as "SIGTERM".
+func signalName(sig os.Signal) string {
+	if s, ok := sig.(unix.Signal); ok {
+		if name := unix.SignalName(s); name != "" {
+			return name
+		}
+	}
+	return sig.String()
+}
+
+// terminationSignal returns the name of the signal that terminated the
+// process, such as "SIGKILL", and whether it dumped core. The name is empty
+// if the process exited normally.
+func terminationSignal(state *os.ProcessState) (signal string, coreDumped bool) {
+	ws, ok := waitStatus(state)
+	if !ok || !ws.Signaled() {
+		return "", false
+	}
+	return unix.SignalName(ws.Signal()), ws.CoreDump()
+}
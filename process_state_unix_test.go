@@ -0,0 +1,59 @@
+//go:build unix
+
+package cmdexec
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestWaitStatus(t *testing.T) {
+	cmd := exec.Command("sh", "-c", "exit 7")
+	_ = cmd.Run()
+	ws, ok := waitStatus(cmd.ProcessState)
+	if !ok {
+		t.Fatal("waitStatus() failed for exited process")
+	}
+	if !ws.Exited() || ws.ExitStatus() != 7 {
+		t.Errorf("wait status = %v, want exit status 7", ws)
+	}
+
+	cmd = exec.Command("sh", "-c", "kill -TERM $$")
+	_ = cmd.Run()
+	ws, ok = waitStatus(cmd.ProcessState)
+	if !ok || !ws.Signaled() || ws.Signal() != unix.SIGTERM {
+		t.Errorf("wait status = %v, want signaled by SIGTERM", ws)
+	}
+
+	if _, ok := waitStatus(nil); ok {
+		t.Error("waitStatus(nil) should fail")
+	}
+}
+
+func TestBasicExecutor_Execute_TerminationSignal(t *testing.T) {
+	result, err := NewBasicExecutor().Execute(context.Background(), ToolConfig{
+		Command: "sh",
+		Args:    []string{"-c", "kill -KILL $$"},
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.ExitCode != -1 || result.Signal != "SIGKILL" || result.CoreDumped {
+		t.Errorf("result = exit %d, signal %q, core dumped %v; want -1, SIGKILL, false",
+			result.ExitCode, result.Signal, result.CoreDumped)
+	}
+
+	result, err = NewBasicExecutor().Execute(context.Background(), ToolConfig{
+		Command: "sh",
+		Args:    []string{"-c", "exit 3"},
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.ExitCode != 3 || result.Signal != "" {
+		t.Errorf("result = exit %d, signal %q; want 3 and no signal", result.ExitCode, result.Signal)
+	}
+}
@@ -0,0 +1,49 @@
+package cmdexec
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// newProgressCallback returns a line callback that matches cfg.ProgressPattern
+// against each line and reports the first capture group of the last match
+// to cfg.OnProgress. It is shared by both streams and serializes calls to
+// OnProgress. It returns nil if progress reporting is not configured.
+func newProgressCallback(cfg ToolConfig) func(string) {
+	if cfg.ProgressPattern == nil || cfg.OnProgress == nil {
+		return nil
+	}
+	var mu sync.Mutex
+	return func(line string) {
+		value, ok := matchProgress(cfg.ProgressPattern, line)
+		if !ok {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		cfg.OnProgress(value)
+	}
+}
+
+// matchProgress extracts the progress value from the last match of re in
+// line. Thousands separators are ignored.
+func matchProgress(re *regexp.Regexp, line string) (float64, bool) {
+	matches := re.FindAllStringSubmatch(line, -1)
+	if len(matches) == 0 {
+		return 0, false
+	}
+	last := matches[len(matches)-1]
+	value, err := strconv.ParseFloat(strings.ReplaceAll(last[1], ",", ""), 64)
+	if err != nil {
+		return 0, false
+	}
+	return value, true
+}
+
+// Common progress patterns for use with ToolConfig.ProgressPattern.
+var (
+	// ProgressPercent matches a percentage such as "42%" or "42.5 %".
+	ProgressPercent = regexp.MustCompile(`(\d+(?:\.\d+)?)\s*%`)
+)
@@ -0,0 +1,58 @@
+package cmdexec
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestMatchProgress(t *testing.T) {
+	tests := []struct {
+		name   string
+		re     *regexp.Regexp
+		line   string
+		want   float64
+		wantOK bool
+	}{
+		{"percent", ProgressPercent, "Downloading 42%", 42, true},
+		{"fractional percent", ProgressPercent, "[=====>   ] 57.5 %", 57.5, true},
+		{"last match wins", ProgressPercent, "10% ... 20%", 20, true},
+		{"no match", ProgressPercent, "starting", 0, false},
+		{"thousands separator", regexp.MustCompile(`([\d,]+) files`), "1,234 files", 1234, true},
+		{"unparsable", regexp.MustCompile(`step (\w+)`), "step two", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := matchProgress(tt.re, tt.line)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("matchProgress() = (%v, %v), want (%v, %v)", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestBasicExecutor_Execute_OnProgress(t *testing.T) {
+	var values []float64
+	_, err := NewBasicExecutor().Execute(context.Background(), ToolConfig{
+		Command:         "sh",
+		Args:            []string{"-c", `printf '10%%\r50%%\r'; echo 'done 100%' >&2`},
+		ProgressPattern: ProgressPercent,
+		OnProgress:      func(v float64) { values = append(values, v) },
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	// stdout and stderr are read concurrently, so the stderr value may
+	// arrive at any point; the stdout values must stay in order.
+	var stdoutValues []float64
+	for _, v := range values {
+		if v != 100 {
+			stdoutValues = append(stdoutValues, v)
+		}
+	}
+	if len(values) != 3 || !reflect.DeepEqual(stdoutValues, []float64{10, 50}) {
+		t.Errorf("progress values = %v, want 10 and 50 in order plus 100", values)
+	}
+}
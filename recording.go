@@ -0,0 +1,155 @@
+package cmdexec
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Transcript is a recorded sequence of executions, as written by
+// RecordingExecutor and served by ReplayExecutor.
+type Transcript struct {
+	Entries []TranscriptEntry `json:"entries"`
+}
+
+// TranscriptEntry is one recorded execution: the identifying parts of its
+// config, and its result or error. Args and Env values are redacted with
+// the Redactor of the config.
+type TranscriptEntry struct {
+	Command    string            `json:"command"`
+	Args       []string          `json:"args"`
+	WorkingDir string            `json:"workingDir,omitempty"`
+	Env        map[string]string `json:"env,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty"`
+
+	// Metadata is the metadata of the execution context; see WithMetadata.
+	Metadata map[string]string `json:"metadata,omitempty"`
+
+	// Result is the recorded result, or nil if the execution failed with
+	// an error.
+	Result *ExecutionResult `json:"result,omitempty"`
+
+	// Error is the message of the recorded error, if any.
+	Error string `json:"error,omitempty"`
+
+	// ErrorDetail records the type and fields of the error, so
+	// ReplayExecutor can return an error of the same type.
+	ErrorDetail *ErrorRecord `json:"errorDetail,omitempty"`
+}
+
+// newTranscriptEntry records cfg and the outcome of executing it.
+func newTranscriptEntry(cfg ToolConfig, result *ExecutionResult, err error) TranscriptEntry {
+	redactor := cfg.Redactor.bind(cfg.Env, cfg.Args)
+	entry := TranscriptEntry{
+		Command:    cfg.Command,
+		Args:       redactor.redactArgs(cfg.Args),
+		WorkingDir: cfg.WorkingDir,
+		Labels:     maps.Clone(cfg.Labels),
+	}
+	if result != nil {
+		copied := *result
+		entry.Result = &copied
+	}
+	if len(cfg.Env) > 0 {
+		entry.Env = make(map[string]string, len(cfg.Env))
+		for k, v := range cfg.Env {
+			entry.Env[k] = redactor.redact(v)
+		}
+	}
+	if err != nil {
+		entry.Error = err.Error()
+		entry.ErrorDetail = NewErrorRecord(err)
+	}
+	return entry
+}
+
+// commandLine renders the entry as a shell-like line with its working
+// directory, environment (sorted) and command line.
+func (e *TranscriptEntry) commandLine() string {
+	var b strings.Builder
+	if e.WorkingDir != "" {
+		fmt.Fprintf(&b, "cd %s && ", quoteIfNeeded(e.WorkingDir))
+	}
+	for _, key := range slices.Sorted(maps.Keys(e.Env)) {
+		fmt.Fprintf(&b, "%s=%s ", key, quoteIfNeeded(e.Env[key]))
+	}
+	b.WriteString(buildCommandString(e.Command, e.Args))
+	return b.String()
+}
+
+// ReadTranscript reads a transcript written by Transcript.WriteFile.
+func ReadTranscript(path string) (*Transcript, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // reading the caller's transcript is the purpose
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transcript: %w", err)
+	}
+	var t Transcript
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("failed to parse transcript %s: %w", path, err)
+	}
+	return &t, nil
+}
+
+// WriteFile writes the transcript to path as indented JSON, so recorded
+// fixtures can be reviewed and diffed.
+func (t *Transcript) WriteFile(path string) error {
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode transcript: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("failed to write transcript: %w", err)
+	}
+	return nil
+}
+
+// RecordingExecutor delegates to another executor and records each
+// execution in a Transcript, the "record" half of record/replay testing:
+// run an integration suite once against real tools, save the transcript,
+// and replay it later with ReplayExecutor.
+type RecordingExecutor struct {
+	executor Executor
+	path     string
+
+	mu         sync.Mutex
+	transcript Transcript
+}
+
+// NewRecordingExecutor returns a RecordingExecutor that records executions
+// on executor. Save writes the transcript to path.
+func NewRecordingExecutor(executor Executor, path string) *RecordingExecutor {
+	return &RecordingExecutor{executor: executor, path: path}
+}
+
+// Execute runs cfg on the wrapped executor and records the outcome.
+func (r *RecordingExecutor) Execute(ctx context.Context, cfg ToolConfig) (*ExecutionResult, error) {
+	result, err := r.executor.Execute(ctx, cfg)
+	r.mu.Lock()
+	entry := newTranscriptEntry(cfg, result, err)
+	entry.Metadata = maps.Clone(Metadata(ctx))
+	r.transcript.Entries = append(r.transcript.Entries, entry)
+	r.mu.Unlock()
+	return result, err //nolint:wrapcheck // delegation pattern
+}
+
+// IsAvailable delegates to the wrapped executor.
+func (r *RecordingExecutor) IsAvailable(command string) bool {
+	return r.executor.IsAvailable(command)
+}
+
+// Transcript returns a copy of the executions recorded so far.
+func (r *RecordingExecutor) Transcript() *Transcript {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return &Transcript{Entries: append([]TranscriptEntry(nil), r.transcript.Entries...)}
+}
+
+// Save writes the executions recorded so far to the transcript file.
+func (r *RecordingExecutor) Save() error {
+	return r.Transcript().WriteFile(r.path)
+}
@@ -0,0 +1,84 @@
+package cmdexec
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRecordingExecutor(t *testing.T) {
+	mock := NewMockExecutor()
+	mock.ExpectCommand("git").WillSucceed("abc123\n", 0).Build()
+	mock.ExpectCommand("deploy").WillError(errors.New("connection refused")).Build()
+	mock.SetAvailableCommand("git", true)
+
+	path := filepath.Join(t.TempDir(), "transcript.json")
+	rec := NewRecordingExecutor(mock, path)
+	ctx := context.Background()
+
+	result, err := rec.Execute(ctx, ToolConfig{
+		Command:    "git",
+		Args:       []string{"rev-parse", "--token=s3cret"},
+		WorkingDir: "/repo",
+		Env:        map[string]string{"TOKEN": "s3cret"},
+		Labels:     map[string]string{"request": "r-1"},
+		Redactor:   &Redactor{Secrets: []string{"s3cret"}},
+	})
+	if err != nil || result.Output != "abc123\n" {
+		t.Fatalf("Execute(git) = (%+v, %v), want the wrapped result", result, err)
+	}
+	if _, err := rec.Execute(ctx, ToolConfig{Command: "deploy"}); err == nil {
+		t.Fatal("Execute(deploy) error = nil, want the wrapped error")
+	}
+	if !rec.IsAvailable("git") {
+		t.Error("IsAvailable(git) = false, want true")
+	}
+
+	if err := rec.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	transcript, err := ReadTranscript(path)
+	if err != nil {
+		t.Fatalf("ReadTranscript() error = %v", err)
+	}
+	if len(transcript.Entries) != 2 {
+		t.Fatalf("transcript has %d entries, want 2", len(transcript.Entries))
+	}
+
+	git := transcript.Entries[0]
+	if git.Command != "git" || git.WorkingDir != "/repo" || git.Result == nil || git.Result.Output != "abc123\n" {
+		t.Errorf("git entry = %+v", git)
+	}
+	if git.Labels["request"] != "r-1" {
+		t.Errorf("git entry labels = %v, want the config's labels", git.Labels)
+	}
+	if git.Args[1] != "--token=[REDACTED]" || git.Env["TOKEN"] != "[REDACTED]" {
+		t.Errorf("git entry args %q env %q, want secrets redacted", git.Args, git.Env)
+	}
+	if deploy := transcript.Entries[1]; deploy.Result != nil || deploy.Error != "connection refused" {
+		t.Errorf("deploy entry = %+v, want the error message", deploy)
+	}
+}
+
+func TestReadTranscript_Errors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := ReadTranscript(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("ReadTranscript(missing) error = nil")
+	}
+	empty := filepath.Join(dir, "empty.json")
+	if err := (&Transcript{}).WriteFile(empty); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if transcript, err := ReadTranscript(empty); err != nil || len(transcript.Entries) != 0 {
+		t.Errorf("ReadTranscript(empty) = (%+v, %v)", transcript, err)
+	}
+	bad := filepath.Join(dir, "bad.json")
+	if err := os.WriteFile(bad, []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadTranscript(bad); err == nil {
+		t.Error("ReadTranscript(malformed) error = nil")
+	}
+}
@@ -0,0 +1,218 @@
+package cmdexec
+
+import (
+	"errors"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// sensitiveEnvMarkers are substrings of environment variable names whose
+// values ToolConfig.Redacted masks even without a Redactor.
+var sensitiveEnvMarkers = []string{"TOKEN", "SECRET", "PASSWORD", "PASSWD", "CREDENTIAL", "API_KEY", "APIKEY", "PRIVATE_KEY"}
+
+// defaultRedaction replaces redacted secrets when Redactor.Replacement is
+// empty.
+const defaultRedaction = "[REDACTED]"
+
+// Redactor removes secrets from execution results, error messages, and
+// debug logs, so that tokens passed on command lines or printed by tools do
+// not leak into persisted results. Set it as ToolConfig.Redactor.
+//
+// Redaction applies to what the executor returns and logs: the result's
+// Args, Output, Stderr, Combined, and Error fields, and the messages of
+// returned errors. Output streamed to StdoutWriter/StderrWriter and spool
+// files are not redacted.
+type Redactor struct {
+	// Secrets are literal strings to redact. Empty strings are ignored.
+	Secrets []string
+
+	// EnvKeys names environment variables whose values are redacted. Values
+	// are taken from ToolConfig.Env, falling back to the process
+	// environment.
+	EnvKeys []string
+
+	// SensitiveFlags names command-line flags, such as "--password", whose
+	// values are redacted. A value is the argument following the flag or
+	// the text after "=" in "--password=value", and it is redacted
+	// wherever it appears, including in output.
+	SensitiveFlags []string
+
+	// Patterns are regular expressions whose matches are redacted.
+	Patterns []*regexp.Regexp
+
+	// Replacement is substituted for each redacted secret. It defaults to
+	// "[REDACTED]".
+	Replacement string
+}
+
+// Redact returns s with all secrets and pattern matches replaced. EnvKeys
+// are resolved against the process environment only. It is safe to call on
+// a nil Redactor.
+func (r *Redactor) Redact(s string) string {
+	return r.bind(nil, nil).redact(s)
+}
+
+// bind returns a copy of r with the values of EnvKeys, looked up in env and
+// then the process environment, and the values of SensitiveFlags in args
+// added to Secrets. It returns nil for a nil Redactor.
+func (r *Redactor) bind(env map[string]string, args []string) *Redactor {
+	if r == nil {
+		return nil
+	}
+	bound := *r
+	bound.Secrets = append([]string(nil), r.Secrets...)
+	for _, key := range r.EnvKeys {
+		value, ok := env[key]
+		if !ok {
+			value = os.Getenv(key)
+		}
+		bound.Secrets = append(bound.Secrets, value)
+	}
+	bound.Secrets = append(bound.Secrets, r.flagValues(args)...)
+	bound.EnvKeys = nil
+	bound.SensitiveFlags = nil
+	return &bound
+}
+
+// flagValues returns the values of SensitiveFlags in args, given either as
+// the next argument or after an equals sign.
+func (r *Redactor) flagValues(args []string) []string {
+	var values []string
+	for i, arg := range args {
+		if slices.Contains(r.SensitiveFlags, arg) && i+1 < len(args) {
+			values = append(values, args[i+1])
+		} else if flag, value, ok := strings.Cut(arg, "="); ok && slices.Contains(r.SensitiveFlags, flag) {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func (r *Redactor) redact(s string) string {
+	if r == nil || s == "" {
+		return s
+	}
+	replacement := r.replacement()
+
+	// Replace longer secrets first so that a secret containing another is
+	// redacted as a whole.
+	secrets := make([]string, 0, len(r.Secrets))
+	for _, secret := range r.Secrets {
+		if secret != "" {
+			secrets = append(secrets, secret)
+		}
+	}
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+	for _, secret := range secrets {
+		s = strings.ReplaceAll(s, secret, replacement)
+	}
+	for _, re := range r.Patterns {
+		s = re.ReplaceAllLiteralString(s, replacement)
+	}
+	return s
+}
+
+// replacement returns the text substituted for secrets. It is safe to call
+// on a nil Redactor.
+func (r *Redactor) replacement() string {
+	if r == nil || r.Replacement == "" {
+		return defaultRedaction
+	}
+	return r.Replacement
+}
+
+// redactArgs returns a redacted copy of args, or args itself if r is nil.
+func (r *Redactor) redactArgs(args []string) []string {
+	if r == nil || args == nil {
+		return args
+	}
+	redacted := make([]string, len(args))
+	for i, a := range args {
+		redacted[i] = r.redact(a)
+	}
+	return redacted
+}
+
+// redactResult redacts the user-visible fields of result in place.
+func (r *Redactor) redactResult(result *ExecutionResult) {
+	if r == nil || result == nil {
+		return
+	}
+	result.Args = r.redactArgs(result.Args)
+	result.Output = r.redact(result.Output)
+	result.Stderr = r.redact(result.Stderr)
+	result.Combined = r.redact(result.Combined)
+	result.Error = r.redact(result.Error)
+}
+
+// redactError returns err with a redacted message. The original error stays
+// reachable through errors.Is and errors.As. If err carries a result, such
+// as RetryExhaustedError.LastResult, that result and the attempt history are
+// redacted as well.
+func (r *Redactor) redactError(err error) error {
+	if r == nil || err == nil {
+		return err
+	}
+	var retryErr *RetryExhaustedError
+	if errors.As(err, &retryErr) {
+		r.redactResult(retryErr.LastResult)
+		for i := range retryErr.History {
+			retryErr.History[i].Stderr = r.redact(retryErr.History[i].Stderr)
+			retryErr.History[i].Error = r.redact(retryErr.History[i].Error)
+		}
+	}
+	msg := err.Error()
+	redacted := r.redact(msg)
+	if redacted == msg {
+		return err
+	}
+	return &redactedError{err: err, msg: redacted}
+}
+
+// redactedError overrides the message of an error that mentions a secret.
+type redactedError struct {
+	err error
+	msg string
+}
+
+func (e *redactedError) Error() string {
+	return e.msg
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// Redacted renders the configuration as a shell-like command line, such as
+// "cd /src && CI=1 git push origin", for logs, error messages and audit
+// records. Arguments and environment values are redacted with the
+// configuration's Redactor. Values of environment variables whose names
+// suggest a secret, such as GITHUB_TOKEN or DB_PASSWORD, are masked even
+// without one.
+func (tc ToolConfig) Redacted() string {
+	entry := newTranscriptEntry(tc, nil, nil)
+	for key := range entry.Env {
+		if isSensitiveEnvKey(key) {
+			entry.Env[key] = tc.Redactor.replacement()
+		}
+	}
+	return entry.commandLine()
+}
+
+// String returns Redacted, so formatting a ToolConfig does not print
+// secrets.
+func (tc ToolConfig) String() string {
+	return tc.Redacted()
+}
+
+// isSensitiveEnvKey reports whether the name of an environment variable
+// suggests that its value is a secret.
+func isSensitiveEnvKey(key string) bool {
+	key = strings.ToUpper(key)
+	return slices.ContainsFunc(sensitiveEnvMarkers, func(marker string) bool {
+		return strings.Contains(key, marker)
+	})
+}
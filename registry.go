@@ -0,0 +1,128 @@
+package cmdexec
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// UnknownToolError is returned by Registry.Resolve for a name that was not
+// registered.
+type UnknownToolError struct {
+	Name string
+}
+
+func (e *UnknownToolError) Error() string {
+	return fmt.Sprintf("unknown tool %q", e.Name)
+}
+
+// Registry maps logical tool names, such as "formatter" or "linter", to the
+// configurations that implement them. Code refers to the stable names while
+// the actual binaries and their flags stay configurable, for example by end
+// users through LoadRegistry. A Registry is safe for concurrent use.
+type Registry struct {
+	mu    sync.RWMutex
+	tools map[string]ToolConfig
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{tools: make(map[string]ToolConfig)}
+}
+
+// LoadRegistry reads a JSON object mapping tool names to ToolConfigFiles
+// from r, such as {"formatter": {"command": "gofumpt", "args": ["-w"]}},
+// and returns a Registry of the validated configurations.
+func LoadRegistry(r io.Reader) (*Registry, error) {
+	var files map[string]ToolConfigFile
+	if err := decodeConfigFile(r, &files); err != nil {
+		return nil, err
+	}
+	registry := NewRegistry()
+	for name, file := range files {
+		cfg := file.ToolConfig()
+		if err := cfg.Validate(); err != nil {
+			return nil, fmt.Errorf("tool %q: %w", name, err)
+		}
+		registry.Register(name, cfg)
+	}
+	return registry, nil
+}
+
+// Register maps name to cfg, replacing any previous registration. cfg is
+// cloned, so later changes to it do not affect the registry.
+func (r *Registry) Register(name string, cfg ToolConfig) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tools[name] = cfg.Clone()
+}
+
+// Lookup returns a copy of the configuration registered for name.
+func (r *Registry) Lookup(name string) (ToolConfig, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	cfg, ok := r.tools[name]
+	if !ok {
+		return ToolConfig{}, false
+	}
+	return cfg.Clone(), true
+}
+
+// Names returns the registered names in sorted order.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return slices.Sorted(maps.Keys(r.tools))
+}
+
+// Resolve returns the configuration for an execution of the tool named by
+// cfg.Command. The registered configuration supplies the command; cfg.Args
+// are appended to its arguments, and the other non-zero fields of cfg are
+// applied on top as with ToolConfig.Merge. It returns an
+// *UnknownToolError if the name was not registered.
+func (r *Registry) Resolve(cfg ToolConfig) (ToolConfig, error) {
+	base, ok := r.Lookup(cfg.Command)
+	if !ok {
+		return ToolConfig{}, &UnknownToolError{Name: cfg.Command}
+	}
+	override := cfg
+	override.Command = ""
+	override.Args = nil
+	resolved := base.Merge(override)
+	resolved.Args = append(resolved.Args, cfg.Args...)
+	return resolved, nil
+}
+
+// RegistryExecutor wraps an Executor and resolves commands that name a
+// registered tool through a Registry before delegating. Other commands are
+// passed through unchanged, so tool names and plain commands can be mixed.
+type RegistryExecutor struct {
+	executor Executor
+	registry *Registry
+}
+
+// NewRegistryExecutor returns a RegistryExecutor delegating to executor.
+func NewRegistryExecutor(executor Executor, registry *Registry) *RegistryExecutor {
+	return &RegistryExecutor{executor: executor, registry: registry}
+}
+
+// Execute resolves cfg if its command is a registered tool name and runs
+// it with the wrapped executor.
+func (e *RegistryExecutor) Execute(ctx context.Context, cfg ToolConfig) (*ExecutionResult, error) {
+	if resolved, err := e.registry.Resolve(cfg); err == nil {
+		cfg = resolved
+	}
+	return e.executor.Execute(ctx, cfg) //nolint:wrapcheck // delegation pattern
+}
+
+// IsAvailable reports whether the command a tool name resolves to, or
+// command itself if it is not a registered name, is available.
+func (e *RegistryExecutor) IsAvailable(command string) bool {
+	if cfg, ok := e.registry.Lookup(command); ok {
+		command = cfg.Command
+	}
+	return e.executor.IsAvailable(command)
+}
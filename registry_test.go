@@ -0,0 +1,87 @@
+package cmdexec
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRegistry_Resolve(t *testing.T) {
+	registry := NewRegistry()
+	base := ToolConfig{Command: "gofumpt", Args: []string{"-l"}, Env: map[string]string{"GOFLAGS": "-mod=mod"}}
+	registry.Register("formatter", base)
+	base.Args[0] = "-w"
+
+	resolved, err := registry.Resolve(ToolConfig{Command: "formatter", Args: []string{"main.go"}, WorkingDir: "/src"})
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if resolved.Command != "gofumpt" || !slices.Equal(resolved.Args, []string{"-l", "main.go"}) || resolved.WorkingDir != "/src" {
+		t.Errorf("Resolve() = %s in %q, want gofumpt -l main.go in /src", CanonicalString(resolved), resolved.WorkingDir)
+	}
+	if resolved.Env["GOFLAGS"] != "-mod=mod" {
+		t.Errorf("Resolve() env = %v, want the registered env", resolved.Env)
+	}
+
+	// Resolving must not change the registered configuration.
+	again, _ := registry.Resolve(ToolConfig{Command: "formatter"})
+	if !slices.Equal(again.Args, []string{"-l"}) {
+		t.Errorf("second Resolve() args = %q, want [-l]", again.Args)
+	}
+
+	var unknown *UnknownToolError
+	if _, err := registry.Resolve(ToolConfig{Command: "linter"}); !errors.As(err, &unknown) || unknown.Name != "linter" {
+		t.Errorf("Resolve(linter) error = %v, want *UnknownToolError", err)
+	}
+	if got := registry.Names(); !slices.Equal(got, []string{"formatter"}) {
+		t.Errorf("Names() = %q", got)
+	}
+}
+
+func TestLoadRegistry(t *testing.T) {
+	registry, err := LoadRegistry(strings.NewReader(`{
+		"formatter": {"command": "gofumpt", "args": ["-w"]},
+		"linter": {"command": "golangci-lint", "args": ["run"], "timeout": "5m"}
+	}`))
+	if err != nil {
+		t.Fatalf("LoadRegistry() error = %v", err)
+	}
+	if got := registry.Names(); !slices.Equal(got, []string{"formatter", "linter"}) {
+		t.Errorf("Names() = %q", got)
+	}
+	if cfg, ok := registry.Lookup("linter"); !ok || cfg.Command != "golangci-lint" {
+		t.Errorf("Lookup(linter) = %+v, %v", cfg, ok)
+	}
+
+	var validationErr *ValidationError
+	_, err = LoadRegistry(strings.NewReader(`{"linter": {"command": "", "args": ["run"]}}`))
+	if !errors.As(err, &validationErr) || !strings.Contains(err.Error(), `"linter"`) {
+		t.Errorf("LoadRegistry(invalid) error = %v, want a *ValidationError naming the tool", err)
+	}
+}
+
+func TestRegistryExecutor(t *testing.T) {
+	mock := NewMockExecutor()
+	mock.ExpectCommandWithArgs("gofumpt", "-w", "main.go").WillSucceed("", 0).Build()
+	mock.ExpectCommandWithArgs("ls").WillSucceed("", 0).Build()
+	mock.AvailableCommands["gofumpt"] = true
+
+	registry := NewRegistry()
+	registry.Register("formatter", ToolConfig{Command: "gofumpt", Args: []string{"-w"}})
+	executor := NewRegistryExecutor(mock, registry)
+
+	if _, err := executor.Execute(context.Background(), ToolConfig{Command: "formatter", Args: []string{"main.go"}}); err != nil {
+		t.Errorf("Execute(formatter) error = %v", err)
+	}
+	if _, err := executor.Execute(context.Background(), ToolConfig{Command: "ls"}); err != nil {
+		t.Errorf("Execute(ls) error = %v", err)
+	}
+	if err := mock.AssertExpectationsMet(); err != nil {
+		t.Error(err)
+	}
+	if !executor.IsAvailable("formatter") || executor.IsAvailable("linter") {
+		t.Error("IsAvailable() should check the command a tool name resolves to")
+	}
+}
@@ -0,0 +1,150 @@
+package cmdexec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ReplayMismatchError is returned by ReplayExecutor.Execute when no unused
+// transcript entry matches the executed config.
+type ReplayMismatchError struct {
+	Command string
+}
+
+func (e *ReplayMismatchError) Error() string {
+	return fmt.Sprintf("no recorded execution matches %q", e.Command)
+}
+
+// ReplayOptions loosen how ReplayExecutor matches configs against
+// transcript entries, for recordings made on another machine or at another
+// time.
+type ReplayOptions struct {
+	// IgnoreWorkingDir matches entries regardless of the working directory.
+	IgnoreWorkingDir bool
+
+	// IgnoreEnv matches entries regardless of the environment.
+	IgnoreEnv bool
+
+	// Normalize, if set, is applied to the arguments and working directory
+	// of both the config and the entry before comparing them, for example
+	// to replace a temporary directory with a fixed placeholder.
+	Normalize func(s string) string
+
+	// Restamp moves the StartTime and EndTime of replayed results to the
+	// time of the replay, keeping their duration.
+	Restamp bool
+}
+
+// ReplayExecutor serves executions from a recorded Transcript instead of
+// running commands, the "replay" half of record/replay testing. Each entry
+// is served once, in transcript order among the entries matching a config.
+// Recorded errors are rebuilt from their ErrorDetail, so errors.As finds
+// the recorded error types; entries without one replay the message only.
+type ReplayExecutor struct {
+	transcript *Transcript
+	opts       ReplayOptions
+
+	mu   sync.Mutex
+	used []bool
+}
+
+// NewReplayExecutor returns a ReplayExecutor serving transcript.
+func NewReplayExecutor(transcript *Transcript, opts ReplayOptions) *ReplayExecutor {
+	return &ReplayExecutor{
+		transcript: transcript,
+		opts:       opts,
+		used:       make([]bool, len(transcript.Entries)),
+	}
+}
+
+// LoadReplayExecutor reads the transcript at path and returns a
+// ReplayExecutor serving it.
+func LoadReplayExecutor(path string, opts ReplayOptions) (*ReplayExecutor, error) {
+	transcript, err := ReadTranscript(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewReplayExecutor(transcript, opts), nil
+}
+
+// Execute returns the recorded outcome of the first unused entry matching
+// cfg, or a *ReplayMismatchError if there is none.
+func (r *ReplayExecutor) Execute(_ context.Context, cfg ToolConfig) (*ExecutionResult, error) {
+	want := newTranscriptEntry(cfg, nil, nil)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.transcript.Entries {
+		entry := &r.transcript.Entries[i]
+		if r.used[i] || !r.matches(entry, &want) {
+			continue
+		}
+		r.used[i] = true
+		if entry.ErrorDetail != nil {
+			return nil, entry.ErrorDetail.Err()
+		}
+		if entry.Error != "" {
+			return nil, errors.New(entry.Error)
+		}
+		return r.replayResult(entry.Result), nil
+	}
+	return nil, &ReplayMismatchError{Command: buildCommandString(want.Command, want.Args)}
+}
+
+// matches reports whether the recorded entry matches the entry of an
+// incoming config under the replay options.
+func (r *ReplayExecutor) matches(recorded, incoming *TranscriptEntry) bool {
+	normalize := r.opts.Normalize
+	if normalize == nil {
+		normalize = func(s string) string { return s }
+	}
+	if recorded.Command != incoming.Command ||
+		!slices.EqualFunc(recorded.Args, incoming.Args, func(a, b string) bool { return normalize(a) == normalize(b) }) {
+		return false
+	}
+	if !r.opts.IgnoreWorkingDir && normalize(recorded.WorkingDir) != normalize(incoming.WorkingDir) {
+		return false
+	}
+	return r.opts.IgnoreEnv || maps.Equal(recorded.Env, incoming.Env)
+}
+
+// replayResult returns a copy of a recorded result, restamped if requested.
+func (r *ReplayExecutor) replayResult(recorded *ExecutionResult) *ExecutionResult {
+	if recorded == nil {
+		return nil
+	}
+	result := *recorded
+	if r.opts.Restamp {
+		duration := result.EndTime.Sub(result.StartTime)
+		result.StartTime = time.Now()
+		result.EndTime = result.StartTime.Add(duration)
+	}
+	return &result
+}
+
+// IsAvailable reports whether the transcript records an execution of
+// command.
+func (r *ReplayExecutor) IsAvailable(command string) bool {
+	return slices.ContainsFunc(r.transcript.Entries, func(e TranscriptEntry) bool {
+		return e.Command == command
+	})
+}
+
+// Remaining returns the number of transcript entries not served yet, so a
+// test can check that the code under test ran every recorded command.
+func (r *ReplayExecutor) Remaining() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	remaining := 0
+	for _, used := range r.used {
+		if !used {
+			remaining++
+		}
+	}
+	return remaining
+}
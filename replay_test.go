@@ -0,0 +1,138 @@
+package cmdexec
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testTranscript() *Transcript {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return &Transcript{Entries: []TranscriptEntry{
+		{Command: "git", Args: []string{"fetch"}, WorkingDir: "/tmp/rec-1/repo", Result: &ExecutionResult{ExitCode: 1, StartTime: start, EndTime: start.Add(time.Second)}},
+		{Command: "git", Args: []string{"fetch"}, WorkingDir: "/tmp/rec-1/repo", Result: &ExecutionResult{Output: "fetched", StartTime: start, EndTime: start.Add(time.Second)}},
+		{Command: "deploy", Env: map[string]string{"STAGE": "prod"}, Error: "connection refused"},
+	}}
+}
+
+func TestReplayExecutor(t *testing.T) {
+	replay := NewReplayExecutor(testTranscript(), ReplayOptions{})
+	ctx := context.Background()
+	fetch := ToolConfig{Command: "git", Args: []string{"fetch"}, WorkingDir: "/tmp/rec-1/repo"}
+
+	// Matching entries are served once each, in order.
+	for i, want := range []int{1, 0} {
+		result, err := replay.Execute(ctx, fetch)
+		if err != nil || result.ExitCode != want {
+			t.Fatalf("fetch %d = (%+v, %v), want exit code %d", i, result, err, want)
+		}
+	}
+	var mismatch *ReplayMismatchError
+	if _, err := replay.Execute(ctx, fetch); !errors.As(err, &mismatch) || mismatch.Command != "git fetch" {
+		t.Errorf("third fetch error = %v, want *ReplayMismatchError", err)
+	}
+
+	if _, err := replay.Execute(ctx, ToolConfig{Command: "deploy"}); !errors.As(err, &mismatch) {
+		t.Errorf("deploy without env error = %v, want *ReplayMismatchError", err)
+	}
+	if replay.Remaining() != 1 {
+		t.Errorf("Remaining() = %d, want 1", replay.Remaining())
+	}
+	_, err := replay.Execute(ctx, ToolConfig{Command: "deploy", Env: map[string]string{"STAGE": "prod"}})
+	if err == nil || err.Error() != "connection refused" {
+		t.Errorf("deploy error = %v, want the recorded error", err)
+	}
+	if replay.Remaining() != 0 {
+		t.Errorf("Remaining() = %d, want 0", replay.Remaining())
+	}
+
+	if !replay.IsAvailable("git") || replay.IsAvailable("svn") {
+		t.Error("IsAvailable() should report the recorded commands only")
+	}
+}
+
+func TestRecordAndReplay_TypedError(t *testing.T) {
+	mock := NewMockExecutor()
+	mock.ExpectCommand("make").WillError(&TimeoutError{Command: "make", Timeout: time.Minute}).Build()
+	recorder := NewRecordingExecutor(mock, filepath.Join(t.TempDir(), "transcript.json"))
+	if _, err := recorder.Execute(context.Background(), ToolConfig{Command: "make"}); err == nil {
+		t.Fatal("recorded Execute() succeeded, want the timeout")
+	}
+	if err := recorder.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	replay, err := LoadReplayExecutor(recorder.path, ReplayOptions{})
+	if err != nil {
+		t.Fatalf("LoadReplayExecutor() error = %v", err)
+	}
+	_, err = replay.Execute(context.Background(), ToolConfig{Command: "make"})
+	var te *TimeoutError
+	if !errors.As(err, &te) || te.Timeout != time.Minute {
+		t.Errorf("replayed error = %#v, want the recorded *TimeoutError", err)
+	}
+}
+
+func TestReplayExecutor_Options(t *testing.T) {
+	ctx := context.Background()
+	cfg := ToolConfig{Command: "git", Args: []string{"fetch"}, WorkingDir: "/tmp/rec-2/repo"}
+
+	strict := NewReplayExecutor(testTranscript(), ReplayOptions{})
+	if _, err := strict.Execute(ctx, cfg); err == nil {
+		t.Error("Execute() in another directory succeeded without options")
+	}
+
+	normalized := NewReplayExecutor(testTranscript(), ReplayOptions{
+		Normalize: func(s string) string {
+			if rest, ok := strings.CutPrefix(s, "/tmp/"); ok {
+				_, after, _ := strings.Cut(rest, "/")
+				return "$TMP/" + after
+			}
+			return s
+		},
+	})
+	if _, err := normalized.Execute(ctx, cfg); err != nil {
+		t.Errorf("Execute() with Normalize error = %v", err)
+	}
+
+	ignoring := NewReplayExecutor(testTranscript(), ReplayOptions{IgnoreWorkingDir: true, IgnoreEnv: true, Restamp: true})
+	before := time.Now()
+	result, err := ignoring.Execute(ctx, cfg)
+	if err != nil {
+		t.Fatalf("Execute() ignoring the working directory error = %v", err)
+	}
+	if result.StartTime.Before(before) || result.EndTime.Sub(result.StartTime) != time.Second {
+		t.Errorf("restamped result times %v to %v, want now with a 1s duration", result.StartTime, result.EndTime)
+	}
+	if _, err := ignoring.Execute(ctx, ToolConfig{Command: "deploy"}); err == nil || err.Error() != "connection refused" {
+		t.Errorf("deploy ignoring env error = %v, want the recorded error", err)
+	}
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transcript.json")
+	rec := NewRecordingExecutor(NewBasicExecutor(), path)
+	cfg := ToolConfig{Command: "echo", Args: []string{"hello"}}
+	recorded, err := rec.Execute(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("recording Execute() error = %v", err)
+	}
+	if err := rec.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	replay, err := LoadReplayExecutor(path, ReplayOptions{})
+	if err != nil {
+		t.Fatalf("LoadReplayExecutor() error = %v", err)
+	}
+	replayed, err := replay.Execute(context.Background(), cfg)
+	if err != nil || replayed.Output != recorded.Output {
+		t.Errorf("replayed = (%+v, %v), want output %q", replayed, err, recorded.Output)
+	}
+	if _, err := LoadReplayExecutor(filepath.Join(t.TempDir(), "missing.json"), ReplayOptions{}); err == nil {
+		t.Error("LoadReplayExecutor(missing) error = nil")
+	}
+}
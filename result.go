@@ -3,8 +3,14 @@ package cmdexec
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 // ExecutionResult stores the result of executing a command.
@@ -24,9 +30,23 @@ type ExecutionResult struct {
 	// Stderr is the stderr output
 	Stderr string `json:"stderr"`
 
-	// ExitCode is the exit code of the command
+	// Combined is stdout and stderr interleaved in the order the process
+	// wrote them. It is only populated when ToolConfig.CombineOutput is set.
+	Combined string `json:"combined,omitempty"`
+
+	// ExitCode is the exit code of the command, or -1 if it was killed by
+	// a signal.
 	ExitCode int `json:"exitCode"`
 
+	// Signal is the name of the signal that terminated the command, such
+	// as "SIGKILL" after an out-of-memory kill, or empty if it exited
+	// normally. It is only set on Unix.
+	Signal string `json:"signal,omitempty"`
+
+	// CoreDumped reports whether the terminating signal produced a core
+	// dump.
+	CoreDumped bool `json:"coreDumped,omitempty"`
+
 	// Error contains any error message if the execution failed
 	Error string `json:"error,omitempty"`
 
@@ -44,6 +64,102 @@ type ExecutionResult struct {
 
 	// StderrTruncated indicates stderr was truncated due to MaxStderrBytes limit.
 	StderrTruncated bool `json:"stderrTruncated,omitempty"`
+
+	// StdoutBytesTotal is the number of bytes the command wrote to stdout,
+	// including any dropped by MaxStdoutBytes or ToolConfig.CaptureFilter.
+	StdoutBytesTotal int64 `json:"stdoutBytesTotal,omitempty"`
+
+	// StderrBytesTotal is the number of bytes the command wrote to stderr,
+	// including any dropped by MaxStderrBytes or ToolConfig.CaptureFilter.
+	StderrBytesTotal int64 `json:"stderrBytesTotal,omitempty"`
+
+	// StdoutBytesDropped is the number of stdout bytes discarded because of
+	// MaxStdoutBytes.
+	StdoutBytesDropped int64 `json:"stdoutBytesDropped,omitempty"`
+
+	// StderrBytesDropped is the number of stderr bytes discarded because of
+	// MaxStderrBytes.
+	StderrBytesDropped int64 `json:"stderrBytesDropped,omitempty"`
+
+	// StdoutSpoolPath is the temporary file holding the complete stdout when
+	// it exceeded ToolConfig.SpoolThreshold. Output then holds only the head.
+	StdoutSpoolPath string `json:"stdoutSpoolPath,omitempty"`
+
+	// StderrSpoolPath is like StdoutSpoolPath, for stderr.
+	StderrSpoolPath string `json:"stderrSpoolPath,omitempty"`
+
+	// StdoutSHA256 is the hex-encoded SHA-256 digest of everything the
+	// command wrote to stdout, including output that was truncated or
+	// filtered from Output. It is only set if ToolConfig.ChecksumStdout is
+	// true.
+	StdoutSHA256 string `json:"stdoutSha256,omitempty"`
+
+	// FallbackDepth is 0 if the configured command produced this result,
+	// or n if the nth command of its ToolConfig.Fallback chain did.
+	FallbackDepth int `json:"fallbackDepth,omitempty"`
+
+	// Attempts is the number of attempts made when retries are configured
+	// with ToolConfig.MaxRetries or RetryPolicy, so a successful result with
+	// Attempts > 1 succeeded only after retrying. It is zero otherwise.
+	Attempts int `json:"attempts,omitempty"`
+
+	// RetryWait is the total time spent waiting between attempts.
+	RetryWait time.Duration `json:"retryWait,omitempty"`
+
+	// Labels are a copy of ToolConfig.Labels.
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// OutputBytes returns stdout as a byte slice. Output is captured byte for
+// byte, so this is exact even for binary data such as tar streams.
+func (er *ExecutionResult) OutputBytes() []byte {
+	return []byte(er.Output)
+}
+
+// StderrBytes returns stderr as a byte slice.
+func (er *ExecutionResult) StderrBytes() []byte {
+	return []byte(er.Stderr)
+}
+
+// StdoutReader returns a reader over the complete stdout: the spool file if
+// stdout was spooled, otherwise Output. The caller must close it.
+func (er *ExecutionResult) StdoutReader() (io.ReadCloser, error) {
+	return openOutput(er.StdoutSpoolPath, er.Output)
+}
+
+// StderrReader is like StdoutReader, for stderr.
+func (er *ExecutionResult) StderrReader() (io.ReadCloser, error) {
+	return openOutput(er.StderrSpoolPath, er.Stderr)
+}
+
+func openOutput(spoolPath, inMemory string) (io.ReadCloser, error) {
+	if spoolPath == "" {
+		return io.NopCloser(strings.NewReader(inMemory)), nil
+	}
+	f, err := os.Open(spoolPath) //nolint:gosec // path was created by this package
+	if err != nil {
+		return nil, fmt.Errorf("failed to open spooled output: %w", err)
+	}
+	return f, nil
+}
+
+// Cleanup removes the result's spool files, if any. It is safe to call more
+// than once and on a nil result.
+func (er *ExecutionResult) Cleanup() error {
+	if er == nil {
+		return nil
+	}
+	var errs []error
+	for _, path := range []string{er.StdoutSpoolPath, er.StderrSpoolPath} {
+		if path == "" {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, fmt.Errorf("failed to remove spool file: %w", err))
+		}
+		untrackTempResource(path)
+	}
+	return errors.Join(errs...)
 }
 
 // Duration calculates the execution time.
@@ -73,38 +189,74 @@ func (er *ExecutionResult) Validate() error {
 }
 
 // Custom JSON marshaling for time fields to ensure consistent format.
+//
+// JSON strings cannot hold arbitrary bytes, so output that is not valid
+// UTF-8 is additionally encoded as base64 in outputBytes/stderrBytes, which
+// take precedence when unmarshaling.
 type executionResultJSON struct {
-	Command         string   `json:"command"`
-	Args            []string `json:"args"`
-	WorkingDir      string   `json:"workingDir"`
-	Output          string   `json:"output"`
-	Stderr          string   `json:"stderr"`
-	ExitCode        int      `json:"exitCode"`
-	Error           string   `json:"error,omitempty"`
-	StartTime       string   `json:"startTime"`
-	EndTime         string   `json:"endTime"`
-	Duration        string   `json:"duration"`
-	TimedOut        bool     `json:"timedOut,omitempty"`
-	StdoutTruncated bool     `json:"stdoutTruncated,omitempty"`
-	StderrTruncated bool     `json:"stderrTruncated,omitempty"`
+	Command            string            `json:"command"`
+	Args               []string          `json:"args"`
+	WorkingDir         string            `json:"workingDir"`
+	Output             string            `json:"output"`
+	Stderr             string            `json:"stderr"`
+	OutputBytes        []byte            `json:"outputBytes,omitempty"`
+	StderrBytes        []byte            `json:"stderrBytes,omitempty"`
+	Combined           string            `json:"combined,omitempty"`
+	ExitCode           int               `json:"exitCode"`
+	Signal             string            `json:"signal,omitempty"`
+	CoreDumped         bool              `json:"coreDumped,omitempty"`
+	Error              string            `json:"error,omitempty"`
+	StartTime          string            `json:"startTime"`
+	EndTime            string            `json:"endTime"`
+	Duration           string            `json:"duration"`
+	TimedOut           bool              `json:"timedOut,omitempty"`
+	StdoutTruncated    bool              `json:"stdoutTruncated,omitempty"`
+	StderrTruncated    bool              `json:"stderrTruncated,omitempty"`
+	StdoutBytesTotal   int64             `json:"stdoutBytesTotal,omitempty"`
+	StderrBytesTotal   int64             `json:"stderrBytesTotal,omitempty"`
+	StdoutBytesDropped int64             `json:"stdoutBytesDropped,omitempty"`
+	StderrBytesDropped int64             `json:"stderrBytesDropped,omitempty"`
+	StdoutSpoolPath    string            `json:"stdoutSpoolPath,omitempty"`
+	StderrSpoolPath    string            `json:"stderrSpoolPath,omitempty"`
+	StdoutSHA256       string            `json:"stdoutSha256,omitempty"`
+	FallbackDepth      int               `json:"fallbackDepth,omitempty"`
+	Attempts           int               `json:"attempts,omitempty"`
+	RetryWait          string            `json:"retryWait,omitempty"`
+	Labels             map[string]string `json:"labels,omitempty"`
 }
 
 // MarshalJSON implements custom JSON marshaling for ExecutionResult.
 func (er ExecutionResult) MarshalJSON() ([]byte, error) {
 	data, err := json.Marshal(executionResultJSON{
-		Command:         er.Command,
-		Args:            er.Args,
-		WorkingDir:      er.WorkingDir,
-		Output:          er.Output,
-		Stderr:          er.Stderr,
-		ExitCode:        er.ExitCode,
-		Error:           er.Error,
-		StartTime:       er.StartTime.Format(time.RFC3339Nano),
-		EndTime:         er.EndTime.Format(time.RFC3339Nano),
-		Duration:        er.Duration().String(),
-		TimedOut:        er.TimedOut,
-		StdoutTruncated: er.StdoutTruncated,
-		StderrTruncated: er.StderrTruncated,
+		Command:            er.Command,
+		Args:               er.Args,
+		WorkingDir:         er.WorkingDir,
+		Output:             er.Output,
+		Stderr:             er.Stderr,
+		OutputBytes:        binaryOnly(er.Output),
+		StderrBytes:        binaryOnly(er.Stderr),
+		Combined:           er.Combined,
+		ExitCode:           er.ExitCode,
+		Signal:             er.Signal,
+		CoreDumped:         er.CoreDumped,
+		Error:              er.Error,
+		StartTime:          er.StartTime.Format(time.RFC3339Nano),
+		EndTime:            er.EndTime.Format(time.RFC3339Nano),
+		Duration:           er.Duration().String(),
+		TimedOut:           er.TimedOut,
+		StdoutTruncated:    er.StdoutTruncated,
+		StderrTruncated:    er.StderrTruncated,
+		StdoutBytesTotal:   er.StdoutBytesTotal,
+		StderrBytesTotal:   er.StderrBytesTotal,
+		StdoutBytesDropped: er.StdoutBytesDropped,
+		StderrBytesDropped: er.StderrBytesDropped,
+		StdoutSpoolPath:    er.StdoutSpoolPath,
+		StderrSpoolPath:    er.StderrSpoolPath,
+		StdoutSHA256:       er.StdoutSHA256,
+		FallbackDepth:      er.FallbackDepth,
+		Attempts:           er.Attempts,
+		RetryWait:          durationString(er.RetryWait),
+		Labels:             er.Labels,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal ExecutionResult: %w", err)
@@ -129,18 +281,63 @@ func (er *ExecutionResult) UnmarshalJSON(data []byte) error {
 		return fmt.Errorf("invalid endTime format: %w", err)
 	}
 
+	var retryWait time.Duration
+	if aux.RetryWait != "" {
+		retryWait, err = time.ParseDuration(aux.RetryWait)
+		if err != nil {
+			return fmt.Errorf("invalid retryWait format: %w", err)
+		}
+	}
+
 	er.Command = aux.Command
 	er.Args = aux.Args
 	er.WorkingDir = aux.WorkingDir
 	er.Output = aux.Output
+	if aux.OutputBytes != nil {
+		er.Output = string(aux.OutputBytes)
+	}
 	er.Stderr = aux.Stderr
+	if aux.StderrBytes != nil {
+		er.Stderr = string(aux.StderrBytes)
+	}
+	er.Combined = aux.Combined
 	er.ExitCode = aux.ExitCode
+	er.Signal = aux.Signal
+	er.CoreDumped = aux.CoreDumped
 	er.Error = aux.Error
 	er.StartTime = startTime
 	er.EndTime = endTime
 	er.TimedOut = aux.TimedOut
 	er.StdoutTruncated = aux.StdoutTruncated
 	er.StderrTruncated = aux.StderrTruncated
+	er.StdoutBytesTotal = aux.StdoutBytesTotal
+	er.StderrBytesTotal = aux.StderrBytesTotal
+	er.StdoutBytesDropped = aux.StdoutBytesDropped
+	er.StderrBytesDropped = aux.StderrBytesDropped
+	er.StdoutSpoolPath = aux.StdoutSpoolPath
+	er.StderrSpoolPath = aux.StderrSpoolPath
+	er.StdoutSHA256 = aux.StdoutSHA256
+	er.FallbackDepth = aux.FallbackDepth
+	er.Attempts = aux.Attempts
+	er.RetryWait = retryWait
+	er.Labels = aux.Labels
 
 	return nil
 }
+
+// durationString formats d like Duration, or returns "" for zero so that
+// it is omitted.
+func durationString(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return d.String()
+}
+
+// binaryOnly returns s as bytes if it is not valid UTF-8, and nil otherwise.
+func binaryOnly(s string) []byte {
+	if utf8.ValidString(s) {
+		return nil
+	}
+	return []byte(s)
+}
@@ -0,0 +1,85 @@
+package cmdexec
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestExecutionResult_JSONRoundTrip(t *testing.T) {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+	orig := ExecutionResult{
+		Command:            "make",
+		Args:               []string{"all"},
+		WorkingDir:         "/src",
+		Output:             "out",
+		Stderr:             "err",
+		Combined:           "out\nerr",
+		ExitCode:           -1,
+		Signal:             "SIGSEGV",
+		CoreDumped:         true,
+		StartTime:          start,
+		EndTime:            start.Add(time.Second),
+		StdoutTruncated:    true,
+		StdoutBytesTotal:   1 << 20,
+		StdoutBytesDropped: 1<<20 - 3,
+		StdoutSpoolPath:    "/tmp/cmdexec-stdout-1",
+		StderrSpoolPath:    "/tmp/cmdexec-stderr-1",
+		StdoutSHA256:       "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		FallbackDepth:      1,
+		Attempts:           3,
+		RetryWait:          1500 * time.Millisecond,
+		Labels:             map[string]string{"tenant": "acme"},
+	}
+
+	data, err := json.Marshal(orig)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	var got ExecutionResult
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, orig) {
+		t.Errorf("round trip = %+v, want %+v", got, orig)
+	}
+}
+
+func TestExecutionResult_JSONBinaryOutput(t *testing.T) {
+	binary := string([]byte{0x1f, 0x8b, 0x08, 0x00, 0xff, 0xfe})
+	orig := ExecutionResult{
+		Command:   "gzip",
+		Output:    binary,
+		Stderr:    "plain text",
+		StartTime: time.Unix(0, 0).UTC(),
+		EndTime:   time.Unix(1, 0).UTC(),
+	}
+
+	data, err := json.Marshal(orig)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if _, ok := raw["outputBytes"]; !ok {
+		t.Error("outputBytes missing for non-UTF-8 output")
+	}
+	if _, ok := raw["stderrBytes"]; ok {
+		t.Error("stderrBytes should be omitted for valid UTF-8")
+	}
+
+	var got ExecutionResult
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !bytes.Equal(got.OutputBytes(), []byte(binary)) {
+		t.Errorf("OutputBytes() = %x, want %x", got.OutputBytes(), []byte(binary))
+	}
+	if got.Stderr != "plain text" {
+		t.Errorf("Stderr = %q, want %q", got.Stderr, "plain text")
+	}
+}
@@ -0,0 +1,124 @@
+package cmdexec
+
+import (
+	"context"
+	"regexp"
+	"time"
+	"unicode/utf8"
+)
+
+// RetryPolicy decides whether and when BasicExecutor retries a failed
+// attempt. Set ToolConfig.RetryPolicy to implement custom strategies such
+// as exponential backoff; without one, MaxRetries and RetryDelay describe a
+// fixed policy.
+//
+// An attempt fails when it returns an error or a non-zero exit code. The
+// executor never retries a missing executable or an execution whose context
+// is done.
+type RetryPolicy interface {
+	// NextDelay returns how long to wait before the next attempt, given the
+	// number of attempts made so far, starting at 1. It returns false when
+	// no attempts remain, in which case Execute returns a
+	// *RetryExhaustedError.
+	NextDelay(attempt int) (time.Duration, bool)
+
+	// ShouldRetry reports whether a failed attempt is worth retrying. If it
+	// returns false, Execute returns the attempt's result and error as they
+	// are, as if retries were not configured.
+	ShouldRetry(result *ExecutionResult, err error) bool
+}
+
+// fixedRetryPolicy retries every failure up to maxRetries times, waiting
+// delay between attempts. It implements MaxRetries and RetryDelay.
+type fixedRetryPolicy struct {
+	maxRetries int
+	delay      time.Duration
+}
+
+func (p fixedRetryPolicy) NextDelay(attempt int) (time.Duration, bool) {
+	return p.delay, attempt <= p.maxRetries
+}
+
+func (p fixedRetryPolicy) ShouldRetry(*ExecutionResult, error) bool {
+	return true
+}
+
+// retryBudget decides whether there is time left for another attempt.
+type retryBudget struct {
+	start      time.Time
+	maxElapsed time.Duration
+}
+
+func newRetryBudget(maxElapsed time.Duration) retryBudget {
+	return retryBudget{start: time.Now(), maxElapsed: maxElapsed}
+}
+
+// allows reports whether an attempt expected to take as long as the
+// previous one may start after waiting delay. The estimate is only checked
+// against the context deadline, since an attempt that has started is not
+// interrupted by MaxRetryElapsed.
+func (b retryBudget) allows(ctx context.Context, delay, previous time.Duration) bool {
+	if b.maxElapsed > 0 && time.Since(b.start)+delay >= b.maxElapsed {
+		return false
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay+previous {
+		return false
+	}
+	return true
+}
+
+// attemptStderrLimit is the number of trailing stderr bytes kept in an
+// AttemptSummary.
+const attemptStderrLimit = 1024
+
+// summarizeAttempt returns the AttemptSummary of a failed attempt.
+func summarizeAttempt(attempt int, result *ExecutionResult, err error, duration time.Duration) AttemptSummary {
+	summary := AttemptSummary{Attempt: attempt, ExitCode: -1, Duration: duration}
+	if result != nil {
+		summary.ExitCode = result.ExitCode
+		summary.Stderr = result.Stderr
+		if len(summary.Stderr) > attemptStderrLimit {
+			tail := summary.Stderr[len(summary.Stderr)-attemptStderrLimit:]
+			// Do not start in the middle of a UTF-8 sequence.
+			for len(tail) > 0 && !utf8.RuneStart(tail[0]) {
+				tail = tail[1:]
+			}
+			summary.Stderr = tail
+		}
+	}
+	if err != nil {
+		summary.Error = err.Error()
+	}
+	return summary
+}
+
+// retryIfPolicy restricts a policy to the failures accepted by retryIf.
+type retryIfPolicy struct {
+	RetryPolicy
+	retryIf func(*ExecutionResult, error) bool
+}
+
+func (p retryIfPolicy) ShouldRetry(result *ExecutionResult, err error) bool {
+	return p.retryIf(result, err) && p.RetryPolicy.ShouldRetry(result, err)
+}
+
+// retryPolicy returns the policy that governs retries of tc.
+func (tc *ToolConfig) retryPolicy() RetryPolicy {
+	var policy RetryPolicy = fixedRetryPolicy{maxRetries: tc.MaxRetries, delay: tc.RetryDelay}
+	if tc.RetryPolicy != nil {
+		policy = tc.RetryPolicy
+	}
+	if tc.RetryIf != nil {
+		policy = retryIfPolicy{RetryPolicy: policy, retryIf: tc.RetryIf}
+	}
+	return policy
+}
+
+// RetryIfStderrMatches returns a ToolConfig.RetryIf predicate that retries
+// attempts whose stderr matches re, such as "resource temporarily
+// unavailable" or "lock held" messages.
+func RetryIfStderrMatches(re *regexp.Regexp) func(*ExecutionResult, error) bool {
+	return func(result *ExecutionResult, _ error) bool {
+		return result != nil && re.MatchString(result.Stderr)
+	}
+}
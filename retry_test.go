@@ -0,0 +1,406 @@
+package cmdexec
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recordingRetryPolicy allows maxAttempts attempts, retries failures for
+// which retry returns true, and records the attempts it was asked about.
+type recordingRetryPolicy struct {
+	maxAttempts int
+	retry       func(*ExecutionResult, error) bool
+	attempts    []int
+}
+
+func (p *recordingRetryPolicy) NextDelay(attempt int) (time.Duration, bool) {
+	p.attempts = append(p.attempts, attempt)
+	return time.Millisecond, attempt < p.maxAttempts
+}
+
+func (p *recordingRetryPolicy) ShouldRetry(result *ExecutionResult, err error) bool {
+	return p.retry == nil || p.retry(result, err)
+}
+
+func TestBasicExecutor_Execute_RetryPolicyExhausted(t *testing.T) {
+	policy := &recordingRetryPolicy{maxAttempts: 3}
+	result, err := NewBasicExecutor().Execute(context.Background(), ToolConfig{
+		Command:     "sh",
+		Args:        []string{"-c", "exit 1"},
+		RetryPolicy: policy,
+	})
+	if result != nil {
+		t.Errorf("result = %v, want nil", result)
+	}
+	var retryErr *RetryExhaustedError
+	if !errors.As(err, &retryErr) {
+		t.Fatalf("error = %v, want *RetryExhaustedError", err)
+	}
+	if retryErr.Attempts != 3 {
+		t.Errorf("Attempts = %d, want 3", retryErr.Attempts)
+	}
+	if len(policy.attempts) != 3 || policy.attempts[2] != 3 {
+		t.Errorf("NextDelay attempts = %v, want [1 2 3]", policy.attempts)
+	}
+}
+
+func TestBasicExecutor_Execute_RetryPolicySucceeds(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "marker")
+	policy := &recordingRetryPolicy{maxAttempts: 5}
+	result, err := NewBasicExecutor().Execute(context.Background(), ToolConfig{
+		Command:     "sh",
+		Args:        []string{"-c", `test -f "$1" && echo ok && exit 0; touch "$1"; exit 1`, "sh", marker},
+		RetryPolicy: policy,
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Output != "ok\n" {
+		t.Errorf("Output = %q, want %q", result.Output, "ok\n")
+	}
+	if len(policy.attempts) != 1 {
+		t.Errorf("NextDelay attempts = %v, want [1]", policy.attempts)
+	}
+}
+
+func TestBasicExecutor_Execute_RetryPolicyNotRetryable(t *testing.T) {
+	policy := &recordingRetryPolicy{
+		maxAttempts: 5,
+		retry:       func(result *ExecutionResult, _ error) bool { return result != nil && result.ExitCode == 75 },
+	}
+	result, err := NewBasicExecutor().Execute(context.Background(), ToolConfig{
+		Command:     "sh",
+		Args:        []string{"-c", "exit 2"},
+		RetryPolicy: policy,
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v, want the attempt's result", err)
+	}
+	if result.ExitCode != 2 {
+		t.Errorf("ExitCode = %d, want 2", result.ExitCode)
+	}
+	if len(policy.attempts) != 0 {
+		t.Errorf("NextDelay attempts = %v, want none", policy.attempts)
+	}
+}
+
+func TestFixedRetryPolicy(t *testing.T) {
+	cfg := ToolConfig{MaxRetries: 2, RetryDelay: time.Second}
+	policy := cfg.retryPolicy()
+	for attempt, wantOK := range map[int]bool{1: true, 2: true, 3: false} {
+		delay, ok := policy.NextDelay(attempt)
+		if ok != wantOK || delay != time.Second {
+			t.Errorf("NextDelay(%d) = (%v, %v), want (1s, %v)", attempt, delay, ok, wantOK)
+		}
+	}
+	if !policy.ShouldRetry(nil, errors.New("x")) {
+		t.Error("ShouldRetry() = false, want true for every failure")
+	}
+
+	custom := &recordingRetryPolicy{}
+	cfg.RetryPolicy = custom
+	if cfg.retryPolicy() != custom {
+		t.Error("retryPolicy() should return the configured RetryPolicy")
+	}
+}
+
+func TestBasicExecutor_Execute_RetryIf(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "marker")
+	// The first attempt reports a lock conflict, the second fails for good.
+	script := `test -f "$1" && echo "fatal: bad revision" >&2 && exit 128; touch "$1"; echo "fatal: index.lock held" >&2; exit 128`
+	result, err := NewBasicExecutor().Execute(context.Background(), ToolConfig{
+		Command:    "sh",
+		Args:       []string{"-c", script, "sh", marker},
+		MaxRetries: 5,
+		RetryIf:    RetryIfStderrMatches(regexp.MustCompile(`lock held`)),
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v, want the non-retryable result", err)
+	}
+	if result.ExitCode != 128 || result.Stderr != "fatal: bad revision\n" {
+		t.Errorf("result = exit %d, stderr %q; want the second attempt", result.ExitCode, result.Stderr)
+	}
+}
+
+func TestToolConfig_RetryPolicyWithRetryIf(t *testing.T) {
+	cfg := ToolConfig{
+		RetryPolicy: &recordingRetryPolicy{
+			maxAttempts: 2,
+			retry:       func(*ExecutionResult, error) bool { return false },
+		},
+		RetryIf: func(*ExecutionResult, error) bool { return true },
+	}
+	if cfg.retryPolicy().ShouldRetry(&ExecutionResult{ExitCode: 1}, nil) {
+		t.Error("ShouldRetry() = true, want false when the policy refuses")
+	}
+	cfg.RetryPolicy = nil
+	if !cfg.retryPolicy().ShouldRetry(&ExecutionResult{ExitCode: 1}, nil) {
+		t.Error("ShouldRetry() = false, want true when RetryIf accepts")
+	}
+	if RetryIfStderrMatches(regexp.MustCompile("x"))(nil, errors.New("x")) {
+		t.Error("RetryIfStderrMatches should not retry attempts without a result")
+	}
+}
+
+func TestBasicExecutor_Execute_MaxRetryElapsed(t *testing.T) {
+	start := time.Now()
+	_, err := NewBasicExecutor().Execute(context.Background(), ToolConfig{
+		Command:         "sh",
+		Args:            []string{"-c", "exit 1"},
+		MaxRetries:      100,
+		RetryDelay:      40 * time.Millisecond,
+		MaxRetryElapsed: 200 * time.Millisecond,
+	})
+	var retryErr *RetryExhaustedError
+	if !errors.As(err, &retryErr) {
+		t.Fatalf("error = %v, want *RetryExhaustedError", err)
+	}
+	if retryErr.Attempts < 2 || retryErr.Attempts > 6 {
+		t.Errorf("Attempts = %d, want the budget to stop retries after a few attempts", retryErr.Attempts)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("retries took %v, want them bounded by MaxRetryElapsed", elapsed)
+	}
+}
+
+func TestBasicExecutor_Execute_RetrySkippedNearDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	// Each attempt takes 200ms, so a second one would not finish in time.
+	_, err := NewBasicExecutor().Execute(ctx, ToolConfig{
+		Command:    "sh",
+		Args:       []string{"-c", "sleep 0.2; exit 1"},
+		MaxRetries: 3,
+	})
+	var retryErr *RetryExhaustedError
+	if !errors.As(err, &retryErr) {
+		t.Fatalf("error = %v, want *RetryExhaustedError instead of a cancelled attempt", err)
+	}
+	if retryErr.Attempts != 1 {
+		t.Errorf("Attempts = %d, want 1", retryErr.Attempts)
+	}
+	if retryErr.LastResult == nil || retryErr.LastResult.ExitCode != 1 {
+		t.Errorf("LastResult = %v, want the completed first attempt", retryErr.LastResult)
+	}
+}
+
+func TestRetryBudget_Allows(t *testing.T) {
+	budget := retryBudget{start: time.Now().Add(-time.Second), maxElapsed: 2 * time.Second}
+	if !budget.allows(context.Background(), 500*time.Millisecond, time.Hour) {
+		t.Error("allows() = false, want true within the budget without a deadline")
+	}
+	if budget.allows(context.Background(), time.Second, 0) {
+		t.Error("allows() = true, want false when the delay exhausts the budget")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	unlimited := newRetryBudget(0)
+	if !unlimited.allows(ctx, time.Second, time.Second) {
+		t.Error("allows() = false, want true when the attempt fits before the deadline")
+	}
+	if unlimited.allows(ctx, time.Second, time.Hour) {
+		t.Error("allows() = true, want false when the attempt cannot finish before the deadline")
+	}
+}
+
+func TestBasicExecutor_Execute_RetryHistory(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "marker")
+	script := `if test -f "$1"; then echo second >&2; exit 3; fi; touch "$1"; echo first >&2; exit 2`
+	_, err := NewBasicExecutor().Execute(context.Background(), ToolConfig{
+		Command:    "sh",
+		Args:       []string{"-c", script, "sh", marker},
+		MaxRetries: 1,
+	})
+	var retryErr *RetryExhaustedError
+	if !errors.As(err, &retryErr) {
+		t.Fatalf("error = %v, want *RetryExhaustedError", err)
+	}
+	if len(retryErr.History) != 2 {
+		t.Fatalf("History = %+v, want 2 attempts", retryErr.History)
+	}
+	for i, want := range []AttemptSummary{
+		{Attempt: 1, ExitCode: 2, Stderr: "first\n"},
+		{Attempt: 2, ExitCode: 3, Stderr: "second\n"},
+	} {
+		got := retryErr.History[i]
+		if got.Attempt != want.Attempt || got.ExitCode != want.ExitCode || got.Stderr != want.Stderr || got.Error != "" {
+			t.Errorf("History[%d] = %+v, want %+v", i, got, want)
+		}
+		if got.Duration <= 0 {
+			t.Errorf("History[%d].Duration = %v, want positive", i, got.Duration)
+		}
+	}
+}
+
+func TestSummarizeAttempt(t *testing.T) {
+	long := strings.Repeat("x", attemptStderrLimit) + "é" + "end"
+	summary := summarizeAttempt(2, &ExecutionResult{ExitCode: 1, Stderr: long}, nil, time.Second)
+	if len(summary.Stderr) > attemptStderrLimit || !strings.HasSuffix(summary.Stderr, "éend") {
+		t.Errorf("Stderr has %d bytes ending in %q, want the tail within the limit",
+			len(summary.Stderr), summary.Stderr[len(summary.Stderr)-5:])
+	}
+
+	// Cutting at the limit would split "é"; the partial rune is dropped.
+	split := "é" + strings.Repeat("y", attemptStderrLimit-1)
+	summary = summarizeAttempt(1, &ExecutionResult{Stderr: split}, nil, 0)
+	if summary.Stderr != strings.Repeat("y", attemptStderrLimit-1) {
+		t.Errorf("Stderr starts with %q, want no partial rune", summary.Stderr[:2])
+	}
+
+	summary = summarizeAttempt(3, nil, &TimeoutError{Command: "x", Timeout: time.Second}, 0)
+	if summary.ExitCode != -1 || summary.Error == "" {
+		t.Errorf("summary = %+v, want exit code -1 and the error message", summary)
+	}
+}
+
+func TestBasicExecutor_Execute_OnRetry(t *testing.T) {
+	type call struct {
+		attempt  int
+		exitCode int
+		stderr   string
+		delay    time.Duration
+	}
+	var calls []call
+	_, err := NewBasicExecutor().Execute(context.Background(), ToolConfig{
+		Command:    "sh",
+		Args:       []string{"-c", "echo token=s3cret >&2; exit 4"},
+		MaxRetries: 2,
+		RetryDelay: time.Millisecond,
+		Redactor:   &Redactor{Secrets: []string{"s3cret"}},
+		OnRetry: func(attempt int, result *ExecutionResult, err error, nextDelay time.Duration) {
+			if err != nil {
+				t.Errorf("OnRetry err = %v, want nil for an exit code failure", err)
+			}
+			calls = append(calls, call{attempt, result.ExitCode, result.Stderr, nextDelay})
+		},
+	})
+	var retryErr *RetryExhaustedError
+	if !errors.As(err, &retryErr) {
+		t.Fatalf("error = %v, want *RetryExhaustedError", err)
+	}
+
+	// The last attempt is not retried, so it is not reported.
+	want := []call{
+		{1, 4, "token=[REDACTED]\n", time.Millisecond},
+		{2, 4, "token=[REDACTED]\n", time.Millisecond},
+	}
+	if len(calls) != len(want) {
+		t.Fatalf("OnRetry calls = %+v, want %+v", calls, want)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Errorf("OnRetry call %d = %+v, want %+v", i, calls[i], want[i])
+		}
+	}
+}
+
+func TestBasicExecutor_Execute_Fallback(t *testing.T) {
+	tests := []struct {
+		name      string
+		primary   ToolConfig
+		wantDepth int
+		wantOut   string
+	}{
+		{
+			name:      "missing executable",
+			primary:   ToolConfig{Command: "nonexistent-python-cmdexec"},
+			wantDepth: 1,
+			wantOut:   "fallback\n",
+		},
+		{
+			name:      "retries exhausted",
+			primary:   ToolConfig{Command: "sh", Args: []string{"-c", "exit 1"}, MaxRetries: 1},
+			wantDepth: 1,
+			wantOut:   "fallback\n",
+		},
+		{
+			name:      "primary succeeds",
+			primary:   ToolConfig{Command: "echo", Args: []string{"primary"}},
+			wantDepth: 0,
+			wantOut:   "primary\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.primary
+			cfg.Fallback = &ToolConfig{Command: "echo", Args: []string{"fallback"}}
+			result, err := NewBasicExecutor().Execute(context.Background(), cfg)
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if result.Output != tt.wantOut || result.FallbackDepth != tt.wantDepth {
+				t.Errorf("Output = %q, FallbackDepth = %d; want %q, %d",
+					result.Output, result.FallbackDepth, tt.wantOut, tt.wantDepth)
+			}
+		})
+	}
+}
+
+func TestBasicExecutor_Execute_FallbackChain(t *testing.T) {
+	result, err := NewBasicExecutor().Execute(context.Background(), ToolConfig{
+		Command: "sh",
+		Args:    []string{"-c", "exit 2"},
+		Fallback: &ToolConfig{
+			Command:  "nonexistent-gtar-cmdexec",
+			Fallback: &ToolConfig{Command: "sh", Args: []string{"-c", "exit 3"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	// The last fallback's failure is reported.
+	if result.ExitCode != 3 || result.FallbackDepth != 2 {
+		t.Errorf("ExitCode = %d, FallbackDepth = %d; want 3, 2", result.ExitCode, result.FallbackDepth)
+	}
+}
+
+func TestBasicExecutor_Execute_NoFallbackAfterCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := NewBasicExecutor().Execute(ctx, ToolConfig{
+		Command:  "sh",
+		Args:     []string{"-c", "exit 1"},
+		Fallback: &ToolConfig{Command: "echo", Args: []string{"fallback"}},
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("error = %v, want context.Canceled without running the fallback", err)
+	}
+}
+
+func TestBasicExecutor_Execute_SuccessWhen(t *testing.T) {
+	policy := &recordingRetryPolicy{maxAttempts: 3}
+	result, err := NewBasicExecutor().Execute(context.Background(), ToolConfig{
+		Command:     "sh",
+		Args:        []string{"-c", "exit 1"},
+		RetryPolicy: policy,
+		SuccessWhen: SucceedOnExitCodes(0, 1),
+		Fallback:    &ToolConfig{Command: "echo", Args: []string{"fallback"}},
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.ExitCode != 1 || result.FallbackDepth != 0 || len(policy.attempts) != 0 {
+		t.Errorf("ExitCode = %d, FallbackDepth = %d, retries = %v; want the accepted first attempt",
+			result.ExitCode, result.FallbackDepth, policy.attempts)
+	}
+
+	// Output-based criteria can also reject a zero exit code.
+	_, err = NewBasicExecutor().Execute(context.Background(), ToolConfig{
+		Command:     "echo",
+		Args:        []string{"ERROR: flaky"},
+		MaxRetries:  1,
+		SuccessWhen: func(result *ExecutionResult) bool { return !strings.Contains(result.Output, "ERROR") },
+	})
+	var retryErr *RetryExhaustedError
+	if !errors.As(err, &retryErr) || retryErr.Attempts != 2 {
+		t.Errorf("error = %v, want *RetryExhaustedError after 2 attempts", err)
+	}
+}
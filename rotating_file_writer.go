@@ -0,0 +1,200 @@
+package cmdexec
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RotationPolicy controls when a RotatingFileWriter starts a new file and
+// how many old files it keeps.
+type RotationPolicy struct {
+	// MaxSize is the size in bytes at which the file is rotated. A single
+	// write is never split, so a file may exceed MaxSize by at most one
+	// write. Zero disables size-based rotation.
+	MaxSize int64
+
+	// MaxAge is how long a file is written before it is rotated. Zero
+	// disables age-based rotation.
+	MaxAge time.Duration
+
+	// MaxBackups is the number of rotated files to keep, named path.1
+	// (newest) through path.N. Older files are removed. Zero keeps none.
+	MaxBackups int
+}
+
+func (p RotationPolicy) validate() error {
+	if p.MaxSize < 0 {
+		return &ValidationError{Field: "MaxSize", Message: "maxSize cannot be negative"}
+	}
+	if p.MaxAge < 0 {
+		return &ValidationError{Field: "MaxAge", Message: "maxAge cannot be negative"}
+	}
+	if p.MaxBackups < 0 {
+		return &ValidationError{Field: "MaxBackups", Message: "maxBackups cannot be negative"}
+	}
+	return nil
+}
+
+// RotatingFileWriter is an io.Writer that appends to a log file and rotates
+// it by size or age, keeping a bounded number of old files. It can be used
+// as StdoutWriter or StderrWriter to supervise long-running daemons without
+// external log rotation. It is safe for concurrent use.
+type RotatingFileWriter struct {
+	path   string
+	policy RotationPolicy
+	now    func() time.Time
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	opened time.Time
+}
+
+// NewRotatingFileWriter opens path for appending, creating it if needed, and
+// returns a writer that rotates it according to policy.
+func NewRotatingFileWriter(path string, policy RotationPolicy) (*RotatingFileWriter, error) {
+	if err := policy.validate(); err != nil {
+		return nil, err
+	}
+	w := &RotatingFileWriter{path: path, policy: policy, now: time.Now}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Write appends p to the current file, rotating first if the policy
+// requires it.
+func (w *RotatingFileWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return 0, fmt.Errorf("rotating file writer for %s is closed", w.path)
+	}
+	if w.shouldRotate(int64(len(p))) {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	if err != nil {
+		return n, fmt.Errorf("failed to write log file: %w", err)
+	}
+	return n, nil
+}
+
+// Rotate closes the current file, shifts the backups, and starts a new file.
+func (w *RotatingFileWriter) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.rotate()
+}
+
+// Sync commits the current file to stable storage.
+func (w *RotatingFileWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	if err := w.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync log file: %w", err)
+	}
+	return nil
+}
+
+// Close closes the current file. Further writes fail.
+func (w *RotatingFileWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	if err != nil {
+		return fmt.Errorf("failed to close log file: %w", err)
+	}
+	return nil
+}
+
+func (w *RotatingFileWriter) shouldRotate(n int64) bool {
+	if w.size == 0 {
+		return false
+	}
+	if w.policy.MaxSize > 0 && w.size+n > w.policy.MaxSize {
+		return true
+	}
+	return w.policy.MaxAge > 0 && w.now().Sub(w.opened) >= w.policy.MaxAge
+}
+
+func (w *RotatingFileWriter) open() error {
+	f, err := os.OpenFile(w.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600) //nolint:gosec // path is chosen by the caller
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+	w.file = f
+	w.size = info.Size()
+	w.opened = w.now()
+	return nil
+}
+
+func (w *RotatingFileWriter) rotate() error {
+	if w.file != nil {
+		if err := w.file.Close(); err != nil {
+			return fmt.Errorf("failed to close log file: %w", err)
+		}
+		w.file = nil
+	}
+	if err := w.shiftBackups(); err != nil {
+		// Keep writing to the current file rather than leaving the writer
+		// closed; the next rotation will try again.
+		if openErr := w.open(); openErr != nil {
+			return errors.Join(err, openErr)
+		}
+		return err
+	}
+	return w.open()
+}
+
+// shiftBackups renames path.N-1 to path.N and so on down to path to path.1,
+// dropping the oldest file. With no backups, the current file is removed.
+func (w *RotatingFileWriter) shiftBackups() error {
+	n := w.policy.MaxBackups
+	if n == 0 {
+		if err := os.Remove(w.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to remove log file: %w", err)
+		}
+		return nil
+	}
+	if err := os.Remove(w.backupName(n)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove old log file: %w", err)
+	}
+	for i := n - 1; i >= 0; i-- {
+		src := w.backupName(i)
+		if err := os.Rename(src, w.backupName(i+1)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to rotate log file: %w", err)
+		}
+	}
+	return nil
+}
+
+// backupName returns the name of the i-th backup, or the live file for 0.
+func (w *RotatingFileWriter) backupName(i int) string {
+	if i == 0 {
+		return w.path
+	}
+	return w.path + "." + strconv.Itoa(i)
+}
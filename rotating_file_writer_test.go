@@ -0,0 +1,136 @@
+package cmdexec
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile(%s) error = %v", path, err)
+	}
+	return string(data)
+}
+
+func TestRotatingFileWriter_Size(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	w, err := NewRotatingFileWriter(path, RotationPolicy{MaxSize: 10, MaxBackups: 2})
+	if err != nil {
+		t.Fatalf("NewRotatingFileWriter() error = %v", err)
+	}
+	defer w.Close()
+
+	for _, line := range []string{"aaaaaa\n", "bbbbbb\n", "cccccc\n", "dddddd\n"} {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+
+	if got := readFile(t, path); got != "dddddd\n" {
+		t.Errorf("current file = %q", got)
+	}
+	if got := readFile(t, path+".1"); got != "cccccc\n" {
+		t.Errorf("backup 1 = %q", got)
+	}
+	if got := readFile(t, path+".2"); got != "bbbbbb\n" {
+		t.Errorf("backup 2 = %q", got)
+	}
+	if _, err := os.Stat(path + ".3"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("backup 3 should not exist, Stat() error = %v", err)
+	}
+}
+
+func TestRotatingFileWriter_Age(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	w, err := NewRotatingFileWriter(path, RotationPolicy{MaxAge: time.Hour, MaxBackups: 1})
+	if err != nil {
+		t.Fatalf("NewRotatingFileWriter() error = %v", err)
+	}
+	defer w.Close()
+
+	now := time.Now()
+	w.now = func() time.Time { return now }
+	w.opened = now
+
+	_, _ = w.Write([]byte("old\n"))
+	now = now.Add(30 * time.Minute)
+	_, _ = w.Write([]byte("still old\n"))
+	now = now.Add(time.Hour)
+	_, _ = w.Write([]byte("new\n"))
+
+	if got := readFile(t, path); got != "new\n" {
+		t.Errorf("current file = %q", got)
+	}
+	if got := readFile(t, path+".1"); got != "old\nstill old\n" {
+		t.Errorf("backup = %q", got)
+	}
+}
+
+func TestRotatingFileWriter_AppendsAndCloses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte("existing\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewRotatingFileWriter(path, RotationPolicy{})
+	if err != nil {
+		t.Fatalf("NewRotatingFileWriter() error = %v", err)
+	}
+
+	result, err := NewBasicExecutor().Execute(context.Background(), ToolConfig{
+		Command:      "echo",
+		Args:         []string{"appended"},
+		StdoutWriter: w,
+	})
+	if err != nil || result.ExitCode != 0 {
+		t.Fatalf("Execute() = %v, %v", result, err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if got := readFile(t, path); got != "existing\nappended\n" {
+		t.Errorf("file = %q", got)
+	}
+	if _, err := w.Write([]byte("x")); err == nil {
+		t.Error("Write() after Close() should fail")
+	}
+}
+
+func TestRotatingFileWriter_RotateFailureKeepsWriting(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	// A non-empty directory in place of the oldest backup cannot be removed.
+	if err := os.MkdirAll(filepath.Join(path+".1", "blocker"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewRotatingFileWriter(path, RotationPolicy{MaxBackups: 1})
+	if err != nil {
+		t.Fatalf("NewRotatingFileWriter() error = %v", err)
+	}
+	defer w.Close()
+
+	if _, err := w.Write([]byte("one\n")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := w.Rotate(); err == nil {
+		t.Fatal("Rotate() should fail when the backup cannot be removed")
+	}
+	if _, err := w.Write([]byte("two\n")); err != nil {
+		t.Fatalf("Write() after failed rotation error = %v", err)
+	}
+	if got := readFile(t, path); got != "one\ntwo\n" {
+		t.Errorf("file = %q, want %q", got, "one\ntwo\n")
+	}
+}
+
+func TestNewRotatingFileWriter_InvalidPolicy(t *testing.T) {
+	_, err := NewRotatingFileWriter(filepath.Join(t.TempDir(), "x.log"), RotationPolicy{MaxBackups: -1})
+	var valErr *ValidationError
+	if !errors.As(err, &valErr) {
+		t.Errorf("error = %v, want *ValidationError", err)
+	}
+}
@@ -0,0 +1,93 @@
+package cmdexec
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// weightedSemaphore limits the total weight of the holders at any time.
+// Waiters are admitted in FIFO order, so a heavy waiter is not starved by a
+// stream of light ones. A waiter heavier than the limit is admitted once
+// the semaphore is empty.
+type weightedSemaphore struct {
+	mu      sync.Mutex
+	limit   int
+	used    int
+	waiters []*semaphoreWaiter
+}
+
+type semaphoreWaiter struct {
+	n     int
+	ready chan struct{}
+}
+
+func newWeightedSemaphore(limit int) *weightedSemaphore {
+	return &weightedSemaphore{limit: max(limit, 1)}
+}
+
+// acquire blocks until n units are available and takes them. If ctx is
+// done first, it leaves the queue without taking any units and returns the
+// context's error.
+func (s *weightedSemaphore) acquire(ctx context.Context, n int) error {
+	s.mu.Lock()
+	if len(s.waiters) == 0 && s.fits(n) {
+		s.used += n
+		s.mu.Unlock()
+		return nil
+	}
+	w := &semaphoreWaiter{n: n, ready: make(chan struct{})}
+	s.waiters = append(s.waiters, w)
+	s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	select {
+	case <-w.ready:
+		// Admitted while cancelling: give the units back.
+		s.used -= n
+	default:
+		s.waiters = slices.DeleteFunc(s.waiters, func(q *semaphoreWaiter) bool { return q == w })
+	}
+	// A heavy waiter leaving the front may let lighter ones in.
+	s.admit()
+	return ctx.Err()
+}
+
+// release returns n units taken by acquire.
+func (s *weightedSemaphore) release(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.used -= n
+	s.admit()
+}
+
+// resize changes the limit. Holders keep their units, so after lowering the
+// limit, waiters are admitted only once enough units have been released.
+func (s *weightedSemaphore) resize(limit int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.limit = max(limit, 1)
+	s.admit()
+}
+
+func (s *weightedSemaphore) fits(n int) bool {
+	return s.used == 0 || s.used+n <= s.limit
+}
+
+// admit wakes waiters from the front of the queue while they fit. The
+// caller must hold s.mu.
+func (s *weightedSemaphore) admit() {
+	for len(s.waiters) > 0 && s.fits(s.waiters[0].n) {
+		w := s.waiters[0]
+		s.waiters = s.waiters[1:]
+		s.used += w.n
+		close(w.ready)
+	}
+}
@@ -0,0 +1,103 @@
+package cmdexec
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWeightedSemaphore(t *testing.T) {
+	s := newWeightedSemaphore(4)
+	acquireUnits(s, 3)
+
+	acquired := make(chan int, 3)
+	go func() { acquireUnits(s, 2); acquired <- 2 }()
+	time.Sleep(10 * time.Millisecond)
+	// A light waiter queued behind a heavier one does not overtake it.
+	go func() { acquireUnits(s, 1); acquired <- 1 }()
+
+	select {
+	case n := <-acquired:
+		t.Fatalf("acquire(%d) succeeded, want the 1-unit waiter to queue behind the 2-unit one", n)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// Releasing admits both, since together they fit.
+	s.release(3)
+	if total := <-acquired + <-acquired; total != 3 || s.used != 3 {
+		t.Errorf("admitted weight %d with %d units used, want both waiters admitted", total, s.used)
+	}
+	s.release(2)
+	s.release(1)
+
+	// A waiter heavier than the limit runs once the semaphore is empty.
+	acquireUnits(s, 10)
+	s.release(10)
+	if s.used != 0 || len(s.waiters) != 0 {
+		t.Errorf("used = %d, waiters = %d, want an empty semaphore", s.used, len(s.waiters))
+	}
+}
+
+func TestWeightedSemaphore_Resize(t *testing.T) {
+	s := newWeightedSemaphore(2)
+	acquireUnits(s, 1)
+	acquireUnits(s, 1)
+
+	// Lowering the limit keeps the holders but delays new ones.
+	s.resize(1)
+	acquired := make(chan struct{})
+	go func() { acquireUnits(s, 1); close(acquired) }()
+	s.release(1)
+	select {
+	case <-acquired:
+		t.Fatal("acquire succeeded while the held units still filled the lowered limit")
+	case <-time.After(20 * time.Millisecond):
+	}
+	s.release(1)
+	<-acquired
+
+	// Raising the limit admits waiters at once.
+	done := make(chan struct{})
+	go func() { acquireUnits(s, 1); close(done) }()
+	time.Sleep(10 * time.Millisecond)
+	s.resize(2)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("raising the limit did not admit the waiter")
+	}
+}
+
+func TestWeightedSemaphore_AcquireCancelled(t *testing.T) {
+	s := newWeightedSemaphore(2)
+	acquireUnits(s, 2)
+
+	// A cancelled waiter leaves the queue without taking units, and a
+	// lighter waiter behind it is then admitted.
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() { errc <- s.acquire(ctx, 2) }()
+	time.Sleep(10 * time.Millisecond)
+	acquired := make(chan struct{})
+	go func() { acquireUnits(s, 1); close(acquired) }()
+	time.Sleep(10 * time.Millisecond)
+	s.release(1)
+	cancel()
+
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Fatalf("acquire() error = %v, want context.Canceled", err)
+	}
+	select {
+	case <-acquired:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the waiter behind the cancelled one was not admitted")
+	}
+	if s.used != 2 || len(s.waiters) != 0 {
+		t.Errorf("used = %d, waiters = %d, want 2 units held and no waiters", s.used, len(s.waiters))
+	}
+}
+
+func acquireUnits(s *weightedSemaphore, n int) {
+	_ = s.acquire(context.Background(), n)
+}
@@ -1,4 +1,4 @@
-//go:build unix
+//go:build unix || windows
 
 package cmdexec
 
@@ -6,8 +6,11 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"os"
+	"sort"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 // WithSignalHandling wraps a BasicExecutor with signal handling capabilities.
@@ -21,7 +24,64 @@ type WithSignalHandling struct {
 	// mu protects the processes map
 	mu sync.Mutex
 	// processes tracks running processes for cleanup
-	processes map[string]context.CancelFunc
+	processes map[string]*trackedExecution
+}
+
+// trackedExecution holds the state needed to cancel, await, and force-kill
+// a single execution.
+type trackedExecution struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	// mu protects process and paused
+	mu sync.Mutex
+	// process is captured when the command starts, so that it can be
+	// paused, resumed, or force-killed.
+	process *os.Process
+	// paused records whether the process is currently suspended.
+	paused bool
+}
+
+func (te *trackedExecution) setProcess(p *os.Process) {
+	te.mu.Lock()
+	defer te.mu.Unlock()
+	te.process = p
+}
+
+// setPaused suspends or resumes the tracked process.
+func (te *trackedExecution) setPaused(paused bool) error {
+	te.mu.Lock()
+	defer te.mu.Unlock()
+	if te.process == nil {
+		return &SignalHandlerError{Message: "process has not started yet"}
+	}
+	if te.paused == paused {
+		return nil
+	}
+	op := resumeProcess
+	if paused {
+		op = suspendProcess
+	}
+	if err := op(te.process); err != nil {
+		return err
+	}
+	te.paused = paused
+	return nil
+}
+
+// kill forcibly terminates the tracked process. It fails if the process
+// has not been started yet.
+func (te *trackedExecution) kill() error {
+	te.mu.Lock()
+	p := te.process
+	te.mu.Unlock()
+	if p == nil {
+		return &ExecutionStateError{Op: "kill", Message: "process not started"}
+	}
+	if err := p.Kill(); err != nil {
+		return fmt.Errorf("failed to kill process %d: %w", p.Pid, err)
+	}
+	return nil
 }
 
 // NewWithSignalHandling creates a new executor with signal handling.
@@ -29,7 +89,7 @@ func NewWithSignalHandling() *WithSignalHandling {
 	return &WithSignalHandling{
 		executor:      NewBasicExecutor(),
 		signalHandler: NewSignalHandler(),
-		processes:     make(map[string]context.CancelFunc),
+		processes:     make(map[string]*trackedExecution),
 	}
 }
 
@@ -41,18 +101,81 @@ func (e *WithSignalHandling) Start() (context.Context, error) {
 // Stop gracefully shuts down the executor and signal handler.
 func (e *WithSignalHandling) Stop() {
 	// Cancel all running processes
-	e.mu.Lock()
-	for id, cancel := range e.processes {
-		slog.Debug("Cancelling process", "id", id)
-		cancel()
-	}
-	e.processes = make(map[string]context.CancelFunc)
-	e.mu.Unlock()
+	e.cancelAll()
 
 	// Stop the signal handler
 	e.signalHandler.Stop()
 }
 
+// StopWithTimeout cancels all running executions and waits up to timeout for
+// them to finish. Executions still running when the timeout elapses are
+// forcibly killed. It returns the IDs of the executions that were killed,
+// sorted for stable output, and the errors of those that could not be,
+// keyed by ID. The signal handler is stopped as well.
+func (e *WithSignalHandling) StopWithTimeout(timeout time.Duration) (killed []string, failed map[string]error) {
+	tracked := e.cancelAll()
+	deadline := time.Now().Add(timeout)
+
+	for id, te := range tracked {
+		if waitDone(te.done, time.Until(deadline)) {
+			continue
+		}
+		slog.Debug("Force-killing process after shutdown deadline", "id", id)
+		if err := te.kill(); err != nil {
+			slog.Debug("Failed to force-kill process", "id", id, "error", err)
+			if failed == nil {
+				failed = make(map[string]error)
+			}
+			failed[id] = err
+			continue
+		}
+		killed = append(killed, id)
+	}
+
+	e.signalHandler.Stop()
+
+	sort.Strings(killed)
+	return killed, failed
+}
+
+// waitDone waits up to d for done to be closed and reports whether it was.
+func waitDone(done <-chan struct{}, d time.Duration) bool {
+	if d <= 0 {
+		select {
+		case <-done:
+			return true
+		default:
+			return false
+		}
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// cancelAll cancels every tracked execution, clears the tracking map, and
+// returns the executions that were cancelled.
+func (e *WithSignalHandling) cancelAll() map[string]*trackedExecution {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	tracked := e.processes
+	for id, te := range tracked {
+		slog.Debug("Cancelling process", "id", id)
+		// A suspended process cannot react to graceful termination.
+		if err := te.setPaused(false); err != nil {
+			slog.Debug("Failed to resume process before cancelling", "id", id, "error", err)
+		}
+		te.cancel()
+	}
+	e.processes = make(map[string]*trackedExecution)
+	return tracked
+}
+
 // Execute runs a command with signal handling support.
 func (e *WithSignalHandling) Execute(ctx context.Context, cfg ToolConfig) (*ExecutionResult, error) {
 	// Create a unique ID for this execution
@@ -60,10 +183,11 @@ func (e *WithSignalHandling) Execute(ctx context.Context, cfg ToolConfig) (*Exec
 
 	// Create a cancellable context for this specific execution
 	execCtx, cancel := context.WithCancel(ctx)
+	te := &trackedExecution{cancel: cancel, done: make(chan struct{})}
 
 	// Register the process
 	e.mu.Lock()
-	e.processes[execID] = cancel
+	e.processes[execID] = te
 	e.mu.Unlock()
 
 	// Clean up when done
@@ -72,15 +196,22 @@ func (e *WithSignalHandling) Execute(ctx context.Context, cfg ToolConfig) (*Exec
 		delete(e.processes, execID)
 		e.mu.Unlock()
 		cancel()
+		close(te.done)
 	}()
 
+	// Let the platform arrange for graceful child termination on cancel,
+	// and record the process so it can be paused or force-killed.
+	cfg.CommandBuilder = gracefulCommandBuilder(cfg.CommandBuilder)
+
 	slog.Debug("Starting command execution with signal handling",
 		"command", cfg.Command,
-		"args", cfg.Args,
+		"args", cfg.Redactor.bind(cfg.Env, cfg.Args).redactArgs(cfg.Args),
+		"labels", cfg.Labels,
+		"metadata", Metadata(ctx),
 		"exec_id", execID)
 
 	// Execute using the wrapped executor
-	result, err := e.executor.Execute(execCtx, cfg)
+	result, err := e.executor.executeWithStartHook(execCtx, cfg, te.setProcess)
 
 	slog.Debug("Command execution completed",
 		"command", cfg.Command,
@@ -100,6 +231,52 @@ func (e *WithSignalHandling) IsAvailable(command string) bool {
 	return e.executor.IsAvailable(command)
 }
 
+// RunningExecutionIDs returns the IDs of the currently running executions,
+// sorted. IDs have the form "<command line>#<n>".
+func (e *WithSignalHandling) RunningExecutionIDs() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	ids := make([]string, 0, len(e.processes))
+	for id := range e.processes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Pause suspends the running execution with the given ID (SIGSTOP on Unix),
+// letting a scheduler yield CPU to higher-priority work without killing it.
+// Only the direct child is suspended; descendants it spawned keep running.
+// Pausing an already paused execution is a no-op. Pause is not supported on
+// Windows.
+func (e *WithSignalHandling) Pause(id string) error {
+	te, err := e.lookup(id)
+	if err != nil {
+		return err
+	}
+	return te.setPaused(true)
+}
+
+// Resume continues an execution previously suspended with Pause (SIGCONT on
+// Unix). Resuming an execution that is not paused is a no-op.
+func (e *WithSignalHandling) Resume(id string) error {
+	te, err := e.lookup(id)
+	if err != nil {
+		return err
+	}
+	return te.setPaused(false)
+}
+
+func (e *WithSignalHandling) lookup(id string) (*trackedExecution, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	te, ok := e.processes[id]
+	if !ok {
+		return nil, &SignalHandlerError{Message: fmt.Sprintf("no running execution with id %q", id)}
+	}
+	return te, nil
+}
+
 // GetRunningProcesses returns the number of currently running processes.
 func (e *WithSignalHandling) GetRunningProcesses() int {
 	e.mu.Lock()
@@ -3,7 +3,11 @@
 package cmdexec
 
 import (
+	"context"
+	"errors"
 	"os"
+	"os/exec"
+	"strings"
 	"testing"
 	"time"
 
@@ -252,3 +256,172 @@ func TestWithSignalHandling_IsAvailable(t *testing.T) {
 		t.Error("nonexistent command should not be available")
 	}
 }
+
+// ignoreCancelBuilder builds commands whose Cancel hook does not terminate
+// the process, simulating a child that ignores graceful termination.
+type ignoreCancelBuilder struct{}
+
+func (ignoreCancelBuilder) Build(ctx context.Context, command string, args []string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, command, args...)
+	cmd.Cancel = func() error { return nil }
+	return cmd
+}
+
+func TestWithSignalHandling_StopWithTimeout_AllFinish(t *testing.T) {
+	executor := NewWithSignalHandling()
+
+	ctx, err := executor.Start()
+	if err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = executor.Execute(ctx, ToolConfig{Command: "sleep", Args: []string{"10"}})
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	killed, failed := executor.StopWithTimeout(2 * time.Second)
+	if len(killed) != 0 || len(failed) != 0 {
+		t.Errorf("StopWithTimeout() = %v, %v, want none killed or failed", killed, failed)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("Command was not cancelled within timeout")
+	}
+}
+
+func TestWithSignalHandling_StopWithTimeout_ForceKillsSurvivors(t *testing.T) {
+	executor := NewWithSignalHandling()
+
+	ctx, err := executor.Start()
+	if err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = executor.Execute(ctx, ToolConfig{
+			Command:        "sleep",
+			Args:           []string{"10"},
+			CommandBuilder: ignoreCancelBuilder{},
+		})
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	start := time.Now()
+	killed, failed := executor.StopWithTimeout(200 * time.Millisecond)
+	if len(killed) != 1 || !strings.HasPrefix(killed[0], "sleep 10#") || len(failed) != 0 {
+		t.Errorf("StopWithTimeout() = %v, %v, want [sleep 10#N] killed", killed, failed)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("Command was not force-killed")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("StopWithTimeout took %v, expected to return shortly after the deadline", elapsed)
+	}
+}
+
+func TestWithSignalHandling_StopWithTimeout_NotStarted(t *testing.T) {
+	executor := NewWithSignalHandling()
+	// An execution still waiting to start its process cannot be killed.
+	executor.processes["pending#1"] = &trackedExecution{cancel: func() {}, done: make(chan struct{})}
+
+	killed, failed := executor.StopWithTimeout(10 * time.Millisecond)
+	var stateErr *ExecutionStateError
+	if len(killed) != 0 || !errors.As(failed["pending#1"], &stateErr) {
+		t.Errorf("StopWithTimeout() = %v, %v, want pending#1 reported as failed", killed, failed)
+	}
+}
+
+func TestWithSignalHandling_PauseResume(t *testing.T) {
+	executor := NewWithSignalHandling()
+
+	ctx, err := executor.Start()
+	if err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	defer executor.Stop()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = executor.Execute(ctx, ToolConfig{Command: "sleep", Args: []string{"0.3"}})
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	ids := executor.RunningExecutionIDs()
+	if len(ids) != 1 {
+		t.Fatalf("RunningExecutionIDs() = %v, want one ID", ids)
+	}
+	if err := executor.Pause(ids[0]); err != nil {
+		t.Fatalf("Pause() error = %v", err)
+	}
+	// Pausing twice is a no-op.
+	if err := executor.Pause(ids[0]); err != nil {
+		t.Fatalf("second Pause() error = %v", err)
+	}
+
+	select {
+	case <-done:
+		t.Fatal("paused command completed")
+	case <-time.After(600 * time.Millisecond):
+	}
+
+	if err := executor.Resume(ids[0]); err != nil {
+		t.Fatalf("Resume() error = %v", err)
+	}
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("resumed command did not complete")
+	}
+}
+
+func TestWithSignalHandling_PauseUnknownID(t *testing.T) {
+	executor := NewWithSignalHandling()
+
+	var shErr *SignalHandlerError
+	if err := executor.Pause("missing#1"); !errors.As(err, &shErr) {
+		t.Errorf("Pause() error = %v, want *SignalHandlerError", err)
+	}
+	if err := executor.Resume("missing#1"); !errors.As(err, &shErr) {
+		t.Errorf("Resume() error = %v, want *SignalHandlerError", err)
+	}
+}
+
+func TestWithSignalHandling_StopCancelsPausedExecution(t *testing.T) {
+	executor := NewWithSignalHandling()
+
+	ctx, err := executor.Start()
+	if err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = executor.Execute(ctx, ToolConfig{Command: "sleep", Args: []string{"10"}})
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	for _, id := range executor.RunningExecutionIDs() {
+		if err := executor.Pause(id); err != nil {
+			t.Fatalf("Pause() error = %v", err)
+		}
+	}
+	executor.Stop()
+
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("paused command was not cancelled by Stop")
+	}
+}
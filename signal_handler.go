@@ -1,4 +1,4 @@
-//go:build unix
+//go:build unix || windows
 
 package cmdexec
 
@@ -8,8 +8,6 @@ import (
 	"os"
 	"os/signal"
 	"sync"
-
-	"golang.org/x/sys/unix"
 )
 
 // SignalHandler manages OS signal handling and graceful shutdown of processes.
@@ -51,18 +49,14 @@ func (sh *SignalHandler) Start() (context.Context, error) {
 	sh.signals = make(chan os.Signal, 1)
 	sh.running = true
 
-	// Register for termination signals
-	signal.Notify(sh.signals,
-		unix.SIGINT,  // Ctrl+C
-		unix.SIGTERM, // Termination signal
-		unix.SIGHUP,  // Hangup
-	)
+	// Register for the platform's termination signals
+	signal.Notify(sh.signals, handledSignals...)
 
 	// Start the signal handling goroutine
 	sh.wg.Add(1)
 	go sh.handleSignals()
 
-	slog.Debug("Signal handler started", "signals", []string{"SIGINT", "SIGTERM", "SIGHUP"})
+	slog.Debug("Signal handler started", "signals", handledSignalNames)
 
 	return ctx, nil
 }
@@ -99,20 +93,18 @@ func (sh *SignalHandler) handleSignals() {
 	for sig := range sh.signals {
 		slog.Debug("Received signal", "signal", sig.String())
 
-		switch sig {
-		case unix.SIGINT, unix.SIGTERM:
-			// Cancel the context for graceful shutdown
-			if sh.cancel != nil {
-				slog.Debug("Initiating graceful shutdown", "signal", sig.String())
-				sh.cancel()
-			}
-			// For SIGINT/SIGTERM, we stop listening for more signals
-			signal.Stop(sh.signals)
-			return
-		case unix.SIGHUP:
-			// SIGHUP typically means reload configuration, but for now we just log it
-			slog.Debug("Received SIGHUP signal (reload not implemented)")
+		if !isShutdownSignal(sig) {
+			continue
+		}
+
+		// Cancel the context for graceful shutdown
+		if sh.cancel != nil {
+			slog.Debug("Initiating graceful shutdown", "signal", sig.String())
+			sh.cancel()
 		}
+		// After a shutdown signal, we stop listening for more signals
+		signal.Stop(sh.signals)
+		return
 	}
 }
 
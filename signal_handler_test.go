@@ -151,3 +151,19 @@ func TestSignalHandlerError(t *testing.T) {
 		t.Errorf("Error() = %q, want %q", err.Error(), expected)
 	}
 }
+
+func TestIsShutdownSignal(t *testing.T) {
+	tests := []struct {
+		sig  os.Signal
+		want bool
+	}{
+		{unix.SIGINT, true},
+		{unix.SIGTERM, true},
+		{unix.SIGHUP, false},
+	}
+	for _, tt := range tests {
+		if got := isShutdownSignal(tt.sig); got != tt.want {
+			t.Errorf("isShutdownSignal(%v) = %v, want %v", tt.sig, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+//go:build unix
+
+package cmdexec
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// handledSignals are the signals SignalHandler listens for on Unix.
+var handledSignals = []os.Signal{
+	unix.SIGINT,  // Ctrl+C
+	unix.SIGTERM, // Termination signal
+	unix.SIGHUP,  // Hangup
+}
+
+var handledSignalNames = []string{"SIGINT", "SIGTERM", "SIGHUP"}
+
+// isShutdownSignal reports whether sig should trigger a graceful shutdown.
+func isShutdownSignal(sig os.Signal) bool {
+	switch sig {
+	case unix.SIGINT, unix.SIGTERM:
+		return true
+	case unix.SIGHUP:
+		// SIGHUP typically means reload configuration, but for now we just log it
+		slog.Debug("Received SIGHUP signal (reload not implemented)")
+	}
+	return false
+}
+
+// gracefulCommandBuilder returns builder unchanged on Unix, where
+// exec.CommandContext already terminates the child on cancellation.
+func gracefulCommandBuilder(builder CommandBuilder) CommandBuilder {
+	return builder
+}
+
+// suspendProcess stops p with SIGSTOP.
+func suspendProcess(p *os.Process) error {
+	if err := p.Signal(unix.SIGSTOP); err != nil {
+		return fmt.Errorf("failed to suspend process %d: %w", p.Pid, err)
+	}
+	return nil
+}
+
+// resumeProcess continues p with SIGCONT.
+func resumeProcess(p *os.Process) error {
+	if err := p.Signal(unix.SIGCONT); err != nil {
+		return fmt.Errorf("failed to resume process %d: %w", p.Pid, err)
+	}
+	return nil
+}
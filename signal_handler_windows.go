@@ -0,0 +1,77 @@
+//go:build windows
+
+package cmdexec
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"syscall" //nolint:depguard // exec.Cmd.SysProcAttr is a syscall type; x/sys has no equivalent
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+// handledSignals are the signals SignalHandler listens for on Windows.
+// The Go runtime delivers both CTRL_C_EVENT and CTRL_BREAK_EVENT as
+// os.Interrupt.
+var handledSignals = []os.Signal{os.Interrupt}
+
+var handledSignalNames = []string{"CTRL_C", "CTRL_BREAK"}
+
+// ctrlBreakGracePeriod is how long a child may take to exit after receiving
+// CTRL_BREAK_EVENT before it is forcibly killed.
+const ctrlBreakGracePeriod = 5 * time.Second
+
+// isShutdownSignal reports whether sig should trigger a graceful shutdown.
+func isShutdownSignal(sig os.Signal) bool {
+	return sig == os.Interrupt
+}
+
+// gracefulCommandBuilder wraps builder so that children are started in their
+// own console process group and receive CTRL_BREAK_EVENT when the execution
+// context is cancelled, instead of being terminated outright.
+func gracefulCommandBuilder(builder CommandBuilder) CommandBuilder {
+	if builder == nil {
+		builder = &DirectCommandBuilder{}
+	}
+	return &consoleCtrlCommandBuilder{inner: builder}
+}
+
+// consoleCtrlCommandBuilder decorates a CommandBuilder with console control
+// event based cancellation.
+type consoleCtrlCommandBuilder struct {
+	inner CommandBuilder
+}
+
+// Build creates the command with the inner builder and configures graceful
+// cancellation via GenerateConsoleCtrlEvent.
+func (b *consoleCtrlCommandBuilder) Build(ctx context.Context, command string, args []string) *exec.Cmd {
+	cmd := b.inner.Build(ctx, command, args)
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.CreationFlags |= windows.CREATE_NEW_PROCESS_GROUP
+	cmd.Cancel = func() error {
+		if cmd.Process == nil {
+			return nil
+		}
+		// The process group ID of a CREATE_NEW_PROCESS_GROUP child is its PID.
+		if err := windows.GenerateConsoleCtrlEvent(windows.CTRL_BREAK_EVENT, uint32(cmd.Process.Pid)); err != nil { //nolint:gosec // PIDs fit in uint32 on Windows
+			return cmd.Process.Kill() //nolint:wrapcheck // passed back to os/exec
+		}
+		return nil
+	}
+	cmd.WaitDelay = ctrlBreakGracePeriod
+	return cmd
+}
+
+// suspendProcess is not supported on Windows.
+func suspendProcess(_ *os.Process) error {
+	return &SignalHandlerError{Message: "pausing processes is not supported on Windows"}
+}
+
+// resumeProcess is not supported on Windows.
+func resumeProcess(_ *os.Process) error {
+	return &SignalHandlerError{Message: "resuming processes is not supported on Windows"}
+}
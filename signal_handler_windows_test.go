@@ -0,0 +1,32 @@
+//go:build windows
+
+package cmdexec
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"golang.org/x/sys/windows"
+)
+
+func TestIsShutdownSignal_Windows(t *testing.T) {
+	if !isShutdownSignal(os.Interrupt) {
+		t.Error("isShutdownSignal(os.Interrupt) = false, want true")
+	}
+}
+
+func TestGracefulCommandBuilder_Windows(t *testing.T) {
+	builder := gracefulCommandBuilder(nil)
+	cmd := builder.Build(context.Background(), "cmd", []string{"/c", "echo", "hi"})
+
+	if cmd.SysProcAttr == nil || cmd.SysProcAttr.CreationFlags&windows.CREATE_NEW_PROCESS_GROUP == 0 {
+		t.Error("expected CREATE_NEW_PROCESS_GROUP to be set")
+	}
+	if cmd.Cancel == nil {
+		t.Error("expected Cancel to be set")
+	}
+	if cmd.WaitDelay != ctrlBreakGracePeriod {
+		t.Errorf("WaitDelay = %v, want %v", cmd.WaitDelay, ctrlBreakGracePeriod)
+	}
+}
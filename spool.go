@@ -0,0 +1,67 @@
+package cmdexec
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+)
+
+// spoolWriter captures output in memory up to threshold bytes. When the
+// threshold is exceeded it creates a temporary file, copies the buffered
+// head into it, and writes all further output to the file, so the file
+// holds the complete output while memory use stays bounded.
+type spoolWriter struct {
+	buf       *bytes.Buffer
+	threshold int64
+	res       *tempResources
+	pattern   string
+	file      *os.File
+}
+
+func (w *spoolWriter) Write(p []byte) (int, error) {
+	if w.file == nil {
+		room := w.threshold - int64(w.buf.Len())
+		if int64(len(p)) <= room {
+			return w.buf.Write(p) //nolint:wrapcheck // bytes.Buffer never fails
+		}
+		if err := w.spill(); err != nil {
+			return 0, err
+		}
+		w.buf.Write(p[:room])
+	}
+	return w.file.Write(p) //nolint:wrapcheck // io.Writer passthrough
+}
+
+// spill creates the spool file and copies the buffered output into it.
+func (w *spoolWriter) spill() error {
+	f, err := os.CreateTemp("", w.pattern)
+	if err != nil {
+		return fmt.Errorf("failed to create spool file: %w", err)
+	}
+	w.res.track(f.Name())
+	w.file = f
+	if _, err := f.Write(w.buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write spool file: %w", err)
+	}
+	return nil
+}
+
+// path returns the spool file path, or "" if output never exceeded the
+// threshold. It is safe to call on a nil writer.
+func (w *spoolWriter) path() string {
+	if w == nil || w.file == nil {
+		return ""
+	}
+	return w.file.Name()
+}
+
+// close closes the spool file, if one was created.
+func (w *spoolWriter) close() error {
+	if w.file == nil {
+		return nil
+	}
+	if err := w.file.Close(); err != nil {
+		return fmt.Errorf("failed to close spool file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package cmdexec
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestBasicExecutor_Execute_SpoolThreshold(t *testing.T) {
+	result, err := NewBasicExecutor().Execute(context.Background(), ToolConfig{
+		Command:        "sh",
+		Args:           []string{"-c", "i=0; while [ $i -lt 100 ]; do echo line$i; i=$((i+1)); done; echo small >&2"},
+		SpoolThreshold: 64,
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	if result.StdoutSpoolPath == "" {
+		t.Fatal("StdoutSpoolPath is empty, want stdout to be spooled")
+	}
+	if result.StderrSpoolPath != "" {
+		t.Errorf("StderrSpoolPath = %q, want stderr kept in memory", result.StderrSpoolPath)
+	}
+	if len(result.Output) != 64 || !strings.HasPrefix(result.Output, "line0\nline1\n") {
+		t.Errorf("Output = %q, want the first 64 bytes", result.Output)
+	}
+
+	r, err := result.StdoutReader()
+	if err != nil {
+		t.Fatalf("StdoutReader() error = %v", err)
+	}
+	data, err := io.ReadAll(r)
+	_ = r.Close()
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != 100 || lines[0] != "line0" || lines[99] != "line99" {
+		t.Errorf("spooled stdout has %d lines (first %q), want line0..line99", len(lines), lines[0])
+	}
+
+	r, err = result.StderrReader()
+	if err != nil {
+		t.Fatalf("StderrReader() error = %v", err)
+	}
+	data, _ = io.ReadAll(r)
+	if string(data) != "small\n" {
+		t.Errorf("StderrReader() = %q, want %q", data, "small\n")
+	}
+
+	if err := CheckTempResourceLeaks(); err == nil {
+		t.Error("spool file should be tracked until Cleanup")
+	}
+	if err := result.Cleanup(); err != nil {
+		t.Fatalf("Cleanup() error = %v", err)
+	}
+	if _, err := os.Stat(result.StdoutSpoolPath); !os.IsNotExist(err) {
+		t.Errorf("spool file still exists after Cleanup: %v", err)
+	}
+	if err := result.Cleanup(); err != nil {
+		t.Errorf("second Cleanup() error = %v", err)
+	}
+	if err := CheckTempResourceLeaks(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBasicExecutor_Execute_SpoolRemovedForRetriedAttempts(t *testing.T) {
+	_, err := NewBasicExecutor().Execute(context.Background(), ToolConfig{
+		Command:        "sh",
+		Args:           []string{"-c", "echo 0123456789abcdef; exit 1"},
+		SpoolThreshold: 4,
+		MaxRetries:     2,
+	})
+	retryErr, ok := err.(*RetryExhaustedError)
+	if !ok {
+		t.Fatalf("Execute() error = %v, want *RetryExhaustedError", err)
+	}
+
+	// Only the last attempt's spool file survives, owned by LastResult.
+	if paths := TempResources(); len(paths) != 1 || paths[0] != retryErr.LastResult.StdoutSpoolPath {
+		t.Errorf("TempResources() = %v, want only %q", paths, retryErr.LastResult.StdoutSpoolPath)
+	}
+	if err := retryErr.LastResult.Cleanup(); err != nil {
+		t.Fatalf("Cleanup() error = %v", err)
+	}
+	if err := CheckTempResourceLeaks(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestExecutionResult_ReaderWithoutSpool(t *testing.T) {
+	result := &ExecutionResult{Output: "in memory"}
+	r, err := result.StdoutReader()
+	if err != nil {
+		t.Fatalf("StdoutReader() error = %v", err)
+	}
+	data, _ := io.ReadAll(r)
+	if string(data) != "in memory" {
+		t.Errorf("StdoutReader() = %q, want %q", data, "in memory")
+	}
+	if err := (*ExecutionResult)(nil).Cleanup(); err != nil {
+		t.Errorf("nil Cleanup() error = %v", err)
+	}
+}
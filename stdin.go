@@ -0,0 +1,161 @@
+package cmdexec
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+)
+
+// SharedStdin holds stdin content that can be fed to many executions at once.
+// Each reader returned by Reader is independent and reads from the same
+// immutable backing buffer, so concurrent executions in a batch do not consume
+// each other's input. A SharedStdin is safe for concurrent use.
+type SharedStdin struct {
+	data []byte
+}
+
+// NewSharedStdin creates a SharedStdin from the given bytes.
+// The data is copied, so later changes to the slice do not affect readers.
+func NewSharedStdin(data []byte) *SharedStdin {
+	return &SharedStdin{data: bytes.Clone(data)}
+}
+
+// NewSharedStdinString creates a SharedStdin from the given string.
+func NewSharedStdinString(s string) *SharedStdin {
+	return &SharedStdin{data: []byte(s)}
+}
+
+// ReadSharedStdin reads r to EOF and returns a SharedStdin holding its content.
+func ReadSharedStdin(r io.Reader) (*SharedStdin, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read shared stdin: %w", err)
+	}
+	return &SharedStdin{data: data}, nil
+}
+
+// Reader returns a new reader positioned at the start of the shared content.
+func (s *SharedStdin) Reader() io.Reader {
+	return bytes.NewReader(s.data)
+}
+
+// Factory returns a function suitable for ToolConfig.StdinFactory. Every call
+// produces a fresh, independent reader, which also makes SharedStdin safe to
+// combine with MaxRetries.
+func (s *SharedStdin) Factory() func() io.Reader {
+	return s.Reader
+}
+
+// Len returns the size of the shared content in bytes.
+func (s *SharedStdin) Len() int {
+	return len(s.data)
+}
+
+// prepareStdin sets cfg.Stdin for one attempt from StdinFactory or
+// StdinProvider. The returned func closes a provided reader that is an
+// io.Closer.
+func prepareStdin(cfg *ToolConfig) (func(), error) {
+	switch {
+	case cfg.StdinFactory != nil:
+		cfg.Stdin = cfg.StdinFactory()
+	case cfg.StdinProvider != nil:
+		r, err := cfg.StdinProvider()
+		if err != nil {
+			return nil, fmt.Errorf("failed to provide stdin: %w", err)
+		}
+		cfg.Stdin = r
+		if closer, ok := r.(io.Closer); ok {
+			return func() { _ = closer.Close() }, nil
+		}
+	}
+	return func() {}, nil
+}
+
+// errStdinLimit stops the copy of stdin to a command that exceeded
+// MaxStdinBytes.
+var errStdinLimit = errors.New("stdin limit exceeded")
+
+// openStdinFile opens the StdinFile of cfg for one attempt, or returns nil
+// if none is set. A regular file larger than MaxStdinBytes is rejected
+// without being read.
+func openStdinFile(cfg ToolConfig) (*os.File, error) {
+	if cfg.StdinFile == "" {
+		return nil, nil
+	}
+	f, err := os.Open(cfg.StdinFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stdin file: %w", err)
+	}
+	if cfg.MaxStdinBytes > 0 {
+		if info, err := f.Stat(); err == nil && info.Mode().IsRegular() && info.Size() > cfg.MaxStdinBytes {
+			_ = f.Close()
+			return nil, &StdinLimitError{Command: buildCommandString(cfg.Command, cfg.Args), Limit: cfg.MaxStdinBytes}
+		}
+	}
+	return f, nil
+}
+
+// stdinGuard enforces MaxStdinBytes for one execution attempt. It reads at
+// most one byte past the limit, and if that byte exists, cancels its
+// context to kill the command instead of passing the input on.
+type stdinGuard struct {
+	r        io.Reader
+	limit    int64
+	read     int64
+	cancel   context.CancelFunc
+	exceeded atomic.Bool
+}
+
+// newStdinGuard derives a context from ctx that is cancelled when the input
+// passed through the guard exceeds limit. For a limit of zero, it returns
+// ctx and a nil guard; all stdinGuard methods are safe to call on nil.
+func newStdinGuard(ctx context.Context, limit int64) (context.Context, *stdinGuard) {
+	if limit <= 0 {
+		return ctx, nil
+	}
+	guardCtx, cancel := context.WithCancel(ctx)
+	return guardCtx, &stdinGuard{limit: limit, cancel: cancel}
+}
+
+// wrap returns r guarded by the limit, or r itself if there is no guard or
+// no input.
+func (g *stdinGuard) wrap(r io.Reader) io.Reader {
+	if g == nil || r == nil {
+		return r
+	}
+	g.r = r
+	return g
+}
+
+// Read reads from the guarded reader. It is only called by the goroutine
+// os/exec uses to copy stdin.
+func (g *stdinGuard) Read(p []byte) (int, error) {
+	if remaining := g.limit - g.read + 1; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	n, err := g.r.Read(p)
+	if g.read+int64(n) > g.limit {
+		g.exceeded.Store(true)
+		g.cancel()
+		return 0, errStdinLimit
+	}
+	g.read += int64(n)
+	return n, err //nolint:wrapcheck // io.Reader errors such as io.EOF must not be wrapped
+}
+
+// limitExceeded reports whether the input exceeded the limit.
+func (g *stdinGuard) limitExceeded() bool {
+	return g != nil && g.exceeded.Load()
+}
+
+// stop releases the guard's context.
+func (g *stdinGuard) stop() {
+	if g == nil {
+		return
+	}
+	g.cancel()
+}
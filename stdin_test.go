@@ -0,0 +1,221 @@
+package cmdexec
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSharedStdin_IndependentReaders(t *testing.T) {
+	shared := NewSharedStdinString("hello\nworld\n")
+
+	if shared.Len() != 12 {
+		t.Errorf("Len() = %d, want 12", shared.Len())
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			data, err := io.ReadAll(shared.Reader())
+			if err != nil {
+				errs <- err
+				return
+			}
+			if string(data) != "hello\nworld\n" {
+				errs <- errors.New("unexpected content: " + string(data))
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestNewSharedStdin_CopiesInput(t *testing.T) {
+	data := []byte("abc")
+	shared := NewSharedStdin(data)
+	data[0] = 'x'
+
+	got, _ := io.ReadAll(shared.Reader())
+	if string(got) != "abc" {
+		t.Errorf("content = %q, want %q", got, "abc")
+	}
+}
+
+func TestReadSharedStdin(t *testing.T) {
+	shared, err := ReadSharedStdin(strings.NewReader("from reader"))
+	if err != nil {
+		t.Fatalf("ReadSharedStdin() error = %v", err)
+	}
+	got, _ := io.ReadAll(shared.Factory()())
+	if string(got) != "from reader" {
+		t.Errorf("content = %q, want %q", got, "from reader")
+	}
+}
+
+func TestSharedStdin_ConcurrentBatch(t *testing.T) {
+	shared := NewSharedStdinString("line1\nline2\n")
+	configs := make([]ToolConfig, 5)
+	for i := range configs {
+		configs[i] = ToolConfig{
+			Command:      "cat",
+			StdinFactory: shared.Factory(),
+		}
+	}
+
+	ce := NewConcurrentExecutor(NewBasicExecutor())
+	results, err := ce.ExecuteAll(context.Background(), configs)
+	if err != nil {
+		t.Fatalf("ExecuteAll() error = %v", err)
+	}
+	for _, r := range results {
+		if r.Error != nil {
+			t.Fatalf("result[%d] error = %v", r.Index, r.Error)
+		}
+		if r.Result.Output != "line1\nline2\n" {
+			t.Errorf("result[%d] output = %q, want full input", r.Index, r.Result.Output)
+		}
+	}
+}
+
+func TestBasicExecutor_Execute_StdinFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "input.txt")
+	if err := os.WriteFile(path, []byte("hello\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	executor := NewBasicExecutor()
+
+	// Every attempt reads the file from the start.
+	_, err := executor.Execute(context.Background(), ToolConfig{
+		Command:    "sh",
+		Args:       []string{"-c", "cat; exit 1"},
+		StdinFile:  path,
+		MaxRetries: 1,
+	})
+	var retryErr *RetryExhaustedError
+	if !errors.As(err, &retryErr) || retryErr.LastResult == nil || retryErr.LastResult.Output != "hello\n" {
+		t.Errorf("retried Execute() error = %v, want the last attempt to read the file again", err)
+	}
+
+	if _, err := executor.Execute(context.Background(), ToolConfig{Command: "cat", StdinFile: path + ".missing"}); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Execute() with a missing file error = %v, want fs.ErrNotExist", err)
+	}
+
+	var validationErr *ValidationError
+	cfg := ToolConfig{Command: "cat", StdinFile: path, Stdin: strings.NewReader("x")}
+	if _, err := executor.Execute(context.Background(), cfg); !errors.As(err, &validationErr) || validationErr.Field != "StdinFile" {
+		t.Errorf("Execute() with StdinFile and Stdin error = %v, want *ValidationError", err)
+	}
+}
+
+func TestBasicExecutor_Execute_MaxStdinBytes(t *testing.T) {
+	executor := NewBasicExecutor()
+	ctx := context.Background()
+
+	result, err := executor.Execute(ctx, ToolConfig{Command: "cat", Stdin: strings.NewReader("12345"), MaxStdinBytes: 5})
+	if err != nil || result.Output != "12345" {
+		t.Fatalf("Execute() at the limit = %v, %v, want the input passed through", result, err)
+	}
+
+	var limitErr *StdinLimitError
+	big := strings.NewReader(strings.Repeat("x", 1<<20))
+	if _, err := executor.Execute(ctx, ToolConfig{Command: "cat", Stdin: big, MaxStdinBytes: 1024}); !errors.As(err, &limitErr) || limitErr.Limit != 1024 {
+		t.Errorf("Execute() over the limit error = %v, want *StdinLimitError", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "input.txt")
+	if err := os.WriteFile(path, []byte("too long"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := executor.Execute(ctx, ToolConfig{Command: "cat", StdinFile: path, MaxStdinBytes: 4}); !errors.As(err, &limitErr) {
+		t.Errorf("Execute() with a large StdinFile error = %v, want *StdinLimitError", err)
+	}
+}
+
+// closeRecorder is a reader that records whether it was closed.
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestBasicExecutor_Execute_StdinProvider(t *testing.T) {
+	executor := NewBasicExecutor()
+	var readers []*closeRecorder
+	_, err := executor.Execute(context.Background(), ToolConfig{
+		Command:    "sh",
+		Args:       []string{"-c", "cat; exit 1"},
+		MaxRetries: 2,
+		StdinProvider: func() (io.Reader, error) {
+			r := &closeRecorder{Reader: strings.NewReader("attempt\n")}
+			readers = append(readers, r)
+			return r, nil
+		},
+	})
+	var retryErr *RetryExhaustedError
+	if !errors.As(err, &retryErr) || retryErr.LastResult == nil || retryErr.LastResult.Output != "attempt\n" {
+		t.Fatalf("Execute() error = %v, want every attempt to receive its input", err)
+	}
+	if len(readers) != 3 {
+		t.Fatalf("StdinProvider called %d times, want once per attempt", len(readers))
+	}
+	for i, r := range readers {
+		if !r.closed {
+			t.Errorf("reader %d was not closed after its attempt", i)
+		}
+	}
+
+	errUnavailable := errors.New("stream unavailable")
+	result, err := executor.Execute(context.Background(), ToolConfig{
+		Command:       "cat",
+		StdinProvider: func() (io.Reader, error) { return nil, errUnavailable },
+	})
+	if result != nil || !errors.Is(err, errUnavailable) {
+		t.Errorf("Execute() with a failing provider = %v, %v, want the provider's error", result, err)
+	}
+
+	// A provider failing on a retry reports the attempts made so far.
+	calls := 0
+	_, err = executor.Execute(context.Background(), ToolConfig{
+		Command:    "sh",
+		Args:       []string{"-c", "cat; exit 1"},
+		MaxRetries: 2,
+		StdinProvider: func() (io.Reader, error) {
+			if calls++; calls > 1 {
+				return nil, errUnavailable
+			}
+			return strings.NewReader("first\n"), nil
+		},
+	})
+	if !errors.As(err, &retryErr) || !errors.Is(err, errUnavailable) {
+		t.Fatalf("Execute() with a provider failing on retry error = %v, want *RetryExhaustedError", err)
+	}
+	if retryErr.Attempts != 2 || len(retryErr.History) != 2 || retryErr.LastResult.Output != "first\n" {
+		t.Errorf("RetryExhaustedError = %+v, want the first attempt and the failed second one", retryErr)
+	}
+
+	var validationErr *ValidationError
+	cfg := ToolConfig{
+		Command:       "cat",
+		StdinFactory:  func() io.Reader { return strings.NewReader("") },
+		StdinProvider: func() (io.Reader, error) { return strings.NewReader(""), nil },
+	}
+	if err := cfg.Validate(); !errors.As(err, &validationErr) || validationErr.Field != "StdinProvider" {
+		t.Errorf("Validate() with StdinFactory and StdinProvider error = %v, want *ValidationError", err)
+	}
+}
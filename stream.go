@@ -0,0 +1,118 @@
+package cmdexec
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// Stream identifies which output stream of a command produced data.
+type Stream string
+
+// Output streams reported in OutputEvent.
+const (
+	StreamStdout Stream = "stdout"
+	StreamStderr Stream = "stderr"
+)
+
+// OutputEvent is a chunk of command output delivered by ExecuteStream.
+type OutputEvent struct {
+	// Stream is the stream the data was written to.
+	Stream Stream `json:"stream"`
+
+	// Data is the chunk as written by the command. It is owned by the
+	// receiver and not reused.
+	Data []byte `json:"data"`
+
+	// Time is when the chunk was read from the command.
+	Time time.Time `json:"time"`
+}
+
+// StreamResult is the outcome of an execution started by ExecuteStream.
+type StreamResult struct {
+	// Result is the execution result, or nil if the execution failed with
+	// an error before producing one.
+	Result *ExecutionResult
+
+	// Error is the error returned by Execute, such as a *TimeoutError or
+	// *ExecutableNotFoundError.
+	Error error
+}
+
+// streamEventBuffer is the capacity of the event channel returned by
+// ExecuteStream, which lets short bursts of output proceed without waiting
+// on the consumer.
+const streamEventBuffer = 64
+
+// ExecuteStream starts cfg on executor and returns a channel of output
+// events followed by a channel that receives the final result. Output is
+// still captured in the result as with Execute, and any StdoutWriter or
+// StderrWriter in cfg still receives it.
+//
+// The events channel is closed when the command finishes, after which the
+// result channel receives exactly one value and is closed. Execution
+// errors are delivered in StreamResult.Error unchanged, so errors.As works
+// on them; only configuration errors are returned directly.
+//
+// Consumers must drain the events channel: the command blocks once the
+// buffer is full. Events are dropped instead if ctx is done.
+func ExecuteStream(ctx context.Context, executor Executor, cfg ToolConfig) (<-chan OutputEvent, <-chan StreamResult, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	events := make(chan OutputEvent, streamEventBuffer)
+	results := make(chan StreamResult, 1)
+
+	cfg = cfg.Clone()
+	cfg.StdoutWriter = newEventWriter(ctx, events, StreamStdout, cfg.StdoutWriter)
+	cfg.StderrWriter = newEventWriter(ctx, events, StreamStderr, cfg.StderrWriter)
+
+	go func() {
+		defer close(results)
+		result, err := executor.Execute(ctx, cfg)
+		close(events)
+		results <- StreamResult{Result: result, Error: err}
+	}()
+
+	return events, results, nil
+}
+
+// eventWriter forwards each write as an OutputEvent and to an optional
+// underlying writer.
+type eventWriter struct {
+	ctx    context.Context
+	events chan<- OutputEvent
+	stream Stream
+	next   io.Writer
+}
+
+func newEventWriter(ctx context.Context, events chan<- OutputEvent, stream Stream, next io.Writer) io.Writer {
+	return &eventWriter{ctx: ctx, events: events, stream: stream, next: next}
+}
+
+func (w *eventWriter) Write(p []byte) (int, error) {
+	if w.next != nil {
+		if n, err := w.next.Write(p); err != nil {
+			return n, err //nolint:wrapcheck // delegating to the caller's writer
+		}
+	}
+
+	ev := OutputEvent{Stream: w.stream, Data: append([]byte(nil), p...), Time: time.Now()}
+	select {
+	case w.events <- ev:
+	case <-w.ctx.Done():
+	}
+	return len(p), nil
+}
+
+// Flush flushes the underlying writer, if it supports flushing.
+func (w *eventWriter) Flush() error {
+	if w.next == nil {
+		return nil
+	}
+	if flush := flushFunc(w.next); flush != nil {
+		return flush()
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package cmdexec
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestExecuteStream(t *testing.T) {
+	var tee strings.Builder
+	events, results, err := ExecuteStream(context.Background(), NewBasicExecutor(), ToolConfig{
+		Command:      "sh",
+		Args:         []string{"-c", "echo out; echo err >&2"},
+		StdoutWriter: &tee,
+	})
+	if err != nil {
+		t.Fatalf("ExecuteStream() error = %v", err)
+	}
+
+	got := map[Stream]string{}
+	for ev := range events {
+		if ev.Time.IsZero() {
+			t.Error("event has zero Time")
+		}
+		got[ev.Stream] += string(ev.Data)
+	}
+	if got[StreamStdout] != "out\n" || got[StreamStderr] != "err\n" {
+		t.Errorf("events = %q, want stdout %q and stderr %q", got, "out\n", "err\n")
+	}
+
+	sr, ok := <-results
+	if !ok || sr.Result == nil || sr.Error != nil {
+		t.Fatalf("results channel delivered %+v, want a result", sr)
+	}
+	result := sr.Result
+	if result.ExitCode != 0 || result.Output != "out\n" || result.Stderr != "err\n" {
+		t.Errorf("result = %+v", result)
+	}
+	if tee.String() != "out\n" {
+		t.Errorf("StdoutWriter got %q, want %q", tee.String(), "out\n")
+	}
+	if _, ok := <-results; ok {
+		t.Error("results channel should be closed after the result")
+	}
+}
+
+func TestExecuteStream_ValidationError(t *testing.T) {
+	_, _, err := ExecuteStream(context.Background(), NewBasicExecutor(), ToolConfig{})
+	var valErr *ValidationError
+	if !errors.As(err, &valErr) {
+		t.Errorf("ExecuteStream() error = %v, want *ValidationError", err)
+	}
+}
+
+func TestExecuteStream_ExecutionError(t *testing.T) {
+	events, results, err := ExecuteStream(context.Background(), NewBasicExecutor(), ToolConfig{
+		Command: "nonexistent-command-12345",
+	})
+	if err != nil {
+		t.Fatalf("ExecuteStream() error = %v", err)
+	}
+	for range events {
+		t.Error("unexpected output event")
+	}
+
+	sr := <-results
+	var notFound *ExecutableNotFoundError
+	if !errors.As(sr.Error, &notFound) || notFound.Command != "nonexistent-command-12345" {
+		t.Errorf("result error = %v, want *ExecutableNotFoundError", sr.Error)
+	}
+}
+
+func TestExecuteStream_CancelledConsumer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	_, results, err := ExecuteStream(ctx, NewBasicExecutor(), ToolConfig{
+		Command: "sh",
+		Args:    []string{"-c", "i=0; while [ $i -lt 500 ]; do echo line $i; i=$((i+1)); done"},
+	})
+	if err != nil {
+		t.Fatalf("ExecuteStream() error = %v", err)
+	}
+
+	// Nobody reads events; cancelling must still let the execution finish.
+	cancel()
+	if _, ok := <-results; !ok {
+		t.Fatal("expected a result after cancellation")
+	}
+}
@@ -0,0 +1,46 @@
+package cmdexec
+
+import (
+	"os"
+	"os/exec"
+)
+
+// ReapEvent describes an orphaned descendant process that was reaped by the
+// subreaper. See EnableSubreaper.
+type ReapEvent struct {
+	// PID is the process ID of the reaped process.
+	PID int
+
+	// ExitCode is the exit status of the process, or -1 if it was killed
+	// by a signal.
+	ExitCode int
+
+	// Signal is the name of the signal that terminated the process, or
+	// empty if it exited normally.
+	Signal string
+}
+
+// SubreaperError represents errors related to subreaper mode.
+type SubreaperError struct {
+	Message string
+}
+
+func (e *SubreaperError) Error() string {
+	return "subreaper error: " + e.Message
+}
+
+// runCommand starts cmd and waits for it to finish. Commands are registered
+// with the active subreaper (if any) for their whole lifetime, so the reaper
+// never collects an exit status that os/exec is waiting for. If onStart is
+// non-nil, it is called with the started process before waiting.
+func runCommand(cmd *exec.Cmd, onStart func(*os.Process)) error {
+	release, err := startCommand(cmd)
+	if err != nil {
+		return err //nolint:wrapcheck // inspected by processExecutionError
+	}
+	defer release()
+	if onStart != nil {
+		onStart(cmd.Process)
+	}
+	return cmd.Wait() //nolint:wrapcheck // inspected by processExecutionError
+}
@@ -0,0 +1,232 @@
+//go:build linux
+
+package cmdexec
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// subreaperPollInterval is how often the subreaper rescans for orphans in
+// addition to reacting to SIGCHLD, which the kernel may coalesce.
+const subreaperPollInterval = time.Second
+
+var (
+	// subreaperMu protects activeSubreaper.
+	subreaperMu sync.Mutex
+	// activeSubreaper is the process-wide subreaper, if enabled.
+	activeSubreaper *Subreaper
+)
+
+// Subreaper makes the current process a child subreaper and reaps orphaned
+// descendants. Create one with EnableSubreaper.
+type Subreaper struct {
+	onReap func(ReapEvent)
+
+	// mu serializes starting managed commands with reaping, and protects managed.
+	mu sync.Mutex
+	// managed holds the PIDs of commands started by this package, which
+	// are waited for by os/exec and must never be reaped here.
+	managed map[int]struct{}
+
+	signals chan os.Signal
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// EnableSubreaper marks the current process as a child subreaper
+// (PR_SET_CHILD_SUBREAPER), so grandchildren orphaned by shell wrappers are
+// re-parented to this process instead of init, and starts reaping them so
+// they do not linger as zombies. onReap, if non-nil, is called for every
+// reaped orphan.
+//
+// Subreaper mode is process-wide: only one Subreaper may be active at a
+// time. Enable it before starting executions, and run all subprocesses
+// through this package while it is active; child processes started by other
+// means (for example directly via os/exec) may have their exit status
+// collected by the reaper. Call Stop to disable it. Only Linux is supported.
+func EnableSubreaper(onReap func(ReapEvent)) (*Subreaper, error) {
+	subreaperMu.Lock()
+	defer subreaperMu.Unlock()
+
+	if activeSubreaper != nil {
+		return nil, &SubreaperError{Message: "subreaper is already enabled"}
+	}
+
+	if err := unix.Prctl(unix.PR_SET_CHILD_SUBREAPER, 1, 0, 0, 0); err != nil {
+		return nil, &SubreaperError{Message: fmt.Sprintf("failed to set child subreaper: %v", err)}
+	}
+
+	s := &Subreaper{
+		onReap:  onReap,
+		managed: make(map[int]struct{}),
+		signals: make(chan os.Signal, 1),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	signal.Notify(s.signals, unix.SIGCHLD)
+	go s.run()
+
+	activeSubreaper = s
+	slog.Debug("Subreaper enabled")
+	return s, nil
+}
+
+// Stop disables subreaper mode and stops the reaping goroutine. Orphans
+// re-parented before Stop are reaped one final time. Stop is idempotent.
+func (s *Subreaper) Stop() {
+	subreaperMu.Lock()
+	defer subreaperMu.Unlock()
+
+	if activeSubreaper != s {
+		return
+	}
+	activeSubreaper = nil
+
+	signal.Stop(s.signals)
+	close(s.stop)
+	<-s.done
+
+	if err := unix.Prctl(unix.PR_SET_CHILD_SUBREAPER, 0, 0, 0, 0); err != nil {
+		slog.Debug("Failed to clear child subreaper", "error", err)
+	}
+	s.reapOrphans()
+	slog.Debug("Subreaper stopped")
+}
+
+func (s *Subreaper) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(subreaperPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-s.signals:
+		case <-ticker.C:
+		}
+		s.reapOrphans()
+	}
+}
+
+// reapOrphans collects every zombie child that was not started by this
+// package and reports it via onReap.
+func (s *Subreaper) reapOrphans() {
+	zombies := zombieChildren()
+	if len(zombies) == 0 {
+		return
+	}
+
+	var events []ReapEvent
+	s.mu.Lock()
+	for _, pid := range zombies {
+		if _, ok := s.managed[pid]; ok {
+			continue
+		}
+		var ws unix.WaitStatus
+		wpid, err := unix.Wait4(pid, &ws, unix.WNOHANG, nil)
+		if err != nil || wpid != pid {
+			continue
+		}
+		events = append(events, newReapEvent(pid, ws))
+	}
+	s.mu.Unlock()
+
+	for _, ev := range events {
+		slog.Debug("Reaped orphaned process", "pid", ev.PID, "exit_code", ev.ExitCode, "signal", ev.Signal)
+		if s.onReap != nil {
+			s.onReap(ev)
+		}
+	}
+}
+
+func newReapEvent(pid int, ws unix.WaitStatus) ReapEvent {
+	ev := ReapEvent{PID: pid, ExitCode: ws.ExitStatus()}
+	if ws.Signaled() {
+		ev.ExitCode = -1
+		ev.Signal = unix.SignalName(ws.Signal())
+	}
+	return ev
+}
+
+// zombieChildren scans /proc for zombie processes whose parent is the
+// current process.
+func zombieChildren() []int {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil
+	}
+
+	self := os.Getpid()
+	var pids []int
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		data, err := os.ReadFile("/proc/" + entry.Name() + "/stat")
+		if err != nil {
+			continue
+		}
+		state, ppid, ok := parseProcStat(data)
+		if ok && state == 'Z' && ppid == self {
+			pids = append(pids, pid)
+		}
+	}
+	return pids
+}
+
+// parseProcStat extracts the state and parent PID from /proc/<pid>/stat.
+// The command name is parenthesized and may itself contain spaces or
+// parentheses, so parsing starts after the last ')'.
+func parseProcStat(data []byte) (state byte, ppid int, ok bool) {
+	end := bytes.LastIndexByte(data, ')')
+	if end < 0 {
+		return 0, 0, false
+	}
+	fields := bytes.Fields(data[end+1:])
+	if len(fields) < 2 || len(fields[0]) != 1 {
+		return 0, 0, false
+	}
+	ppid, err := strconv.Atoi(string(fields[1]))
+	if err != nil {
+		return 0, 0, false
+	}
+	return fields[0][0], ppid, true
+}
+
+// startCommand starts cmd. While a subreaper is active, the command's PID is
+// registered atomically with starting it, and released by the returned func.
+func startCommand(cmd *exec.Cmd) (func(), error) {
+	subreaperMu.Lock()
+	s := activeSubreaper
+	subreaperMu.Unlock()
+
+	if s == nil {
+		return func() {}, cmd.Start() //nolint:wrapcheck // inspected by processExecutionError
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := cmd.Start(); err != nil {
+		return nil, err //nolint:wrapcheck // inspected by processExecutionError
+	}
+	pid := cmd.Process.Pid
+	s.managed[pid] = struct{}{}
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.managed, pid)
+	}, nil
+}
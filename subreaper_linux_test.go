@@ -0,0 +1,113 @@
+//go:build linux
+
+package cmdexec
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseProcStat(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      string
+		wantState byte
+		wantPPID  int
+		wantOK    bool
+	}{
+		{"simple", "123 (sleep) Z 45 123 45 0", 'Z', 45, true},
+		{"comm with spaces and parens", "123 (my (odd) proc) S 7 1 1", 'S', 7, true},
+		{"truncated", "123 (sleep)", 0, 0, false},
+		{"no parens", "garbage", 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, ppid, ok := parseProcStat([]byte(tt.data))
+			if state != tt.wantState || ppid != tt.wantPPID || ok != tt.wantOK {
+				t.Errorf("parseProcStat() = (%q, %d, %v), want (%q, %d, %v)",
+					state, ppid, ok, tt.wantState, tt.wantPPID, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestEnableSubreaper_ReapsOrphans(t *testing.T) {
+	events := make(chan ReapEvent, 10)
+	s, err := EnableSubreaper(func(ev ReapEvent) { events <- ev })
+	if err != nil {
+		t.Fatalf("EnableSubreaper() error = %v", err)
+	}
+	defer s.Stop()
+
+	if _, err := EnableSubreaper(nil); err == nil {
+		t.Error("second EnableSubreaper() should fail")
+	} else {
+		var srErr *SubreaperError
+		if !errors.As(err, &srErr) {
+			t.Errorf("error = %T, want *SubreaperError", err)
+		}
+	}
+
+	// The subshell exits immediately, orphaning the background sleep,
+	// which is then re-parented to the test process.
+	executor := NewBasicExecutor()
+	result, err := executor.Execute(context.Background(), ToolConfig{
+		Command: "sh",
+		Args:    []string{"-c", "(sleep 0.2 >/dev/null 2>&1 &); exit 0"},
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.ExitCode != 0 {
+		t.Fatalf("ExitCode = %d, want 0", result.ExitCode)
+	}
+
+	select {
+	case ev := <-events:
+		if ev.PID <= 0 || ev.ExitCode != 0 || ev.Signal != "" {
+			t.Errorf("unexpected reap event: %+v", ev)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("orphaned process was not reaped")
+	}
+}
+
+func TestEnableSubreaper_DoesNotStealManagedExitStatus(t *testing.T) {
+	s, err := EnableSubreaper(nil)
+	if err != nil {
+		t.Fatalf("EnableSubreaper() error = %v", err)
+	}
+	defer s.Stop()
+
+	executor := NewBasicExecutor()
+	for i := 0; i < 20; i++ {
+		result, err := executor.Execute(context.Background(), ToolConfig{
+			Command: "sh",
+			Args:    []string{"-c", "exit 3"},
+		})
+		if err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		if result.ExitCode != 3 {
+			t.Fatalf("ExitCode = %d, want 3", result.ExitCode)
+		}
+	}
+}
+
+func TestSubreaper_StopIdempotent(t *testing.T) {
+	s, err := EnableSubreaper(nil)
+	if err != nil {
+		t.Fatalf("EnableSubreaper() error = %v", err)
+	}
+	s.Stop()
+	s.Stop()
+
+	// Re-enabling after Stop must succeed.
+	s2, err := EnableSubreaper(nil)
+	if err != nil {
+		t.Fatalf("EnableSubreaper() after Stop error = %v", err)
+	}
+	s2.Stop()
+}
@@ -0,0 +1,26 @@
+//go:build !linux
+
+package cmdexec
+
+import (
+	"os/exec"
+)
+
+// Subreaper makes the current process a child subreaper and reaps orphaned
+// descendants. It is only supported on Linux.
+type Subreaper struct{}
+
+// EnableSubreaper is only supported on Linux; on other platforms it always
+// returns a *SubreaperError.
+func EnableSubreaper(_ func(ReapEvent)) (*Subreaper, error) {
+	return nil, &SubreaperError{Message: "subreaper mode is only supported on Linux"}
+}
+
+// Stop is a no-op on platforms without subreaper support.
+func (s *Subreaper) Stop() {}
+
+// startCommand starts cmd. Without subreaper support there is nothing to
+// register, so the returned release func is a no-op.
+func startCommand(cmd *exec.Cmd) (func(), error) {
+	return func() {}, cmd.Start() //nolint:wrapcheck // inspected by processExecutionError
+}
@@ -0,0 +1,126 @@
+package cmdexec
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// liveTempResources is the package-wide set of temporary paths that have
+// been created but not yet cleaned up, used for leak auditing.
+var liveTempResources = struct {
+	mu    sync.Mutex
+	paths map[string]struct{}
+}{paths: make(map[string]struct{})}
+
+// tempResources tracks the temporary files and directories created for one
+// execution attempt. cleanup is deferred by the executor, so resources are
+// removed on normal completion, timeout, cancellation, and panic alike.
+type tempResources struct {
+	mu    sync.Mutex
+	paths []string
+}
+
+func newTempResources() *tempResources {
+	return &tempResources{}
+}
+
+func (r *tempResources) track(path string) {
+	r.mu.Lock()
+	r.paths = append(r.paths, path)
+	r.mu.Unlock()
+
+	liveTempResources.mu.Lock()
+	liveTempResources.paths[path] = struct{}{}
+	liveTempResources.mu.Unlock()
+}
+
+// createFile writes content to a new temporary file and tracks it.
+func (r *tempResources) createFile(pattern, content string) (string, error) {
+	f, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	r.track(f.Name())
+	if _, err := f.WriteString(content); err != nil {
+		_ = f.Close()
+		return "", fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("failed to close temp file: %w", err)
+	}
+	return f.Name(), nil
+}
+
+// mkdirTemp creates a new temporary directory and tracks it.
+func (r *tempResources) mkdirTemp(pattern string) (string, error) {
+	dir, err := os.MkdirTemp("", pattern)
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	r.track(dir)
+	return dir, nil
+}
+
+// cleanup removes all tracked resources in reverse creation order.
+func (r *tempResources) cleanup() {
+	r.mu.Lock()
+	paths := r.paths
+	r.paths = nil
+	r.mu.Unlock()
+
+	for i := len(paths) - 1; i >= 0; i-- {
+		removeTempResource(paths[i])
+	}
+}
+
+func removeTempResource(path string) {
+	if err := os.RemoveAll(path); err != nil {
+		slog.Debug("Failed to remove temp resource", "path", path, "error", err)
+	}
+	untrackTempResource(path)
+}
+
+// untrackTempResource removes path from the leak-auditing set without
+// touching the filesystem.
+func untrackTempResource(path string) {
+	liveTempResources.mu.Lock()
+	delete(liveTempResources.paths, path)
+	liveTempResources.mu.Unlock()
+}
+
+// TempResources returns the paths of temporary files and directories that
+// this package has created for in-flight executions, or as spool files of
+// results that have not been cleaned up, sorted.
+func TempResources() []string {
+	liveTempResources.mu.Lock()
+	defer liveTempResources.mu.Unlock()
+	paths := make([]string, 0, len(liveTempResources.paths))
+	for p := range liveTempResources.paths {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// CheckTempResourceLeaks returns an error listing any temporary resources
+// that are still tracked. Call it in tests after all executions have
+// finished to verify that nothing leaked.
+func CheckTempResourceLeaks() error {
+	if paths := TempResources(); len(paths) > 0 {
+		return fmt.Errorf("leaked %d temp resource(s): %s", len(paths), strings.Join(paths, ", "))
+	}
+	return nil
+}
+
+// CleanupTempResources removes every tracked temporary resource. It is a
+// last-resort hook for process shutdown paths (for example before os.Exit)
+// where in-flight executions will not get to clean up after themselves.
+func CleanupTempResources() {
+	for _, p := range TempResources() {
+		removeTempResource(p)
+	}
+}
@@ -0,0 +1,84 @@
+package cmdexec
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTempResources_CleanupRemovesAll(t *testing.T) {
+	res := newTempResources()
+	file, err := res.createFile("cmdexec-test-*", "data")
+	if err != nil {
+		t.Fatalf("createFile() error = %v", err)
+	}
+	dir, err := res.mkdirTemp("cmdexec-test-*")
+	if err != nil {
+		t.Fatalf("mkdirTemp() error = %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "nested"), []byte("x"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := CheckTempResourceLeaks(); err == nil || !strings.Contains(err.Error(), file) {
+		t.Errorf("CheckTempResourceLeaks() = %v, want leak report including %s", err, file)
+	}
+
+	res.cleanup()
+
+	for _, p := range []string{file, dir} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Errorf("%s still exists after cleanup", p)
+		}
+	}
+	if err := CheckTempResourceLeaks(); err != nil {
+		t.Errorf("CheckTempResourceLeaks() after cleanup = %v", err)
+	}
+}
+
+func TestTempResources_CleanupOnPanic(t *testing.T) {
+	var path string
+	func() {
+		defer func() { _ = recover() }()
+		res := newTempResources()
+		defer res.cleanup()
+		path, _ = res.createFile("cmdexec-test-*", "data")
+		panic("boom")
+	}()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("%s survived a panic", path)
+	}
+}
+
+func TestBasicExecutor_Execute_NoTempLeaksOnTimeout(t *testing.T) {
+	_, err := NewBasicExecutor().Execute(context.Background(), ToolConfig{
+		Command: "sh",
+		Args:    []string{"-c", "exec sleep 5", "ignored"},
+		ArgFile: &ArgFileConfig{Style: ArgFileFlag, Flag: "--unused", KeepArgs: 2},
+		Timeout: 100 * time.Millisecond,
+	})
+	if err == nil {
+		t.Fatal("expected timeout error")
+	}
+	if err := CheckTempResourceLeaks(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCleanupTempResources(t *testing.T) {
+	res := newTempResources()
+	path, err := res.createFile("cmdexec-test-*", "data")
+	if err != nil {
+		t.Fatalf("createFile() error = %v", err)
+	}
+	CleanupTempResources()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("%s still exists after CleanupTempResources", path)
+	}
+	if len(TempResources()) != 0 {
+		t.Errorf("TempResources() = %v, want empty", TempResources())
+	}
+}
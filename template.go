@@ -0,0 +1,106 @@
+package cmdexec
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// Template describes a family of configurations that differ in a few
+// values, such as the same command run against different targets. Command,
+// Args, Env values and WorkingDir may contain text/template placeholders
+// like {{.Target}}, which Render fills from a parameters map. Each argument
+// renders to exactly one argument, however many spaces or quotes the values
+// contain, so no shell quoting is involved.
+type Template struct {
+	Command    string
+	Args       []string
+	Env        map[string]string
+	WorkingDir string
+
+	// Base supplies the remaining settings, such as Timeout or retries, of
+	// the rendered configurations. Its Env is merged under the rendered
+	// Env.
+	Base ToolConfig
+}
+
+// TemplateError is returned by Template.Render when a field cannot be
+// rendered, for example because it refers to a parameter that was not
+// given.
+type TemplateError struct {
+	// Field names the template field, such as "Args[1]" or "Env[HOME]".
+	Field string
+	Err   error
+}
+
+func (e *TemplateError) Error() string {
+	return fmt.Sprintf("template field %s: %v", e.Field, e.Err)
+}
+
+func (e *TemplateError) Unwrap() error {
+	return e.Err
+}
+
+// Render returns the configuration with the placeholders filled from
+// params. Referring to a parameter missing from params is an error rather
+// than an empty string, so a typo cannot silently drop an argument.
+func (t *Template) Render(params map[string]any) (ToolConfig, error) {
+	cfg := t.Base.Clone()
+	var err error
+	if cfg.Command, err = renderField("Command", t.Command, params); err != nil {
+		return ToolConfig{}, err
+	}
+	if cfg.WorkingDir, err = renderField("WorkingDir", t.WorkingDir, params); err != nil {
+		return ToolConfig{}, err
+	}
+	if t.Args != nil {
+		cfg.Args = make([]string, len(t.Args))
+		for i, arg := range t.Args {
+			if cfg.Args[i], err = renderField(fmt.Sprintf("Args[%d]", i), arg, params); err != nil {
+				return ToolConfig{}, err
+			}
+		}
+	}
+	if len(t.Env) > 0 {
+		if cfg.Env == nil {
+			cfg.Env = make(map[string]string, len(t.Env))
+		}
+		for key, value := range t.Env {
+			if cfg.Env[key], err = renderField("Env["+key+"]", value, params); err != nil {
+				return ToolConfig{}, err
+			}
+		}
+	}
+	return cfg, nil
+}
+
+// RenderAll renders the template once per parameters map, for example to
+// build a batch for ConcurrentExecutor.ExecuteAll.
+func (t *Template) RenderAll(params ...map[string]any) ([]ToolConfig, error) {
+	configs := make([]ToolConfig, len(params))
+	for i, p := range params {
+		cfg, err := t.Render(p)
+		if err != nil {
+			return nil, fmt.Errorf("rendering parameters %d: %w", i, err)
+		}
+		configs[i] = cfg
+	}
+	return configs, nil
+}
+
+// renderField renders one template field. Text without placeholders is
+// returned as is.
+func renderField(field, text string, params map[string]any) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+	tmpl, err := template.New(field).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", &TemplateError{Field: field, Err: err}
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, params); err != nil {
+		return "", &TemplateError{Field: field, Err: err}
+	}
+	return b.String(), nil
+}
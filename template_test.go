@@ -0,0 +1,78 @@
+package cmdexec
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTemplate_Render(t *testing.T) {
+	tmpl := &Template{
+		Command:    "go",
+		Args:       []string{"test", "{{.Package}}", "-run", "{{.Test}}"},
+		Env:        map[string]string{"GOOS": "{{.OS}}"},
+		WorkingDir: "{{.Root}}/src",
+		Base: ToolConfig{
+			Timeout: time.Minute,
+			Env:     map[string]string{"CI": "1", "GOOS": "linux"},
+		},
+	}
+	cfg, err := tmpl.Render(map[string]any{
+		"Package": "./pkg/...",
+		"Test":    "Test A; rm -rf /",
+		"OS":      "darwin",
+		"Root":    "/home/me/repo",
+	})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	want := []string{"test", "./pkg/...", "-run", "Test A; rm -rf /"}
+	if strings.Join(cfg.Args, "|") != strings.Join(want, "|") {
+		t.Errorf("Args = %q, want %q", cfg.Args, want)
+	}
+	if cfg.Command != "go" || cfg.WorkingDir != "/home/me/repo/src" || cfg.Timeout != time.Minute {
+		t.Errorf("config = %+v", cfg)
+	}
+	if cfg.Env["GOOS"] != "darwin" || cfg.Env["CI"] != "1" {
+		t.Errorf("Env = %v, want the rendered Env merged over the base", cfg.Env)
+	}
+	if tmpl.Base.Env["GOOS"] != "linux" {
+		t.Error("Render() modified the base Env")
+	}
+}
+
+func TestTemplate_RenderErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		tmpl      Template
+		wantField string
+	}{
+		{name: "missing parameter", tmpl: Template{Command: "go", Args: []string{"test", "{{.Pkg}}"}}, wantField: "Args[1]"},
+		{name: "missing env parameter", tmpl: Template{Command: "go", Env: map[string]string{"HOME": "{{.Home}}"}}, wantField: "Env[HOME]"},
+		{name: "malformed", tmpl: Template{Command: "{{.Tool"}, wantField: "Command"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.tmpl.Render(nil)
+			var tmplErr *TemplateError
+			if !errors.As(err, &tmplErr) || tmplErr.Field != tt.wantField {
+				t.Errorf("Render() error = %v, want *TemplateError for %s", err, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestTemplate_RenderAll(t *testing.T) {
+	tmpl := &Template{Command: "ping", Args: []string{"-c", "1", "{{.Host}}"}}
+	configs, err := tmpl.RenderAll(map[string]any{"Host": "a.example"}, map[string]any{"Host": "b.example"})
+	if err != nil {
+		t.Fatalf("RenderAll() error = %v", err)
+	}
+	if len(configs) != 2 || configs[0].Args[2] != "a.example" || configs[1].Args[2] != "b.example" {
+		t.Errorf("configs = %+v", configs)
+	}
+	if _, err := tmpl.RenderAll(map[string]any{"Host": "a"}, map[string]any{}); err == nil || !strings.Contains(err.Error(), "parameters 1") {
+		t.Errorf("RenderAll() error = %v, want it to name parameters 1", err)
+	}
+}
@@ -1,8 +1,16 @@
 package cmdexec
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"maps"
+	"os"
+	"reflect"
+	"regexp"
+	"runtime"
+	"slices"
 	"time"
 )
 
@@ -18,20 +26,113 @@ type ToolConfig struct {
 	// If empty, uses the current working directory
 	WorkingDir string
 
+	// EnsureWorkingDir creates WorkingDir, including missing parents,
+	// before running the command, like mkdir -p. Without it, a missing
+	// WorkingDir fails the execution with a *WorkingDirError.
+	EnsureWorkingDir bool
+
+	// ExpandVariables expands a leading ~ and $VAR or ${VAR} references in
+	// Command, Args and WorkingDir before the command runs, as Expand
+	// does. It is off by default so arguments are passed literally.
+	ExpandVariables bool
+
+	// MinVersion, if set, is the oldest acceptable version of the tool,
+	// such as "1.22" or "v2.3.1". BasicExecutor checks it once per tool,
+	// using VersionCheck, and fails with a *VersionTooOldError if the
+	// installed version is older.
+	MinVersion string
+
+	// VersionCheck configures how the version is found for MinVersion. If
+	// nil, the tool is run with "--version" and the first dotted number in
+	// its output is used.
+	VersionCheck *VersionCheck
+
 	// Timeout is the maximum duration to allow the command to run
 	// If zero, no timeout is applied
 	Timeout time.Duration
 
+	// IdleTimeout is the maximum duration the command may go without
+	// producing any stdout or stderr output. When exceeded, the process is
+	// killed and Execute returns an *IdleTimeoutError. This catches hung
+	// tools long before a generous wall-clock Timeout would.
+	// If zero, no idle timeout is applied
+	IdleTimeout time.Duration
+
+	// CPUTimeLimit is the maximum CPU time (user + system) the command may
+	// consume, independent of wall-clock time. It is enforced with
+	// RLIMIT_CPU, so it is rounded up to whole seconds and is only
+	// supported on Linux. When exceeded, the process is killed and Execute
+	// returns a *CPUTimeLimitError. If zero, no CPU time limit is applied
+	CPUTimeLimit time.Duration
+
+	// KillPolicy is the signal sequence used to terminate the command when
+	// it is cancelled by Timeout, IdleTimeout, or its context, for example
+	// SIGINT, then SIGTERM, then SIGKILL. If empty, the process is killed
+	// immediately. The signal that ended the process is reported in
+	// TimeoutError.Signal and IdleTimeoutError.Signal.
+	KillPolicy KillPolicy
+
 	// MaxRetries is the maximum number of retry attempts for flaky tools
 	MaxRetries int
 
 	// RetryDelay is the delay between retry attempts
 	RetryDelay time.Duration
 
+	// SuccessWhen, if set, decides whether a completed execution succeeded,
+	// for tools that signal success with a non-zero exit code or through
+	// their output, such as grep, which exits with 1 when nothing matches.
+	// It is used by the retry loop, Fallback, and the *WithConfig helpers;
+	// see Succeeded and SucceedOnExitCodes. By default only exit code 0 is
+	// a success.
+	SuccessWhen func(result *ExecutionResult) bool
+
+	// Fallback, if set, is an alternate command that is run when this one
+	// fails for good: once its retries are exhausted, when its executable
+	// is not found, or when it does not succeed (see SuccessWhen). Examples are
+	// gtar when tar fails, or python3 when python is missing. Fallbacks may
+	// have fallbacks of their own, and ExecutionResult.FallbackDepth tells
+	// which one produced the result. Fallbacks are not tried after a
+	// ValidationError or once the context is done. Hooks and the Redactor
+	// of the outer configuration apply to the whole chain.
+	Fallback *ToolConfig
+
+	// OnRetry, if set, is called after a failed attempt that will be
+	// retried, before waiting nextDelay, so applications can log or count
+	// retries without wrapping the executor. attempt is the number of the
+	// failed attempt, starting at 1, and result is nil if it failed with
+	// an error. Both are redacted with Redactor.
+	OnRetry func(attempt int, result *ExecutionResult, err error, nextDelay time.Duration)
+
+	// MaxRetryElapsed bounds the total time spent on an execution with
+	// retries. No further attempt is started once the time elapsed since
+	// the first attempt, plus the next retry delay, reaches it, even if
+	// attempts remain. Zero means no limit. Independently of it, no attempt
+	// is started when the context deadline would expire before the delay
+	// and the duration of the previous attempt have passed.
+	MaxRetryElapsed time.Duration
+
+	// RetryPolicy, if set, replaces MaxRetries and RetryDelay with a custom
+	// retry strategy. See RetryPolicy.
+	RetryPolicy RetryPolicy
+
+	// RetryIf, if set, restricts retries to the failed attempts for which
+	// it returns true, for example based on stderr content that exit codes
+	// alone cannot express (see RetryIfStderrMatches). result is nil when
+	// the attempt failed with an error. Other failures are returned as is,
+	// as if retries were not configured. It is consulted in addition to
+	// RetryPolicy.ShouldRetry.
+	RetryIf func(result *ExecutionResult, err error) bool
+
 	// Env contains additional environment variables for the command
 	// These will be added to the current environment
 	Env map[string]string
 
+	// Labels tag the execution with caller metadata, such as a request ID,
+	// tenant or build target. They do not affect the command; they are
+	// copied to ExecutionResult.Labels and included in debug logs,
+	// OutputLog records and recorded transcripts.
+	Labels map[string]string
+
 	// Stdin is an optional reader for providing input to the command.
 	// If nil, the command will have no stdin.
 	//
@@ -46,6 +147,40 @@ type ToolConfig struct {
 	// StdinFactory are set, StdinFactory takes precedence.
 	StdinFactory func() io.Reader
 
+	// StdinProvider is like StdinFactory for input that may fail to open,
+	// such as a network stream. It is called before each attempt; an error
+	// fails the execution without running the command, wrapped in a
+	// *RetryExhaustedError if earlier attempts ran. A reader that is
+	// also an io.Closer is closed after the attempt. If both Stdin and
+	// StdinProvider are set, StdinProvider takes precedence. It cannot be
+	// combined with StdinFactory or StdinFile.
+	StdinProvider func() (io.Reader, error)
+
+	// StdinFile, if set, is the path of a file passed to the command as its
+	// input. The file is opened anew for each attempt, so unlike Stdin it
+	// can be combined with retries. It cannot be combined with Stdin,
+	// StdinFactory or StdinProvider.
+	StdinFile string
+
+	// MaxStdinBytes, if positive, limits how much input the command may
+	// read from Stdin, StdinFactory, StdinProvider or StdinFile. Larger input kills the
+	// command and fails the execution with a *StdinLimitError, rather than
+	// letting it act on partial input. A StdinFile larger than the limit
+	// fails before the command starts.
+	MaxStdinBytes int64
+
+	// ExtraFiles are open files inherited by the command in addition to
+	// stdin, stdout and stderr: entry i becomes file descriptor 3+i in the
+	// child, as with exec.Cmd.ExtraFiles. They let protocols such as
+	// systemd-style socket activation pass sockets and pipes to helper
+	// processes. The files stay owned by the caller. Not supported on
+	// Windows.
+	ExtraFiles []*os.File
+
+	// ProcessAttributes, if set, are operating system attributes of the
+	// command's process, such as a new process group or session.
+	ProcessAttributes *ProcessAttributes
+
 	// CommandBuilder defines how to build the command for execution.
 	// If nil, defaults to DirectCommandBuilder for direct execution.
 	// Use ShellCommandBuilder for tools that need shell execution (e.g., Bazel, Gradle).
@@ -63,11 +198,57 @@ type ToolConfig struct {
 	// The caller is responsible for thread-safety of the provided writer.
 	StderrWriter io.Writer
 
+	// OnStdoutLine is an optional callback invoked for each line of stdout
+	// as it arrives, without the line terminator. Carriage returns also end
+	// a line, so progress updates are delivered individually. A trailing
+	// partial line is delivered when the command exits. Callbacks for one
+	// stream are invoked sequentially from the goroutine copying that stream.
+	OnStdoutLine func(line string)
+
+	// OnStderrLine is like OnStdoutLine, for stderr.
+	OnStderrLine func(line string)
+
+	// OutputLog, if set, mirrors each line of stdout and stderr into slog,
+	// at debug and error level by default. See OutputLog.
+	OutputLog *OutputLog
+
+	// ProgressPattern, together with OnProgress, extracts progress from
+	// output as it is produced. The pattern is matched against every line
+	// of stdout and stderr (a carriage return also ends a line), and the
+	// first capture group of the last match is parsed as a number and
+	// passed to OnProgress. ProgressPercent covers the common "42%" form.
+	ProgressPattern *regexp.Regexp
+
+	// OnProgress receives values extracted by ProgressPattern, for example
+	// to drive a progress bar. Calls are serialized.
+	OnProgress func(value float64)
+
+	// StreamTimestampFormat, if set, prefixes every line written to
+	// StdoutWriter and StderrWriter with the time the line was completed,
+	// formatted with this layout (for example time.RFC3339) and followed by
+	// a space. Lines are then delivered whole rather than as raw chunks.
+	// Captured output is not affected.
+	StreamTimestampFormat string
+
+	// FlushInterval, if positive, periodically flushes StdoutWriter and
+	// StderrWriter while the command runs. Regardless of this setting,
+	// writers that implement Flush() error, Flush(), or Sync() error (such
+	// as *bufio.Writer, *gzip.Writer, and *os.File) are flushed once the
+	// command exits, so buffered sinks do not lose the tail of the output.
+	// Periodic flushes are serialized with writes to the same stream; a
+	// writer shared by both streams must still be safe for concurrent use.
+	FlushInterval time.Duration
+
 	// CommandValidator is an optional function that validates whether the
 	// command is allowed to execute. It receives the command name and args.
 	// Return a non-nil error to block execution. If nil, all commands are allowed.
 	CommandValidator func(command string, args []string) error
 
+	// ArgValidator, if set, checks Args during validation, for example
+	// with an ArgSchema that forbids --force. Its errors are returned as
+	// *ValidationError.
+	ArgValidator ArgValidator
+
 	// MaxStdoutBytes limits the maximum number of bytes captured from stdout.
 	// When exceeded, output is truncated and ExecutionResult.StdoutTruncated
 	// is set to true. Zero means no limit.
@@ -77,6 +258,116 @@ type ToolConfig struct {
 	// When exceeded, output is truncated and ExecutionResult.StderrTruncated
 	// is set to true. Zero means no limit.
 	MaxStderrBytes int64
+
+	// MaxOutputBytes limits the number of bytes captured from stdout and
+	// stderr combined, in addition to MaxStdoutBytes and MaxStderrBytes.
+	// With TruncateHead the budget goes to whichever stream writes first;
+	// with TruncateTail the most recent output of either stream is kept.
+	// The truncated and dropped fields of ExecutionResult report which
+	// stream lost output. Zero means no limit.
+	MaxOutputBytes int64
+
+	// TruncateMode selects which part of the output is kept when
+	// MaxStdoutBytes, MaxStderrBytes or MaxOutputBytes is exceeded. The
+	// default, TruncateHead, keeps the beginning of the output.
+	TruncateMode TruncateMode
+
+	// CaptureFilter, if set, selects the lines of stdout and stderr that
+	// are captured into ExecutionResult.Output and Stderr, for example to
+	// keep only the errors and warnings of a very chatty tool. It receives
+	// each line without its terminator; LinesMatching builds one from a
+	// regular expression. StdoutWriter, StderrWriter, the line callbacks
+	// and Combined still receive the full output. The output limits and
+	// the dropped byte counts apply to the filtered output, while
+	// StdoutBytesTotal and StderrBytesTotal count the full output.
+	CaptureFilter func(line string) bool
+
+	// ChecksumStdout records the SHA-256 digest of the complete stdout in
+	// ExecutionResult.StdoutSHA256, for caching, deduplication and integrity
+	// checks of artifact-producing commands. The digest covers the raw
+	// output, even when the stored Output is truncated or filtered.
+	ChecksumStdout bool
+
+	// OverflowPolicy selects whether exceeding an output limit truncates
+	// the output (the default), aborts the execution with an
+	// *OutputLimitError, or blocks the command until it times out. Policies
+	// other than OverflowTruncate always keep the head of the output.
+	OverflowPolicy OverflowPolicy
+
+	// CollapseRepeatedLines collapses runs of identical output lines into
+	// the first line followed by "last message repeated N times". It applies
+	// to both captured output and StdoutWriter/StderrWriter, and is applied
+	// before MaxStdoutBytes/MaxStderrBytes.
+	CollapseRepeatedLines bool
+
+	// OutputEncoding, if set, converts captured stdout and stderr from the
+	// command's character encoding to UTF-8 in ExecutionResult. Output
+	// streamed to StdoutWriter/StderrWriter and spool files keep the
+	// original bytes.
+	OutputEncoding OutputDecoder
+
+	// InvalidUTF8 selects how bytes that are still not valid UTF-8 after
+	// decoding are handled in ExecutionResult. The default keeps them.
+	InvalidUTF8 InvalidUTF8Policy
+
+	// SpoolThreshold, if positive, bounds the memory used to capture each
+	// output stream. Once a stream exceeds this many bytes, its complete
+	// output is written to a temporary file instead: ExecutionResult.Output
+	// (or Stderr) keeps only the first SpoolThreshold bytes, and
+	// StdoutSpoolPath (or StderrSpoolPath) names the file. Spool files of
+	// failed attempts are removed automatically; the caller removes those of
+	// a returned result with ExecutionResult.Cleanup. Spooling applies after
+	// MaxStdoutBytes/MaxStderrBytes.
+	SpoolThreshold int64
+
+	// CombineOutput additionally captures stdout and stderr into
+	// ExecutionResult.Combined, interleaved in the order the process wrote
+	// them. MaxOutputBytes and TruncateMode also bound the combined copy;
+	// the per-stream limits MaxStdoutBytes and MaxStderrBytes do not.
+	CombineOutput bool
+
+	// MergeStderr sends the command's stderr to the same pipe as its
+	// stdout, like "2>&1" in a shell, so diagnostics stay interleaved with
+	// output exactly as written. The merged output is captured in
+	// ExecutionResult.Output and streamed to StdoutWriter and OnStdoutLine,
+	// subject to MaxStdoutBytes; Stderr stays empty and StderrWriter,
+	// OnStderrLine and MaxStderrBytes are ignored.
+	MergeStderr bool
+
+	// DiscardOutput connects stdout and stderr to the null device instead of
+	// capturing them, for fire-and-forget invocations in hot loops where
+	// only the exit code matters. No output is buffered, so Output and
+	// Stderr stay empty and the other output settings are ignored. It
+	// cannot be combined with IdleTimeout, which needs to observe output.
+	DiscardOutput bool
+
+	// PreExec is an optional hook invoked by BasicExecutor once per Execute
+	// call, before validation. It may inspect or mutate the configuration
+	// (for example to inject environment variables). Replace Args and Env
+	// rather than modifying them in place, since they may be shared with the
+	// caller (see Clone). A non-nil error aborts the execution and is
+	// returned wrapped from Execute.
+	PreExec func(ctx context.Context, cfg *ToolConfig) error
+
+	// PostExec is an optional hook invoked by BasicExecutor once per Execute
+	// call, after the execution (including all retries) completes, with the
+	// values Execute is about to return. It is not called if PreExec fails.
+	PostExec func(ctx context.Context, result *ExecutionResult, err error)
+
+	// ArgFile, if set, passes long argument lists through a temporary file
+	// using a tool-specific convention (see ArgFileConfig). The file is
+	// created for each attempt and removed when the attempt finishes.
+	ArgFile *ArgFileConfig
+
+	// Redactor, if set, removes secrets from the returned result and error
+	// and from debug logs. See Redactor for what is covered.
+	Redactor *Redactor
+
+	// Weight is the share of a ConcurrentExecutor's concurrency limit that
+	// this command occupies while it runs, so that heavy commands (a build)
+	// and light ones (a status query) can share one limit. Zero means 1. A
+	// command heavier than the whole limit runs alone.
+	Weight int
 }
 
 // Validate ensures the ToolConfig has valid data.
@@ -85,31 +376,51 @@ func (tc *ToolConfig) Validate() error {
 		return &ValidationError{Field: "Command", Message: "command cannot be empty"}
 	}
 
-	if tc.MaxRetries < 0 {
-		return &ValidationError{Field: "MaxRetries", Message: "maxRetries cannot be negative"}
+	if err := tc.validateTiming(); err != nil {
+		return err
 	}
 
-	if tc.RetryDelay < 0 {
-		return &ValidationError{Field: "RetryDelay", Message: "retryDelay cannot be negative"}
+	if err := tc.KillPolicy.validate(); err != nil {
+		return err
 	}
 
-	if tc.Timeout < 0 {
-		return &ValidationError{Field: "Timeout", Message: "timeout cannot be negative"}
+	if err := tc.validateStdin(); err != nil {
+		return err
 	}
 
-	if tc.Stdin != nil && tc.MaxRetries > 0 && tc.StdinFactory == nil {
+	if tc.Stdin != nil && (tc.MaxRetries > 0 || tc.RetryPolicy != nil) && tc.StdinFactory == nil && tc.StdinProvider == nil {
 		return &ValidationError{
 			Field:   "Stdin",
-			Message: "use StdinFactory instead of Stdin when MaxRetries > 0; a single reader is consumed after the first attempt",
+			Message: "use StdinFactory or StdinProvider instead of Stdin when retrying; a single reader is consumed after the first attempt",
 		}
 	}
 
-	if tc.MaxStdoutBytes < 0 {
-		return &ValidationError{Field: "MaxStdoutBytes", Message: "maxStdoutBytes cannot be negative"}
+	if err := tc.validateOutput(); err != nil {
+		return err
 	}
 
-	if tc.MaxStderrBytes < 0 {
-		return &ValidationError{Field: "MaxStderrBytes", Message: "maxStderrBytes cannot be negative"}
+	if err := tc.validateProcess(); err != nil {
+		return err
+	}
+
+	if tc.ArgFile != nil {
+		if err := tc.ArgFile.validate(); err != nil {
+			return err
+		}
+	}
+
+	if err := tc.validateFallback(); err != nil {
+		return err
+	}
+
+	if tc.ArgValidator != nil {
+		if err := tc.ArgValidator.ValidateArgs(tc.Args); err != nil {
+			var validationErr *ValidationError
+			if errors.As(err, &validationErr) {
+				return validationErr
+			}
+			return &ValidationError{Field: "Args", Message: err.Error()}
+		}
 	}
 
 	if tc.CommandValidator != nil {
@@ -124,6 +435,231 @@ func (tc *ToolConfig) Validate() error {
 	return nil
 }
 
+// validateTiming checks the retry, timeout, and resource limit settings.
+func (tc *ToolConfig) validateTiming() error {
+	if tc.MaxRetries < 0 {
+		return &ValidationError{Field: "MaxRetries", Message: "maxRetries cannot be negative"}
+	}
+
+	if tc.RetryDelay < 0 {
+		return &ValidationError{Field: "RetryDelay", Message: "retryDelay cannot be negative"}
+	}
+
+	if tc.MaxRetryElapsed < 0 {
+		return &ValidationError{Field: "MaxRetryElapsed", Message: "maxRetryElapsed cannot be negative"}
+	}
+
+	if tc.Timeout < 0 {
+		return &ValidationError{Field: "Timeout", Message: "timeout cannot be negative"}
+	}
+
+	if tc.IdleTimeout < 0 {
+		return &ValidationError{Field: "IdleTimeout", Message: "idleTimeout cannot be negative"}
+	}
+
+	if tc.CPUTimeLimit < 0 {
+		return &ValidationError{Field: "CPUTimeLimit", Message: "cpuTimeLimit cannot be negative"}
+	}
+
+	if tc.CPUTimeLimit > 0 && !cpuTimeLimitSupported {
+		return &ValidationError{Field: "CPUTimeLimit", Message: "CPU time limits are only supported on Linux"}
+	}
+
+	if tc.Weight < 0 {
+		return &ValidationError{Field: "Weight", Message: "weight cannot be negative"}
+	}
+
+	return nil
+}
+
+// Succeeded reports whether result is a successful execution of tc,
+// according to SuccessWhen or, if it is nil, a zero exit code.
+func (tc *ToolConfig) Succeeded(result *ExecutionResult) bool {
+	if tc.SuccessWhen != nil {
+		return tc.SuccessWhen(result)
+	}
+	return result.ExitCode == 0
+}
+
+// SucceedOnExitCodes returns a ToolConfig.SuccessWhen predicate that treats
+// the given exit codes as success.
+func SucceedOnExitCodes(codes ...int) func(*ExecutionResult) bool {
+	return func(result *ExecutionResult) bool {
+		return slices.Contains(codes, result.ExitCode)
+	}
+}
+
+// validateFallback reports configuration errors in the fallback chain up
+// front, so they do not surface only after the primary command has failed.
+// Commands rejected by a fallback's CommandValidator are reported when the
+// fallback runs.
+func (tc *ToolConfig) validateFallback() error {
+	if tc.Fallback == nil {
+		return nil
+	}
+	var validationErr *ValidationError
+	if err := tc.Fallback.Validate(); errors.As(err, &validationErr) {
+		return &ValidationError{Field: "Fallback." + validationErr.Field, Message: validationErr.Message}
+	}
+	return nil
+}
+
+// validateStdin checks the input settings.
+func (tc *ToolConfig) validateStdin() error {
+	if tc.StdinFile != "" && (tc.Stdin != nil || tc.StdinFactory != nil || tc.StdinProvider != nil) {
+		return &ValidationError{Field: "StdinFile", Message: "stdinFile cannot be combined with Stdin, StdinFactory or StdinProvider"}
+	}
+
+	if tc.StdinProvider != nil && tc.StdinFactory != nil {
+		return &ValidationError{Field: "StdinProvider", Message: "stdinProvider cannot be combined with StdinFactory"}
+	}
+
+	if tc.MaxStdinBytes < 0 {
+		return &ValidationError{Field: "MaxStdinBytes", Message: "maxStdinBytes cannot be negative"}
+	}
+
+	return nil
+}
+
+// validateProcess checks the settings of the child process itself.
+func (tc *ToolConfig) validateProcess() error {
+	if len(tc.ExtraFiles) > 0 && runtime.GOOS == "windows" {
+		return &ValidationError{Field: "ExtraFiles", Message: "extra files are not supported on Windows"}
+	}
+
+	if tc.MinVersion != "" && !minVersionPattern.MatchString(tc.MinVersion) {
+		return &ValidationError{Field: "MinVersion", Message: "minVersion must be a dotted number such as 1.22"}
+	}
+
+	return tc.ProcessAttributes.validate()
+}
+
+// validateOutput checks the output capture and streaming settings.
+func (tc *ToolConfig) validateOutput() error {
+	if tc.FlushInterval < 0 {
+		return &ValidationError{Field: "FlushInterval", Message: "flushInterval cannot be negative"}
+	}
+
+	if tc.MaxStdoutBytes < 0 {
+		return &ValidationError{Field: "MaxStdoutBytes", Message: "maxStdoutBytes cannot be negative"}
+	}
+
+	if tc.MaxStderrBytes < 0 {
+		return &ValidationError{Field: "MaxStderrBytes", Message: "maxStderrBytes cannot be negative"}
+	}
+
+	if tc.MaxOutputBytes < 0 {
+		return &ValidationError{Field: "MaxOutputBytes", Message: "maxOutputBytes cannot be negative"}
+	}
+
+	if tc.TruncateMode < TruncateHead || tc.TruncateMode > TruncateHeadAndTail {
+		return &ValidationError{Field: "TruncateMode", Message: "unknown truncate mode"}
+	}
+
+	if err := tc.OverflowPolicy.validate(tc); err != nil {
+		return err
+	}
+
+	if err := tc.InvalidUTF8.validate(); err != nil {
+		return err
+	}
+
+	if tc.ProgressPattern != nil && tc.ProgressPattern.NumSubexp() < 1 {
+		return &ValidationError{Field: "ProgressPattern", Message: "progressPattern must have a capture group"}
+	}
+
+	if tc.DiscardOutput && tc.IdleTimeout > 0 {
+		return &ValidationError{Field: "DiscardOutput", Message: "discardOutput cannot be combined with IdleTimeout"}
+	}
+
+	if tc.SpoolThreshold < 0 {
+		return &ValidationError{Field: "SpoolThreshold", Message: "spoolThreshold cannot be negative"}
+	}
+
+	return nil
+}
+
+// Clone returns a deep copy of the configuration. Args, Env, Labels,
+// KillPolicy, ExtraFiles, ProcessAttributes, ArgFile, and Fallback are
+// copied so the clone can be mutated without affecting the original; the
+// files in ExtraFiles are shared.
+//
+// Stdin, StdoutWriter, StderrWriter, and the function-valued fields
+// (StdinFactory, StdinProvider, CommandBuilder, CommandValidator) are copied
+// by reference: readers, writers, and functions cannot be duplicated in
+// general, so the clone shares them with the original. In particular, a
+// Stdin reader is still consumed by whichever execution reads it first; use
+// StdinFactory when a config is executed more than once. Redactor, OutputLog,
+// RetryPolicy and ArgValidator are also shared, as they are not modified
+// by execution.
+func (tc ToolConfig) Clone() ToolConfig {
+	clone := tc
+	if tc.Args != nil {
+		clone.Args = slices.Clone(tc.Args)
+	}
+	if tc.Env != nil {
+		clone.Env = maps.Clone(tc.Env)
+	}
+	if tc.Labels != nil {
+		clone.Labels = maps.Clone(tc.Labels)
+	}
+	if tc.KillPolicy != nil {
+		clone.KillPolicy = slices.Clone(tc.KillPolicy)
+	}
+	if tc.ExtraFiles != nil {
+		clone.ExtraFiles = slices.Clone(tc.ExtraFiles)
+	}
+	clone.ProcessAttributes = tc.ProcessAttributes.clone()
+	if tc.ArgFile != nil {
+		argFile := *tc.ArgFile
+		clone.ArgFile = &argFile
+	}
+	if tc.Fallback != nil {
+		fallback := tc.Fallback.Clone()
+		clone.Fallback = &fallback
+	}
+	return clone
+}
+
+// Merge returns a copy of the configuration with the non-zero fields of
+// override applied on top, so a family of related commands can be derived
+// from one base:
+//
+//	lint := base.Merge(cmdexec.ToolConfig{Args: []string{"run"}, Timeout: time.Minute})
+//
+// Env and Labels are merged key by key, with override's values taking
+// precedence; every other non-zero field of override replaces the base's.
+// Because zero values mean "not set", an override cannot clear a field or
+// set a bool back to false. Both inputs are cloned, so the result shares no
+// slices or maps with either.
+func (tc ToolConfig) Merge(override ToolConfig) ToolConfig {
+	merged := tc.Clone()
+	override = override.Clone()
+	dst := reflect.ValueOf(&merged).Elem()
+	src := reflect.ValueOf(override)
+	for i := range src.NumField() {
+		field := src.Field(i)
+		if field.IsZero() || !dst.Field(i).CanSet() {
+			continue
+		}
+		dst.Field(i).Set(field)
+	}
+	if len(tc.Env) > 0 && len(override.Env) > 0 {
+		merged.Env = mergeMaps(tc.Env, override.Env)
+	}
+	if len(tc.Labels) > 0 && len(override.Labels) > 0 {
+		merged.Labels = mergeMaps(tc.Labels, override.Labels)
+	}
+	return merged
+}
+
+// mergeMaps returns a copy of base with the entries of override applied.
+func mergeMaps(base, override map[string]string) map[string]string {
+	result := maps.Clone(base)
+	maps.Copy(result, override)
+	return result
+}
+
 // Error types for different failure scenarios
 
 // ValidationError represents a validation failure in tool configuration.
@@ -140,12 +676,45 @@ func (e *ValidationError) Error() string {
 type TimeoutError struct {
 	Command string
 	Timeout time.Duration
+
+	// Signal is the name of the signal that stopped the process, such as
+	// "SIGKILL": the signal that terminated it according to its wait
+	// status, or else the last KillPolicy signal it exited after. It is
+	// empty if neither is known.
+	Signal string
 }
 
 func (e *TimeoutError) Error() string {
 	return "command '" + e.Command + "' timed out after " + e.Timeout.String()
 }
 
+// IdleTimeoutError represents a command killed because it produced no
+// output for longer than its configured IdleTimeout.
+type IdleTimeoutError struct {
+	Command     string
+	IdleTimeout time.Duration
+
+	// Signal is the name of the signal that stopped the process, as in
+	// TimeoutError.
+	Signal string
+}
+
+func (e *IdleTimeoutError) Error() string {
+	return "command '" + e.Command + "' produced no output for " + e.IdleTimeout.String()
+}
+
+// CPUTimeLimitError represents a command killed because it consumed more
+// CPU time than its configured CPUTimeLimit.
+type CPUTimeLimitError struct {
+	Command string
+	Limit   time.Duration
+	Used    time.Duration
+}
+
+func (e *CPUTimeLimitError) Error() string {
+	return fmt.Sprintf("command '%s' exceeded CPU time limit of %s (used %s)", e.Command, e.Limit, e.Used)
+}
+
 // ExecutableNotFoundError represents a missing executable.
 type ExecutableNotFoundError struct {
 	Command string
@@ -155,6 +724,23 @@ func (e *ExecutableNotFoundError) Error() string {
 	return "executable not found: " + e.Command
 }
 
+// WorkingDirError is returned when the working directory of a command does
+// not exist, is not a directory, or cannot be created or entered. Err is
+// the underlying error, so errors.Is(err, fs.ErrNotExist) and similar
+// checks work.
+type WorkingDirError struct {
+	Dir string
+	Err error
+}
+
+func (e *WorkingDirError) Error() string {
+	return fmt.Sprintf("working directory %s: %v", e.Dir, e.Err)
+}
+
+func (e *WorkingDirError) Unwrap() error {
+	return e.Err
+}
+
 // CommandNotAllowedError is returned when a command fails the CommandValidator check.
 type CommandNotAllowedError struct {
 	Command string
@@ -165,12 +751,12 @@ func (e *CommandNotAllowedError) Error() string {
 	return fmt.Sprintf("command %q not allowed: %s", e.Command, e.Reason)
 }
 
-// OutputLimitError is returned when output exceeds configured MaxStdoutBytes
-// or MaxStderrBytes limits. Note: this error is not returned by Execute itself
-// (which signals truncation via ExecutionResult fields), but is provided for
-// callers who need a typed error for limit violations in custom pipelines.
+// OutputLimitError is returned by Execute when output exceeds MaxStdoutBytes,
+// MaxStderrBytes or MaxOutputBytes and OverflowPolicy is OverflowAbort. With
+// the default policy, truncation is signalled via ExecutionResult fields
+// instead.
 type OutputLimitError struct {
-	Stream string // "stdout" or "stderr"
+	Stream string // "stdout", "stderr", or "output" for MaxOutputBytes
 	Limit  int64
 }
 
@@ -178,6 +764,17 @@ func (e *OutputLimitError) Error() string {
 	return fmt.Sprintf("%s output exceeded limit of %d bytes", e.Stream, e.Limit)
 }
 
+// StdinLimitError is returned when the input of a command exceeds
+// ToolConfig.MaxStdinBytes.
+type StdinLimitError struct {
+	Command string
+	Limit   int64
+}
+
+func (e *StdinLimitError) Error() string {
+	return fmt.Sprintf("stdin of command '%s' exceeded limit of %d bytes", e.Command, e.Limit)
+}
+
 // RetryExhaustedError represents failure after all retry attempts.
 type RetryExhaustedError struct {
 	Command   string
@@ -188,6 +785,30 @@ type RetryExhaustedError struct {
 	// This preserves structured diagnostics (Output, Stderr, ExitCode) that
 	// would otherwise be lost when retries convert results into errors.
 	LastResult *ExecutionResult
+
+	// History summarizes every attempt, in order, so callers can see how
+	// each one failed and not only the last.
+	History []AttemptSummary
+}
+
+// AttemptSummary describes one attempt of an execution with retries.
+type AttemptSummary struct {
+	// Attempt is the attempt number, starting at 1.
+	Attempt int
+
+	// ExitCode is the exit code of the attempt, or -1 if it failed with an
+	// error.
+	ExitCode int
+
+	// Duration is how long the attempt took.
+	Duration time.Duration
+
+	// Stderr holds the last bytes of the attempt's stderr, at most
+	// attemptStderrLimit of them.
+	Stderr string
+
+	// Error is the message of the error the attempt failed with, if any.
+	Error string
 }
 
 func (e *RetryExhaustedError) Error() string {
@@ -200,6 +821,58 @@ func (e *RetryExhaustedError) Unwrap() error {
 	return e.LastError
 }
 
+// BatchError is returned by ConcurrentExecutor batch executions, when
+// enabled with SetBatchError, if any command failed. It unwraps to one
+// *BatchItemError per failed command, so errors.Is and errors.As see every
+// failure.
+type BatchError struct {
+	// Failures lists the failed commands in index order.
+	Failures []*BatchItemError
+
+	// Total is the number of commands in the batch.
+	Total int
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("%d of %d commands failed:\n%v", len(e.Failures), e.Total, errors.Join(e.Unwrap()...))
+}
+
+// Unwrap returns the per-command errors.
+func (e *BatchError) Unwrap() []error {
+	errs := make([]error, len(e.Failures))
+	for i, f := range e.Failures {
+		errs[i] = f
+	}
+	return errs
+}
+
+// FailedIndices returns the indices of the failed commands.
+func (e *BatchError) FailedIndices() []int {
+	indices := make([]int, len(e.Failures))
+	for i, f := range e.Failures {
+		indices[i] = f.Index
+	}
+	return indices
+}
+
+// BatchItemError describes one failed command of a batch. Err is the error
+// returned by Execute, or an *ExitError if the command ran but did not
+// succeed.
+type BatchItemError struct {
+	Index   int
+	Command string
+	Err     error
+}
+
+func (e *BatchItemError) Error() string {
+	return fmt.Sprintf("command %d (%s): %v", e.Index, e.Command, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *BatchItemError) Unwrap() error {
+	return e.Err
+}
+
 // AllowCommands returns a CommandValidator that only allows the specified
 // command names. Any command not in the list will be rejected.
 func AllowCommands(allowed ...string) func(string, []string) error {
@@ -1,6 +1,10 @@
 package cmdexec
 
 import (
+	"maps"
+	"os"
+	"regexp"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -66,6 +70,142 @@ func TestToolConfig_Validate(t *testing.T) {
 			wantErr: true,
 			errMsg:  "timeout cannot be negative",
 		},
+		{
+			name: "negative flush interval",
+			config: ToolConfig{
+				Command:       "go",
+				FlushInterval: -1 * time.Second,
+			},
+			wantErr: true,
+			errMsg:  "flushInterval cannot be negative",
+		},
+		{
+			name: "negative idle timeout",
+			config: ToolConfig{
+				Command:     "go",
+				IdleTimeout: -1 * time.Second,
+			},
+			wantErr: true,
+			errMsg:  "idleTimeout cannot be negative",
+		},
+		{
+			name: "negative cpu time limit",
+			config: ToolConfig{
+				Command:      "go",
+				CPUTimeLimit: -1 * time.Second,
+			},
+			wantErr: true,
+			errMsg:  "cpuTimeLimit cannot be negative",
+		},
+		{
+			name: "negative spool threshold",
+			config: ToolConfig{
+				Command:        "go",
+				SpoolThreshold: -1,
+			},
+			wantErr: true,
+			errMsg:  "spoolThreshold cannot be negative",
+		},
+		{
+			name: "unknown truncate mode",
+			config: ToolConfig{
+				Command:      "go",
+				TruncateMode: TruncateMode(99),
+			},
+			wantErr: true,
+			errMsg:  "unknown truncate mode",
+		},
+		{
+			name: "unknown invalid UTF-8 policy",
+			config: ToolConfig{
+				Command:     "go",
+				InvalidUTF8: InvalidUTF8Policy(7),
+			},
+			wantErr: true,
+			errMsg:  "unknown invalid UTF-8 policy",
+		},
+		{
+			name: "progress pattern without capture group",
+			config: ToolConfig{
+				Command:         "go",
+				ProgressPattern: regexp.MustCompile(`\d+%`),
+			},
+			wantErr: true,
+			errMsg:  "progressPattern must have a capture group",
+		},
+		{
+			name: "negative max output bytes",
+			config: ToolConfig{
+				Command:        "go",
+				MaxOutputBytes: -1,
+			},
+			wantErr: true,
+			errMsg:  "maxOutputBytes cannot be negative",
+		},
+		{
+			name: "unknown overflow policy",
+			config: ToolConfig{
+				Command:        "go",
+				OverflowPolicy: OverflowPolicy(99),
+			},
+			wantErr: true,
+			errMsg:  "unknown overflow policy",
+		},
+		{
+			name: "overflow block without timeout",
+			config: ToolConfig{
+				Command:        "go",
+				OverflowPolicy: OverflowBlock,
+			},
+			wantErr: true,
+			errMsg:  "overflowBlock requires Timeout or IdleTimeout",
+		},
+		{
+			name: "overflow block with idle timeout",
+			config: ToolConfig{
+				Command:        "go",
+				OverflowPolicy: OverflowBlock,
+				IdleTimeout:    time.Second,
+			},
+			wantErr: false,
+		},
+		{
+			name: "discard output with idle timeout",
+			config: ToolConfig{
+				Command:       "go",
+				DiscardOutput: true,
+				IdleTimeout:   time.Second,
+			},
+			wantErr: true,
+			errMsg:  "discardOutput cannot be combined with IdleTimeout",
+		},
+		{
+			name: "negative max retry elapsed",
+			config: ToolConfig{
+				Command:         "go",
+				MaxRetryElapsed: -1,
+			},
+			wantErr: true,
+			errMsg:  "maxRetryElapsed cannot be negative",
+		},
+		{
+			name: "invalid fallback",
+			config: ToolConfig{
+				Command:  "go",
+				Fallback: &ToolConfig{Command: "go", MaxRetries: -1},
+			},
+			wantErr: true,
+			errMsg:  "validation error in field 'Fallback.MaxRetries': maxRetries cannot be negative",
+		},
+		{
+			name: "negative weight",
+			config: ToolConfig{
+				Command: "bazel",
+				Weight:  -1,
+			},
+			wantErr: true,
+			errMsg:  "weight cannot be negative",
+		},
 	}
 
 	for _, tt := range tests {
@@ -174,3 +314,96 @@ func TestToolConfig_Fields(t *testing.T) {
 		t.Errorf("Env[GOOS] = %v, want linux", config.Env["GOOS"])
 	}
 }
+
+func TestToolConfig_Clone(t *testing.T) {
+	var out strings.Builder
+	orig := ToolConfig{
+		Command:      "go",
+		Args:         []string{"test", "./..."},
+		Env:          map[string]string{"CI": "1"},
+		StdoutWriter: &out,
+		Fallback:     &ToolConfig{Command: "go1.24", Args: []string{"test"}},
+	}
+
+	clone := orig.Clone()
+	clone.Args[0] = "build"
+	clone.Env["CI"] = "0"
+	clone.Fallback.Args[0] = "vet"
+
+	if orig.Args[0] != "test" {
+		t.Errorf("orig.Args[0] = %q, want %q", orig.Args[0], "test")
+	}
+	if orig.Env["CI"] != "1" {
+		t.Errorf("orig.Env[CI] = %q, want %q", orig.Env["CI"], "1")
+	}
+	if orig.Fallback.Args[0] != "test" {
+		t.Errorf("orig.Fallback.Args[0] = %q, want %q", orig.Fallback.Args[0], "test")
+	}
+	if clone.StdoutWriter != orig.StdoutWriter {
+		t.Error("StdoutWriter should be shared by reference")
+	}
+
+	empty := ToolConfig{Command: "ls"}.Clone()
+	if empty.Args != nil || empty.Env != nil {
+		t.Error("Clone() should preserve nil Args and Env")
+	}
+}
+
+func TestCPUTimeLimitError(t *testing.T) {
+	err := &CPUTimeLimitError{Command: "spin", Limit: 2 * time.Second, Used: 2100 * time.Millisecond}
+	want := "command 'spin' exceeded CPU time limit of 2s (used 2.1s)"
+	if err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+}
+
+func TestToolConfig_CloneExtraFiles(t *testing.T) {
+	orig := ToolConfig{Command: "sh", ExtraFiles: []*os.File{os.Stdin}}
+	clone := orig.Clone()
+	clone.ExtraFiles[0] = os.Stdout
+	if orig.ExtraFiles[0] != os.Stdin {
+		t.Error("Clone() should copy the ExtraFiles slice")
+	}
+}
+
+func TestToolConfig_Merge(t *testing.T) {
+	base := ToolConfig{
+		Command:       "golangci-lint",
+		Args:          []string{"run"},
+		Env:           map[string]string{"CI": "1", "GOFLAGS": "-mod=mod"},
+		Timeout:       time.Minute,
+		CombineOutput: true,
+	}
+	merged := base.Merge(ToolConfig{
+		Args:       []string{"run", "--fix"},
+		Env:        map[string]string{"GOFLAGS": "-mod=vendor"},
+		WorkingDir: "/src",
+	})
+
+	if merged.Command != "golangci-lint" || merged.Timeout != time.Minute || !merged.CombineOutput {
+		t.Errorf("Merge() lost base fields: %+v", merged)
+	}
+	if !slices.Equal(merged.Args, []string{"run", "--fix"}) || merged.WorkingDir != "/src" {
+		t.Errorf("Merge() args %q dir %q, want the overrides", merged.Args, merged.WorkingDir)
+	}
+	wantEnv := map[string]string{"CI": "1", "GOFLAGS": "-mod=vendor"}
+	if !maps.Equal(merged.Env, wantEnv) {
+		t.Errorf("Merge() env = %v, want %v", merged.Env, wantEnv)
+	}
+
+	merged.Env["CI"] = "0"
+	merged.Args[0] = "lint"
+	if base.Env["CI"] != "1" || base.Args[0] != "run" {
+		t.Error("Merge() result should not alias the base")
+	}
+
+	labeled := ToolConfig{Labels: map[string]string{"team": "a", "env": "ci"}}.Merge(ToolConfig{Labels: map[string]string{"env": "prod"}})
+	if !maps.Equal(labeled.Labels, map[string]string{"team": "a", "env": "prod"}) {
+		t.Errorf("Merge() labels = %v, want them merged", labeled.Labels)
+	}
+
+	onlyOverrideEnv := ToolConfig{Command: "ls"}.Merge(ToolConfig{Env: map[string]string{"A": "1"}})
+	if onlyOverrideEnv.Env["A"] != "1" {
+		t.Errorf("Merge() env = %v, want the override's", onlyOverrideEnv.Env)
+	}
+}
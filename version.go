@@ -0,0 +1,207 @@
+package cmdexec
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// versionCheckTimeout bounds the execution that reports a tool's version.
+const versionCheckTimeout = 10 * time.Second
+
+// defaultVersionPattern matches dotted version numbers such as "1.22.3".
+var defaultVersionPattern = regexp.MustCompile(`\d+(?:\.\d+)+`)
+
+// minVersionPattern matches the accepted forms of ToolConfig.MinVersion.
+var minVersionPattern = regexp.MustCompile(`^v?\d+(\.\d+)*$`)
+
+// VersionCheck describes how to find out the version of a tool.
+type VersionCheck struct {
+	// Args make the tool print its version. If nil, "--version" is used.
+	Args []string
+
+	// Pattern extracts the version from the tool's stdout and stderr: its
+	// first capture group if it has one, or else the whole match. If nil,
+	// the first dotted number such as "1.22.3" is used.
+	Pattern *regexp.Regexp
+}
+
+// VersionTooOldError is returned when the installed version of a tool is
+// older than ToolConfig.MinVersion.
+type VersionTooOldError struct {
+	Command    string
+	Version    string
+	MinVersion string
+}
+
+func (e *VersionTooOldError) Error() string {
+	return fmt.Sprintf("%s version %s is older than the required %s", e.Command, e.Version, e.MinVersion)
+}
+
+// ToolVersion runs the version check of cfg with executor and returns the
+// version the tool reports. The check runs cfg.Command with the
+// VersionCheck arguments in the same working directory and environment.
+func ToolVersion(ctx context.Context, executor Executor, cfg ToolConfig) (string, error) {
+	probe := versionProbe(cfg)
+	result, err := executor.Execute(ctx, probe)
+	if err != nil {
+		return "", fmt.Errorf("failed to determine version of %s: %w", cfg.Command, err)
+	}
+	pattern := defaultVersionPattern
+	if cfg.VersionCheck != nil && cfg.VersionCheck.Pattern != nil {
+		pattern = cfg.VersionCheck.Pattern
+	}
+	match := pattern.FindStringSubmatch(result.Output + "\n" + result.Stderr)
+	if match == nil {
+		return "", fmt.Errorf("no version found in the output of %s", CanonicalString(probe))
+	}
+	if len(match) > 1 {
+		return match[1], nil
+	}
+	return match[0], nil
+}
+
+// CheckMinVersion returns a *VersionTooOldError if the tool of cfg, run with
+// executor, reports a version older than cfg.MinVersion. It returns nil if
+// MinVersion is empty, and the error of ToolVersion if the version cannot
+// be determined, for example an *ExecutableNotFoundError.
+func CheckMinVersion(ctx context.Context, executor Executor, cfg ToolConfig) error {
+	if cfg.MinVersion == "" {
+		return nil
+	}
+	version, err := ToolVersion(ctx, executor, cfg)
+	if err != nil {
+		return err
+	}
+	return checkVersion(cfg, version)
+}
+
+// checkVersion compares version with the MinVersion of cfg.
+func checkVersion(cfg ToolConfig, version string) error {
+	if compareVersions(version, cfg.MinVersion) < 0 {
+		return &VersionTooOldError{Command: cfg.Command, Version: version, MinVersion: cfg.MinVersion}
+	}
+	return nil
+}
+
+// versionProbe returns the configuration that makes the tool of cfg print
+// its version.
+func versionProbe(cfg ToolConfig) ToolConfig {
+	args := []string{"--version"}
+	if cfg.VersionCheck != nil && cfg.VersionCheck.Args != nil {
+		args = slices.Clone(cfg.VersionCheck.Args)
+	}
+	return ToolConfig{
+		Command:        cfg.Command,
+		Args:           args,
+		WorkingDir:     cfg.WorkingDir,
+		Env:            cfg.Env,
+		CommandBuilder: cfg.CommandBuilder,
+		Timeout:        versionCheckTimeout,
+	}
+}
+
+// versionKey identifies a version check. The CommandBuilder is part of
+// the key itself, so differently configured builders of the same type do
+// not share a check.
+type versionKey struct {
+	check   string
+	builder CommandBuilder
+}
+
+// versionCacheKey returns the key of the version check of cfg, or false if
+// the check cannot be cached because its CommandBuilder is not comparable,
+// as with a function.
+func versionCacheKey(cfg ToolConfig) (versionKey, bool) {
+	if cfg.CommandBuilder != nil && !reflect.TypeOf(cfg.CommandBuilder).Comparable() {
+		return versionKey{}, false
+	}
+	pattern := defaultVersionPattern
+	if cfg.VersionCheck != nil && cfg.VersionCheck.Pattern != nil {
+		pattern = cfg.VersionCheck.Pattern
+	}
+	probe := versionProbe(cfg)
+	check := fmt.Sprintf("%q %q %q %q %q", probe.Command, probe.Args, probe.WorkingDir, probe.Env, pattern)
+	return versionKey{check: check, builder: cfg.CommandBuilder}, true
+}
+
+// versionCache runs each distinct version check once. Concurrent checks
+// with the same key share a single run; failed checks are not cached, so
+// the next execution tries again.
+type versionCache struct {
+	mu      sync.Mutex
+	entries map[versionKey]*versionEntry
+}
+
+// versionEntry is one cached version check.
+type versionEntry struct {
+	get func() (string, error)
+}
+
+func newVersionCache() *versionCache {
+	return &versionCache{entries: make(map[versionKey]*versionEntry)}
+}
+
+// version returns the version for key, calling check if it has not been
+// found yet. It is safe to call on a nil cache, which calls check every
+// time.
+func (c *versionCache) version(key versionKey, check func() (string, error)) (string, error) {
+	if c == nil {
+		return check()
+	}
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	if !ok {
+		entry = &versionEntry{get: sync.OnceValues(check)}
+		c.entries[key] = entry
+	}
+	c.mu.Unlock()
+
+	version, err := entry.get()
+	if err != nil {
+		c.mu.Lock()
+		if c.entries[key] == entry {
+			delete(c.entries, key)
+		}
+		c.mu.Unlock()
+	}
+	return version, err
+}
+
+// compareVersions compares dotted versions numerically, component by
+// component, returning -1, 0 or 1. A leading "v" is ignored, missing
+// components count as zero, and a component's non-numeric suffix, as in
+// "3-rc1", is ignored.
+func compareVersions(a, b string) int {
+	as := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	bs := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := range max(len(as), len(bs)) {
+		if c := cmp.Compare(versionComponent(as, i), versionComponent(bs, i)); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// versionComponent returns the leading number of parts[i], or zero.
+func versionComponent(parts []string, i int) int {
+	if i >= len(parts) {
+		return 0
+	}
+	end := strings.IndexFunc(parts[i], func(r rune) bool { return r < '0' || r > '9' })
+	if end < 0 {
+		end = len(parts[i])
+	}
+	n, err := strconv.Atoi(parts[i][:end])
+	if err != nil {
+		return 0
+	}
+	return n
+}
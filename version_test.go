@@ -0,0 +1,140 @@
+package cmdexec
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.22.3", "1.22", 1},
+		{"1.9", "1.10", -1},
+		{"v2.0", "2", 0},
+		{"3.1.0-rc1", "3.1", 0},
+		{"0.9.9", "1.0.0", -1},
+	}
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestCheckMinVersion(t *testing.T) {
+	mock := NewMockExecutor()
+	mock.ExpectCommandWithArgs("go", "version").WillSucceed("go version go1.21.5 linux/amd64\n", 0).Build()
+	mock.ExpectCommandWithArgs("tool", "--version").WillSucceed("", 0).Build()
+
+	cfg := ToolConfig{
+		Command:      "go",
+		MinVersion:   "1.22",
+		VersionCheck: &VersionCheck{Args: []string{"version"}, Pattern: regexp.MustCompile(`go(\d+\.\d+(\.\d+)?)`)},
+	}
+	var tooOld *VersionTooOldError
+	if err := CheckMinVersion(context.Background(), mock, cfg); !errors.As(err, &tooOld) || tooOld.Version != "1.21.5" {
+		t.Errorf("CheckMinVersion() error = %v, want *VersionTooOldError for 1.21.5", err)
+	}
+	cfg.MinVersion = "1.21"
+	if err := CheckMinVersion(context.Background(), mock, cfg); err != nil {
+		t.Errorf("CheckMinVersion() error = %v, want nil", err)
+	}
+	if err := CheckMinVersion(context.Background(), mock, ToolConfig{Command: "tool", MinVersion: "1.0"}); err == nil {
+		t.Error("CheckMinVersion() without a version in the output error = nil")
+	}
+	if err := CheckMinVersion(context.Background(), mock, ToolConfig{Command: "tool"}); err != nil {
+		t.Errorf("CheckMinVersion() without MinVersion error = %v", err)
+	}
+}
+
+// fixedVersionBuilder runs a shell command reporting version, whatever the
+// command.
+type fixedVersionBuilder struct {
+	version string
+}
+
+func (b fixedVersionBuilder) Build(ctx context.Context, _ string, _ []string) *exec.Cmd {
+	return exec.CommandContext(ctx, "echo", "tool", b.version)
+}
+
+func TestBasicExecutor_Execute_MinVersionCacheKey(t *testing.T) {
+	executor := NewBasicExecutor()
+	ctx := context.Background()
+
+	// Builders of the same type but configured differently are checked
+	// separately.
+	var tooOld *VersionTooOldError
+	if _, err := executor.Execute(ctx, ToolConfig{Command: "tool", MinVersion: "2.0", CommandBuilder: fixedVersionBuilder{"1.0"}}); !errors.As(err, &tooOld) {
+		t.Errorf("Execute() with version 1.0 error = %v, want *VersionTooOldError", err)
+	}
+	if _, err := executor.Execute(ctx, ToolConfig{Command: "tool", MinVersion: "2.0", CommandBuilder: fixedVersionBuilder{"3.0"}}); err != nil {
+		t.Errorf("Execute() with version 3.0 error = %v, want the check to pass", err)
+	}
+}
+
+func TestBasicExecutor_Execute_MinVersionConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	counter := filepath.Join(dir, "count")
+	script := filepath.Join(dir, "tool")
+	body := "#!/bin/sh\nif [ \"$1\" = --version ]; then echo x >> " + counter + "; sleep 0.2; echo 'tool 2.3.1'; fi\n"
+	if err := os.WriteFile(script, []byte(body), 0o700); err != nil { //nolint:gosec // the script must be executable
+		t.Fatal(err)
+	}
+	executor := NewBasicExecutor()
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := executor.Execute(context.Background(), ToolConfig{Command: script, MinVersion: "2.0"}); err != nil {
+				t.Errorf("Execute() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if data, err := os.ReadFile(counter); err != nil || string(data) != "x\n" {
+		t.Errorf("version checks = %q (err %v), want a single check shared by concurrent executions", data, err)
+	}
+}
+
+func TestBasicExecutor_Execute_MinVersion(t *testing.T) {
+	dir := t.TempDir()
+	counter := filepath.Join(dir, "count")
+	script := filepath.Join(dir, "tool")
+	// The tool reports version 2.3.1 and records each version check.
+	body := "#!/bin/sh\nif [ \"$1\" = --version ]; then echo x >> " + counter + "; echo 'tool 2.3.1'; else echo ran; fi\n"
+	if err := os.WriteFile(script, []byte(body), 0o700); err != nil { //nolint:gosec // the script must be executable
+		t.Fatal(err)
+	}
+	executor := NewBasicExecutor()
+	ctx := context.Background()
+
+	var tooOld *VersionTooOldError
+	_, err := executor.Execute(ctx, ToolConfig{Command: script, MinVersion: "2.4"})
+	if !errors.As(err, &tooOld) || tooOld.Version != "2.3.1" || tooOld.MinVersion != "2.4" {
+		t.Fatalf("Execute() error = %v, want *VersionTooOldError", err)
+	}
+	for range 2 {
+		result, err := executor.Execute(ctx, ToolConfig{Command: script, MinVersion: "v2.3"})
+		if err != nil || result.Output != "ran\n" {
+			t.Fatalf("Execute() = %v, %v, want the tool to run", result, err)
+		}
+	}
+	if data, err := os.ReadFile(counter); err != nil || string(data) != "x\n" {
+		t.Errorf("version checks = %q (err %v), want one cached check", data, err)
+	}
+
+	var validationErr *ValidationError
+	if _, err := executor.Execute(ctx, ToolConfig{Command: script, MinVersion: "latest"}); !errors.As(err, &validationErr) {
+		t.Errorf("Execute() with a malformed MinVersion error = %v, want *ValidationError", err)
+	}
+}